@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/WhiCu/school-museum/db"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/config"
+	"github.com/WhiCu/school-museum/internal/egress"
+	appserver "github.com/WhiCu/school-museum/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var checkLinksCmd = &cobra.Command{
+	Use:   "check-links",
+	Short: "Check the image links of news and exhibits and list the broken ones",
+	Long: `Checks every external image link of news and exhibits, stores the results
+shown in the admin and prints the broken links. The server runs the same
+check every media.link_check_interval.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cfg.Storage.Driver == config.DriverMemory {
+			return errors.New("check-links needs the postgres storage driver: the memory storage lives in the server process")
+		}
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		egressOpts, err := appserver.EgressOptions(cfg.Egress)
+		if err != nil {
+			return err
+		}
+		database, err := db.NewDB(ctx, cfg.Storage.DSN())
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		links := appserver.NewLinkChecker(
+			storage.NewNewsStorage(database),
+			storage.NewExhibitStorage(database),
+			storage.NewLinkCheckStorage(database),
+			egress.NewClient(egressOpts),
+			cfg.Media,
+			log.WithGroup("link-check"))
+
+		sum, err := links.CheckAll(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("checked %d links, %d broken\n", sum.Links, sum.Broken())
+
+		broken, err := links.Broken(ctx)
+		if err != nil || len(broken) == 0 {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TYPE\tID\tTITLE\tSTATUS\tERROR\tURL")
+		for _, g := range broken {
+			for _, l := range g.Links {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", g.OwnerType, g.OwnerID, g.Title, l.Status, l.Error, l.URL)
+			}
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkLinksCmd)
+}
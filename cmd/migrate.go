@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/WhiCu/school-museum/db"
+	"github.com/WhiCu/school-museum/db/migrate"
+	"github.com/spf13/cobra"
+)
+
+// migrateCmd groups the schema migration commands.
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage database schema migrations",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, closeDB, err := newMigrator(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		applied, err := m.Up(cmd.Context())
+		for _, mg := range applied {
+			fmt.Printf("applied  %04d_%s\n", mg.Version, mg.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+		return nil
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [steps]",
+	Short: "Roll back the last applied migrations (1 by default)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		steps := 1
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid steps %q: must be a positive integer", args[0])
+			}
+			steps = n
+		}
+
+		m, closeDB, err := newMigrator(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		reverted, err := m.Down(cmd.Context(), steps)
+		for _, mg := range reverted {
+			fmt.Printf("reverted %04d_%s\n", mg.Version, mg.Name)
+		}
+		return err
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, closeDB, err := newMigrator(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		statuses, err := m.Status(cmd.Context())
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, st := range statuses {
+			state, at := "pending", "-"
+			if st.Applied {
+				state, at = "applied", st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", st.Version, st.Name, state, at)
+		}
+		w.Flush()
+		return err
+	},
+}
+
+// newMigrator connects to the configured database and returns a migrator for it.
+func newMigrator(ctx context.Context) (*migrate.Migrator, func(), error) {
+	database, err := db.NewDB(ctx, cfg.Storage.DSN())
+	if err != nil {
+		return nil, nil, err
+	}
+	return migrate.NewMigrator(database, log.WithGroup("migrate")), func() { _ = database.Close() }, nil
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
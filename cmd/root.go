@@ -60,5 +60,5 @@ func Execute() {
 func init() {
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
-	rootCmd.Flags().StringP("filetype", "t", "yaml", "")
+	rootCmd.PersistentFlags().StringP("filetype", "t", "yaml", "")
 }
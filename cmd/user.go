@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/WhiCu/school-museum/db"
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/auth"
+	"github.com/WhiCu/school-museum/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// userCmd groups the admin user commands.
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage admin users",
+	Long: `Manages the users of the admin. Passwords are read from the standard
+input, one line each, so they stay out of the shell history.`,
+}
+
+var userRole string
+
+var userAddCmd = &cobra.Command{
+	Use:   "add <login>",
+	Short: "Add an admin user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		users, closeDB, err := newUsers(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		u, err := users.Add(cmd.Context(), args[0], password, model.Role(userRole))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("added %s (%s)\n", u.Login, u.Role)
+		return nil
+	},
+}
+
+var userPasswdCmd = &cobra.Command{
+	Use:   "passwd <login>",
+	Short: "Set the password of an admin user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		users, closeDB, err := newUsers(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		u, err := users.GetByLogin(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("user %q: %w", args[0], err)
+		}
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		if err := users.SetPassword(cmd.Context(), u.ID, password); err != nil {
+			return err
+		}
+		fmt.Printf("password of %s changed\n", u.Login)
+		return nil
+	},
+}
+
+var userDisableCmd = &cobra.Command{
+	Use:   "disable <login>",
+	Short: "Disable an admin user, who can no longer sign in",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setDisabled(cmd.Context(), args[0], true)
+	},
+}
+
+var userEnableCmd = &cobra.Command{
+	Use:   "enable <login>",
+	Short: "Enable a disabled admin user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setDisabled(cmd.Context(), args[0], false)
+	},
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the admin users",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		users, closeDB, err := newUsers(cmd.Context())
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+
+		list, err := users.List(cmd.Context())
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "LOGIN\tROLE\tSTATUS\tCREATED AT")
+		for _, u := range list {
+			status := "active"
+			if u.Disabled {
+				status = "disabled"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", u.Login, u.Role, status, u.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+		return w.Flush()
+	},
+}
+
+func setDisabled(ctx context.Context, login string, disabled bool) error {
+	users, closeDB, err := newUsers(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	u, err := users.GetByLogin(ctx, login)
+	if err != nil {
+		return fmt.Errorf("user %q: %w", login, err)
+	}
+	if u, err = users.Update(ctx, u.ID, u.Role, disabled); err != nil {
+		return err
+	}
+	state := "enabled"
+	if u.Disabled {
+		state = "disabled"
+	}
+	fmt.Printf("%s %s\n", u.Login, state)
+	return nil
+}
+
+// newUsers connects to the configured database and returns its admin users.
+func newUsers(ctx context.Context) (*auth.Users, func(), error) {
+	if cfg.Storage.Driver == config.DriverMemory {
+		return nil, nil, errors.New("user needs the postgres storage driver: the memory storage lives in the server process")
+	}
+	database, err := db.NewDB(ctx, cfg.Storage.DSN())
+	if err != nil {
+		return nil, nil, err
+	}
+	users := auth.NewUsers(storage.NewAdminUserStorage(database), storage.NewAdminSessionStorage(database), storage.NewTxManager(database), nil, log.WithGroup("auth"))
+	return users, func() { _ = database.Close() }, nil
+}
+
+// readPassword reads a password line from the standard input.
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("empty password")
+	}
+	return password, nil
+}
+
+func init() {
+	userAddCmd.Flags().StringVar(&userRole, "role", string(model.RoleEditor), "role of the user: owner, editor or viewer")
+	userCmd.AddCommand(userAddCmd, userPasswdCmd, userDisableCmd, userEnableCmd, userListCmd)
+	rootCmd.AddCommand(userCmd)
+}
@@ -0,0 +1,88 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Initial schema. Statements use IF NOT EXISTS so that databases created by
+// the pre-migration bootstrap code are adopted without changes.
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "initial",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`,
+				`CREATE TABLE IF NOT EXISTS news (
+					id         uuid NOT NULL DEFAULT uuid_generate_v4(),
+					title      text,
+					content    text,
+					image_urls text[] DEFAULT '{}',
+					created_at timestamptz NOT NULL DEFAULT current_timestamp,
+					updated_at timestamptz NOT NULL DEFAULT current_timestamp,
+					deleted_at timestamptz,
+					PRIMARY KEY (id)
+				)`,
+				`CREATE UNIQUE INDEX IF NOT EXISTS news_id_idx ON news (id)`,
+				`CREATE TABLE IF NOT EXISTS exhibitions (
+					id          uuid NOT NULL DEFAULT uuid_generate_v4(),
+					title       varchar,
+					description varchar,
+					created_at  timestamptz NOT NULL DEFAULT current_timestamp,
+					updated_at  timestamptz NOT NULL DEFAULT current_timestamp,
+					deleted_at  timestamptz,
+					PRIMARY KEY (id)
+				)`,
+				`CREATE UNIQUE INDEX IF NOT EXISTS exhibition_id_idx ON exhibitions (id)`,
+				`CREATE TABLE IF NOT EXISTS exhibits (
+					id            uuid NOT NULL DEFAULT uuid_generate_v4(),
+					exhibition_id uuid,
+					title         varchar,
+					description   varchar,
+					image_urls    text[] DEFAULT '{}',
+					created_at    timestamptz NOT NULL DEFAULT current_timestamp,
+					updated_at    timestamptz NOT NULL DEFAULT current_timestamp,
+					deleted_at    timestamptz,
+					PRIMARY KEY (id)
+				)`,
+				`CREATE UNIQUE INDEX IF NOT EXISTS exhibit_id_idx ON exhibits (id)`,
+				`CREATE TABLE IF NOT EXISTS visitors (
+					id             bigserial NOT NULL,
+					ip             text NOT NULL,
+					user_agent     text,
+					page           text,
+					referrer       text,
+					screen_width   bigint DEFAULT 0,
+					screen_height  bigint DEFAULT 0,
+					language       text,
+					visit_count    bigint NOT NULL DEFAULT 1,
+					first_visit_at timestamptz NOT NULL DEFAULT current_timestamp,
+					last_visit_at  timestamptz NOT NULL DEFAULT current_timestamp,
+					PRIMARY KEY (id),
+					UNIQUE (ip)
+				)`,
+				`CREATE UNIQUE INDEX IF NOT EXISTS visitors_ip_idx ON visitors (ip)`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`DROP TABLE IF EXISTS visitors`,
+				`DROP TABLE IF EXISTS exhibits`,
+				`DROP TABLE IF EXISTS exhibitions`,
+				`DROP TABLE IF EXISTS news`,
+			)
+		},
+	})
+}
+
+// execAll runs the statements in order, stopping at the first error.
+func execAll(ctx context.Context, tx bun.Tx, queries ...string) error {
+	for _, q := range queries {
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Replaces the single image_url column of news and exhibits with the
+// image_urls array, carrying over existing values.
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "image_urls",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			for _, table := range []string{"news", "exhibits"} {
+				err := execAll(ctx, tx,
+					"ALTER TABLE "+table+" ADD COLUMN IF NOT EXISTS image_urls text[] DEFAULT '{}'",
+				)
+				if err != nil {
+					return err
+				}
+
+				legacy, err := columnExists(ctx, tx, table, "image_url")
+				if err != nil {
+					return err
+				}
+				if !legacy {
+					continue
+				}
+
+				err = execAll(ctx, tx,
+					"UPDATE "+table+" SET image_urls = ARRAY[image_url] WHERE image_url IS NOT NULL AND image_url != '' AND (image_urls IS NULL OR image_urls = '{}')",
+					"ALTER TABLE "+table+" DROP COLUMN image_url",
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			for _, table := range []string{"news", "exhibits"} {
+				err := execAll(ctx, tx,
+					"ALTER TABLE "+table+" ADD COLUMN IF NOT EXISTS image_url text",
+					"UPDATE "+table+" SET image_url = image_urls[1] WHERE cardinality(image_urls) > 0",
+					"ALTER TABLE "+table+" DROP COLUMN IF EXISTS image_urls",
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// columnExists reports whether table has the given column in the current schema.
+func columnExists(ctx context.Context, tx bun.Tx, table, column string) (bool, error) {
+	var exists bool
+	err := tx.NewRaw(
+		"SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?)",
+		table, column,
+	).Scan(ctx, &exists)
+	return exists, err
+}
@@ -0,0 +1,25 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds the preview exhibit reference to exhibitions.
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "exhibition_preview",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				"ALTER TABLE exhibitions ADD COLUMN IF NOT EXISTS preview_exhibit_id uuid",
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				"ALTER TABLE exhibitions DROP COLUMN IF EXISTS preview_exhibit_id",
+			)
+		},
+	})
+}
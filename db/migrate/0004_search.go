@@ -0,0 +1,45 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds generated tsvector columns with Russian morphology and GIN indexes
+// for full-text search. Titles weigh more than the body text.
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "search",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`ALTER TABLE news ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+					setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+					setweight(to_tsvector('russian', coalesce(content, '')), 'B')
+				) STORED`,
+				`CREATE INDEX IF NOT EXISTS news_search_idx ON news USING GIN (search)`,
+				`ALTER TABLE exhibitions ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+					setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+					setweight(to_tsvector('russian', coalesce(description, '')), 'B')
+				) STORED`,
+				`CREATE INDEX IF NOT EXISTS exhibitions_search_idx ON exhibitions USING GIN (search)`,
+				`ALTER TABLE exhibits ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+					setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+					setweight(to_tsvector('russian', coalesce(description, '')), 'B')
+				) STORED`,
+				`CREATE INDEX IF NOT EXISTS exhibits_search_idx ON exhibits USING GIN (search)`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`DROP INDEX IF EXISTS exhibits_search_idx`,
+				`ALTER TABLE exhibits DROP COLUMN IF EXISTS search`,
+				`DROP INDEX IF EXISTS exhibitions_search_idx`,
+				`ALTER TABLE exhibitions DROP COLUMN IF EXISTS search`,
+				`DROP INDEX IF EXISTS news_search_idx`,
+				`ALTER TABLE news DROP COLUMN IF EXISTS search`,
+			)
+		},
+	})
+}
@@ -0,0 +1,36 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds the append-only revision history of content edited through the admin API.
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "revisions",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`CREATE TABLE IF NOT EXISTS revisions (
+					id          BIGSERIAL PRIMARY KEY,
+					entity_type TEXT NOT NULL,
+					entity_id   UUID NOT NULL,
+					rev         INTEGER NOT NULL,
+					action      TEXT NOT NULL,
+					author      TEXT,
+					snapshot    JSONB,
+					changes     JSONB,
+					created_at  TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+					UNIQUE (entity_type, entity_id, rev)
+				)`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`DROP TABLE IF EXISTS revisions`,
+			)
+		},
+	})
+}
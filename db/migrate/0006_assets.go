@@ -0,0 +1,33 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds the records of files uploaded to the local media storage.
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "assets",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`CREATE TABLE IF NOT EXISTS assets (
+					hash       TEXT PRIMARY KEY,
+					url        TEXT NOT NULL,
+					mime_type  TEXT NOT NULL,
+					size       BIGINT NOT NULL,
+					name       TEXT NOT NULL DEFAULT '',
+					created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp
+				)`,
+				`CREATE INDEX IF NOT EXISTS assets_created_at_idx ON assets (created_at DESC)`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`DROP TABLE IF EXISTS assets`,
+			)
+		},
+	})
+}
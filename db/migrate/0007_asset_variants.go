@@ -0,0 +1,32 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds image dimensions and resized variants to assets.
+// Variants stay NULL until the variant worker has processed the asset.
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "asset_variants",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`ALTER TABLE assets ADD COLUMN IF NOT EXISTS width INTEGER NOT NULL DEFAULT 0`,
+				`ALTER TABLE assets ADD COLUMN IF NOT EXISTS height INTEGER NOT NULL DEFAULT 0`,
+				`ALTER TABLE assets ADD COLUMN IF NOT EXISTS variants JSONB`,
+				`CREATE INDEX IF NOT EXISTS assets_url_idx ON assets (url)`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`DROP INDEX IF EXISTS assets_url_idx`,
+				`ALTER TABLE assets DROP COLUMN IF EXISTS variants`,
+				`ALTER TABLE assets DROP COLUMN IF EXISTS height`,
+				`ALTER TABLE assets DROP COLUMN IF EXISTS width`,
+			)
+		},
+	})
+}
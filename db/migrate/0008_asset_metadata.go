@@ -0,0 +1,25 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds the metadata extracted from uploaded images to assets.
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "asset_metadata",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`ALTER TABLE assets ADD COLUMN IF NOT EXISTS metadata JSONB`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`ALTER TABLE assets DROP COLUMN IF EXISTS metadata`,
+			)
+		},
+	})
+}
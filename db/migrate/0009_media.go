@@ -0,0 +1,51 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds the media library and the attachments of its items to news and exhibits.
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "media",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`CREATE TABLE IF NOT EXISTS media (
+					id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					url        TEXT NOT NULL,
+					caption    TEXT NOT NULL DEFAULT '',
+					alt        TEXT NOT NULL DEFAULT '',
+					credit     TEXT NOT NULL DEFAULT '',
+					license    TEXT NOT NULL DEFAULT '',
+					created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+					updated_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp
+				)`,
+				`CREATE INDEX IF NOT EXISTS media_created_at_idx ON media (created_at DESC)`,
+				`CREATE TABLE IF NOT EXISTS news_media (
+					news_id  UUID NOT NULL REFERENCES news (id) ON DELETE CASCADE,
+					media_id UUID NOT NULL REFERENCES media (id) ON DELETE CASCADE,
+					position INTEGER NOT NULL,
+					PRIMARY KEY (news_id, media_id)
+				)`,
+				`CREATE INDEX IF NOT EXISTS news_media_media_id_idx ON news_media (media_id)`,
+				`CREATE TABLE IF NOT EXISTS exhibit_media (
+					exhibit_id UUID NOT NULL REFERENCES exhibits (id) ON DELETE CASCADE,
+					media_id   UUID NOT NULL REFERENCES media (id) ON DELETE CASCADE,
+					position   INTEGER NOT NULL,
+					PRIMARY KEY (exhibit_id, media_id)
+				)`,
+				`CREATE INDEX IF NOT EXISTS exhibit_media_media_id_idx ON exhibit_media (media_id)`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`DROP TABLE IF EXISTS exhibit_media`,
+				`DROP TABLE IF EXISTS news_media`,
+				`DROP TABLE IF EXISTS media`,
+			)
+		},
+	})
+}
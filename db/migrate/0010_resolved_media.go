@@ -0,0 +1,35 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds the persistent cache of resolved external media links.
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "resolved_media",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`CREATE TABLE IF NOT EXISTS resolved_media (
+					source_url    TEXT PRIMARY KEY,
+					url           TEXT NOT NULL,
+					type          TEXT NOT NULL,
+					provider      TEXT NOT NULL,
+					title         TEXT NOT NULL DEFAULT '',
+					thumbnail_url TEXT NOT NULL DEFAULT '',
+					resolved_at   TIMESTAMPTZ NOT NULL,
+					expires_at    TIMESTAMPTZ NOT NULL
+				)`,
+				`CREATE INDEX IF NOT EXISTS resolved_media_expires_at_idx ON resolved_media (expires_at)`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`DROP TABLE IF EXISTS resolved_media`,
+			)
+		},
+	})
+}
@@ -0,0 +1,37 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds the jobs importing external images into the local media storage.
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "import_jobs",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`CREATE TABLE IF NOT EXISTS import_jobs (
+					id          UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					owner_type  TEXT NOT NULL,
+					owner_id    UUID NOT NULL,
+					status      TEXT NOT NULL,
+					items       JSONB NOT NULL DEFAULT '[]',
+					error       TEXT NOT NULL DEFAULT '',
+					author      TEXT NOT NULL DEFAULT '',
+					created_at  TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+					finished_at TIMESTAMPTZ
+				)`,
+				`CREATE INDEX IF NOT EXISTS import_jobs_pending_idx ON import_jobs (created_at) WHERE status IN ('pending', 'running')`,
+				`CREATE INDEX IF NOT EXISTS import_jobs_owner_idx ON import_jobs (owner_type, owner_id, created_at)`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`DROP TABLE IF EXISTS import_jobs`,
+			)
+		},
+	})
+}
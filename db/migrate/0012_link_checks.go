@@ -0,0 +1,35 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds the results of checking the image links of news and exhibits.
+func init() {
+	register(Migration{
+		Version: 12,
+		Name:    "link_checks",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`CREATE TABLE IF NOT EXISTS link_checks (
+					owner_type TEXT NOT NULL,
+					owner_id   UUID NOT NULL,
+					url        TEXT NOT NULL,
+					status     TEXT NOT NULL,
+					final_url  TEXT NOT NULL DEFAULT '',
+					error      TEXT NOT NULL DEFAULT '',
+					checked_at TIMESTAMPTZ NOT NULL,
+					PRIMARY KEY (owner_type, owner_id, url)
+				)`,
+				`CREATE INDEX IF NOT EXISTS link_checks_status_idx ON link_checks (status)`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`DROP TABLE IF EXISTS link_checks`,
+			)
+		},
+	})
+}
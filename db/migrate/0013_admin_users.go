@@ -0,0 +1,34 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds the accounts of the admin, which replace the single login and
+// password of the configuration.
+func init() {
+	register(Migration{
+		Version: 13,
+		Name:    "admin_users",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`CREATE TABLE IF NOT EXISTS admin_users (
+					id            UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					login         TEXT NOT NULL UNIQUE,
+					password_hash TEXT NOT NULL,
+					role          TEXT NOT NULL,
+					disabled      BOOLEAN NOT NULL DEFAULT FALSE,
+					created_at    TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+					updated_at    TIMESTAMPTZ NOT NULL DEFAULT current_timestamp
+				)`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`DROP TABLE IF EXISTS admin_users`,
+			)
+		},
+	})
+}
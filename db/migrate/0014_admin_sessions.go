@@ -0,0 +1,36 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds the sessions of the admin login form, which end with their user.
+func init() {
+	register(Migration{
+		Version: 14,
+		Name:    "admin_sessions",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`CREATE TABLE IF NOT EXISTS admin_sessions (
+					id           UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					user_id      UUID NOT NULL REFERENCES admin_users (id) ON DELETE CASCADE,
+					token_hash   TEXT NOT NULL UNIQUE,
+					csrf_token   TEXT NOT NULL,
+					user_agent   TEXT NOT NULL DEFAULT '',
+					ip           TEXT NOT NULL DEFAULT '',
+					created_at   TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+					last_seen_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+					expires_at   TIMESTAMPTZ NOT NULL
+				)`,
+				`CREATE INDEX IF NOT EXISTS admin_sessions_user_id_idx ON admin_sessions (user_id)`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`DROP TABLE IF EXISTS admin_sessions`,
+			)
+		},
+	})
+}
@@ -0,0 +1,38 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds the personal API tokens of the admin users, which end with their
+// user.
+func init() {
+	register(Migration{
+		Version: 15,
+		Name:    "api_tokens",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`CREATE TABLE IF NOT EXISTS api_tokens (
+					id           UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					user_id      UUID NOT NULL REFERENCES admin_users (id) ON DELETE CASCADE,
+					name         TEXT NOT NULL,
+					prefix       TEXT NOT NULL,
+					token_hash   TEXT NOT NULL UNIQUE,
+					scopes       TEXT[] NOT NULL,
+					expires_at   TIMESTAMPTZ,
+					last_used_at TIMESTAMPTZ,
+					last_used_ip TEXT NOT NULL DEFAULT '',
+					created_at   TIMESTAMPTZ NOT NULL DEFAULT current_timestamp
+				)`,
+				`CREATE INDEX IF NOT EXISTS api_tokens_user_id_idx ON api_tokens (user_id)`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`DROP TABLE IF EXISTS api_tokens`,
+			)
+		},
+	})
+}
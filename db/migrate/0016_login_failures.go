@@ -0,0 +1,34 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds the failed sign-ins to the admin, for the security log.
+func init() {
+	register(Migration{
+		Version: 16,
+		Name:    "login_failures",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`CREATE TABLE IF NOT EXISTS login_failures (
+					id         UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					login      TEXT NOT NULL,
+					ip         TEXT NOT NULL,
+					user_agent TEXT NOT NULL DEFAULT '',
+					locked     BOOLEAN NOT NULL DEFAULT FALSE,
+					created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp
+				)`,
+				`CREATE INDEX IF NOT EXISTS login_failures_created_at_idx ON login_failures (created_at)`,
+				`CREATE INDEX IF NOT EXISTS login_failures_login_idx ON login_failures (login, created_at)`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`DROP TABLE IF EXISTS login_failures`,
+			)
+		},
+	})
+}
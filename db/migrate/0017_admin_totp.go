@@ -0,0 +1,32 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Adds the two-factor authentication of the admin users: the TOTP secret,
+// the last accepted time step and the hashes of the recovery codes.
+func init() {
+	register(Migration{
+		Version: 17,
+		Name:    "admin_totp",
+		Up: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS totp_secret TEXT NOT NULL DEFAULT ''`,
+				`ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE`,
+				`ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0`,
+				`ALTER TABLE admin_users ADD COLUMN IF NOT EXISTS recovery_codes TEXT[] NOT NULL DEFAULT '{}'`,
+			)
+		},
+		Down: func(ctx context.Context, tx bun.Tx) error {
+			return execAll(ctx, tx,
+				`ALTER TABLE admin_users DROP COLUMN IF EXISTS recovery_codes`,
+				`ALTER TABLE admin_users DROP COLUMN IF EXISTS totp_last_step`,
+				`ALTER TABLE admin_users DROP COLUMN IF EXISTS totp_enabled`,
+				`ALTER TABLE admin_users DROP COLUMN IF EXISTS totp_secret`,
+			)
+		},
+	})
+}
@@ -0,0 +1,238 @@
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// lockKey is the pg_advisory_lock key that serialises migrations between
+// several server replicas sharing one database.
+const lockKey int64 = 0x5343484d55534555 // "SCHMUSEU"
+
+var (
+	ErrNoMigrations   = errors.New("no migrations to roll back")
+	ErrUnknownVersion = errors.New("database has migrations unknown to this binary")
+)
+
+// MigrationFunc applies (or reverts) a single schema change inside a transaction.
+type MigrationFunc func(ctx context.Context, tx bun.Tx) error
+
+// Migration is a numbered schema change with its rollback.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      MigrationFunc
+	Down    MigrationFunc
+}
+
+// Status describes whether a known migration has been applied.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// schemaMigration is a row of the schema_migrations table.
+type schemaMigration struct {
+	bun.BaseModel `bun:"table:schema_migrations"`
+
+	Version   int64     `bun:"version,pk"`
+	Name      string    `bun:"name,type:text,notnull"`
+	AppliedAt time.Time `bun:"applied_at,nullzero,notnull,default:current_timestamp"`
+}
+
+// registry holds all migrations of the application, filled from init()
+// functions of the numbered files in this package.
+var registry []Migration
+
+func register(m Migration) {
+	registry = append(registry, m)
+}
+
+// Migrator applies the registered migrations to a database.
+type Migrator struct {
+	db         *bun.DB
+	migrations []Migration
+	log        *slog.Logger
+}
+
+func NewMigrator(db *bun.DB, log *slog.Logger) *Migrator {
+	ms := slices.Clone(registry)
+	slices.SortFunc(ms, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return &Migrator{
+		db:         db,
+		migrations: ms,
+		log:        log,
+	}
+}
+
+// Up applies all pending migrations in version order.
+// Every migration runs in its own transaction together with its
+// schema_migrations record, so a failure leaves no partial state behind.
+func (m *Migrator) Up(ctx context.Context) (applied []Migration, err error) {
+	err = m.withLock(ctx, func(conn bun.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.checkKnown(done); err != nil {
+			return err
+		}
+
+		for _, mg := range m.migrations {
+			if _, ok := done[mg.Version]; ok {
+				continue
+			}
+			m.log.Info("applying migration", slog.Int64("version", mg.Version), slog.String("name", mg.Name))
+			err := conn.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+				if err := mg.Up(ctx, tx); err != nil {
+					return err
+				}
+				_, err := tx.NewInsert().
+					Model(&schemaMigration{Version: mg.Version, Name: mg.Name}).
+					Exec(ctx)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			applied = append(applied, mg)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last n applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, n int) (reverted []Migration, err error) {
+	err = m.withLock(ctx, func(conn bun.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.checkKnown(done); err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < n; i-- {
+			mg := m.migrations[i]
+			if _, ok := done[mg.Version]; !ok {
+				continue
+			}
+			m.log.Info("reverting migration", slog.Int64("version", mg.Version), slog.String("name", mg.Name))
+			err := conn.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+				if mg.Down != nil {
+					if err := mg.Down(ctx, tx); err != nil {
+						return err
+					}
+				}
+				_, err := tx.NewDelete().
+					Model((*schemaMigration)(nil)).
+					Where("version = ?", mg.Version).
+					Exec(ctx)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			reverted = append(reverted, mg)
+		}
+		if len(reverted) == 0 {
+			return ErrNoMigrations
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status reports every known migration together with its applied state.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	done, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		st := Status{Version: mg.Version, Name: mg.Name}
+		if row, ok := done[mg.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = row.AppliedAt
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, m.checkKnown(done)
+}
+
+// withLock runs f on a dedicated connection holding the migrations advisory lock.
+// Concurrent callers (e.g. a second replica starting up) block until it is released.
+func (m *Migrator) withLock(ctx context.Context, f func(conn bun.Conn) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock(?)", lockKey); err != nil {
+		return fmt.Errorf("acquire migrations lock: %w", err)
+	}
+	defer func() {
+		// The lock must be released even if ctx is already cancelled.
+		_, unlockErr := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(?)", lockKey)
+		if err == nil && unlockErr != nil {
+			err = fmt.Errorf("release migrations lock: %w", unlockErr)
+		}
+	}()
+
+	return f(conn)
+}
+
+// applied ensures the schema_migrations table exists and returns its rows by version.
+func (m *Migrator) applied(ctx context.Context, conn bun.Conn) (map[int64]schemaMigration, error) {
+	_, err := conn.NewCreateTable().
+		Model((*schemaMigration)(nil)).
+		IfNotExists().
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []schemaMigration
+	err = conn.NewSelect().Model(&rows).Order("version").Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	done := make(map[int64]schemaMigration, len(rows))
+	for _, r := range rows {
+		done[r.Version] = r
+	}
+	return done, nil
+}
+
+// checkKnown fails if the database was migrated by a newer binary.
+func (m *Migrator) checkKnown(done map[int64]schemaMigration) error {
+	for v, row := range done {
+		if !slices.ContainsFunc(m.migrations, func(mg Migration) bool { return mg.Version == v }) {
+			return fmt.Errorf("%w: %d_%s", ErrUnknownVersion, v, row.Name)
+		}
+	}
+	return nil
+}
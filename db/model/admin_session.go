@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// AdminSession is a sign-in of an admin user through the login form. The
+// browser keeps the token in a cookie; only its hash is stored.
+type AdminSession struct {
+	bun.BaseModel `bun:"table:admin_sessions,alias:ases"`
+
+	ID     uuid.UUID `json:"id" bun:"id,pk,type:uuid,default:uuid_generate_v4()"`
+	UserID uuid.UUID `json:"user_id" bun:"user_id,type:uuid,notnull"`
+	// TokenHash is the hex SHA-256 of the token in the cookie.
+	TokenHash string `json:"-" bun:"token_hash,type:text,notnull,unique"`
+	// CSRFToken must come in the X-CSRF-Token header of the requests
+	// changing something.
+	CSRFToken string `json:"-" bun:"csrf_token,type:text,notnull"`
+	UserAgent string `json:"user_agent" bun:"user_agent,type:text,notnull"`
+	IP        string `json:"ip" bun:"ip,type:text,notnull"`
+
+	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	// LastSeenAt is the time of the last request, updated at most once a
+	// minute. The session ends when it is idle for too long.
+	LastSeenAt time.Time `json:"last_seen_at" bun:",nullzero,notnull,default:current_timestamp"`
+	// ExpiresAt ends the session however active it is.
+	ExpiresAt time.Time `json:"expires_at" bun:",notnull"`
+
+	// Current marks the session of the request in listings.
+	Current bool `json:"current" bun:"-"`
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Role is what an admin user may do. Each role may do everything the
+// roles below it may.
+type Role string
+
+const (
+	// RoleOwner manages the admin users.
+	RoleOwner Role = "owner"
+	// RoleEditor changes content and media.
+	RoleEditor Role = "editor"
+	// RoleViewer only reads: content, history, statistics.
+	RoleViewer Role = "viewer"
+)
+
+// Roles lists the roles from the most to the least powerful.
+var Roles = []Role{RoleOwner, RoleEditor, RoleViewer}
+
+func (r Role) rank() int {
+	switch r {
+	case RoleOwner:
+		return 3
+	case RoleEditor:
+		return 2
+	case RoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	return r.rank() > 0
+}
+
+// Allows reports whether r may do what need may.
+func (r Role) Allows(need Role) bool {
+	return r.Valid() && r.rank() >= need.rank()
+}
+
+// AdminUser is an account of the admin.
+type AdminUser struct {
+	bun.BaseModel `bun:"table:admin_users,alias:au"`
+
+	ID    uuid.UUID `json:"id" bun:"id,pk,type:uuid,default:uuid_generate_v4()"`
+	Login string    `json:"login" bun:"login,type:text,notnull,unique"`
+	// PasswordHash is the bcrypt hash of the password.
+	PasswordHash string `json:"-" bun:"password_hash,type:text,notnull"`
+	Role         Role   `json:"role" bun:"role,type:text,notnull" enum:"owner,editor,viewer"`
+	// Disabled users cannot sign in.
+	Disabled bool `json:"disabled" bun:"disabled,notnull"`
+
+	// TOTPSecret is the base32 secret shared with the authenticator app of
+	// the user, set from the start of the enrollment.
+	TOTPSecret string `json:"-" bun:"totp_secret,type:text,notnull"`
+	// TOTPEnabled is set once the enrollment is confirmed with a code;
+	// from then on signing in needs a one-time code.
+	TOTPEnabled bool `json:"totp_enabled" bun:"totp_enabled,notnull"`
+	// TOTPLastStep is the time step of the last accepted code, so a code
+	// cannot be used twice.
+	TOTPLastStep int64 `json:"-" bun:"totp_last_step,notnull"`
+	// RecoveryCodes are the SHA-256 hashes of the unused recovery codes.
+	RecoveryCodes []string `json:"-" bun:"recovery_codes,type:text[],array,nullzero,notnull,default:'{}'"`
+
+	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
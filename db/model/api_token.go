@@ -0,0 +1,75 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Scope is what an API token may do: a resource and an access level, like
+// news:write. Writing includes reading.
+type Scope string
+
+const (
+	ScopeNewsRead         Scope = "news:read"
+	ScopeNewsWrite        Scope = "news:write"
+	ScopeExhibitionsRead  Scope = "exhibitions:read"
+	ScopeExhibitionsWrite Scope = "exhibitions:write"
+	ScopeExhibitsRead     Scope = "exhibits:read"
+	ScopeExhibitsWrite    Scope = "exhibits:write"
+	ScopeMediaRead        Scope = "media:read"
+	ScopeMediaWrite       Scope = "media:write"
+	ScopeTrashRead        Scope = "trash:read"
+	ScopeTrashWrite       Scope = "trash:write"
+	ScopeStatsRead        Scope = "stats:read"
+)
+
+// Scopes lists the known scopes.
+var Scopes = []Scope{
+	ScopeNewsRead, ScopeNewsWrite,
+	ScopeExhibitionsRead, ScopeExhibitionsWrite,
+	ScopeExhibitsRead, ScopeExhibitsWrite,
+	ScopeMediaRead, ScopeMediaWrite,
+	ScopeTrashRead, ScopeTrashWrite,
+	ScopeStatsRead,
+}
+
+// Valid reports whether s is a known scope.
+func (s Scope) Valid() bool {
+	return slices.Contains(Scopes, s)
+}
+
+// Write reports whether s allows changes.
+func (s Scope) Write() bool {
+	return strings.HasSuffix(string(s), ":write")
+}
+
+// Allows reports whether s grants access to resource, for changes if write.
+func (s Scope) Allows(resource string, write bool) bool {
+	res, _, _ := strings.Cut(string(s), ":")
+	return res == resource && (s.Write() || !write)
+}
+
+// APIToken is a personal token of an admin user for scripts. It acts for
+// its user, limited to its scopes. Only the hash of the token is stored.
+type APIToken struct {
+	bun.BaseModel `bun:"table:api_tokens,alias:apit"`
+
+	ID     uuid.UUID `json:"id" bun:"id,pk,type:uuid,default:uuid_generate_v4()"`
+	UserID uuid.UUID `json:"user_id" bun:"user_id,type:uuid,notnull"`
+	Name   string    `json:"name" bun:"name,type:text,notnull"`
+	// Prefix is the start of the token, to tell tokens apart.
+	Prefix string `json:"prefix" bun:"prefix,type:text,notnull"`
+	// TokenHash is the hex SHA-256 of the token.
+	TokenHash string  `json:"-" bun:"token_hash,type:text,notnull,unique"`
+	Scopes    []Scope `json:"scopes" bun:"scopes,type:text[],array,notnull"`
+	// ExpiresAt is nil for tokens that never expire.
+	ExpiresAt  *time.Time `json:"expires_at" bun:"expires_at,type:timestamptz"`
+	LastUsedAt *time.Time `json:"last_used_at" bun:"last_used_at,type:timestamptz"`
+	LastUsedIP string     `json:"last_used_ip" bun:"last_used_ip,type:text,notnull"`
+
+	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Asset is a file uploaded to the local media storage. Assets are
+// content-addressed: Hash is the SHA-256 of the file, so uploading the same
+// file again yields the same asset and URL.
+type Asset struct {
+	bun.BaseModel `bun:"table:assets,alias:a"`
+
+	Hash     string `json:"hash" bun:"hash,pk,type:text"`
+	URL      string `json:"url" bun:"url,type:text,notnull"`
+	MIMEType string `json:"mime_type" bun:"mime_type,type:text,notnull"`
+	Size     int64  `json:"size" bun:"size,notnull"`
+	// Name is the file name the asset was first uploaded with.
+	Name string `json:"name" bun:"name,type:text,notnull"`
+	// Width and Height are the dimensions of an image, zero for other files
+	// and for images whose variants are not generated yet.
+	Width  int `json:"width,omitempty" bun:"width,notnull,default:0"`
+	Height int `json:"height,omitempty" bun:"height,notnull,default:0"`
+	// Variants are the resized copies of an image. Nil until they are
+	// generated; empty if the image is too small or the file is not an image.
+	Variants []ImageVariant `json:"variants" bun:"variants,type:jsonb,nullzero"`
+	// Metadata is what was learned from an uploaded image, nil for other files.
+	Metadata *MediaMetadata `json:"metadata,omitempty" bun:"metadata,type:jsonb"`
+
+	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// MediaMetadata is what was learned from the metadata of an uploaded image.
+// The metadata itself is removed from the stored file.
+type MediaMetadata struct {
+	// CapturedAt is the capture date from EXIF.
+	CapturedAt *time.Time `json:"captured_at,omitempty"`
+	Width      int        `json:"width"`
+	Height     int        `json:"height"`
+	// Orientation is the EXIF orientation of the upload, 1 to 8, absent if
+	// the upload had none.
+	Orientation int `json:"orientation,omitempty" minimum:"1" maximum:"8"`
+	// Rotated reports that the stored image was turned upright according to Orientation.
+	Rotated bool `json:"rotated"`
+	// Removed lists the kinds of metadata removed from the file.
+	Removed []string `json:"removed" enum:"exif,gps,device,xmp,iptc,text" example:"exif,gps,device"`
+}
+
+// ImageVariant is a resized copy of an image asset.
+type ImageVariant struct {
+	// Name is the size class: thumb, medium or large.
+	Name   string `json:"name" example:"thumb"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// Image is an image URL of an entity with the variants it is available in,
+// in the form of an HTML srcset. Variants and Srcset are empty for images
+// that are not stored locally or have no variants.
+type Image struct {
+	URL      string         `json:"url"`
+	Srcset   string         `json:"srcset,omitempty" example:"/media/ab/ab…_thumb.jpg 320w, /media/ab/ab….jpg 1200w"`
+	Variants []ImageVariant `json:"variants,omitempty"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// ImportStatus is the state of an ImportJob or of one of its items.
+type ImportStatus string
+
+const (
+	ImportPending ImportStatus = "pending"
+	ImportRunning ImportStatus = "running"
+	// ImportDone is a finished job, whatever became of its items, or an
+	// imported item.
+	ImportDone   ImportStatus = "done"
+	ImportFailed ImportStatus = "failed"
+	// ImportSkipped is an item that is a player rather than a file.
+	ImportSkipped ImportStatus = "skipped"
+)
+
+// ImportJob copies the external images of a news item or an exhibit into
+// the local media storage and points its image URLs to the copies.
+type ImportJob struct {
+	bun.BaseModel `bun:"table:import_jobs,alias:ij"`
+
+	ID        uuid.UUID    `json:"id" bun:"id,pk,type:uuid,default:uuid_generate_v4()"`
+	OwnerType EntityType   `json:"owner_type" bun:"owner_type,type:text,notnull" enum:"news,exhibit"`
+	OwnerID   uuid.UUID    `json:"owner_id" bun:"owner_id,type:uuid,notnull"`
+	Status    ImportStatus `json:"status" bun:"status,type:text,notnull" enum:"pending,running,done,failed"`
+	Items     []ImportItem `json:"items" bun:"items,type:jsonb,notnull"`
+	// Error is why the job failed as a whole.
+	Error string `json:"error,omitempty" bun:"error,type:text,notnull"`
+	// Author is the admin who started the import; the changes it makes are
+	// recorded in their name.
+	Author string `json:"author,omitempty" bun:"author,type:text,notnull"`
+
+	CreatedAt  time.Time  `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" bun:"finished_at"`
+}
+
+// ImportItem is an external URL of an ImportJob.
+type ImportItem struct {
+	SourceURL string       `json:"source_url"`
+	Status    ImportStatus `json:"status" enum:"pending,done,failed,skipped"`
+	// URL is the local copy of an imported item.
+	URL   string `json:"url,omitempty"`
+	Error string `json:"error,omitempty"`
+}
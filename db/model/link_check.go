@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// LinkStatus is the outcome of checking an external image link.
+type LinkStatus string
+
+const (
+	LinkOK LinkStatus = "ok"
+	// LinkRedirect is a working link that leads to another address, see
+	// LinkCheck.FinalURL.
+	LinkRedirect LinkStatus = "redirect"
+	// LinkNotFound is media that is gone or no longer public.
+	LinkNotFound LinkStatus = "not_found"
+	LinkTimeout  LinkStatus = "timeout"
+	// LinkError is any other failure: an unavailable service, a page
+	// without media, an address the server may not fetch.
+	LinkError LinkStatus = "error"
+)
+
+// BrokenLinkStatuses are the statuses of links that do not show media.
+var BrokenLinkStatuses = []LinkStatus{LinkNotFound, LinkTimeout, LinkError}
+
+// LinkCheck is the last result of checking an image URL of a news item or
+// an exhibit.
+type LinkCheck struct {
+	bun.BaseModel `bun:"table:link_checks,alias:lc"`
+
+	OwnerType EntityType `json:"owner_type" bun:"owner_type,pk,type:text" enum:"news,exhibit"`
+	OwnerID   uuid.UUID  `json:"owner_id" bun:"owner_id,pk,type:uuid"`
+	URL       string     `json:"url" bun:"url,pk,type:text"`
+	Status    LinkStatus `json:"status" bun:"status,type:text,notnull" enum:"ok,redirect,not_found,timeout,error"`
+	// FinalURL is where a redirected link leads.
+	FinalURL string `json:"final_url,omitempty" bun:"final_url,type:text,notnull"`
+	// Error is the code of the failure, as in API errors.
+	Error     string    `json:"error,omitempty" bun:"error,type:text,notnull"`
+	CheckedAt time.Time `json:"checked_at" bun:"checked_at,notnull"`
+}
+
+// BrokenLinks are the broken image links of one news item or exhibit.
+type BrokenLinks struct {
+	OwnerType EntityType  `json:"owner_type" enum:"news,exhibit"`
+	OwnerID   uuid.UUID   `json:"owner_id"`
+	Title     string      `json:"title"`
+	Links     []LinkCheck `json:"links"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// LoginFailure is a failed sign-in to the admin, kept for the security log
+// of the owners.
+type LoginFailure struct {
+	bun.BaseModel `bun:"table:login_failures,alias:lf"`
+
+	ID uuid.UUID `json:"id" bun:"id,pk,type:uuid,default:uuid_generate_v4()"`
+	// Login is the login tried, which may belong to no user.
+	Login     string `json:"login" bun:"login,type:text,notnull"`
+	IP        string `json:"ip" bun:"ip,type:text,notnull"`
+	UserAgent string `json:"user_agent" bun:"user_agent,type:text,notnull"`
+	// Locked is set on the failures that locked the login or the address
+	// out for a while.
+	Locked bool `json:"locked" bun:"locked,notnull"`
+
+	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Media is an item of the media library: an image or video, uploaded or
+// external, with the descriptive fields a bare image URL lacks. Items are
+// attached to news and exhibits in a given order (AttachedMedia).
+type Media struct {
+	bun.BaseModel `bun:"table:media,alias:m"`
+
+	ID  uuid.UUID `json:"id,omitzero" bun:"id,pk,type:uuid,default:uuid_generate_v4()"`
+	URL string    `json:"url" bun:"url,type:text,notnull"`
+	// Caption is shown under the image.
+	Caption string `json:"caption,omitempty" bun:"caption,type:text,notnull"`
+	// Alt is the text alternative for screen readers.
+	Alt string `json:"alt,omitempty" bun:"alt,type:text,notnull"`
+	// Credit names the author of the photo or the source of the file.
+	Credit string `json:"credit,omitempty" bun:"credit,type:text,notnull"`
+	// License is the license the file is used under, e.g. CC BY-SA 4.0.
+	License string `json:"license,omitempty" bun:"license,type:text,notnull"`
+
+	CreatedAt time.Time `json:"created_at,omitzero" bun:",nullzero,notnull,default:current_timestamp"`
+	UpdatedAt time.Time `json:"updated_at,omitzero" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// AttachedMedia is a media item as attached to a news item or an exhibit.
+type AttachedMedia struct {
+	Media `bun:",extend"`
+
+	// OwnerID is the news item or exhibit the media is attached to.
+	OwnerID uuid.UUID `json:"-" bun:"owner_id,scanonly"`
+	// Position is the sort order of the media among the media of the owner, from 0.
+	Position int `json:"position" bun:"position,scanonly"`
+	// Srcset lists the variants of an uploaded image, see Image.
+	Srcset string `json:"srcset,omitempty" bun:"-"`
+}
@@ -1 +1,10 @@
 package model
+
+// EntityType names a kind of museum content.
+type EntityType string
+
+const (
+	EntityNews       EntityType = "news"
+	EntityExhibition EntityType = "exhibition"
+	EntityExhibit    EntityType = "exhibit"
+)
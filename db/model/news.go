@@ -14,6 +14,12 @@ type News struct {
 	Title     string    `json:"title" bun:"title,type:text"`
 	Content   string    `json:"content" bun:"content,type:text"`
 	ImageURLs []string  `json:"image_urls" bun:"image_urls,type:text[],default:'{}'"`
+	// Images describes ImageURLs with their variants. It is filled for the
+	// public API only.
+	Images []Image `json:"images,omitempty" bun:"-"`
+	// Media are the attached media items followed by the ImageURLs that are
+	// not among them, in display order. It is filled for the public API only.
+	Media []AttachedMedia `json:"media,omitempty" bun:"-"`
 
 	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
 	UpdatedAt time.Time `json:"updated_at" bun:",nullzero,notnull,default:current_timestamp"`
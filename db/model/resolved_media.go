@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// ResolvedMedia is a cached result of resolving a link to an external media
+// service into a file or player URL.
+type ResolvedMedia struct {
+	bun.BaseModel `bun:"table:resolved_media,alias:rm"`
+
+	// SourceURL is the normalised link that was resolved.
+	SourceURL string `json:"source_url" bun:"source_url,pk,type:text"`
+	URL       string `json:"url" bun:"url,type:text,notnull"`
+	Type      string `json:"type" bun:"type,type:text,notnull"`
+	Provider  string `json:"provider" bun:"provider,type:text,notnull"`
+	Title     string `json:"title,omitempty" bun:"title,type:text,notnull"`
+	Thumbnail string `json:"thumbnail_url,omitempty" bun:"thumbnail_url,type:text,notnull"`
+
+	ResolvedAt time.Time `json:"resolved_at" bun:"resolved_at,notnull"`
+	ExpiresAt  time.Time `json:"expires_at" bun:"expires_at,notnull"`
+}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RevisionAction is what happened to an entity in a revision.
+type RevisionAction string
+
+const (
+	RevisionCreate   RevisionAction = "create"
+	RevisionUpdate   RevisionAction = "update"
+	RevisionDelete   RevisionAction = "delete"
+	RevisionRestore  RevisionAction = "restore"
+	RevisionRollback RevisionAction = "rollback"
+)
+
+// Revision is an append-only record of a change made through the admin API.
+// Snapshot holds the full entity after the change (before it, for deletes).
+type Revision struct {
+	bun.BaseModel `bun:"table:revisions,alias:rev"`
+
+	ID         int64           `json:"-" bun:"id,pk,autoincrement"`
+	EntityType EntityType      `json:"entity_type" bun:"entity_type,type:text,notnull"`
+	EntityID   uuid.UUID       `json:"entity_id" bun:"entity_id,type:uuid,notnull"`
+	Rev        int             `json:"rev" bun:"rev,notnull"`
+	Action     RevisionAction  `json:"action" bun:"action,type:text,notnull"`
+	Author     string          `json:"author" bun:"author,type:text"`
+	Snapshot   json.RawMessage `json:"snapshot" bun:"snapshot,type:jsonb"`
+	Changes    []FieldChange   `json:"changes" bun:"changes,type:jsonb"`
+
+	CreatedAt time.Time `json:"created_at" bun:",nullzero,notnull,default:current_timestamp"`
+}
+
+// FieldChange is a single changed field of an entity snapshot.
+// Old or New is absent when the field did not exist on that side.
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old,omitempty"`
+	New   json.RawMessage `json:"new,omitempty"`
+}
+
+// diffIgnored are snapshot fields that never change or change on their own,
+// and are left out of diffs.
+var diffIgnored = []string{"id", "created_at", "updated_at", "exhibits"}
+
+// Diff compares two JSON object snapshots field by field and returns the
+// changed fields in name order. A nil snapshot counts as an empty object.
+func Diff(before, after json.RawMessage) ([]FieldChange, error) {
+	var a, b map[string]json.RawMessage
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &a); err != nil {
+			return nil, err
+		}
+	}
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &b); err != nil {
+			return nil, err
+		}
+	}
+
+	fields := make([]string, 0, len(a)+len(b))
+	for f := range a {
+		fields = append(fields, f)
+	}
+	for f := range b {
+		if _, ok := a[f]; !ok {
+			fields = append(fields, f)
+		}
+	}
+	slices.Sort(fields)
+
+	changes := []FieldChange{}
+	for _, f := range fields {
+		if slices.Contains(diffIgnored, f) {
+			continue
+		}
+		old, nw := a[f], b[f]
+		if jsonEqual(old, nw) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: f, Old: old, New: nw})
+	}
+	return changes, nil
+}
+
+// jsonEqual reports whether two JSON values are equal, ignoring formatting.
+func jsonEqual(a, b json.RawMessage) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SearchHit is a single ranked full-text search result.
+type SearchHit struct {
+	Type         EntityType `json:"type" bun:"type"`
+	ID           uuid.UUID  `json:"id" bun:"id"`
+	ExhibitionID *uuid.UUID `json:"exhibition_id,omitempty" bun:"exhibition_id"`
+	Title        string     `json:"title" bun:"title"`
+	// Snippet is an HTML-escaped excerpt with matches wrapped in <mark>.
+	Snippet   string    `json:"snippet" bun:"snippet"`
+	Rank      float64   `json:"rank" bun:"rank"`
+	CreatedAt time.Time `json:"created_at" bun:"created_at"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TrashItem is a soft-deleted piece of content as shown in the admin trash bin.
+type TrashItem struct {
+	Type         EntityType `json:"type"`
+	ID           uuid.UUID  `json:"id"`
+	Title        string     `json:"title"`
+	ExhibitionID *uuid.UUID `json:"exhibition_id,omitempty"`
+	DeletedAt    time.Time  `json:"deleted_at"`
+	// DeletedExhibits is the number of exhibits deleted together with an exhibition.
+	DeletedExhibits int `json:"deleted_exhibits,omitempty"`
+}
+
+// PurgeResult reports how many rows of each kind were permanently removed.
+type PurgeResult struct {
+	News        int `json:"news"`
+	Exhibitions int `json:"exhibitions"`
+	Exhibits    int `json:"exhibits"`
+}
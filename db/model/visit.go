@@ -12,6 +12,19 @@ type CtxKey string
 const (
 	CtxKeyVisitorIP CtxKey = "visitor_ip"
 	CtxKeyVisitorUA CtxKey = "visitor_ua"
+	// CtxKeyAdmin holds the login of the authenticated admin.
+	CtxKeyAdmin CtxKey = "admin"
+	// CtxKeyAdminRole holds the Role of the authenticated admin.
+	CtxKeyAdminRole CtxKey = "admin_role"
+	// CtxKeyAdminSession holds the ID of the AdminSession of the request,
+	// if it is made with a session cookie.
+	CtxKeyAdminSession CtxKey = "admin_session"
+	// CtxKeyAdminScopes holds the []Scope of the APIToken of the request,
+	// if it is made with a token.
+	CtxKeyAdminScopes CtxKey = "admin_scopes"
+	// CtxKeyAdminTOTPSetup is set when the role of the admin requires
+	// two-factor authentication they have not enabled yet.
+	CtxKeyAdminTOTPSetup CtxKey = "admin_totp_setup"
 )
 
 // Visitor represents a unique site visitor, tracked by IP address.
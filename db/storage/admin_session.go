@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// AdminSessions stores the sessions of the admin login form.
+type AdminSessions interface {
+	Create(ctx context.Context, s model.AdminSession) (model.AdminSession, error)
+	GetByToken(ctx context.Context, tokenHash string) (model.AdminSession, error)
+	// Touch sets the time of the last request of the session.
+	Touch(ctx context.Context, id uuid.UUID, t time.Time) error
+	// ListByUser returns the sessions of a user, the last used first.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]model.AdminSession, error)
+	// Delete fails with ErrNotFound unless the session belongs to the user.
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+	// DeleteByUser deletes the sessions of a user but except, which may be
+	// uuid.Nil, and returns their number.
+	DeleteByUser(ctx context.Context, userID, except uuid.UUID) (int, error)
+	// DeleteExpired deletes the sessions last used before idleBefore or
+	// expiring by now and returns their number.
+	DeleteExpired(ctx context.Context, idleBefore, now time.Time) (int, error)
+}
+
+type AdminSessionStorage struct {
+	db *bun.DB
+}
+
+var _ AdminSessions = (*AdminSessionStorage)(nil)
+
+func NewAdminSessionStorage(db *bun.DB) *AdminSessionStorage {
+	return &AdminSessionStorage{
+		db: db,
+	}
+}
+
+func (s *AdminSessionStorage) Create(ctx context.Context, as model.AdminSession) (model.AdminSession, error) {
+	err := conn(ctx, s.db).NewInsert().
+		Model(&as).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		return model.AdminSession{}, err
+	}
+	return as, nil
+}
+
+func (s *AdminSessionStorage) GetByToken(ctx context.Context, tokenHash string) (model.AdminSession, error) {
+	var as model.AdminSession
+	err := conn(ctx, s.db).NewSelect().
+		Model(&as).
+		Where("token_hash = ?", tokenHash).
+		Scan(ctx)
+	if err != nil {
+		return model.AdminSession{}, notFound(err)
+	}
+	return as, nil
+}
+
+func (s *AdminSessionStorage) Touch(ctx context.Context, id uuid.UUID, t time.Time) error {
+	return affected(conn(ctx, s.db).NewUpdate().
+		Model((*model.AdminSession)(nil)).
+		Set("last_seen_at = ?", t).
+		Where("id = ?", id).
+		Exec(ctx))
+}
+
+func (s *AdminSessionStorage) ListByUser(ctx context.Context, userID uuid.UUID) ([]model.AdminSession, error) {
+	sessions := []model.AdminSession{}
+	err := conn(ctx, s.db).NewSelect().
+		Model(&sessions).
+		Where("user_id = ?", userID).
+		Order("last_seen_at DESC", "id").
+		Scan(ctx)
+	return sessions, err
+}
+
+func (s *AdminSessionStorage) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	return affected(conn(ctx, s.db).NewDelete().
+		Model((*model.AdminSession)(nil)).
+		Where("id = ?", id).
+		Where("user_id = ?", userID).
+		Exec(ctx))
+}
+
+func (s *AdminSessionStorage) DeleteByUser(ctx context.Context, userID, except uuid.UUID) (int, error) {
+	res, err := conn(ctx, s.db).NewDelete().
+		Model((*model.AdminSession)(nil)).
+		Where("user_id = ?", userID).
+		Where("id <> ?", except).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *AdminSessionStorage) DeleteExpired(ctx context.Context, idleBefore, now time.Time) (int, error) {
+	res, err := conn(ctx, s.db).NewDelete().
+		Model((*model.AdminSession)(nil)).
+		Where("last_seen_at < ? OR expires_at <= ?", idleBefore, now).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// AdminUsers stores the accounts of the admin.
+type AdminUsers interface {
+	// Create fails with ErrConflict if the login is taken.
+	Create(ctx context.Context, u model.AdminUser) (model.AdminUser, error)
+	Get(ctx context.Context, id uuid.UUID) (model.AdminUser, error)
+	GetByLogin(ctx context.Context, login string) (model.AdminUser, error)
+	// List returns all users ordered by login.
+	List(ctx context.Context) ([]model.AdminUser, error)
+	// LockOwners returns the enabled owners ordered by login. In a unit of
+	// work they stay locked until it ends, so the units of work that change
+	// owners see each other's changes.
+	LockOwners(ctx context.Context) ([]model.AdminUser, error)
+	// Update stores the password hash, role and disabled flag of u and
+	// bumps updated_at.
+	Update(ctx context.Context, u model.AdminUser) (model.AdminUser, error)
+	// UpdateTOTP stores the TOTP secret, flag and last step and the
+	// recovery codes of u and bumps updated_at.
+	UpdateTOTP(ctx context.Context, u model.AdminUser) (model.AdminUser, error)
+	// UseTOTPStep records step as the last accepted TOTP time step of a
+	// user. It fails with ErrConflict unless step is after the last one,
+	// so a code is accepted once even by concurrent sign-ins.
+	UseTOTPStep(ctx context.Context, id uuid.UUID, step int64) error
+	// UseRecoveryCode removes the recovery code hash from a user. It fails
+	// with ErrNotFound if the user has no such code.
+	UseRecoveryCode(ctx context.Context, id uuid.UUID, hash string) error
+}
+
+type AdminUserStorage struct {
+	db *bun.DB
+}
+
+var _ AdminUsers = (*AdminUserStorage)(nil)
+
+func NewAdminUserStorage(db *bun.DB) *AdminUserStorage {
+	return &AdminUserStorage{
+		db: db,
+	}
+}
+
+func (s *AdminUserStorage) Create(ctx context.Context, u model.AdminUser) (model.AdminUser, error) {
+	err := conn(ctx, s.db).NewInsert().
+		Model(&u).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		return model.AdminUser{}, err
+	}
+	return u, nil
+}
+
+func (s *AdminUserStorage) Get(ctx context.Context, id uuid.UUID) (model.AdminUser, error) {
+	return s.getBy(ctx, "id = ?", id)
+}
+
+func (s *AdminUserStorage) GetByLogin(ctx context.Context, login string) (model.AdminUser, error) {
+	return s.getBy(ctx, "login = ?", login)
+}
+
+func (s *AdminUserStorage) getBy(ctx context.Context, query string, arg any) (model.AdminUser, error) {
+	var u model.AdminUser
+	err := conn(ctx, s.db).NewSelect().
+		Model(&u).
+		Where(query, arg).
+		Scan(ctx)
+	if err != nil {
+		return model.AdminUser{}, notFound(err)
+	}
+	return u, nil
+}
+
+func (s *AdminUserStorage) List(ctx context.Context) ([]model.AdminUser, error) {
+	users := []model.AdminUser{}
+	err := conn(ctx, s.db).NewSelect().
+		Model(&users).
+		Order("login").
+		Scan(ctx)
+	return users, err
+}
+
+func (s *AdminUserStorage) LockOwners(ctx context.Context) ([]model.AdminUser, error) {
+	users := []model.AdminUser{}
+	err := conn(ctx, s.db).NewSelect().
+		Model(&users).
+		Where("role = ?", model.RoleOwner).
+		Where("NOT disabled").
+		Order("login").
+		For("UPDATE").
+		Scan(ctx)
+	return users, err
+}
+
+func (s *AdminUserStorage) Update(ctx context.Context, u model.AdminUser) (model.AdminUser, error) {
+	err := conn(ctx, s.db).NewUpdate().
+		Model(&u).
+		Column("password_hash", "role", "disabled", "updated_at").
+		Value("updated_at", bumpUpdatedAt).
+		WherePK().
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		return model.AdminUser{}, notFound(err)
+	}
+	return u, nil
+}
+
+func (s *AdminUserStorage) UpdateTOTP(ctx context.Context, u model.AdminUser) (model.AdminUser, error) {
+	codes := u.RecoveryCodes
+	if codes == nil {
+		codes = []string{}
+	}
+	err := conn(ctx, s.db).NewUpdate().
+		Model(&u).
+		Column("totp_secret", "totp_enabled", "totp_last_step", "recovery_codes", "updated_at").
+		Value("recovery_codes", "?", pgdialect.Array(codes)).
+		Value("updated_at", bumpUpdatedAt).
+		WherePK().
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		return model.AdminUser{}, notFound(err)
+	}
+	return u, nil
+}
+
+func (s *AdminUserStorage) UseTOTPStep(ctx context.Context, id uuid.UUID, step int64) error {
+	res, err := conn(ctx, s.db).NewUpdate().
+		Model((*model.AdminUser)(nil)).
+		Set("totp_last_step = ?", step).
+		Where("id = ?", id).
+		Where("totp_last_step < ?", step).
+		Exec(ctx)
+	if err := affected(res, err); !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	return ErrConflict
+}
+
+func (s *AdminUserStorage) UseRecoveryCode(ctx context.Context, id uuid.UUID, hash string) error {
+	return affected(conn(ctx, s.db).NewUpdate().
+		Model((*model.AdminUser)(nil)).
+		Set("recovery_codes = array_remove(recovery_codes, ?)", hash).
+		Where("id = ?", id).
+		Where("? = ANY(recovery_codes)", hash).
+		Exec(ctx))
+}
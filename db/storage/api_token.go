@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// APITokens stores the API tokens of the admin users.
+type APITokens interface {
+	Create(ctx context.Context, t model.APIToken) (model.APIToken, error)
+	GetByHash(ctx context.Context, tokenHash string) (model.APIToken, error)
+	// ListByUser returns the tokens of a user, the newest first.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]model.APIToken, error)
+	// Touch sets the time and address of the last use of the token.
+	Touch(ctx context.Context, id uuid.UUID, t time.Time, ip string) error
+	// Delete fails with ErrNotFound unless the token belongs to the user.
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type APITokenStorage struct {
+	db *bun.DB
+}
+
+var _ APITokens = (*APITokenStorage)(nil)
+
+func NewAPITokenStorage(db *bun.DB) *APITokenStorage {
+	return &APITokenStorage{
+		db: db,
+	}
+}
+
+func (s *APITokenStorage) Create(ctx context.Context, t model.APIToken) (model.APIToken, error) {
+	err := conn(ctx, s.db).NewInsert().
+		Model(&t).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		return model.APIToken{}, err
+	}
+	return t, nil
+}
+
+func (s *APITokenStorage) GetByHash(ctx context.Context, tokenHash string) (model.APIToken, error) {
+	var t model.APIToken
+	err := conn(ctx, s.db).NewSelect().
+		Model(&t).
+		Where("token_hash = ?", tokenHash).
+		Scan(ctx)
+	if err != nil {
+		return model.APIToken{}, notFound(err)
+	}
+	return t, nil
+}
+
+func (s *APITokenStorage) ListByUser(ctx context.Context, userID uuid.UUID) ([]model.APIToken, error) {
+	tokens := []model.APIToken{}
+	err := conn(ctx, s.db).NewSelect().
+		Model(&tokens).
+		Where("user_id = ?", userID).
+		Order("created_at DESC", "id").
+		Scan(ctx)
+	return tokens, err
+}
+
+func (s *APITokenStorage) Touch(ctx context.Context, id uuid.UUID, t time.Time, ip string) error {
+	return affected(conn(ctx, s.db).NewUpdate().
+		Model((*model.APIToken)(nil)).
+		Set("last_used_at = ?", t).
+		Set("last_used_ip = ?", ip).
+		Where("id = ?", id).
+		Exec(ctx))
+}
+
+func (s *APITokenStorage) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	return affected(conn(ctx, s.db).NewDelete().
+		Model((*model.APIToken)(nil)).
+		Where("id = ?", id).
+		Where("user_id = ?", userID).
+		Exec(ctx))
+}
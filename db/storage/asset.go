@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/uptrace/bun"
+)
+
+// Assets records the files of the local media storage.
+type Assets interface {
+	// Save stores a unless an asset with the same hash exists.
+	// It returns the stored asset and whether it was created.
+	Save(ctx context.Context, a model.Asset) (model.Asset, bool, error)
+	Get(ctx context.Context, hash string) (model.Asset, error)
+	// List returns a page of assets, newest first, and the total number of assets.
+	// A zero limit means no limit.
+	List(ctx context.Context, limit, offset int) ([]model.Asset, int, error)
+	// ByURLs returns the assets stored under any of urls, in no particular order.
+	ByURLs(ctx context.Context, urls []string) ([]model.Asset, error)
+	// Pending returns the assets whose variants are not generated yet, oldest first.
+	Pending(ctx context.Context) ([]model.Asset, error)
+	// SetVariants records the dimensions and the variants of an image asset.
+	// A nil v is stored as empty, so the asset is no longer pending.
+	SetVariants(ctx context.Context, hash string, width, height int, v []model.ImageVariant) error
+}
+
+type AssetStorage struct {
+	db *bun.DB
+}
+
+var _ Assets = (*AssetStorage)(nil)
+
+func NewAssetStorage(db *bun.DB) *AssetStorage {
+	return &AssetStorage{
+		db: db,
+	}
+}
+
+func (s *AssetStorage) Save(ctx context.Context, a model.Asset) (model.Asset, bool, error) {
+	err := conn(ctx, s.db).NewInsert().
+		Model(&a).
+		On("CONFLICT (hash) DO NOTHING").
+		Returning("*").
+		Scan(ctx)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		existing, err := s.Get(ctx, a.Hash)
+		return existing, false, err
+	case err != nil:
+		return model.Asset{}, false, err
+	}
+	return a, true, nil
+}
+
+func (s *AssetStorage) Get(ctx context.Context, hash string) (model.Asset, error) {
+	var a model.Asset
+	err := conn(ctx, s.db).NewSelect().
+		Model(&a).
+		Where("hash = ?", hash).
+		Scan(ctx)
+	if err != nil {
+		return model.Asset{}, notFound(err)
+	}
+	return a, nil
+}
+
+func (s *AssetStorage) List(ctx context.Context, limit, offset int) ([]model.Asset, int, error) {
+	assets := []model.Asset{}
+	total, err := conn(ctx, s.db).NewSelect().
+		Model(&assets).
+		Order("created_at DESC", "hash").
+		Limit(limit).
+		Offset(offset).
+		ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return assets, total, nil
+}
+
+func (s *AssetStorage) ByURLs(ctx context.Context, urls []string) ([]model.Asset, error) {
+	assets := []model.Asset{}
+	if len(urls) == 0 {
+		return assets, nil
+	}
+	err := conn(ctx, s.db).NewSelect().
+		Model(&assets).
+		Where("url IN (?)", bun.In(urls)).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+func (s *AssetStorage) Pending(ctx context.Context) ([]model.Asset, error) {
+	assets := []model.Asset{}
+	err := conn(ctx, s.db).NewSelect().
+		Model(&assets).
+		Where("variants IS NULL").
+		Order("created_at", "hash").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+func (s *AssetStorage) SetVariants(ctx context.Context, hash string, width, height int, v []model.ImageVariant) error {
+	if v == nil {
+		v = []model.ImageVariant{}
+	}
+	a := model.Asset{Hash: hash, Width: width, Height: height, Variants: v}
+	res, err := conn(ctx, s.db).NewUpdate().
+		Model(&a).
+		Column("width", "height", "variants").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
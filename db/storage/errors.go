@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// Error kinds. Every failure that callers are expected to handle is, or
+// wraps, one of them, whatever backend or service produced it.
+var (
+	// ErrNotFound: the row does not exist or is soft-deleted.
+	ErrNotFound = errors.New("Not Found")
+	// ErrConflict: the change contradicts the current state of the data.
+	ErrConflict = errors.New("Conflict")
+	// ErrInvalid: the data itself is unacceptable, whatever the current state.
+	ErrInvalid = errors.New("Invalid")
+	// ErrUnavailable: a dependency (the database, an external service) failed;
+	// the same request may succeed later.
+	ErrUnavailable = errors.New("Unavailable")
+	// ErrRateLimited: the caller tried too often; the same request may
+	// succeed after a while.
+	ErrRateLimited = errors.New("Rate Limited")
+)
+
+// ErrStale is returned by Update when the updated_at precondition fails.
+var ErrStale = NewError(ErrConflict, "stale_version", "row was changed concurrently")
+
+// Error is a failure of a known kind with a machine-readable code,
+// e.g. NewError(ErrConflict, "exhibition_deleted", "exhibition is in the trash").
+// errors.Is reports it as its kind.
+type Error struct {
+	Kind error
+	Code string
+	Msg  string
+}
+
+func NewError(kind error, code, msg string) *Error {
+	return &Error{Kind: kind, Code: code, Msg: msg}
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// Classify returns the kind of err: ErrNotFound, ErrConflict, ErrInvalid,
+// ErrUnavailable or ErrRateLimited, or nil if err is not a failure of a
+// known kind.
+// Besides the kinds themselves, it recognises the errors of the database
+// driver, so raw bun errors are classified as well.
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, kind := range []error{ErrNotFound, ErrConflict, ErrInvalid, ErrUnavailable, ErrRateLimited} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pgErr pgdriver.Error
+	if errors.As(err, &pgErr) {
+		switch code := pgErr.Field('C'); {
+		// unique_violation, serialization_failure, deadlock_detected
+		case code == "23505", code == "40001", code == "40P01":
+			return ErrConflict
+		// other integrity constraint violations and data exceptions
+		case strings.HasPrefix(code, "23"), strings.HasPrefix(code, "22"):
+			return ErrInvalid
+		// connection exceptions, insufficient resources, operator intervention
+		case strings.HasPrefix(code, "08"), strings.HasPrefix(code, "53"), strings.HasPrefix(code, "57"):
+			return ErrUnavailable
+		}
+		return nil
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr),
+		errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, sql.ErrConnDone),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, context.DeadlineExceeded):
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// Code returns the machine-readable code of err: the code of the Error it
+// wraps, if any, or otherwise the snake_case name of its kind.
+// It is empty if err is not a failure of a known kind.
+func Code(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	switch Classify(err) {
+	case ErrNotFound:
+		return "not_found"
+	case ErrConflict:
+		return "conflict"
+	case ErrInvalid:
+		return "invalid"
+	case ErrUnavailable:
+		return "unavailable"
+	case ErrRateLimited:
+		return "rate_limited"
+	}
+	return ""
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/WhiCu/school-museum/db/model"
 	"github.com/google/uuid"
@@ -12,7 +13,10 @@ type ExhibitStorage struct {
 	db *bun.DB
 }
 
-var _ Storage[model.Exhibit] = (*ExhibitStorage)(nil)
+var (
+	_ Storage[model.Exhibit] = (*ExhibitStorage)(nil)
+	_ Trash[model.Exhibit]   = (*ExhibitStorage)(nil)
+)
 
 func NewExhibitStorage(db *bun.DB) *ExhibitStorage {
 	return &ExhibitStorage{
@@ -22,15 +26,15 @@ func NewExhibitStorage(db *bun.DB) *ExhibitStorage {
 
 func (s *ExhibitStorage) Read(ctx context.Context, id uuid.UUID) (model.Exhibit, error) {
 	var e model.Exhibit
-	err := s.db.NewSelect().Model(&e).Where("id = ?", id).Scan(ctx, &e)
+	err := conn(ctx, s.db).NewSelect().Model(&e).Where("id = ?", id).Scan(ctx, &e)
 	if err != nil {
-		return model.Exhibit{}, err
+		return model.Exhibit{}, notFound(err)
 	}
 	return e, nil
 }
 
 func (s *ExhibitStorage) Create(ctx context.Context, e model.Exhibit) (uuid.UUID, error) {
-	err := s.db.NewInsert().Model(&e).Returning("id").Scan(ctx, &e.ID)
+	err := conn(ctx, s.db).NewInsert().Model(&e).Returning("id").Scan(ctx, &e.ID)
 	if err != nil {
 		return uuid.Nil, err
 	}
@@ -38,31 +42,57 @@ func (s *ExhibitStorage) Create(ctx context.Context, e model.Exhibit) (uuid.UUID
 }
 
 func (s *ExhibitStorage) Delete(ctx context.Context, id uuid.UUID) error {
-	_, err := s.db.NewDelete().Model((*model.Exhibit)(nil)).Where("id = ?", id).Exec(ctx)
+	_, err := conn(ctx, s.db).NewDelete().Model((*model.Exhibit)(nil)).Where("id = ?", id).Exec(ctx)
 	return err
 }
 
 func (s *ExhibitStorage) Update(ctx context.Context, e model.Exhibit) (model.Exhibit, error) {
-	_, err := s.db.NewUpdate().
+	expected := e.UpdatedAt
+	q := conn(ctx, s.db).NewUpdate().
 		Model(&e).
 		WherePK().
 		OmitZero().
+		Value("updated_at", bumpUpdatedAt)
+	if !expected.IsZero() {
+		q = q.Where("updated_at = ?", expected)
+	}
+	if err := q.Returning("*").Scan(ctx); err != nil {
+		return model.Exhibit{}, conditional[model.Exhibit](ctx, conn(ctx, s.db), e.ID, expected, err)
+	}
+	return e, nil
+}
+
+func (s *ExhibitStorage) Replace(ctx context.Context, e model.Exhibit) (model.Exhibit, error) {
+	if e.ImageURLs == nil {
+		e.ImageURLs = []string{}
+	}
+	err := conn(ctx, s.db).NewUpdate().
+		Model(&e).
+		Column("exhibition_id", "title", "description", "image_urls", "updated_at").
+		Value("updated_at", bumpUpdatedAt).
+		WherePK().
 		Returning("*").
-		Exec(ctx)
+		Scan(ctx)
 	if err != nil {
-		return model.Exhibit{}, err
+		return model.Exhibit{}, notFound(err)
 	}
 	return e, nil
 }
 
 func (s *ExhibitStorage) List(ctx context.Context) (exhibits []model.Exhibit, err error) {
-	err = s.db.NewSelect().Model(&exhibits).Scan(ctx)
+	err = conn(ctx, s.db).NewSelect().Model(&exhibits).Scan(ctx)
 	return exhibits, err
 }
 
+func (s *ExhibitStorage) Query(ctx context.Context, opts ListOptions) (exhibits []model.Exhibit, total int, err error) {
+	q := conn(ctx, s.db).NewSelect().Model(&exhibits)
+	total, err = opts.apply(q, "e").ScanAndCount(ctx)
+	return exhibits, total, err
+}
+
 func (s *ExhibitStorage) First(ctx context.Context, f func(model.Exhibit) bool) (model.Exhibit, error) {
 	var exhibits []model.Exhibit
-	err := s.db.NewSelect().Model(&exhibits).Scan(ctx)
+	err := conn(ctx, s.db).NewSelect().Model(&exhibits).Scan(ctx)
 	if err != nil {
 		return model.Exhibit{}, err
 	}
@@ -73,3 +103,21 @@ func (s *ExhibitStorage) First(ctx context.Context, f func(model.Exhibit) bool)
 	}
 	return model.Exhibit{}, ErrNotFound
 }
+
+// --- Trash ---
+
+func (s *ExhibitStorage) ListDeleted(ctx context.Context) ([]model.Exhibit, error) {
+	return listDeleted[model.Exhibit](ctx, conn(ctx, s.db))
+}
+
+func (s *ExhibitStorage) Restore(ctx context.Context, id uuid.UUID) error {
+	return restore[model.Exhibit](ctx, conn(ctx, s.db), id)
+}
+
+func (s *ExhibitStorage) Purge(ctx context.Context, id uuid.UUID) error {
+	return purge[model.Exhibit](ctx, conn(ctx, s.db), id)
+}
+
+func (s *ExhibitStorage) PurgeDeletedBefore(ctx context.Context, t time.Time) (int, error) {
+	return purgeDeletedBefore[model.Exhibit](ctx, conn(ctx, s.db), t)
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/WhiCu/school-museum/db/model"
 	"github.com/google/uuid"
@@ -12,7 +13,10 @@ type ExhibitionStorage struct {
 	db *bun.DB
 }
 
-var _ Storage[model.Exhibition] = (*ExhibitionStorage)(nil)
+var (
+	_ Exhibitions     = (*ExhibitionStorage)(nil)
+	_ ExhibitionTrash = (*ExhibitionStorage)(nil)
+)
 
 func NewExhibitionStorage(db *bun.DB) *ExhibitionStorage {
 	return &ExhibitionStorage{
@@ -22,62 +26,102 @@ func NewExhibitionStorage(db *bun.DB) *ExhibitionStorage {
 
 func (s *ExhibitionStorage) Read(ctx context.Context, id uuid.UUID) (model.Exhibition, error) {
 	var ex model.Exhibition
-	err := s.db.NewSelect().
+	err := conn(ctx, s.db).NewSelect().
 		Model(&ex).
 		Relation("Exhibits").
 		Where("ex.id = ?", id).
 		Scan(ctx)
 	if err != nil {
-		return model.Exhibition{}, err
+		return model.Exhibition{}, notFound(err)
 	}
 	return ex, nil
 }
 
 func (s *ExhibitionStorage) Create(ctx context.Context, ex model.Exhibition) (uuid.UUID, error) {
-	_, err := s.db.NewInsert().Model(&ex).Returning("id").Exec(ctx)
+	err := conn(ctx, s.db).NewInsert().Model(&ex).Returning("id").Scan(ctx, &ex.ID)
 	if err != nil {
 		return uuid.Nil, err
 	}
 	return ex.ID, nil
 }
 
+// Delete soft-deletes the exhibition together with its exhibits in one
+// transaction. All rows get the same deleted_at so that RestoreWithExhibits
+// can find the exhibits later.
 func (s *ExhibitionStorage) Delete(ctx context.Context, id uuid.UUID) error {
-	_, err := s.db.NewDelete().
-		Model((*model.Exhibit)(nil)).
-		Where("exhibition_id = ?", id).
-		Exec(ctx)
-	if err != nil {
-		return err
-	}
+	now := time.Now()
+	return inTx(ctx, s.db, func(ctx context.Context) error {
+		_, err := conn(ctx, s.db).NewUpdate().
+			Model((*model.Exhibit)(nil)).
+			Set("deleted_at = ?", now).
+			Where("exhibition_id = ?", id).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
 
-	_, err = s.db.NewDelete().Model((*model.Exhibition)(nil)).Where("id = ?", id).Exec(ctx)
-	return err
+		_, err = conn(ctx, s.db).NewUpdate().
+			Model((*model.Exhibition)(nil)).
+			Set("deleted_at = ?", now).
+			Where("id = ?", id).
+			Exec(ctx)
+		return err
+	})
 }
 
 func (s *ExhibitionStorage) Update(ctx context.Context, ex model.Exhibition) (model.Exhibition, error) {
-	_, err := s.db.NewUpdate().
+	expected := ex.UpdatedAt
+	q := conn(ctx, s.db).NewUpdate().
 		Model(&ex).
 		WherePK().
 		OmitZero().
+		Value("updated_at", bumpUpdatedAt)
+	if !expected.IsZero() {
+		q = q.Where("updated_at = ?", expected)
+	}
+	if err := q.Returning("*").Scan(ctx); err != nil {
+		return model.Exhibition{}, conditional[model.Exhibition](ctx, conn(ctx, s.db), ex.ID, expected, err)
+	}
+	return ex, nil
+}
+
+func (s *ExhibitionStorage) Replace(ctx context.Context, ex model.Exhibition) (model.Exhibition, error) {
+	err := conn(ctx, s.db).NewUpdate().
+		Model(&ex).
+		Column("title", "description", "preview_exhibit_id", "updated_at").
+		Value("updated_at", bumpUpdatedAt).
+		WherePK().
 		Returning("*").
-		Exec(ctx)
+		Scan(ctx)
 	if err != nil {
-		return model.Exhibition{}, err
+		return model.Exhibition{}, notFound(err)
 	}
 	return ex, nil
 }
 
 func (s *ExhibitionStorage) List(ctx context.Context) (exhibitions []model.Exhibition, err error) {
-	err = s.db.NewSelect().
+	err = conn(ctx, s.db).NewSelect().
 		Model(&exhibitions).
 		Relation("Exhibits").
 		Scan(ctx)
 	return exhibitions, err
 }
 
+func (s *ExhibitionStorage) Query(ctx context.Context, opts ListOptions) (exhibitions []model.Exhibition, total int, err error) {
+	q := conn(ctx, s.db).NewSelect().Model(&exhibitions)
+	if opts.WithRelations {
+		q = q.Relation("Exhibits")
+	}
+	total, err = opts.apply(q, "ex").ScanAndCount(ctx)
+	return exhibitions, total, err
+}
+
 func (s *ExhibitionStorage) First(ctx context.Context, f func(model.Exhibition) bool) (model.Exhibition, error) {
 	var exhibitions []model.Exhibition
-	err := s.db.NewSelect().Model(&exhibitions).Scan(ctx)
+	err := conn(ctx, s.db).NewSelect().
+		Model(&exhibitions).
+		Relation("Exhibits").
+		Scan(ctx)
 	if err != nil {
 		return model.Exhibition{}, err
 	}
@@ -90,12 +134,79 @@ func (s *ExhibitionStorage) First(ctx context.Context, f func(model.Exhibition)
 }
 
 // SetPreview sets the preview exhibit for an exhibition.
-// Pass nil to clear the preview.
+// Pass nil to clear the preview. Missing or deleted exhibitions report ErrNotFound.
 func (s *ExhibitionStorage) SetPreview(ctx context.Context, exhibitionID uuid.UUID, exhibitID *uuid.UUID) error {
-	_, err := s.db.NewUpdate().
+	return affected(conn(ctx, s.db).NewUpdate().
 		Model((*model.Exhibition)(nil)).
 		Set("preview_exhibit_id = ?", exhibitID).
+		Set("updated_at = "+bumpUpdatedAt).
 		Where("id = ?", exhibitionID).
-		Exec(ctx)
-	return err
+		Exec(ctx))
+}
+
+// --- Trash ---
+
+func (s *ExhibitionStorage) ListDeleted(ctx context.Context) ([]model.Exhibition, error) {
+	return listDeleted[model.Exhibition](ctx, conn(ctx, s.db))
+}
+
+func (s *ExhibitionStorage) Restore(ctx context.Context, id uuid.UUID) error {
+	return restore[model.Exhibition](ctx, conn(ctx, s.db), id)
+}
+
+func (s *ExhibitionStorage) RestoreWithExhibits(ctx context.Context, id uuid.UUID) (restored int, err error) {
+	err = inTx(ctx, s.db, func(ctx context.Context) error {
+		var deletedAt time.Time
+		err := conn(ctx, s.db).NewSelect().
+			Model((*model.Exhibition)(nil)).
+			Column("deleted_at").
+			WhereDeleted().
+			Where("id = ?", id).
+			Scan(ctx, &deletedAt)
+		if err != nil {
+			return notFound(err)
+		}
+
+		res, err := conn(ctx, s.db).NewUpdate().
+			Model((*model.Exhibit)(nil)).
+			WhereDeleted().
+			Set("deleted_at = NULL").
+			Where("exhibition_id = ?", id).
+			Where("deleted_at = ?", deletedAt).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		restored = int(n)
+
+		return restore[model.Exhibition](ctx, conn(ctx, s.db), id)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return restored, nil
+}
+
+// Purge permanently removes the exhibition and its deleted exhibits.
+func (s *ExhibitionStorage) Purge(ctx context.Context, id uuid.UUID) error {
+	return inTx(ctx, s.db, func(ctx context.Context) error {
+		if err := purge[model.Exhibition](ctx, conn(ctx, s.db), id); err != nil {
+			return err
+		}
+		_, err := conn(ctx, s.db).NewDelete().
+			Model((*model.Exhibit)(nil)).
+			WhereDeleted().
+			Where("exhibition_id = ?", id).
+			ForceDelete().
+			Exec(ctx)
+		return err
+	})
+}
+
+func (s *ExhibitionStorage) PurgeDeletedBefore(ctx context.Context, t time.Time) (int, error) {
+	return purgeDeletedBefore[model.Exhibition](ctx, conn(ctx, s.db), t)
 }
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// ImportJobs records the jobs importing external images.
+type ImportJobs interface {
+	Create(ctx context.Context, j model.ImportJob) (model.ImportJob, error)
+	Get(ctx context.Context, id uuid.UUID) (model.ImportJob, error)
+	// Update stores the status, items, error and finish time of j.
+	Update(ctx context.Context, j model.ImportJob) error
+	// Pending returns the jobs that are pending or were left running, oldest first.
+	Pending(ctx context.Context) ([]model.ImportJob, error)
+	// ByOwner returns the jobs of a news item or an exhibit, newest first.
+	ByOwner(ctx context.Context, owner model.EntityType, ownerID uuid.UUID) ([]model.ImportJob, error)
+}
+
+type ImportJobStorage struct {
+	db *bun.DB
+}
+
+var _ ImportJobs = (*ImportJobStorage)(nil)
+
+func NewImportJobStorage(db *bun.DB) *ImportJobStorage {
+	return &ImportJobStorage{
+		db: db,
+	}
+}
+
+func (s *ImportJobStorage) Create(ctx context.Context, j model.ImportJob) (model.ImportJob, error) {
+	if j.Items == nil {
+		j.Items = []model.ImportItem{}
+	}
+	err := conn(ctx, s.db).NewInsert().
+		Model(&j).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		return model.ImportJob{}, err
+	}
+	return j, nil
+}
+
+func (s *ImportJobStorage) Get(ctx context.Context, id uuid.UUID) (model.ImportJob, error) {
+	var j model.ImportJob
+	err := conn(ctx, s.db).NewSelect().
+		Model(&j).
+		Where("id = ?", id).
+		Scan(ctx)
+	if err != nil {
+		return model.ImportJob{}, notFound(err)
+	}
+	return j, nil
+}
+
+func (s *ImportJobStorage) Update(ctx context.Context, j model.ImportJob) error {
+	if j.Items == nil {
+		j.Items = []model.ImportItem{}
+	}
+	res, err := conn(ctx, s.db).NewUpdate().
+		Model(&j).
+		Column("status", "items", "error", "finished_at").
+		WherePK().
+		Exec(ctx)
+	return affected(res, err)
+}
+
+func (s *ImportJobStorage) Pending(ctx context.Context) ([]model.ImportJob, error) {
+	jobs := []model.ImportJob{}
+	err := conn(ctx, s.db).NewSelect().
+		Model(&jobs).
+		Where("status IN (?)", bun.In([]model.ImportStatus{model.ImportPending, model.ImportRunning})).
+		Order("created_at", "id").
+		Scan(ctx)
+	return jobs, err
+}
+
+func (s *ImportJobStorage) ByOwner(ctx context.Context, owner model.EntityType, ownerID uuid.UUID) ([]model.ImportJob, error) {
+	jobs := []model.ImportJob{}
+	err := conn(ctx, s.db).NewSelect().
+		Model(&jobs).
+		Where("owner_type = ?", owner).
+		Where("owner_id = ?", ownerID).
+		Order("created_at DESC", "id").
+		Scan(ctx)
+	return jobs, err
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// LinkChecks records the results of checking the image links of news and
+// exhibits.
+type LinkChecks interface {
+	// Save replaces the results of a news item or an exhibit with checks.
+	Save(ctx context.Context, owner model.EntityType, ownerID uuid.UUID, checks []model.LinkCheck) error
+	// DeleteBefore removes the results checked before t and returns how
+	// many there were.
+	DeleteBefore(ctx context.Context, t time.Time) (int, error)
+	// List returns the results with one of statuses, or all results if
+	// none are given, ordered by owner and URL.
+	List(ctx context.Context, statuses ...model.LinkStatus) ([]model.LinkCheck, error)
+}
+
+type LinkCheckStorage struct {
+	db *bun.DB
+}
+
+var _ LinkChecks = (*LinkCheckStorage)(nil)
+
+func NewLinkCheckStorage(db *bun.DB) *LinkCheckStorage {
+	return &LinkCheckStorage{
+		db: db,
+	}
+}
+
+func (s *LinkCheckStorage) Save(ctx context.Context, owner model.EntityType, ownerID uuid.UUID, checks []model.LinkCheck) error {
+	// The previous results must survive a failed insert.
+	return inTx(ctx, s.db, func(ctx context.Context) error {
+		db := conn(ctx, s.db)
+		_, err := db.NewDelete().
+			Model((*model.LinkCheck)(nil)).
+			Where("owner_type = ?", owner).
+			Where("owner_id = ?", ownerID).
+			Exec(ctx)
+		if err != nil || len(checks) == 0 {
+			return err
+		}
+
+		rows := make([]model.LinkCheck, len(checks))
+		for i, c := range checks {
+			c.OwnerType, c.OwnerID = owner, ownerID
+			rows[i] = c
+		}
+		_, err = db.NewInsert().
+			Model(&rows).
+			Exec(ctx)
+		return err
+	})
+}
+
+func (s *LinkCheckStorage) DeleteBefore(ctx context.Context, t time.Time) (int, error) {
+	res, err := conn(ctx, s.db).NewDelete().
+		Model((*model.LinkCheck)(nil)).
+		Where("checked_at < ?", t).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *LinkCheckStorage) List(ctx context.Context, statuses ...model.LinkStatus) ([]model.LinkCheck, error) {
+	checks := []model.LinkCheck{}
+	q := conn(ctx, s.db).NewSelect().
+		Model(&checks).
+		Order("owner_type", "owner_id", "url")
+	if len(statuses) > 0 {
+		q = q.Where("status IN (?)", bun.In(statuses))
+	}
+	err := q.Scan(ctx)
+	return checks, err
+}
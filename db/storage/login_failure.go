@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/uptrace/bun"
+)
+
+// LoginFailures stores the failed sign-ins to the admin.
+type LoginFailures interface {
+	Create(ctx context.Context, f model.LoginFailure) (model.LoginFailure, error)
+	// Query returns a page of the failures of login, or of all logins if
+	// it is empty, and their total number. They are always sorted by time.
+	Query(ctx context.Context, login string, opts ListOptions) ([]model.LoginFailure, int, error)
+	// DeleteBefore removes the failures before t and returns how many
+	// there were.
+	DeleteBefore(ctx context.Context, t time.Time) (int, error)
+}
+
+type LoginFailureStorage struct {
+	db *bun.DB
+}
+
+var _ LoginFailures = (*LoginFailureStorage)(nil)
+
+func NewLoginFailureStorage(db *bun.DB) *LoginFailureStorage {
+	return &LoginFailureStorage{
+		db: db,
+	}
+}
+
+func (s *LoginFailureStorage) Create(ctx context.Context, f model.LoginFailure) (model.LoginFailure, error) {
+	err := conn(ctx, s.db).NewInsert().
+		Model(&f).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		return model.LoginFailure{}, err
+	}
+	return f, nil
+}
+
+func (s *LoginFailureStorage) Query(ctx context.Context, login string, opts ListOptions) ([]model.LoginFailure, int, error) {
+	failures := []model.LoginFailure{}
+	opts.Sort = SortCreatedAt
+	q := conn(ctx, s.db).NewSelect().Model(&failures)
+	if login != "" {
+		q = q.Where("lf.login = ?", login)
+	}
+	total, err := opts.apply(q, "lf").ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return failures, total, nil
+}
+
+func (s *LoginFailureStorage) DeleteBefore(ctx context.Context, t time.Time) (int, error) {
+	res, err := conn(ctx, s.db).NewDelete().
+		Model((*model.LoginFailure)(nil)).
+		Where("created_at < ?", t).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
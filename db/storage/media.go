@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// MediaLibrary stores the items of the media library and their attachments
+// to news and exhibits.
+type MediaLibrary interface {
+	Create(ctx context.Context, m model.Media) (model.Media, error)
+	Read(ctx context.Context, id uuid.UUID) (model.Media, error)
+	// Update overwrites the editable fields of m and bumps updated_at.
+	// A non-zero UpdatedAt of m is a precondition, as in Storage.Update.
+	Update(ctx context.Context, m model.Media) (model.Media, error)
+	// Delete removes the item along with its attachments.
+	Delete(ctx context.Context, id uuid.UUID) error
+	// Query returns a page of items and the total number of matching items.
+	// Items have no title, so SortTitle orders them by caption.
+	Query(ctx context.Context, opts ListOptions) ([]model.Media, int, error)
+
+	// Attach replaces the media attached to the news item or exhibit
+	// ownerID with the items ids, in that order. Unknown items are ErrInvalid.
+	Attach(ctx context.Context, owner model.EntityType, ownerID uuid.UUID, ids []uuid.UUID) error
+	// Attached returns the media attached to the given news items or
+	// exhibits, by owner, each in sort order.
+	Attached(ctx context.Context, owner model.EntityType, ownerIDs []uuid.UUID) (map[uuid.UUID][]model.AttachedMedia, error)
+}
+
+type MediaStorage struct {
+	db *bun.DB
+}
+
+var _ MediaLibrary = (*MediaStorage)(nil)
+
+func NewMediaStorage(db *bun.DB) *MediaStorage {
+	return &MediaStorage{
+		db: db,
+	}
+}
+
+// mediaLinks returns the join table of the media of owner and its owner column.
+func mediaLinks(owner model.EntityType) (table, column string, err error) {
+	switch owner {
+	case model.EntityNews:
+		return "news_media", "news_id", nil
+	case model.EntityExhibit:
+		return "exhibit_media", "exhibit_id", nil
+	}
+	return "", "", fmt.Errorf("%s has no media: %w", owner, ErrInvalid)
+}
+
+func (s *MediaStorage) Create(ctx context.Context, m model.Media) (model.Media, error) {
+	err := conn(ctx, s.db).NewInsert().
+		Model(&m).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		return model.Media{}, err
+	}
+	return m, nil
+}
+
+func (s *MediaStorage) Read(ctx context.Context, id uuid.UUID) (model.Media, error) {
+	var m model.Media
+	err := conn(ctx, s.db).NewSelect().
+		Model(&m).
+		Where("id = ?", id).
+		Scan(ctx)
+	if err != nil {
+		return model.Media{}, notFound(err)
+	}
+	return m, nil
+}
+
+func (s *MediaStorage) Update(ctx context.Context, m model.Media) (model.Media, error) {
+	expected := m.UpdatedAt
+	q := conn(ctx, s.db).NewUpdate().
+		Model(&m).
+		Column("url", "caption", "alt", "credit", "license", "updated_at").
+		Value("updated_at", bumpUpdatedAt).
+		WherePK()
+	if !expected.IsZero() {
+		q = q.Where("updated_at = ?", expected)
+	}
+	if err := q.Returning("*").Scan(ctx); err != nil {
+		return model.Media{}, conditional[model.Media](ctx, conn(ctx, s.db), m.ID, expected, err)
+	}
+	return m, nil
+}
+
+func (s *MediaStorage) Delete(ctx context.Context, id uuid.UUID) error {
+	res, err := conn(ctx, s.db).NewDelete().
+		Model((*model.Media)(nil)).
+		Where("id = ?", id).
+		Exec(ctx)
+	return affected(res, err)
+}
+
+func (s *MediaStorage) Query(ctx context.Context, opts ListOptions) ([]model.Media, int, error) {
+	media := []model.Media{}
+	if opts.Sort == SortTitle {
+		opts.Sort = sortCaption
+	}
+	total, err := opts.apply(conn(ctx, s.db).NewSelect().Model(&media), "m").ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return media, total, nil
+}
+
+func (s *MediaStorage) Attach(ctx context.Context, owner model.EntityType, ownerID uuid.UUID, ids []uuid.UUID) error {
+	table, column, err := mediaLinks(owner)
+	if err != nil {
+		return err
+	}
+	// The previous attachments must survive a failed insert.
+	return inTx(ctx, s.db, func(ctx context.Context) error {
+		db := conn(ctx, s.db)
+		_, err := db.NewDelete().
+			TableExpr(table).
+			Where("? = ?", bun.Ident(column), ownerID).
+			Exec(ctx)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		// The position of an item is its index in ids.
+		_, err = db.NewRaw(
+			`INSERT INTO ? (?, media_id, position)
+			SELECT ?, t.id, t.ord - 1 FROM unnest(?::uuid[]) WITH ORDINALITY AS t(id, ord)`,
+			bun.Ident(table), bun.Ident(column), ownerID, pgdialect.Array(ids)).
+			Exec(ctx)
+		return err
+	})
+}
+
+func (s *MediaStorage) Attached(ctx context.Context, owner model.EntityType, ownerIDs []uuid.UUID) (map[uuid.UUID][]model.AttachedMedia, error) {
+	table, column, err := mediaLinks(owner)
+	if err != nil {
+		return nil, err
+	}
+	byOwner := make(map[uuid.UUID][]model.AttachedMedia)
+	if len(ownerIDs) == 0 {
+		return byOwner, nil
+	}
+
+	var attached []model.AttachedMedia
+	err = conn(ctx, s.db).NewSelect().
+		Model(&attached).
+		ColumnExpr("m.*").
+		ColumnExpr("l.? AS owner_id, l.position", bun.Ident(column)).
+		Join("JOIN ? AS l ON l.media_id = m.id", bun.Ident(table)).
+		Where("l.? IN (?)", bun.Ident(column), bun.In(ownerIDs)).
+		OrderExpr("l.position").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range attached {
+		byOwner[a.OwnerID] = append(byOwner[a.OwnerID], a)
+	}
+	return byOwner, nil
+}
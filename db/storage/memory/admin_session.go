@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+type AdminSessionStorage struct {
+	db *DB
+}
+
+var _ storage.AdminSessions = (*AdminSessionStorage)(nil)
+
+func NewAdminSessionStorage(db *DB) *AdminSessionStorage {
+	return &AdminSessionStorage{
+		db: db,
+	}
+}
+
+func (s *AdminSessionStorage) Create(ctx context.Context, as model.AdminSession) (model.AdminSession, error) {
+	defer s.db.write(ctx)()
+
+	as.ID = newID(as.ID)
+	if _, ok := s.db.adminSessions[as.ID]; ok {
+		return model.AdminSession{}, storage.ErrConflict
+	}
+	// The foreign key on admin_users.
+	if _, ok := s.db.adminUsers[as.UserID]; !ok {
+		return model.AdminSession{}, storage.ErrInvalid
+	}
+	for _, other := range s.db.adminSessions {
+		if other.TokenHash == as.TokenHash {
+			return model.AdminSession{}, storage.ErrConflict
+		}
+	}
+	now := s.db.now()
+	as.CreatedAt = orDefault(as.CreatedAt, now)
+	as.LastSeenAt = orDefault(as.LastSeenAt, now)
+
+	s.db.adminSessions[as.ID] = as
+	return as, nil
+}
+
+func (s *AdminSessionStorage) GetByToken(ctx context.Context, tokenHash string) (model.AdminSession, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, as := range s.db.adminSessions {
+		if as.TokenHash == tokenHash {
+			return as, nil
+		}
+	}
+	return model.AdminSession{}, storage.ErrNotFound
+}
+
+func (s *AdminSessionStorage) Touch(ctx context.Context, id uuid.UUID, t time.Time) error {
+	defer s.db.write(ctx)()
+
+	as, ok := s.db.adminSessions[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	as.LastSeenAt = t
+	s.db.adminSessions[id] = as
+	return nil
+}
+
+func (s *AdminSessionStorage) ListByUser(ctx context.Context, userID uuid.UUID) ([]model.AdminSession, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	sessions := []model.AdminSession{}
+	for _, as := range s.db.adminSessions {
+		if as.UserID == userID {
+			sessions = append(sessions, as)
+		}
+	}
+	slices.SortFunc(sessions, func(a, b model.AdminSession) int {
+		return cmp.Or(b.LastSeenAt.Compare(a.LastSeenAt), cmp.Compare(a.ID.String(), b.ID.String()))
+	})
+	return sessions, nil
+}
+
+func (s *AdminSessionStorage) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	defer s.db.write(ctx)()
+
+	as, ok := s.db.adminSessions[id]
+	if !ok || as.UserID != userID {
+		return storage.ErrNotFound
+	}
+	delete(s.db.adminSessions, id)
+	return nil
+}
+
+func (s *AdminSessionStorage) DeleteByUser(ctx context.Context, userID, except uuid.UUID) (int, error) {
+	defer s.db.write(ctx)()
+
+	n := 0
+	for id, as := range s.db.adminSessions {
+		if as.UserID == userID && id != except {
+			delete(s.db.adminSessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *AdminSessionStorage) DeleteExpired(ctx context.Context, idleBefore, now time.Time) (int, error) {
+	defer s.db.write(ctx)()
+
+	n := 0
+	for id, as := range s.db.adminSessions {
+		if as.LastSeenAt.Before(idleBefore) || !as.ExpiresAt.After(now) {
+			delete(s.db.adminSessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+type AdminUserStorage struct {
+	db *DB
+}
+
+var _ storage.AdminUsers = (*AdminUserStorage)(nil)
+
+func NewAdminUserStorage(db *DB) *AdminUserStorage {
+	return &AdminUserStorage{
+		db: db,
+	}
+}
+
+func (s *AdminUserStorage) Create(ctx context.Context, u model.AdminUser) (model.AdminUser, error) {
+	defer s.db.write(ctx)()
+
+	u.ID = newID(u.ID)
+	if _, ok := s.db.adminUsers[u.ID]; ok {
+		return model.AdminUser{}, storage.ErrConflict
+	}
+	for _, other := range s.db.adminUsers {
+		if other.Login == u.Login {
+			return model.AdminUser{}, storage.ErrConflict
+		}
+	}
+	// The stored codes are never changed in place, only replaced.
+	u.RecoveryCodes = append([]string{}, u.RecoveryCodes...)
+	now := s.db.now()
+	u.CreatedAt = orDefault(u.CreatedAt, now)
+	u.UpdatedAt = orDefault(u.UpdatedAt, now)
+
+	s.db.adminUsers[u.ID] = u
+	return u, nil
+}
+
+func (s *AdminUserStorage) Get(ctx context.Context, id uuid.UUID) (model.AdminUser, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	u, ok := s.db.adminUsers[id]
+	if !ok {
+		return model.AdminUser{}, storage.ErrNotFound
+	}
+	return u, nil
+}
+
+func (s *AdminUserStorage) GetByLogin(ctx context.Context, login string) (model.AdminUser, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, u := range s.db.adminUsers {
+		if u.Login == login {
+			return u, nil
+		}
+	}
+	return model.AdminUser{}, storage.ErrNotFound
+}
+
+func (s *AdminUserStorage) List(ctx context.Context) ([]model.AdminUser, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	users := make([]model.AdminUser, 0, len(s.db.adminUsers))
+	for _, u := range s.db.adminUsers {
+		users = append(users, u)
+	}
+	slices.SortFunc(users, func(a, b model.AdminUser) int { return cmp.Compare(a.Login, b.Login) })
+	return users, nil
+}
+
+// LockOwners relies on InTx running one unit of work at a time.
+func (s *AdminUserStorage) LockOwners(ctx context.Context) ([]model.AdminUser, error) {
+	users, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(users, func(u model.AdminUser) bool {
+		return u.Role != model.RoleOwner || u.Disabled
+	}), nil
+}
+
+func (s *AdminUserStorage) Update(ctx context.Context, u model.AdminUser) (model.AdminUser, error) {
+	defer s.db.write(ctx)()
+
+	stored, ok := s.db.adminUsers[u.ID]
+	if !ok {
+		return model.AdminUser{}, storage.ErrNotFound
+	}
+	stored.PasswordHash = u.PasswordHash
+	stored.Role = u.Role
+	stored.Disabled = u.Disabled
+	stored.UpdatedAt = s.db.bump(stored.UpdatedAt)
+	s.db.adminUsers[u.ID] = stored
+	return stored, nil
+}
+
+func (s *AdminUserStorage) UpdateTOTP(ctx context.Context, u model.AdminUser) (model.AdminUser, error) {
+	defer s.db.write(ctx)()
+
+	stored, ok := s.db.adminUsers[u.ID]
+	if !ok {
+		return model.AdminUser{}, storage.ErrNotFound
+	}
+	stored.TOTPSecret = u.TOTPSecret
+	stored.TOTPEnabled = u.TOTPEnabled
+	stored.TOTPLastStep = u.TOTPLastStep
+	stored.RecoveryCodes = append([]string{}, u.RecoveryCodes...)
+	stored.UpdatedAt = s.db.bump(stored.UpdatedAt)
+	s.db.adminUsers[u.ID] = stored
+	return stored, nil
+}
+
+func (s *AdminUserStorage) UseTOTPStep(ctx context.Context, id uuid.UUID, step int64) error {
+	defer s.db.write(ctx)()
+
+	u, ok := s.db.adminUsers[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if step <= u.TOTPLastStep {
+		return storage.ErrConflict
+	}
+	u.TOTPLastStep = step
+	s.db.adminUsers[id] = u
+	return nil
+}
+
+func (s *AdminUserStorage) UseRecoveryCode(ctx context.Context, id uuid.UUID, hash string) error {
+	defer s.db.write(ctx)()
+
+	u, ok := s.db.adminUsers[id]
+	if !ok || !slices.Contains(u.RecoveryCodes, hash) {
+		return storage.ErrNotFound
+	}
+	codes := make([]string, 0, len(u.RecoveryCodes)-1)
+	for _, c := range u.RecoveryCodes {
+		if c != hash {
+			codes = append(codes, c)
+		}
+	}
+	u.RecoveryCodes = codes
+	s.db.adminUsers[id] = u
+	return nil
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+type APITokenStorage struct {
+	db *DB
+}
+
+var _ storage.APITokens = (*APITokenStorage)(nil)
+
+func NewAPITokenStorage(db *DB) *APITokenStorage {
+	return &APITokenStorage{
+		db: db,
+	}
+}
+
+func (s *APITokenStorage) Create(ctx context.Context, t model.APIToken) (model.APIToken, error) {
+	defer s.db.write(ctx)()
+
+	t.ID = newID(t.ID)
+	if _, ok := s.db.apiTokens[t.ID]; ok {
+		return model.APIToken{}, storage.ErrConflict
+	}
+	// The foreign key on admin_users.
+	if _, ok := s.db.adminUsers[t.UserID]; !ok {
+		return model.APIToken{}, storage.ErrInvalid
+	}
+	for _, other := range s.db.apiTokens {
+		if other.TokenHash == t.TokenHash {
+			return model.APIToken{}, storage.ErrConflict
+		}
+	}
+	t.Scopes = slices.Clone(t.Scopes)
+	t.CreatedAt = orDefault(t.CreatedAt, s.db.now())
+
+	s.db.apiTokens[t.ID] = t
+	return t, nil
+}
+
+func (s *APITokenStorage) GetByHash(ctx context.Context, tokenHash string) (model.APIToken, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, t := range s.db.apiTokens {
+		if t.TokenHash == tokenHash {
+			t.Scopes = slices.Clone(t.Scopes)
+			return t, nil
+		}
+	}
+	return model.APIToken{}, storage.ErrNotFound
+}
+
+func (s *APITokenStorage) ListByUser(ctx context.Context, userID uuid.UUID) ([]model.APIToken, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	tokens := []model.APIToken{}
+	for _, t := range s.db.apiTokens {
+		if t.UserID == userID {
+			t.Scopes = slices.Clone(t.Scopes)
+			tokens = append(tokens, t)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b model.APIToken) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(a.ID.String(), b.ID.String()))
+	})
+	return tokens, nil
+}
+
+func (s *APITokenStorage) Touch(ctx context.Context, id uuid.UUID, t time.Time, ip string) error {
+	defer s.db.write(ctx)()
+
+	tok, ok := s.db.apiTokens[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	tok.LastUsedAt, tok.LastUsedIP = &t, ip
+	s.db.apiTokens[id] = tok
+	return nil
+}
+
+func (s *APITokenStorage) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	defer s.db.write(ctx)()
+
+	t, ok := s.db.apiTokens[id]
+	if !ok || t.UserID != userID {
+		return storage.ErrNotFound
+	}
+	delete(s.db.apiTokens, id)
+	return nil
+}
@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+)
+
+type AssetStorage struct {
+	db *DB
+}
+
+var _ storage.Assets = (*AssetStorage)(nil)
+
+func NewAssetStorage(db *DB) *AssetStorage {
+	return &AssetStorage{
+		db: db,
+	}
+}
+
+func (s *AssetStorage) Save(ctx context.Context, a model.Asset) (model.Asset, bool, error) {
+	defer s.db.write(ctx)()
+
+	if existing, ok := s.db.assets[a.Hash]; ok {
+		return existing, false, nil
+	}
+	a.CreatedAt = orDefault(a.CreatedAt, s.db.now())
+	s.db.assets[a.Hash] = a
+	return a, true, nil
+}
+
+func (s *AssetStorage) Get(ctx context.Context, hash string) (model.Asset, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	a, ok := s.db.assets[hash]
+	if !ok {
+		return model.Asset{}, storage.ErrNotFound
+	}
+	return a, nil
+}
+
+func (s *AssetStorage) List(ctx context.Context, limit, offset int) ([]model.Asset, int, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	assets := make([]model.Asset, 0, len(s.db.assets))
+	for _, a := range s.db.assets {
+		assets = append(assets, a)
+	}
+	slices.SortFunc(assets, func(a, b model.Asset) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Hash, b.Hash)
+	})
+	return page(assets, limit, offset), len(assets), nil
+}
+
+func (s *AssetStorage) ByURLs(ctx context.Context, urls []string) ([]model.Asset, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	assets := []model.Asset{}
+	for _, a := range s.db.assets {
+		if slices.Contains(urls, a.URL) {
+			assets = append(assets, a)
+		}
+	}
+	return assets, nil
+}
+
+func (s *AssetStorage) Pending(ctx context.Context) ([]model.Asset, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	assets := []model.Asset{}
+	for _, a := range s.db.assets {
+		if a.Variants == nil {
+			assets = append(assets, a)
+		}
+	}
+	slices.SortFunc(assets, func(a, b model.Asset) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Hash, b.Hash)
+	})
+	return assets, nil
+}
+
+func (s *AssetStorage) SetVariants(ctx context.Context, hash string, width, height int, v []model.ImageVariant) error {
+	defer s.db.write(ctx)()
+
+	a, ok := s.db.assets[hash]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	a.Width, a.Height = width, height
+	a.Variants = append([]model.ImageVariant{}, v...)
+	s.db.assets[hash] = a
+	return nil
+}
@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+type ExhibitStorage struct {
+	db *DB
+}
+
+var _ storage.Storage[model.Exhibit] = (*ExhibitStorage)(nil)
+
+func NewExhibitStorage(db *DB) *ExhibitStorage {
+	return &ExhibitStorage{
+		db: db,
+	}
+}
+
+func (s *ExhibitStorage) Read(ctx context.Context, id uuid.UUID) (model.Exhibit, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	e, ok := s.db.exhibits[id]
+	if !ok || !e.DeletedAt.IsZero() {
+		return model.Exhibit{}, storage.ErrNotFound
+	}
+	return cloneExhibit(e), nil
+}
+
+func (s *ExhibitStorage) Create(ctx context.Context, e model.Exhibit) (uuid.UUID, error) {
+	defer s.db.write(ctx)()
+
+	e.ID = newID(e.ID)
+	if _, ok := s.db.exhibits[e.ID]; ok {
+		return uuid.Nil, storage.ErrConflict
+	}
+	now := s.db.now()
+	e.CreatedAt = orDefault(e.CreatedAt, now)
+	e.UpdatedAt = orDefault(e.UpdatedAt, now)
+	e.ImageURLs = images(e.ImageURLs)
+	e.DeletedAt = time.Time{}
+
+	s.db.exhibits[e.ID] = e
+	return e.ID, nil
+}
+
+func (s *ExhibitStorage) Delete(ctx context.Context, id uuid.UUID) error {
+	defer s.db.write(ctx)()
+
+	if e, ok := s.db.exhibits[id]; ok && e.DeletedAt.IsZero() {
+		e.DeletedAt = s.db.now()
+		s.db.exhibits[id] = e
+	}
+	return nil
+}
+
+// Update applies the non-zero fields of e, like bun's OmitZero update,
+// after checking the updated_at precondition.
+func (s *ExhibitStorage) Update(ctx context.Context, e model.Exhibit) (model.Exhibit, error) {
+	defer s.db.write(ctx)()
+
+	cur, ok := s.db.exhibits[e.ID]
+	if !ok || !cur.DeletedAt.IsZero() {
+		return model.Exhibit{}, storage.ErrNotFound
+	}
+	if !e.UpdatedAt.IsZero() && !e.UpdatedAt.Equal(cur.UpdatedAt) {
+		return model.Exhibit{}, storage.ErrStale
+	}
+	if e.ExhibitionID != uuid.Nil {
+		cur.ExhibitionID = e.ExhibitionID
+	}
+	if e.Title != "" {
+		cur.Title = e.Title
+	}
+	if e.Description != "" {
+		cur.Description = e.Description
+	}
+	if e.ImageURLs != nil {
+		cur.ImageURLs = images(e.ImageURLs)
+	}
+	if !e.CreatedAt.IsZero() {
+		cur.CreatedAt = e.CreatedAt
+	}
+	cur.UpdatedAt = s.db.bump(cur.UpdatedAt)
+
+	s.db.exhibits[cur.ID] = cur
+	return cloneExhibit(cur), nil
+}
+
+func (s *ExhibitStorage) Replace(ctx context.Context, e model.Exhibit) (model.Exhibit, error) {
+	defer s.db.write(ctx)()
+
+	cur, ok := s.db.exhibits[e.ID]
+	if !ok || !cur.DeletedAt.IsZero() {
+		return model.Exhibit{}, storage.ErrNotFound
+	}
+	cur.ExhibitionID = e.ExhibitionID
+	cur.Title = e.Title
+	cur.Description = e.Description
+	cur.ImageURLs = images(e.ImageURLs)
+	cur.UpdatedAt = s.db.bump(cur.UpdatedAt)
+
+	s.db.exhibits[cur.ID] = cur
+	return cloneExhibit(cur), nil
+}
+
+func (s *ExhibitStorage) List(ctx context.Context) ([]model.Exhibit, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	exhibits := alive(s.db.exhibits,
+		func(e model.Exhibit) time.Time { return e.DeletedAt },
+		func(e model.Exhibit) time.Time { return e.CreatedAt })
+	for i := range exhibits {
+		exhibits[i] = cloneExhibit(exhibits[i])
+	}
+	return exhibits, nil
+}
+
+var exhibitFields = fields[model.Exhibit]{
+	id:        func(e model.Exhibit) uuid.UUID { return e.ID },
+	title:     func(e model.Exhibit) string { return e.Title },
+	createdAt: func(e model.Exhibit) time.Time { return e.CreatedAt },
+}
+
+func (s *ExhibitStorage) Query(ctx context.Context, opts storage.ListOptions) ([]model.Exhibit, int, error) {
+	list, err := s.List(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	exhibits, total := query(list, opts, exhibitFields)
+	return exhibits, total, nil
+}
+
+func (s *ExhibitStorage) First(ctx context.Context, f func(model.Exhibit) bool) (model.Exhibit, error) {
+	exhibits, err := s.List(ctx)
+	if err != nil {
+		return model.Exhibit{}, err
+	}
+	for _, e := range exhibits {
+		if f(e) {
+			return e, nil
+		}
+	}
+	return model.Exhibit{}, storage.ErrNotFound
+}
+
+// exhibitsOf returns the non-deleted exhibits of an exhibition, as bun's
+// Relation("Exhibits") does. The caller must hold db.mu.
+func (db *DB) exhibitsOf(exhibitionID uuid.UUID) []model.Exhibit {
+	var out []model.Exhibit
+	for _, e := range db.exhibits {
+		if e.ExhibitionID == exhibitionID && e.DeletedAt.IsZero() {
+			out = append(out, cloneExhibit(e))
+		}
+	}
+	slices.SortFunc(out, byExhibitCreated)
+	return out
+}
+
+func cloneExhibit(e model.Exhibit) model.Exhibit {
+	e.ImageURLs = images(e.ImageURLs)
+	return e
+}
@@ -0,0 +1,198 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+type ExhibitionStorage struct {
+	db *DB
+}
+
+var _ storage.Exhibitions = (*ExhibitionStorage)(nil)
+
+func NewExhibitionStorage(db *DB) *ExhibitionStorage {
+	return &ExhibitionStorage{
+		db: db,
+	}
+}
+
+func (s *ExhibitionStorage) Read(ctx context.Context, id uuid.UUID) (model.Exhibition, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	ex, ok := s.db.exhibitions[id]
+	if !ok || !ex.DeletedAt.IsZero() {
+		return model.Exhibition{}, storage.ErrNotFound
+	}
+	ex = cloneExhibition(ex)
+	ex.Exhibits = s.db.exhibitsOf(ex.ID)
+	return ex, nil
+}
+
+func (s *ExhibitionStorage) Create(ctx context.Context, ex model.Exhibition) (uuid.UUID, error) {
+	defer s.db.write(ctx)()
+
+	ex.ID = newID(ex.ID)
+	if _, ok := s.db.exhibitions[ex.ID]; ok {
+		return uuid.Nil, storage.ErrConflict
+	}
+	now := s.db.now()
+	ex.CreatedAt = orDefault(ex.CreatedAt, now)
+	ex.UpdatedAt = orDefault(ex.UpdatedAt, now)
+	ex.DeletedAt = time.Time{}
+	// Exhibits are a relation, not a column: they are never inserted along with the exhibition.
+	ex.Exhibits = nil
+
+	s.db.exhibitions[ex.ID] = cloneExhibition(ex)
+	return ex.ID, nil
+}
+
+// Delete soft-deletes the exhibition together with all its exhibits.
+func (s *ExhibitionStorage) Delete(ctx context.Context, id uuid.UUID) error {
+	defer s.db.write(ctx)()
+
+	now := s.db.now()
+	for eid, e := range s.db.exhibits {
+		if e.ExhibitionID == id && e.DeletedAt.IsZero() {
+			e.DeletedAt = now
+			s.db.exhibits[eid] = e
+		}
+	}
+	if ex, ok := s.db.exhibitions[id]; ok && ex.DeletedAt.IsZero() {
+		ex.DeletedAt = now
+		s.db.exhibitions[id] = ex
+	}
+	return nil
+}
+
+// Update applies the non-zero fields of ex, like bun's OmitZero update,
+// after checking the updated_at precondition.
+// The returned exhibition does not carry its exhibits.
+func (s *ExhibitionStorage) Update(ctx context.Context, ex model.Exhibition) (model.Exhibition, error) {
+	defer s.db.write(ctx)()
+
+	cur, ok := s.db.exhibitions[ex.ID]
+	if !ok || !cur.DeletedAt.IsZero() {
+		return model.Exhibition{}, storage.ErrNotFound
+	}
+	if !ex.UpdatedAt.IsZero() && !ex.UpdatedAt.Equal(cur.UpdatedAt) {
+		return model.Exhibition{}, storage.ErrStale
+	}
+	if ex.Title != "" {
+		cur.Title = ex.Title
+	}
+	if ex.Description != "" {
+		cur.Description = ex.Description
+	}
+	if ex.PreviewExhibitID != nil {
+		cur.PreviewExhibitID = ex.PreviewExhibitID
+	}
+	if !ex.CreatedAt.IsZero() {
+		cur.CreatedAt = ex.CreatedAt
+	}
+	cur.UpdatedAt = s.db.bump(cur.UpdatedAt)
+
+	s.db.exhibitions[cur.ID] = cur
+	return cloneExhibition(cur), nil
+}
+
+// Replace overwrites the exhibition's fields.
+// The returned exhibition does not carry its exhibits.
+func (s *ExhibitionStorage) Replace(ctx context.Context, ex model.Exhibition) (model.Exhibition, error) {
+	defer s.db.write(ctx)()
+
+	cur, ok := s.db.exhibitions[ex.ID]
+	if !ok || !cur.DeletedAt.IsZero() {
+		return model.Exhibition{}, storage.ErrNotFound
+	}
+	cur.Title = ex.Title
+	cur.Description = ex.Description
+	cur.PreviewExhibitID = ex.PreviewExhibitID
+	cur.UpdatedAt = s.db.bump(cur.UpdatedAt)
+
+	s.db.exhibitions[cur.ID] = cur
+	return cloneExhibition(cur), nil
+}
+
+func (s *ExhibitionStorage) List(ctx context.Context) ([]model.Exhibition, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	exhibitions := alive(s.db.exhibitions,
+		func(ex model.Exhibition) time.Time { return ex.DeletedAt },
+		func(ex model.Exhibition) time.Time { return ex.CreatedAt })
+	for i := range exhibitions {
+		exhibitions[i] = cloneExhibition(exhibitions[i])
+		exhibitions[i].Exhibits = s.db.exhibitsOf(exhibitions[i].ID)
+	}
+	return exhibitions, nil
+}
+
+var exhibitionFields = fields[model.Exhibition]{
+	id:        func(ex model.Exhibition) uuid.UUID { return ex.ID },
+	title:     func(ex model.Exhibition) string { return ex.Title },
+	createdAt: func(ex model.Exhibition) time.Time { return ex.CreatedAt },
+}
+
+func (s *ExhibitionStorage) Query(ctx context.Context, opts storage.ListOptions) ([]model.Exhibition, int, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	exhibitions, total := query(alive(s.db.exhibitions,
+		func(ex model.Exhibition) time.Time { return ex.DeletedAt },
+		func(ex model.Exhibition) time.Time { return ex.CreatedAt }),
+		opts, exhibitionFields)
+	for i := range exhibitions {
+		exhibitions[i] = cloneExhibition(exhibitions[i])
+		if opts.WithRelations {
+			exhibitions[i].Exhibits = s.db.exhibitsOf(exhibitions[i].ID)
+		}
+	}
+	return exhibitions, total, nil
+}
+
+func (s *ExhibitionStorage) First(ctx context.Context, f func(model.Exhibition) bool) (model.Exhibition, error) {
+	exhibitions, err := s.List(ctx)
+	if err != nil {
+		return model.Exhibition{}, err
+	}
+	for _, ex := range exhibitions {
+		if f(ex) {
+			return ex, nil
+		}
+	}
+	return model.Exhibition{}, storage.ErrNotFound
+}
+
+// SetPreview sets the preview exhibit for an exhibition.
+// Pass nil to clear the preview.
+func (s *ExhibitionStorage) SetPreview(ctx context.Context, exhibitionID uuid.UUID, exhibitID *uuid.UUID) error {
+	defer s.db.write(ctx)()
+
+	ex, ok := s.db.exhibitions[exhibitionID]
+	if !ok || !ex.DeletedAt.IsZero() {
+		return storage.ErrNotFound
+	}
+	if exhibitID != nil {
+		id := *exhibitID
+		exhibitID = &id
+	}
+	ex.PreviewExhibitID = exhibitID
+	ex.UpdatedAt = s.db.bump(ex.UpdatedAt)
+	s.db.exhibitions[exhibitionID] = ex
+	return nil
+}
+
+func cloneExhibition(ex model.Exhibition) model.Exhibition {
+	if ex.PreviewExhibitID != nil {
+		id := *ex.PreviewExhibitID
+		ex.PreviewExhibitID = &id
+	}
+	ex.Exhibits = nil
+	return ex
+}
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+type ImportJobStorage struct {
+	db *DB
+}
+
+var _ storage.ImportJobs = (*ImportJobStorage)(nil)
+
+func NewImportJobStorage(db *DB) *ImportJobStorage {
+	return &ImportJobStorage{
+		db: db,
+	}
+}
+
+func (s *ImportJobStorage) Create(ctx context.Context, j model.ImportJob) (model.ImportJob, error) {
+	defer s.db.write(ctx)()
+
+	j.ID = newID(j.ID)
+	if _, ok := s.db.importJobs[j.ID]; ok {
+		return model.ImportJob{}, storage.ErrConflict
+	}
+	j.CreatedAt = orDefault(j.CreatedAt, s.db.now())
+	j.Items = slices.Clone(j.Items)
+	if j.Items == nil {
+		j.Items = []model.ImportItem{}
+	}
+
+	s.db.importJobs[j.ID] = j
+	return cloneImportJob(j), nil
+}
+
+func (s *ImportJobStorage) Get(ctx context.Context, id uuid.UUID) (model.ImportJob, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	j, ok := s.db.importJobs[id]
+	if !ok {
+		return model.ImportJob{}, storage.ErrNotFound
+	}
+	return cloneImportJob(j), nil
+}
+
+func (s *ImportJobStorage) Update(ctx context.Context, j model.ImportJob) error {
+	defer s.db.write(ctx)()
+
+	stored, ok := s.db.importJobs[j.ID]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	stored.Status = j.Status
+	stored.Items = slices.Clone(j.Items)
+	if stored.Items == nil {
+		stored.Items = []model.ImportItem{}
+	}
+	stored.Error = j.Error
+	stored.FinishedAt = j.FinishedAt
+	s.db.importJobs[j.ID] = stored
+	return nil
+}
+
+func (s *ImportJobStorage) Pending(ctx context.Context) ([]model.ImportJob, error) {
+	return s.filter(func(j model.ImportJob) bool {
+		return j.Status == model.ImportPending || j.Status == model.ImportRunning
+	}, 1), nil
+}
+
+func (s *ImportJobStorage) ByOwner(ctx context.Context, owner model.EntityType, ownerID uuid.UUID) ([]model.ImportJob, error) {
+	return s.filter(func(j model.ImportJob) bool {
+		return j.OwnerType == owner && j.OwnerID == ownerID
+	}, -1), nil
+}
+
+// filter returns the jobs f accepts ordered by creation time, oldest first
+// for a positive dir and newest first for a negative one.
+func (s *ImportJobStorage) filter(f func(model.ImportJob) bool, dir int) []model.ImportJob {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	jobs := []model.ImportJob{}
+	for _, j := range s.db.importJobs {
+		if f(j) {
+			jobs = append(jobs, cloneImportJob(j))
+		}
+	}
+	slices.SortFunc(jobs, func(a, b model.ImportJob) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return dir * c
+		}
+		return cmp.Compare(a.ID.String(), b.ID.String())
+	})
+	return jobs
+}
+
+// cloneImportJob copies the items, so callers cannot change the stored job.
+func cloneImportJob(j model.ImportJob) model.ImportJob {
+	j.Items = slices.Clone(j.Items)
+	return j
+}
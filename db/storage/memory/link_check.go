@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+type LinkCheckStorage struct {
+	db *DB
+}
+
+var _ storage.LinkChecks = (*LinkCheckStorage)(nil)
+
+func NewLinkCheckStorage(db *DB) *LinkCheckStorage {
+	return &LinkCheckStorage{
+		db: db,
+	}
+}
+
+func (s *LinkCheckStorage) Save(ctx context.Context, owner model.EntityType, ownerID uuid.UUID, checks []model.LinkCheck) error {
+	defer s.db.write(ctx)()
+
+	key := mediaOwner{owner, ownerID}
+	rows := make([]model.LinkCheck, 0, len(checks))
+	for _, c := range checks {
+		if slices.ContainsFunc(rows, func(r model.LinkCheck) bool { return r.URL == c.URL }) {
+			return storage.ErrConflict
+		}
+		c.OwnerType, c.OwnerID = owner, ownerID
+		rows = append(rows, c)
+	}
+	if len(rows) == 0 {
+		delete(s.db.linkChecks, key)
+		return nil
+	}
+	s.db.linkChecks[key] = rows
+	return nil
+}
+
+func (s *LinkCheckStorage) DeleteBefore(ctx context.Context, t time.Time) (int, error) {
+	defer s.db.write(ctx)()
+
+	n := 0
+	for key, rows := range s.db.linkChecks {
+		kept := slices.DeleteFunc(slices.Clone(rows), func(c model.LinkCheck) bool { return c.CheckedAt.Before(t) })
+		n += len(rows) - len(kept)
+		if len(kept) == 0 {
+			delete(s.db.linkChecks, key)
+		} else {
+			s.db.linkChecks[key] = kept
+		}
+	}
+	return n, nil
+}
+
+func (s *LinkCheckStorage) List(ctx context.Context, statuses ...model.LinkStatus) ([]model.LinkCheck, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	checks := []model.LinkCheck{}
+	for _, rows := range s.db.linkChecks {
+		for _, c := range rows {
+			if len(statuses) == 0 || slices.Contains(statuses, c.Status) {
+				checks = append(checks, c)
+			}
+		}
+	}
+	slices.SortFunc(checks, func(a, b model.LinkCheck) int {
+		return cmp.Or(
+			cmp.Compare(a.OwnerType, b.OwnerType),
+			cmp.Compare(a.OwnerID.String(), b.OwnerID.String()),
+			cmp.Compare(a.URL, b.URL),
+		)
+	})
+	return checks, nil
+}
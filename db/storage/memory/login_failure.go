@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+type LoginFailureStorage struct {
+	db *DB
+}
+
+var _ storage.LoginFailures = (*LoginFailureStorage)(nil)
+
+func NewLoginFailureStorage(db *DB) *LoginFailureStorage {
+	return &LoginFailureStorage{
+		db: db,
+	}
+}
+
+var loginFailureFields = fields[model.LoginFailure]{
+	id:        func(f model.LoginFailure) uuid.UUID { return f.ID },
+	title:     func(f model.LoginFailure) string { return f.Login },
+	createdAt: func(f model.LoginFailure) time.Time { return f.CreatedAt },
+}
+
+func (s *LoginFailureStorage) Create(ctx context.Context, f model.LoginFailure) (model.LoginFailure, error) {
+	defer s.db.write(ctx)()
+
+	f.ID = newID(f.ID)
+	if _, ok := s.db.loginFailures[f.ID]; ok {
+		return model.LoginFailure{}, storage.ErrConflict
+	}
+	f.CreatedAt = orDefault(f.CreatedAt, s.db.now())
+
+	s.db.loginFailures[f.ID] = f
+	return f, nil
+}
+
+func (s *LoginFailureStorage) Query(ctx context.Context, login string, opts storage.ListOptions) ([]model.LoginFailure, int, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	rows := make([]model.LoginFailure, 0, len(s.db.loginFailures))
+	for _, f := range s.db.loginFailures {
+		if login == "" || f.Login == login {
+			rows = append(rows, f)
+		}
+	}
+	opts.Sort = storage.SortCreatedAt
+	failures, total := query(rows, opts, loginFailureFields)
+	return failures, total, nil
+}
+
+func (s *LoginFailureStorage) DeleteBefore(ctx context.Context, t time.Time) (int, error) {
+	defer s.db.write(ctx)()
+
+	n := 0
+	for id, f := range s.db.loginFailures {
+		if f.CreatedAt.Before(t) {
+			delete(s.db.loginFailures, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// mediaOwner keys the attachments of media and the link checks of a news
+// item or an exhibit.
+type mediaOwner struct {
+	typ model.EntityType
+	id  uuid.UUID
+}
+
+type MediaStorage struct {
+	db *DB
+}
+
+var _ storage.MediaLibrary = (*MediaStorage)(nil)
+
+func NewMediaStorage(db *DB) *MediaStorage {
+	return &MediaStorage{
+		db: db,
+	}
+}
+
+func (s *MediaStorage) Create(ctx context.Context, m model.Media) (model.Media, error) {
+	defer s.db.write(ctx)()
+
+	m.ID = newID(m.ID)
+	if _, ok := s.db.media[m.ID]; ok {
+		return model.Media{}, storage.ErrConflict
+	}
+	now := s.db.now()
+	m.CreatedAt = orDefault(m.CreatedAt, now)
+	m.UpdatedAt = orDefault(m.UpdatedAt, now)
+
+	s.db.media[m.ID] = m
+	return m, nil
+}
+
+func (s *MediaStorage) Read(ctx context.Context, id uuid.UUID) (model.Media, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	m, ok := s.db.media[id]
+	if !ok {
+		return model.Media{}, storage.ErrNotFound
+	}
+	return m, nil
+}
+
+func (s *MediaStorage) Update(ctx context.Context, m model.Media) (model.Media, error) {
+	defer s.db.write(ctx)()
+
+	cur, ok := s.db.media[m.ID]
+	if !ok {
+		return model.Media{}, storage.ErrNotFound
+	}
+	if !m.UpdatedAt.IsZero() && !m.UpdatedAt.Equal(cur.UpdatedAt) {
+		return model.Media{}, storage.ErrStale
+	}
+	cur.URL = m.URL
+	cur.Caption = m.Caption
+	cur.Alt = m.Alt
+	cur.Credit = m.Credit
+	cur.License = m.License
+	cur.UpdatedAt = s.db.bump(cur.UpdatedAt)
+
+	s.db.media[cur.ID] = cur
+	return cur, nil
+}
+
+// Delete removes the item and, like ON DELETE CASCADE, its attachments.
+func (s *MediaStorage) Delete(ctx context.Context, id uuid.UUID) error {
+	defer s.db.write(ctx)()
+
+	if _, ok := s.db.media[id]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(s.db.media, id)
+	for owner, ids := range s.db.mediaLinks {
+		if slices.Contains(ids, id) {
+			s.db.mediaLinks[owner] = slices.DeleteFunc(slices.Clone(ids), func(m uuid.UUID) bool { return m == id })
+		}
+	}
+	return nil
+}
+
+var mediaFields = fields[model.Media]{
+	id:        func(m model.Media) uuid.UUID { return m.ID },
+	title:     func(m model.Media) string { return m.Caption },
+	createdAt: func(m model.Media) time.Time { return m.CreatedAt },
+}
+
+func (s *MediaStorage) Query(ctx context.Context, opts storage.ListOptions) ([]model.Media, int, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	rows := make([]model.Media, 0, len(s.db.media))
+	for _, m := range s.db.media {
+		rows = append(rows, m)
+	}
+	media, total := query(rows, opts, mediaFields)
+	return media, total, nil
+}
+
+func (s *MediaStorage) Attach(ctx context.Context, owner model.EntityType, ownerID uuid.UUID, ids []uuid.UUID) error {
+	defer s.db.write(ctx)()
+
+	if err := s.db.checkOwner(owner, ownerID); err != nil {
+		return err
+	}
+	for i, id := range ids {
+		if _, ok := s.db.media[id]; !ok {
+			return fmt.Errorf("media %s: %w", id, storage.ErrInvalid)
+		}
+		if slices.Contains(ids[:i], id) {
+			return fmt.Errorf("media %s attached twice: %w", id, storage.ErrConflict)
+		}
+	}
+
+	key := mediaOwner{owner, ownerID}
+	if len(ids) == 0 {
+		delete(s.db.mediaLinks, key)
+		return nil
+	}
+	s.db.mediaLinks[key] = slices.Clone(ids)
+	return nil
+}
+
+// checkOwner mirrors the foreign key of the join table on the owner.
+// The caller must hold db.mu.
+func (db *DB) checkOwner(owner model.EntityType, id uuid.UUID) error {
+	var ok bool
+	switch owner {
+	case model.EntityNews:
+		_, ok = db.news[id]
+	case model.EntityExhibit:
+		_, ok = db.exhibits[id]
+	default:
+		return fmt.Errorf("%s has no media: %w", owner, storage.ErrInvalid)
+	}
+	if !ok {
+		return fmt.Errorf("%s %s: %w", owner, id, storage.ErrInvalid)
+	}
+	return nil
+}
+
+func (s *MediaStorage) Attached(ctx context.Context, owner model.EntityType, ownerIDs []uuid.UUID) (map[uuid.UUID][]model.AttachedMedia, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	if owner != model.EntityNews && owner != model.EntityExhibit {
+		return nil, fmt.Errorf("%s has no media: %w", owner, storage.ErrInvalid)
+	}
+	byOwner := make(map[uuid.UUID][]model.AttachedMedia)
+	for _, ownerID := range ownerIDs {
+		for i, id := range s.db.mediaLinks[mediaOwner{owner, ownerID}] {
+			byOwner[ownerID] = append(byOwner[ownerID], model.AttachedMedia{
+				Media:    s.db.media[id],
+				OwnerID:  ownerID,
+				Position: i,
+			})
+		}
+	}
+	return byOwner, nil
+}
@@ -0,0 +1,168 @@
+// Package memory implements the db/storage interfaces on top of in-process maps.
+// It is meant for tests and for running the site in demo mode without Postgres,
+// and mirrors the soft-delete behaviour of the bun-backed storages.
+package memory
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// DB is the shared in-memory dataset. Storages created from the same DB see
+// each other's data, just like the Postgres storages sharing one *bun.DB.
+type DB struct {
+	mu sync.RWMutex
+	// txMu serialises units of work and the writes made outside of them,
+	// see InTx.
+	txMu sync.Mutex
+
+	news          map[uuid.UUID]model.News
+	exhibitions   map[uuid.UUID]model.Exhibition
+	exhibits      map[uuid.UUID]model.Exhibit
+	visitors      map[string]model.Visitor
+	revisions     []model.Revision
+	assets        map[string]model.Asset
+	media         map[uuid.UUID]model.Media
+	mediaLinks    map[mediaOwner][]uuid.UUID
+	resolved      map[string]model.ResolvedMedia
+	importJobs    map[uuid.UUID]model.ImportJob
+	linkChecks    map[mediaOwner][]model.LinkCheck
+	adminUsers    map[uuid.UUID]model.AdminUser
+	adminSessions map[uuid.UUID]model.AdminSession
+	apiTokens     map[uuid.UUID]model.APIToken
+	loginFailures map[uuid.UUID]model.LoginFailure
+
+	lastVisitorID int64
+
+	now func() time.Time
+}
+
+func NewDB() *DB {
+	return &DB{
+		news:          make(map[uuid.UUID]model.News),
+		exhibitions:   make(map[uuid.UUID]model.Exhibition),
+		exhibits:      make(map[uuid.UUID]model.Exhibit),
+		visitors:      make(map[string]model.Visitor),
+		assets:        make(map[string]model.Asset),
+		media:         make(map[uuid.UUID]model.Media),
+		mediaLinks:    make(map[mediaOwner][]uuid.UUID),
+		resolved:      make(map[string]model.ResolvedMedia),
+		importJobs:    make(map[uuid.UUID]model.ImportJob),
+		linkChecks:    make(map[mediaOwner][]model.LinkCheck),
+		adminUsers:    make(map[uuid.UUID]model.AdminUser),
+		adminSessions: make(map[uuid.UUID]model.AdminSession),
+		apiTokens:     make(map[uuid.UUID]model.APIToken),
+		loginFailures: make(map[uuid.UUID]model.LoginFailure),
+		now:           now,
+	}
+}
+
+// now returns the current time with the microsecond precision of Postgres timestamptz.
+func now() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+// bump returns the next updated_at of a row last updated at prev.
+// Like the SQL storages it never goes backwards or repeats, so updated_at
+// can serve as the row version.
+func (db *DB) bump(prev time.Time) time.Time {
+	next := db.now()
+	if !next.After(prev) {
+		next = prev.Add(time.Microsecond)
+	}
+	return next
+}
+
+// newID returns id, or a fresh random UUID if it is zero (uuid_generate_v4() default).
+func newID(id uuid.UUID) uuid.UUID {
+	if id == uuid.Nil {
+		return uuid.New()
+	}
+	return id
+}
+
+// orDefault returns t, or def if t is zero (nullzero,default:current_timestamp).
+func orDefault(t, def time.Time) time.Time {
+	if t.IsZero() {
+		return def
+	}
+	return t
+}
+
+// images mirrors the text[] DEFAULT '{}' column: nil slices are stored as empty.
+func images(urls []string) []string {
+	if urls == nil {
+		return []string{}
+	}
+	return slices.Clone(urls)
+}
+
+// alive returns the non-deleted values of m ordered by creation time.
+func alive[T any](m map[uuid.UUID]T, deletedAt, createdAt func(T) time.Time) []T {
+	out := make([]T, 0, len(m))
+	for _, v := range m {
+		if deletedAt(v).IsZero() {
+			out = append(out, v)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b T) int {
+		return createdAt(a).Compare(createdAt(b))
+	})
+	return out
+}
+
+func byExhibitCreated(a, b model.Exhibit) int {
+	return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), slices.Compare(a.ID[:], b.ID[:]))
+}
+
+// fields exposes the columns ListOptions can filter and sort by.
+type fields[T any] struct {
+	id        func(T) uuid.UUID
+	title     func(T) string
+	createdAt func(T) time.Time
+}
+
+// query filters, sorts and pages rows like storage.ListOptions does in SQL.
+// It returns the page and the total number of matching rows.
+func query[T any](rows []T, opts storage.ListOptions, f fields[T]) ([]T, int) {
+	matched := make([]T, 0, len(rows))
+	for _, r := range rows {
+		if opts.Match(f.createdAt(r)) {
+			matched = append(matched, r)
+		}
+	}
+
+	slices.SortStableFunc(matched, func(a, b T) int {
+		var c int
+		switch opts.SortColumn() {
+		case storage.SortTitle:
+			c = storage.CompareTitles(f.title(a), f.title(b))
+		default:
+			c = f.createdAt(a).Compare(f.createdAt(b))
+		}
+		ida, idb := f.id(a), f.id(b)
+		c = cmp.Or(c, slices.Compare(ida[:], idb[:]))
+		if opts.Desc {
+			return -c
+		}
+		return c
+	})
+
+	return page(matched, opts.Limit, opts.Offset), len(matched)
+}
+
+// page returns rows[offset:offset+limit], clamped to the slice; a zero limit means no limit.
+func page[T any](rows []T, limit, offset int) []T {
+	start := min(offset, len(rows))
+	end := len(rows)
+	if limit > 0 {
+		end = min(start+limit, len(rows))
+	}
+	return rows[start:end]
+}
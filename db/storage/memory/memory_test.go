@@ -0,0 +1,200 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/db/storage/memory"
+	"github.com/WhiCu/school-museum/db/storage/storagetest"
+	"github.com/google/uuid"
+)
+
+func TestSharedDB(t *testing.T) {
+	ctx := context.Background()
+	db := memory.NewDB()
+	id, err := memory.NewNewsStorage(db).Create(ctx, model.News{Title: "Новость"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := memory.NewNewsStorage(db).Read(ctx, id); err != nil {
+		t.Errorf("Read through another storage of the same DB: %v", err)
+	}
+	if _, err := memory.NewNewsStorage(memory.NewDB()).Read(ctx, id); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Read through a storage of another DB: %v, want ErrNotFound", err)
+	}
+}
+
+func TestRowsAreCopied(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewNewsStorage(memory.NewDB())
+	urls := []string{"https://example.com/1.jpg"}
+	id, err := s.Create(ctx, model.News{Title: "Новость", ImageURLs: urls})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	urls[0] = "changed by the caller"
+	n, err := s.Read(ctx, id)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	n.ImageURLs[0] = "changed by the reader"
+	if n, _ = s.Read(ctx, id); n.ImageURLs[0] != "https://example.com/1.jpg" {
+		t.Errorf("ImageURLs = %q, want the stored value unchanged", n.ImageURLs)
+	}
+}
+
+// TestInTxWriteOutside checks that a rollback keeps the writes made outside
+// of InTx while the unit of work ran; RunTx covers the rest of InTx.
+func TestInTxWriteOutside(t *testing.T) {
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+	db := memory.NewDB()
+	s := memory.NewNewsStorage(db)
+	type result struct {
+		id  uuid.UUID
+		err error
+	}
+	outside := make(chan result, 1)
+	err := db.InTx(ctx, func(txCtx context.Context) error {
+		if _, err := s.Create(txCtx, model.News{Title: "Новость"}); err != nil {
+			return err
+		}
+		go func() {
+			id, err := s.Create(ctx, model.News{Title: "Вне транзакции"})
+			outside <- result{id, err}
+		}()
+		// Give the write outside a chance to land before the rollback.
+		time.Sleep(10 * time.Millisecond)
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("InTx: %v, want the error of f", err)
+	}
+	r := <-outside
+	if r.err != nil {
+		t.Fatalf("Create outside InTx: %v", r.err)
+	}
+	if _, err := s.Read(ctx, r.id); err != nil {
+		t.Errorf("Read of a row written outside InTx during a rollback: %v", err)
+	}
+	if _, total, _ := s.Query(ctx, storage.ListOptions{}); total != 1 {
+		t.Errorf("Query total = %d, want only the row written outside InTx", total)
+	}
+}
+
+func TestNews(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage[model.News] {
+		return memory.NewNewsStorage(memory.NewDB())
+	}, storagetest.News)
+}
+
+func TestExhibitions(t *testing.T) {
+	storagetest.RunExhibitions(t, func(t *testing.T) (storage.Exhibitions, storage.Storage[model.Exhibit]) {
+		db := memory.NewDB()
+		return memory.NewExhibitionStorage(db), memory.NewExhibitStorage(db)
+	})
+}
+
+func TestExhibits(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage[model.Exhibit] {
+		return memory.NewExhibitStorage(memory.NewDB())
+	}, storagetest.Exhibit)
+}
+
+func TestTrash(t *testing.T) {
+	storagetest.RunTrash(t, func(t *testing.T) storagetest.TrashStorage[model.News] {
+		return memory.NewNewsStorage(memory.NewDB())
+	}, storagetest.News)
+	storagetest.RunTrash(t, func(t *testing.T) storagetest.TrashStorage[model.Exhibit] {
+		return memory.NewExhibitStorage(memory.NewDB())
+	}, storagetest.Exhibit)
+	storagetest.RunTrash(t, func(t *testing.T) storagetest.TrashStorage[model.Exhibition] {
+		return memory.NewExhibitionStorage(memory.NewDB())
+	}, storagetest.Exhibition)
+	storagetest.RunExhibitionTrash(t, func(t *testing.T) (storage.Exhibitions, storage.ExhibitionTrash, storage.Storage[model.Exhibit]) {
+		db := memory.NewDB()
+		ex := memory.NewExhibitionStorage(db)
+		return ex, ex, memory.NewExhibitStorage(db)
+	})
+}
+
+func TestTx(t *testing.T) {
+	storagetest.RunTx(t, func(t *testing.T) (storage.Transactor, storage.Exhibitions, storage.Storage[model.Exhibit]) {
+		db := memory.NewDB()
+		return db, memory.NewExhibitionStorage(db), memory.NewExhibitStorage(db)
+	})
+}
+
+func TestRevisions(t *testing.T) {
+	storagetest.RunRevisions(t, func(t *testing.T) storage.Revisions {
+		return memory.NewRevisionStorage(memory.NewDB())
+	})
+}
+
+func TestAssets(t *testing.T) {
+	storagetest.RunAssets(t, func(t *testing.T) storage.Assets {
+		return memory.NewAssetStorage(memory.NewDB())
+	})
+}
+
+func TestMedia(t *testing.T) {
+	storagetest.RunMedia(t, func(t *testing.T) (storage.MediaLibrary, storage.Storage[model.News]) {
+		db := memory.NewDB()
+		return memory.NewMediaStorage(db), memory.NewNewsStorage(db)
+	})
+}
+
+func TestResolvedMedia(t *testing.T) {
+	storagetest.RunResolvedMedia(t, func(t *testing.T) storage.ResolvedMedia {
+		return memory.NewResolvedMediaStorage(memory.NewDB())
+	})
+}
+
+func TestImportJobs(t *testing.T) {
+	storagetest.RunImportJobs(t, func(t *testing.T) storage.ImportJobs {
+		return memory.NewImportJobStorage(memory.NewDB())
+	})
+}
+
+func TestLinkChecks(t *testing.T) {
+	storagetest.RunLinkChecks(t, func(t *testing.T) storage.LinkChecks {
+		return memory.NewLinkCheckStorage(memory.NewDB())
+	})
+}
+
+func TestAdminUsers(t *testing.T) {
+	storagetest.RunAdminUsers(t, func(t *testing.T) storage.AdminUsers {
+		return memory.NewAdminUserStorage(memory.NewDB())
+	})
+}
+
+func TestLockOwners(t *testing.T) {
+	storagetest.RunLockOwners(t, func(t *testing.T) (storage.Transactor, storage.AdminUsers) {
+		db := memory.NewDB()
+		return db, memory.NewAdminUserStorage(db)
+	})
+}
+
+func TestAdminSessions(t *testing.T) {
+	storagetest.RunAdminSessions(t, func(t *testing.T) (storage.AdminUsers, storage.AdminSessions) {
+		db := memory.NewDB()
+		return memory.NewAdminUserStorage(db), memory.NewAdminSessionStorage(db)
+	})
+}
+
+func TestAPITokens(t *testing.T) {
+	storagetest.RunAPITokens(t, func(t *testing.T) (storage.AdminUsers, storage.APITokens) {
+		db := memory.NewDB()
+		return memory.NewAdminUserStorage(db), memory.NewAPITokenStorage(db)
+	})
+}
+
+func TestLoginFailures(t *testing.T) {
+	storagetest.RunLoginFailures(t, func(t *testing.T) storage.LoginFailures {
+		return memory.NewLoginFailureStorage(memory.NewDB())
+	})
+}
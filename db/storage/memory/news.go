@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+type NewsStorage struct {
+	db *DB
+}
+
+var _ storage.Storage[model.News] = (*NewsStorage)(nil)
+
+func NewNewsStorage(db *DB) *NewsStorage {
+	return &NewsStorage{
+		db: db,
+	}
+}
+
+func (s *NewsStorage) Read(ctx context.Context, id uuid.UUID) (model.News, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	n, ok := s.db.news[id]
+	if !ok || !n.DeletedAt.IsZero() {
+		return model.News{}, storage.ErrNotFound
+	}
+	return cloneNews(n), nil
+}
+
+func (s *NewsStorage) Create(ctx context.Context, n model.News) (uuid.UUID, error) {
+	defer s.db.write(ctx)()
+
+	n.ID = newID(n.ID)
+	if _, ok := s.db.news[n.ID]; ok {
+		return uuid.Nil, storage.ErrConflict
+	}
+	now := s.db.now()
+	n.CreatedAt = orDefault(n.CreatedAt, now)
+	n.UpdatedAt = orDefault(n.UpdatedAt, now)
+	n.ImageURLs = images(n.ImageURLs)
+	n.DeletedAt = time.Time{}
+
+	s.db.news[n.ID] = n
+	return n.ID, nil
+}
+
+func (s *NewsStorage) Delete(ctx context.Context, id uuid.UUID) error {
+	defer s.db.write(ctx)()
+
+	if n, ok := s.db.news[id]; ok && n.DeletedAt.IsZero() {
+		n.DeletedAt = s.db.now()
+		s.db.news[id] = n
+	}
+	return nil
+}
+
+// Update applies the non-zero fields of n, like bun's OmitZero update,
+// after checking the updated_at precondition.
+func (s *NewsStorage) Update(ctx context.Context, n model.News) (model.News, error) {
+	defer s.db.write(ctx)()
+
+	cur, ok := s.db.news[n.ID]
+	if !ok || !cur.DeletedAt.IsZero() {
+		return model.News{}, storage.ErrNotFound
+	}
+	if !n.UpdatedAt.IsZero() && !n.UpdatedAt.Equal(cur.UpdatedAt) {
+		return model.News{}, storage.ErrStale
+	}
+	if n.Title != "" {
+		cur.Title = n.Title
+	}
+	if n.Content != "" {
+		cur.Content = n.Content
+	}
+	if n.ImageURLs != nil {
+		cur.ImageURLs = images(n.ImageURLs)
+	}
+	if !n.CreatedAt.IsZero() {
+		cur.CreatedAt = n.CreatedAt
+	}
+	cur.UpdatedAt = s.db.bump(cur.UpdatedAt)
+
+	s.db.news[cur.ID] = cur
+	return cloneNews(cur), nil
+}
+
+func (s *NewsStorage) Replace(ctx context.Context, n model.News) (model.News, error) {
+	defer s.db.write(ctx)()
+
+	cur, ok := s.db.news[n.ID]
+	if !ok || !cur.DeletedAt.IsZero() {
+		return model.News{}, storage.ErrNotFound
+	}
+	cur.Title = n.Title
+	cur.Content = n.Content
+	cur.ImageURLs = images(n.ImageURLs)
+	cur.UpdatedAt = s.db.bump(cur.UpdatedAt)
+
+	s.db.news[cur.ID] = cur
+	return cloneNews(cur), nil
+}
+
+func (s *NewsStorage) List(ctx context.Context) ([]model.News, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	news := alive(s.db.news,
+		func(n model.News) time.Time { return n.DeletedAt },
+		func(n model.News) time.Time { return n.CreatedAt })
+	for i := range news {
+		news[i] = cloneNews(news[i])
+	}
+	return news, nil
+}
+
+var newsFields = fields[model.News]{
+	id:        func(n model.News) uuid.UUID { return n.ID },
+	title:     func(n model.News) string { return n.Title },
+	createdAt: func(n model.News) time.Time { return n.CreatedAt },
+}
+
+func (s *NewsStorage) Query(ctx context.Context, opts storage.ListOptions) ([]model.News, int, error) {
+	list, err := s.List(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	news, total := query(list, opts, newsFields)
+	return news, total, nil
+}
+
+func (s *NewsStorage) First(ctx context.Context, f func(model.News) bool) (model.News, error) {
+	news, err := s.List(ctx)
+	if err != nil {
+		return model.News{}, err
+	}
+	for _, n := range news {
+		if f(n) {
+			return n, nil
+		}
+	}
+	return model.News{}, storage.ErrNotFound
+}
+
+func cloneNews(n model.News) model.News {
+	n.ImageURLs = images(n.ImageURLs)
+	return n
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+)
+
+type ResolvedMediaStorage struct {
+	db *DB
+}
+
+var _ storage.ResolvedMedia = (*ResolvedMediaStorage)(nil)
+
+func NewResolvedMediaStorage(db *DB) *ResolvedMediaStorage {
+	return &ResolvedMediaStorage{
+		db: db,
+	}
+}
+
+func (s *ResolvedMediaStorage) Get(ctx context.Context, sourceURL string, now time.Time) (model.ResolvedMedia, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	m, ok := s.db.resolved[sourceURL]
+	if !ok || !m.ExpiresAt.After(now) {
+		return model.ResolvedMedia{}, storage.ErrNotFound
+	}
+	return m, nil
+}
+
+func (s *ResolvedMediaStorage) Put(ctx context.Context, m model.ResolvedMedia) error {
+	defer s.db.write(ctx)()
+
+	s.db.resolved[m.SourceURL] = m
+	return nil
+}
+
+func (s *ResolvedMediaStorage) Delete(ctx context.Context, sourceURL string) error {
+	defer s.db.write(ctx)()
+
+	if _, ok := s.db.resolved[sourceURL]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(s.db.resolved, sourceURL)
+	return nil
+}
+
+func (s *ResolvedMediaStorage) DeleteAll(ctx context.Context) (int, error) {
+	defer s.db.write(ctx)()
+
+	n := len(s.db.resolved)
+	clear(s.db.resolved)
+	return n, nil
+}
+
+func (s *ResolvedMediaStorage) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	defer s.db.write(ctx)()
+
+	n := 0
+	for k, m := range s.db.resolved {
+		if !m.ExpiresAt.After(now) {
+			delete(s.db.resolved, k)
+			n++
+		}
+	}
+	return n, nil
+}
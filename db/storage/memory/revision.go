@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+type RevisionStorage struct {
+	db *DB
+}
+
+var _ storage.Revisions = (*RevisionStorage)(nil)
+
+func NewRevisionStorage(db *DB) *RevisionStorage {
+	return &RevisionStorage{
+		db: db,
+	}
+}
+
+func (s *RevisionStorage) Append(ctx context.Context, r model.Revision) (model.Revision, error) {
+	defer s.db.write(ctx)()
+
+	r.Rev = 1
+	for _, prev := range s.db.revisions {
+		if prev.EntityType == r.EntityType && prev.EntityID == r.EntityID {
+			r.Rev = max(r.Rev, prev.Rev+1)
+		}
+	}
+	r.ID = int64(len(s.db.revisions) + 1)
+	r.CreatedAt = s.db.now()
+	r = cloneRevision(r)
+
+	s.db.revisions = append(s.db.revisions, r)
+	return cloneRevision(r), nil
+}
+
+func (s *RevisionStorage) List(ctx context.Context, t model.EntityType, id uuid.UUID) ([]model.Revision, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	revs := []model.Revision{}
+	for _, r := range s.db.revisions {
+		if r.EntityType == t && r.EntityID == id {
+			revs = append(revs, cloneRevision(r))
+		}
+	}
+	return revs, nil
+}
+
+func (s *RevisionStorage) Get(ctx context.Context, t model.EntityType, id uuid.UUID, rev int) (model.Revision, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, r := range s.db.revisions {
+		if r.EntityType == t && r.EntityID == id && r.Rev == rev {
+			return cloneRevision(r), nil
+		}
+	}
+	return model.Revision{}, storage.ErrNotFound
+}
+
+func cloneRevision(r model.Revision) model.Revision {
+	r.Snapshot = json.RawMessage(slices.Clone([]byte(r.Snapshot)))
+	r.Changes = slices.Clone(r.Changes)
+	return r
+}
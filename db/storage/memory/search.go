@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// snippetRadius is the number of runes kept around the first match.
+const snippetRadius = 60
+
+// SearchStorage is the in-process fallback for full-text search:
+// a case-insensitive substring match over titles and texts.
+type SearchStorage struct {
+	db *DB
+}
+
+var _ storage.Searcher = (*SearchStorage)(nil)
+
+func NewSearchStorage(db *DB) *SearchStorage {
+	return &SearchStorage{db: db}
+}
+
+// Search ranks title matches above text matches, newest first within a rank.
+func (s *SearchStorage) Search(ctx context.Context, q storage.SearchQuery) ([]model.SearchHit, error) {
+	needle := []rune(strings.TrimSpace(q.Text))
+	hits := []model.SearchHit{}
+	if len(needle) == 0 {
+		return hits, nil
+	}
+
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	add := func(typ model.EntityType, id uuid.UUID, exhibitionID *uuid.UUID, title, text string, hit model.SearchHit) {
+		inTitle := len(matches(title, needle)) > 0
+		inText := len(matches(text, needle)) > 0
+		if !inTitle && !inText {
+			return
+		}
+		hit.Type, hit.ID, hit.ExhibitionID, hit.Title = typ, id, exhibitionID, title
+		if inTitle {
+			hit.Rank += 1
+		}
+		if inText {
+			hit.Rank += 0.4
+		}
+		if text == "" {
+			text = title
+		}
+		hit.Snippet = storage.HighlightSnippet(snippet(text, needle))
+		hits = append(hits, hit)
+	}
+
+	if q.Has(model.EntityNews) {
+		for _, n := range s.db.news {
+			if n.DeletedAt.IsZero() {
+				add(model.EntityNews, n.ID, nil, n.Title, n.Content, model.SearchHit{CreatedAt: n.CreatedAt})
+			}
+		}
+	}
+	if q.Has(model.EntityExhibition) {
+		for _, ex := range s.db.exhibitions {
+			if ex.DeletedAt.IsZero() {
+				add(model.EntityExhibition, ex.ID, nil, ex.Title, ex.Description, model.SearchHit{CreatedAt: ex.CreatedAt})
+			}
+		}
+	}
+	if q.Has(model.EntityExhibit) {
+		for _, e := range s.db.exhibits {
+			if e.DeletedAt.IsZero() {
+				exhibitionID := e.ExhibitionID
+				add(model.EntityExhibit, e.ID, &exhibitionID, e.Title, e.Description, model.SearchHit{CreatedAt: e.CreatedAt})
+			}
+		}
+	}
+
+	slices.SortFunc(hits, func(a, b model.SearchHit) int {
+		return cmp.Or(cmp.Compare(b.Rank, a.Rank), b.CreatedAt.Compare(a.CreatedAt))
+	})
+	if q.Limit > 0 && len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+	return hits, nil
+}
+
+// matches returns the rune offsets of case-insensitive occurrences of needle in text.
+func matches(text string, needle []rune) []int {
+	hay := lowerRunes([]rune(text))
+	n := lowerRunes(needle)
+	var idx []int
+	for i := 0; i+len(n) <= len(hay); i++ {
+		if slices.Equal(hay[i:i+len(n)], n) {
+			idx = append(idx, i)
+			i += len(n) - 1
+		}
+	}
+	return idx
+}
+
+// snippet cuts a window around the first match of needle and wraps
+// every match inside it in storage.SnippetStart/SnippetStop.
+func snippet(text string, needle []rune) string {
+	runes := []rune(text)
+	idx := matches(text, needle)
+
+	start, end := 0, min(len(runes), 2*snippetRadius)
+	if len(idx) > 0 {
+		start = max(0, idx[0]-snippetRadius)
+		end = min(len(runes), idx[0]+len(needle)+snippetRadius)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("… ")
+	}
+	pos := start
+	for _, i := range idx {
+		if i < start || i+len(needle) > end {
+			continue
+		}
+		b.WriteString(string(runes[pos:i]))
+		b.WriteString(storage.SnippetStart)
+		b.WriteString(string(runes[i : i+len(needle)]))
+		b.WriteString(storage.SnippetStop)
+		pos = i + len(needle)
+	}
+	b.WriteString(string(runes[pos:end]))
+	if end < len(runes) {
+		b.WriteString(" …")
+	}
+	return b.String()
+}
+
+func lowerRunes(r []rune) []rune {
+	out := make([]rune, len(r))
+	for i, c := range r {
+		out[i] = unicode.ToLower(c)
+	}
+	return out
+}
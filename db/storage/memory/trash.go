@@ -0,0 +1,201 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+var (
+	_ storage.Trash[model.News]    = (*NewsStorage)(nil)
+	_ storage.Trash[model.Exhibit] = (*ExhibitStorage)(nil)
+	_ storage.ExhibitionTrash      = (*ExhibitionStorage)(nil)
+)
+
+// trash implements storage.Trash operations over one entity map.
+// The caller must hold db.mu.
+type trash[T any] struct {
+	rows      map[uuid.UUID]T
+	deletedAt func(T) time.Time
+	restore   func(T) T
+}
+
+func (t trash[T]) list() []T {
+	out := []T{}
+	for _, v := range t.rows {
+		if !t.deletedAt(v).IsZero() {
+			out = append(out, v)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b T) int {
+		return cmp.Compare(t.deletedAt(b).UnixNano(), t.deletedAt(a).UnixNano())
+	})
+	return out
+}
+
+func (t trash[T]) restoreOne(id uuid.UUID) error {
+	v, ok := t.rows[id]
+	if !ok || t.deletedAt(v).IsZero() {
+		return storage.ErrNotFound
+	}
+	t.rows[id] = t.restore(v)
+	return nil
+}
+
+func (t trash[T]) purge(id uuid.UUID) error {
+	v, ok := t.rows[id]
+	if !ok || t.deletedAt(v).IsZero() {
+		return storage.ErrNotFound
+	}
+	delete(t.rows, id)
+	return nil
+}
+
+func (t trash[T]) purgeBefore(before time.Time) int {
+	n := 0
+	for id, v := range t.rows {
+		if d := t.deletedAt(v); !d.IsZero() && d.Before(before) {
+			delete(t.rows, id)
+			n++
+		}
+	}
+	return n
+}
+
+func (db *DB) newsTrash() trash[model.News] {
+	return trash[model.News]{
+		rows:      db.news,
+		deletedAt: func(n model.News) time.Time { return n.DeletedAt },
+		restore:   func(n model.News) model.News { n.DeletedAt = time.Time{}; return n },
+	}
+}
+
+func (db *DB) exhibitTrash() trash[model.Exhibit] {
+	return trash[model.Exhibit]{
+		rows:      db.exhibits,
+		deletedAt: func(e model.Exhibit) time.Time { return e.DeletedAt },
+		restore:   func(e model.Exhibit) model.Exhibit { e.DeletedAt = time.Time{}; return e },
+	}
+}
+
+func (db *DB) exhibitionTrash() trash[model.Exhibition] {
+	return trash[model.Exhibition]{
+		rows:      db.exhibitions,
+		deletedAt: func(ex model.Exhibition) time.Time { return ex.DeletedAt },
+		restore:   func(ex model.Exhibition) model.Exhibition { ex.DeletedAt = time.Time{}; return ex },
+	}
+}
+
+// --- News ---
+
+func (s *NewsStorage) ListDeleted(ctx context.Context) ([]model.News, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+	news := s.db.newsTrash().list()
+	for i := range news {
+		news[i] = cloneNews(news[i])
+	}
+	return news, nil
+}
+
+func (s *NewsStorage) Restore(ctx context.Context, id uuid.UUID) error {
+	defer s.db.write(ctx)()
+	return s.db.newsTrash().restoreOne(id)
+}
+
+func (s *NewsStorage) Purge(ctx context.Context, id uuid.UUID) error {
+	defer s.db.write(ctx)()
+	return s.db.newsTrash().purge(id)
+}
+
+func (s *NewsStorage) PurgeDeletedBefore(ctx context.Context, t time.Time) (int, error) {
+	defer s.db.write(ctx)()
+	return s.db.newsTrash().purgeBefore(t), nil
+}
+
+// --- Exhibits ---
+
+func (s *ExhibitStorage) ListDeleted(ctx context.Context) ([]model.Exhibit, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+	exhibits := s.db.exhibitTrash().list()
+	for i := range exhibits {
+		exhibits[i] = cloneExhibit(exhibits[i])
+	}
+	return exhibits, nil
+}
+
+func (s *ExhibitStorage) Restore(ctx context.Context, id uuid.UUID) error {
+	defer s.db.write(ctx)()
+	return s.db.exhibitTrash().restoreOne(id)
+}
+
+func (s *ExhibitStorage) Purge(ctx context.Context, id uuid.UUID) error {
+	defer s.db.write(ctx)()
+	return s.db.exhibitTrash().purge(id)
+}
+
+func (s *ExhibitStorage) PurgeDeletedBefore(ctx context.Context, t time.Time) (int, error) {
+	defer s.db.write(ctx)()
+	return s.db.exhibitTrash().purgeBefore(t), nil
+}
+
+// --- Exhibitions ---
+
+func (s *ExhibitionStorage) ListDeleted(ctx context.Context) ([]model.Exhibition, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+	exhibitions := s.db.exhibitionTrash().list()
+	for i := range exhibitions {
+		exhibitions[i] = cloneExhibition(exhibitions[i])
+	}
+	return exhibitions, nil
+}
+
+func (s *ExhibitionStorage) Restore(ctx context.Context, id uuid.UUID) error {
+	defer s.db.write(ctx)()
+	return s.db.exhibitionTrash().restoreOne(id)
+}
+
+func (s *ExhibitionStorage) RestoreWithExhibits(ctx context.Context, id uuid.UUID) (int, error) {
+	defer s.db.write(ctx)()
+
+	ex, ok := s.db.exhibitions[id]
+	if !ok || ex.DeletedAt.IsZero() {
+		return 0, storage.ErrNotFound
+	}
+	n := 0
+	for eid, e := range s.db.exhibits {
+		if e.ExhibitionID == id && e.DeletedAt.Equal(ex.DeletedAt) {
+			e.DeletedAt = time.Time{}
+			s.db.exhibits[eid] = e
+			n++
+		}
+	}
+	return n, s.db.exhibitionTrash().restoreOne(id)
+}
+
+// Purge permanently removes the exhibition and its deleted exhibits.
+func (s *ExhibitionStorage) Purge(ctx context.Context, id uuid.UUID) error {
+	defer s.db.write(ctx)()
+
+	if err := s.db.exhibitionTrash().purge(id); err != nil {
+		return err
+	}
+	for eid, e := range s.db.exhibits {
+		if e.ExhibitionID == id && !e.DeletedAt.IsZero() {
+			delete(s.db.exhibits, eid)
+		}
+	}
+	return nil
+}
+
+func (s *ExhibitionStorage) PurgeDeletedBefore(ctx context.Context, t time.Time) (int, error) {
+	defer s.db.write(ctx)()
+	return s.db.exhibitionTrash().purgeBefore(t), nil
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/WhiCu/school-museum/db/storage"
+)
+
+type txKey struct{}
+
+var _ storage.Transactor = (*DB)(nil)
+
+// InTx runs f as a unit of work. Units of work are serialised; if f fails the
+// dataset is reset to the snapshot taken before it started. Writes made
+// outside of InTx wait for the running unit of work, so a rollback never
+// undoes them. Reads do not wait and may see the writes of f before it
+// commits, which is acceptable for the tests and demo mode this backend is
+// for.
+func (db *DB) InTx(ctx context.Context, f func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return f(ctx)
+	}
+
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+
+	snap := db.snapshot()
+	if err := f(context.WithValue(ctx, txKey{}, struct{}{})); err != nil {
+		db.rollback(snap)
+		return err
+	}
+	return nil
+}
+
+// write locks the dataset for a write made with ctx and returns the unlock
+// function. Outside of InTx it first waits for the running unit of work.
+func (db *DB) write(ctx context.Context) (unlock func()) {
+	if ctx.Value(txKey{}) != nil {
+		db.mu.Lock()
+		return db.mu.Unlock
+	}
+	db.txMu.Lock()
+	db.mu.Lock()
+	return func() {
+		db.mu.Unlock()
+		db.txMu.Unlock()
+	}
+}
+
+// snapshot copies the dataset. Rows are values that writers replace as a
+// whole, so shallow copies of the maps are enough.
+func (db *DB) snapshot() *DB {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return &DB{
+		news:          maps.Clone(db.news),
+		exhibitions:   maps.Clone(db.exhibitions),
+		exhibits:      maps.Clone(db.exhibits),
+		visitors:      maps.Clone(db.visitors),
+		revisions:     slices.Clone(db.revisions),
+		assets:        maps.Clone(db.assets),
+		media:         maps.Clone(db.media),
+		mediaLinks:    maps.Clone(db.mediaLinks),
+		resolved:      maps.Clone(db.resolved),
+		importJobs:    maps.Clone(db.importJobs),
+		linkChecks:    maps.Clone(db.linkChecks),
+		adminUsers:    maps.Clone(db.adminUsers),
+		adminSessions: maps.Clone(db.adminSessions),
+		apiTokens:     maps.Clone(db.apiTokens),
+		loginFailures: maps.Clone(db.loginFailures),
+		lastVisitorID: db.lastVisitorID,
+	}
+}
+
+func (db *DB) rollback(snap *DB) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.news = snap.news
+	db.exhibitions = snap.exhibitions
+	db.exhibits = snap.exhibits
+	db.visitors = snap.visitors
+	db.revisions = snap.revisions
+	db.assets = snap.assets
+	db.media = snap.media
+	db.mediaLinks = snap.mediaLinks
+	db.resolved = snap.resolved
+	db.importJobs = snap.importJobs
+	db.linkChecks = snap.linkChecks
+	db.adminUsers = snap.adminUsers
+	db.adminSessions = snap.adminSessions
+	db.apiTokens = snap.apiTokens
+	db.loginFailures = snap.loginFailures
+	db.lastVisitorID = snap.lastVisitorID
+}
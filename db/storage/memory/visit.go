@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+)
+
+// VisitStorage handles visitor tracking and statistics.
+type VisitStorage struct {
+	db *DB
+}
+
+var _ storage.Visits = (*VisitStorage)(nil)
+
+func NewVisitStorage(db *DB) *VisitStorage {
+	return &VisitStorage{db: db}
+}
+
+// Record upserts a visitor record by IP with the same rules as the
+// ON CONFLICT (ip) DO UPDATE statement of the Postgres storage.
+func (s *VisitStorage) Record(ctx context.Context, v model.Visitor) error {
+	defer s.db.write(ctx)()
+
+	now := s.db.now()
+	cur, ok := s.db.visitors[v.IP]
+	if !ok {
+		s.db.lastVisitorID++
+		v.ID = s.db.lastVisitorID
+		v.VisitCount = 1
+		v.LastVisitAt = now
+		if v.FirstVisitAt.IsZero() {
+			v.FirstVisitAt = now
+		}
+		s.db.visitors[v.IP] = v
+		return nil
+	}
+
+	cur.VisitCount++
+	cur.LastVisitAt = now
+	cur.UserAgent = v.UserAgent
+	cur.Page = v.Page
+	if v.Referrer != "" {
+		cur.Referrer = v.Referrer
+	}
+	if v.ScreenWidth > 0 {
+		cur.ScreenWidth = v.ScreenWidth
+	}
+	if v.ScreenHeight > 0 {
+		cur.ScreenHeight = v.ScreenHeight
+	}
+	if v.Language != "" {
+		cur.Language = v.Language
+	}
+	s.db.visitors[v.IP] = cur
+	return nil
+}
+
+// Stats returns aggregated visit statistics along with entity counts.
+func (s *VisitStorage) Stats(ctx context.Context) (model.VisitStats, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var stats model.VisitStats
+
+	now := s.db.now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	weekAgo := today.AddDate(0, 0, -7)
+	monthAgo := today.AddDate(0, -1, 0)
+
+	since := func(t, from time.Time) int {
+		if !t.Before(from) {
+			return 1
+		}
+		return 0
+	}
+
+	for _, v := range s.db.visitors {
+		stats.TotalVisits++
+
+		// ── Active visitors by last_visit_at ──
+		stats.TodayVisits += since(v.LastVisitAt, today)
+		stats.WeekVisits += since(v.LastVisitAt, weekAgo)
+		stats.MonthVisits += since(v.LastVisitAt, monthAgo)
+
+		// ── New visitors by first_visit_at ──
+		stats.NewToday += since(v.FirstVisitAt, today)
+		stats.NewWeek += since(v.FirstVisitAt, weekAgo)
+		stats.NewMonth += since(v.FirstVisitAt, monthAgo)
+
+		// ── Engagement ──
+		if v.VisitCount > 1 {
+			stats.ReturningVisitors++
+		}
+		stats.TotalPageViews += v.VisitCount
+	}
+
+	if stats.TotalVisits > 0 {
+		stats.AvgVisitsPerUser = float64(stats.TotalPageViews) / float64(stats.TotalVisits)
+	}
+
+	// ── Daily breakdown (last 7 days by last_visit_at) ──
+
+	stats.DailyVisits = make([]model.DailyVisitCount, 0, 8)
+	for day := weekAgo; !day.After(today); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		count := 0
+		for _, v := range s.db.visitors {
+			if !v.LastVisitAt.Before(day) && v.LastVisitAt.Before(next) {
+				count++
+			}
+		}
+		stats.DailyVisits = append(stats.DailyVisits, model.DailyVisitCount{
+			Date:  day.Format("2006-01-02"),
+			Count: count,
+		})
+	}
+
+	// ── Entity counts ──
+
+	for _, ex := range s.db.exhibitions {
+		if ex.DeletedAt.IsZero() {
+			stats.ExhibitionCount++
+		}
+	}
+	for _, e := range s.db.exhibits {
+		if e.DeletedAt.IsZero() {
+			stats.ExhibitCount++
+		}
+	}
+	for _, n := range s.db.news {
+		if n.DeletedAt.IsZero() {
+			stats.NewsCount++
+		}
+	}
+
+	return stats, nil
+}
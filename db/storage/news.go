@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/WhiCu/school-museum/db/model"
 	"github.com/google/uuid"
@@ -12,7 +13,10 @@ type NewsStorage struct {
 	db *bun.DB
 }
 
-var _ Storage[model.News] = (*NewsStorage)(nil)
+var (
+	_ Storage[model.News] = (*NewsStorage)(nil)
+	_ Trash[model.News]   = (*NewsStorage)(nil)
+)
 
 func NewNewsStorage(db *bun.DB) *NewsStorage {
 	return &NewsStorage{
@@ -22,15 +26,15 @@ func NewNewsStorage(db *bun.DB) *NewsStorage {
 
 func (s *NewsStorage) Read(ctx context.Context, id uuid.UUID) (model.News, error) {
 	var n model.News
-	err := s.db.NewSelect().Model(&n).Where("id = ?", id).Scan(ctx, &n)
+	err := conn(ctx, s.db).NewSelect().Model(&n).Where("id = ?", id).Scan(ctx, &n)
 	if err != nil {
-		return model.News{}, err
+		return model.News{}, notFound(err)
 	}
 	return n, nil
 }
 
 func (s *NewsStorage) Create(ctx context.Context, n model.News) (uuid.UUID, error) {
-	err := s.db.NewInsert().Model(&n).Returning("id").Scan(ctx, &n.ID)
+	err := conn(ctx, s.db).NewInsert().Model(&n).Returning("id").Scan(ctx, &n.ID)
 	if err != nil {
 		return uuid.Nil, err
 	}
@@ -38,31 +42,57 @@ func (s *NewsStorage) Create(ctx context.Context, n model.News) (uuid.UUID, erro
 }
 
 func (s *NewsStorage) Delete(ctx context.Context, id uuid.UUID) error {
-	_, err := s.db.NewDelete().Model((*model.News)(nil)).Where("id = ?", id).Exec(ctx)
+	_, err := conn(ctx, s.db).NewDelete().Model((*model.News)(nil)).Where("id = ?", id).Exec(ctx)
 	return err
 }
 
 func (s *NewsStorage) Update(ctx context.Context, n model.News) (model.News, error) {
-	_, err := s.db.NewUpdate().
+	expected := n.UpdatedAt
+	q := conn(ctx, s.db).NewUpdate().
 		Model(&n).
 		WherePK().
 		OmitZero().
+		Value("updated_at", bumpUpdatedAt)
+	if !expected.IsZero() {
+		q = q.Where("updated_at = ?", expected)
+	}
+	if err := q.Returning("*").Scan(ctx); err != nil {
+		return model.News{}, conditional[model.News](ctx, conn(ctx, s.db), n.ID, expected, err)
+	}
+	return n, nil
+}
+
+func (s *NewsStorage) Replace(ctx context.Context, n model.News) (model.News, error) {
+	if n.ImageURLs == nil {
+		n.ImageURLs = []string{}
+	}
+	err := conn(ctx, s.db).NewUpdate().
+		Model(&n).
+		Column("title", "content", "image_urls", "updated_at").
+		Value("updated_at", bumpUpdatedAt).
+		WherePK().
 		Returning("*").
-		Exec(ctx)
+		Scan(ctx)
 	if err != nil {
-		return model.News{}, err
+		return model.News{}, notFound(err)
 	}
 	return n, nil
 }
 
 func (s *NewsStorage) List(ctx context.Context) (news []model.News, err error) {
-	err = s.db.NewSelect().Model(&news).Scan(ctx)
+	err = conn(ctx, s.db).NewSelect().Model(&news).Scan(ctx)
 	return news, err
 }
 
+func (s *NewsStorage) Query(ctx context.Context, opts ListOptions) (news []model.News, total int, err error) {
+	q := conn(ctx, s.db).NewSelect().Model(&news)
+	total, err = opts.apply(q, "n").ScanAndCount(ctx)
+	return news, total, err
+}
+
 func (s *NewsStorage) First(ctx context.Context, f func(model.News) bool) (model.News, error) {
 	var news []model.News
-	err := s.db.NewSelect().Model(&news).Scan(ctx)
+	err := conn(ctx, s.db).NewSelect().Model(&news).Scan(ctx)
 	if err != nil {
 		return model.News{}, err
 	}
@@ -73,3 +103,21 @@ func (s *NewsStorage) First(ctx context.Context, f func(model.News) bool) (model
 	}
 	return model.News{}, ErrNotFound
 }
+
+// --- Trash ---
+
+func (s *NewsStorage) ListDeleted(ctx context.Context) ([]model.News, error) {
+	return listDeleted[model.News](ctx, conn(ctx, s.db))
+}
+
+func (s *NewsStorage) Restore(ctx context.Context, id uuid.UUID) error {
+	return restore[model.News](ctx, conn(ctx, s.db), id)
+}
+
+func (s *NewsStorage) Purge(ctx context.Context, id uuid.UUID) error {
+	return purge[model.News](ctx, conn(ctx, s.db), id)
+}
+
+func (s *NewsStorage) PurgeDeletedBefore(ctx context.Context, t time.Time) (int, error) {
+	return purgeDeletedBefore[model.News](ctx, conn(ctx, s.db), t)
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"cmp"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// SortField is a column list queries can be ordered by.
+type SortField string
+
+const (
+	SortCreatedAt SortField = "created_at"
+	SortTitle     SortField = "title"
+
+	// sortCaption stands for SortTitle in models that have a caption instead.
+	sortCaption SortField = "caption"
+)
+
+// ListOptions controls paging, ordering and filtering of Storage.Query.
+type ListOptions struct {
+	// Limit is the maximum number of rows to return; 0 means no limit, for
+	// internal callers only: the public API always pages.
+	Limit  int
+	Offset int
+
+	// Sort defaults to SortCreatedAt. Titles sort as CompareTitles does.
+	Sort SortField
+	Desc bool
+
+	// CreatedAfter and CreatedBefore bound created_at when non-zero (inclusive / exclusive).
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	// WithRelations eager-loads relations (the exhibits of an exhibition).
+	WithRelations bool
+}
+
+// SortColumn returns the validated sort column.
+func (o ListOptions) SortColumn() SortField {
+	switch o.Sort {
+	case SortTitle:
+		return SortTitle
+	case sortCaption:
+		return sortCaption
+	default:
+		return SortCreatedAt
+	}
+}
+
+// CompareTitles orders titles the way Query sorts by SortTitle: ignoring
+// case first, then by code points.
+func CompareTitles(a, b string) int {
+	return cmp.Or(cmp.Compare(strings.ToLower(a), strings.ToLower(b)), cmp.Compare(a, b))
+}
+
+// Match reports whether created falls into the created_at filter.
+func (o ListOptions) Match(created time.Time) bool {
+	if !o.CreatedAfter.IsZero() && created.Before(o.CreatedAfter) {
+		return false
+	}
+	if !o.CreatedBefore.IsZero() && !created.Before(o.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// apply adds the filter, order and paging clauses of o to q.
+// alias is the table alias of the queried model.
+func (o ListOptions) apply(q *bun.SelectQuery, alias string) *bun.SelectQuery {
+	if !o.CreatedAfter.IsZero() {
+		q = q.Where("?.created_at >= ?", bun.Ident(alias), o.CreatedAfter)
+	}
+	if !o.CreatedBefore.IsZero() {
+		q = q.Where("?.created_at < ?", bun.Ident(alias), o.CreatedBefore)
+	}
+
+	dir := "ASC"
+	if o.Desc {
+		dir = "DESC"
+	}
+	if col := o.SortColumn(); col == SortCreatedAt {
+		q = q.OrderExpr("?.? "+dir, bun.Ident(alias), bun.Ident(string(col)))
+	} else {
+		// Byte order after lower() matches CompareTitles whatever the
+		// collation of the database.
+		q = q.OrderExpr("lower(?.?) COLLATE \"C\" "+dir+", ?.? COLLATE \"C\" "+dir,
+			bun.Ident(alias), bun.Ident(string(col)), bun.Ident(alias), bun.Ident(string(col)))
+	}
+	q = q.OrderExpr("?.id "+dir, bun.Ident(alias))
+
+	if o.Limit > 0 {
+		q = q.Limit(o.Limit)
+	}
+	if o.Offset > 0 {
+		q = q.Offset(o.Offset)
+	}
+	return q
+}
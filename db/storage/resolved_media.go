@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/uptrace/bun"
+)
+
+// ResolvedMedia persists resolved external media links between restarts.
+type ResolvedMedia interface {
+	// Get returns the entry for sourceURL unless it expired by now.
+	Get(ctx context.Context, sourceURL string, now time.Time) (model.ResolvedMedia, error)
+	// Put stores m, replacing the entry for the same source URL.
+	Put(ctx context.Context, m model.ResolvedMedia) error
+	Delete(ctx context.Context, sourceURL string) error
+	// DeleteAll removes every entry and returns how many there were.
+	DeleteAll(ctx context.Context) (int, error)
+	// DeleteExpired removes the entries that expired by now.
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+type ResolvedMediaStorage struct {
+	db *bun.DB
+}
+
+var _ ResolvedMedia = (*ResolvedMediaStorage)(nil)
+
+func NewResolvedMediaStorage(db *bun.DB) *ResolvedMediaStorage {
+	return &ResolvedMediaStorage{
+		db: db,
+	}
+}
+
+func (s *ResolvedMediaStorage) Get(ctx context.Context, sourceURL string, now time.Time) (model.ResolvedMedia, error) {
+	var m model.ResolvedMedia
+	err := conn(ctx, s.db).NewSelect().
+		Model(&m).
+		Where("source_url = ?", sourceURL).
+		Where("expires_at > ?", now).
+		Scan(ctx)
+	if err != nil {
+		return model.ResolvedMedia{}, notFound(err)
+	}
+	return m, nil
+}
+
+func (s *ResolvedMediaStorage) Put(ctx context.Context, m model.ResolvedMedia) error {
+	_, err := conn(ctx, s.db).NewInsert().
+		Model(&m).
+		On("CONFLICT (source_url) DO UPDATE").
+		Set("url = EXCLUDED.url").
+		Set("type = EXCLUDED.type").
+		Set("provider = EXCLUDED.provider").
+		Set("title = EXCLUDED.title").
+		Set("thumbnail_url = EXCLUDED.thumbnail_url").
+		Set("resolved_at = EXCLUDED.resolved_at").
+		Set("expires_at = EXCLUDED.expires_at").
+		Exec(ctx)
+	return err
+}
+
+func (s *ResolvedMediaStorage) Delete(ctx context.Context, sourceURL string) error {
+	res, err := conn(ctx, s.db).NewDelete().
+		Model((*model.ResolvedMedia)(nil)).
+		Where("source_url = ?", sourceURL).
+		Exec(ctx)
+	return affected(res, err)
+}
+
+func (s *ResolvedMediaStorage) DeleteAll(ctx context.Context) (int, error) {
+	return s.deleteWhere(ctx, "TRUE")
+}
+
+func (s *ResolvedMediaStorage) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	return s.deleteWhere(ctx, "expires_at <= ?", now)
+}
+
+func (s *ResolvedMediaStorage) deleteWhere(ctx context.Context, query string, args ...any) (int, error) {
+	res, err := conn(ctx, s.db).NewDelete().
+		Model((*model.ResolvedMedia)(nil)).
+		Where(query, args...).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
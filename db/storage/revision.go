@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Revisions is the append-only history of content changes.
+type Revisions interface {
+	// Append stores r as the next revision of its entity and returns it
+	// with Rev and CreatedAt filled in.
+	Append(ctx context.Context, r model.Revision) (model.Revision, error)
+	// List returns the revisions of an entity, oldest first.
+	List(ctx context.Context, t model.EntityType, id uuid.UUID) ([]model.Revision, error)
+	// Get returns a single revision of an entity.
+	Get(ctx context.Context, t model.EntityType, id uuid.UUID, rev int) (model.Revision, error)
+}
+
+type RevisionStorage struct {
+	db *bun.DB
+}
+
+var _ Revisions = (*RevisionStorage)(nil)
+
+func NewRevisionStorage(db *bun.DB) *RevisionStorage {
+	return &RevisionStorage{
+		db: db,
+	}
+}
+
+// Append numbers revisions per entity. Concurrent appends to the same entity
+// are serialised with a transaction-scoped advisory lock.
+func (s *RevisionStorage) Append(ctx context.Context, r model.Revision) (model.Revision, error) {
+	err := inTx(ctx, s.db, func(ctx context.Context) error {
+		db := conn(ctx, s.db)
+		if _, err := db.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext(?))", r.EntityID.String()); err != nil {
+			return err
+		}
+		err := db.NewSelect().
+			Model((*model.Revision)(nil)).
+			ColumnExpr("coalesce(max(rev), 0) + 1").
+			Where("entity_type = ?", r.EntityType).
+			Where("entity_id = ?", r.EntityID).
+			Scan(ctx, &r.Rev)
+		if err != nil {
+			return err
+		}
+		return db.NewInsert().
+			Model(&r).
+			Returning("id, created_at").
+			Scan(ctx)
+	})
+	if err != nil {
+		return model.Revision{}, err
+	}
+	return r, nil
+}
+
+func (s *RevisionStorage) List(ctx context.Context, t model.EntityType, id uuid.UUID) ([]model.Revision, error) {
+	revs := []model.Revision{}
+	err := conn(ctx, s.db).NewSelect().
+		Model(&revs).
+		Where("entity_type = ?", t).
+		Where("entity_id = ?", id).
+		Order("rev").
+		Scan(ctx)
+	return revs, err
+}
+
+func (s *RevisionStorage) Get(ctx context.Context, t model.EntityType, id uuid.UUID, rev int) (model.Revision, error) {
+	var r model.Revision
+	err := conn(ctx, s.db).NewSelect().
+		Model(&r).
+		Where("entity_type = ?", t).
+		Where("entity_id = ?", id).
+		Where("rev = ?", rev).
+		Scan(ctx)
+	if err != nil {
+		return model.Revision{}, notFound(err)
+	}
+	return r, nil
+}
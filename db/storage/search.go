@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"html"
+	"slices"
+	"strings"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/uptrace/bun"
+)
+
+// Snippet match delimiters. Backends wrap matches in raw snippets with them,
+// and HighlightSnippet turns them into <mark> tags after HTML-escaping.
+const (
+	SnippetStart = "\x02"
+	SnippetStop  = "\x03"
+)
+
+// SearchQuery is a full-text search request.
+type SearchQuery struct {
+	Text string
+	// Types restricts the entity types searched; empty means all.
+	Types []model.EntityType
+	Limit int
+}
+
+// Has reports whether entities of type t are searched.
+func (q SearchQuery) Has(t model.EntityType) bool {
+	return len(q.Types) == 0 || slices.Contains(q.Types, t)
+}
+
+// Searcher finds news, exhibitions and exhibits by text.
+type Searcher interface {
+	Search(ctx context.Context, q SearchQuery) ([]model.SearchHit, error)
+}
+
+// HighlightSnippet HTML-escapes a raw snippet and replaces the
+// SnippetStart/SnippetStop delimiters with <mark> tags.
+func HighlightSnippet(raw string) string {
+	s := html.EscapeString(raw)
+	s = strings.ReplaceAll(s, SnippetStart, "<mark>")
+	return strings.ReplaceAll(s, SnippetStop, "</mark>")
+}
+
+// SearchStorage implements Searcher with Postgres full-text search
+// over the generated "search" tsvector columns.
+type SearchStorage struct {
+	db *bun.DB
+}
+
+var _ Searcher = (*SearchStorage)(nil)
+
+func NewSearchStorage(db *bun.DB) *SearchStorage {
+	return &SearchStorage{db: db}
+}
+
+const headlineOptions = "StartSel=" + SnippetStart + ", StopSel=" + SnippetStop +
+	", MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=\" … \""
+
+var searchSources = []struct {
+	typ   model.EntityType
+	query string
+}{
+	{model.EntityNews, `SELECT 'news' AS type, t.id, NULL::uuid AS exhibition_id, t.title,
+		ts_headline('russian', coalesce(nullif(t.content, ''), t.title), q.q, ?) AS snippet,
+		ts_rank(t.search, q.q) AS rank, t.created_at
+		FROM news AS t, q WHERE t.deleted_at IS NULL AND t.search @@ q.q`},
+	{model.EntityExhibition, `SELECT 'exhibition' AS type, t.id, NULL::uuid AS exhibition_id, t.title,
+		ts_headline('russian', coalesce(nullif(t.description, ''), t.title), q.q, ?) AS snippet,
+		ts_rank(t.search, q.q) AS rank, t.created_at
+		FROM exhibitions AS t, q WHERE t.deleted_at IS NULL AND t.search @@ q.q`},
+	{model.EntityExhibit, `SELECT 'exhibit' AS type, t.id, t.exhibition_id, t.title,
+		ts_headline('russian', coalesce(nullif(t.description, ''), t.title), q.q, ?) AS snippet,
+		ts_rank(t.search, q.q) AS rank, t.created_at
+		FROM exhibits AS t, q WHERE t.deleted_at IS NULL AND t.search @@ q.q`},
+}
+
+// Search ranks matches with ts_rank and highlights them with ts_headline.
+// The text is parsed with websearch_to_tsquery, so quotes, "or" and "-" work.
+func (s *SearchStorage) Search(ctx context.Context, q SearchQuery) ([]model.SearchHit, error) {
+	args := []any{q.Text}
+	parts := make([]string, 0, len(searchSources))
+	for _, src := range searchSources {
+		if q.Has(src.typ) {
+			parts = append(parts, src.query)
+			args = append(args, headlineOptions)
+		}
+	}
+	if len(parts) == 0 {
+		return []model.SearchHit{}, nil
+	}
+
+	query := "WITH q AS (SELECT websearch_to_tsquery('russian', ?) AS q) " +
+		strings.Join(parts, " UNION ALL ") +
+		" ORDER BY rank DESC, created_at DESC"
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	hits := []model.SearchHit{}
+	if err := conn(ctx, s.db).NewRaw(query, args...).Scan(ctx, &hits); err != nil {
+		return nil, err
+	}
+	for i := range hits {
+		hits[i].Snippet = HighlightSnippet(hits[i].Snippet)
+	}
+	return hits, nil
+}
@@ -2,21 +2,91 @@ package storage
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"time"
 
+	"github.com/WhiCu/school-museum/db/model"
 	"github.com/google/uuid"
+	"github.com/uptrace/bun"
 )
 
 type Storage[T any] interface {
 	Read(ctx context.Context, id uuid.UUID) (T, error)
 	Create(ctx context.Context, t T) (uuid.UUID, error)
+	// Update applies the non-zero fields of t and bumps updated_at.
+	// A non-zero UpdatedAt of t is a precondition: if the stored row has a
+	// different updated_at, nothing is written and ErrStale is returned.
 	Update(ctx context.Context, t T) (T, error)
+	// Replace overwrites all user-editable fields of t, zero values included,
+	// and bumps updated_at.
+	Replace(ctx context.Context, t T) (T, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 	List(ctx context.Context) ([]T, error)
+	// Query returns a page of rows selected by opts and the total number of matching rows.
+	Query(ctx context.Context, opts ListOptions) ([]T, int, error)
 	First(ctx context.Context, f func(T) bool) (T, error)
 }
 
-var (
-	ErrNotFound = errors.New("Not Found")
-	ErrConflict = errors.New("Conflict")
-)
+// affected returns ErrNotFound if a statement matched no rows.
+func affected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// bumpUpdatedAt is the new updated_at of a changed row. It always moves
+// forward, even for several updates within one transaction, so updated_at
+// can serve as the row version.
+const bumpUpdatedAt = "greatest(current_timestamp, updated_at + interval '1 microsecond')"
+
+// conditional maps the error of an update guarded by an expected updated_at:
+// when no row matched, it tells a missing row (ErrNotFound) from a row that
+// was changed in the meantime (ErrStale).
+func conditional[T any](ctx context.Context, db bun.IDB, id uuid.UUID, expected time.Time, err error) error {
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if expected.IsZero() {
+		return ErrNotFound
+	}
+	exists, err := db.NewSelect().Model((*T)(nil)).Where("id = ?", id).Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrStale
+	}
+	return ErrNotFound
+}
+
+// notFound maps sql.ErrNoRows to ErrNotFound so that callers
+// don't depend on the database driver.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Exhibitions is a Storage of exhibitions that can also manage their preview exhibit.
+type Exhibitions interface {
+	Storage[model.Exhibition]
+	// SetPreview sets or, with a nil exhibitID, clears the preview exhibit
+	// of a live exhibition, or reports ErrNotFound.
+	SetPreview(ctx context.Context, exhibitionID uuid.UUID, exhibitID *uuid.UUID) error
+}
+
+// Visits records site visitors and aggregates visit statistics.
+type Visits interface {
+	Record(ctx context.Context, v model.Visitor) error
+	Stats(ctx context.Context) (model.VisitStats, error)
+}
@@ -0,0 +1,185 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/WhiCu/school-museum/db"
+	"github.com/WhiCu/school-museum/db/migrate"
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/db/storage/storagetest"
+	"github.com/uptrace/bun"
+)
+
+// dsnEnv names the environment variable with the DSN of a Postgres database
+// the tests may wipe. Without it the tests are skipped.
+const dsnEnv = "STORAGE_TEST_DSN"
+
+var testDB *bun.DB
+
+func TestMain(m *testing.M) {
+	if dsn := os.Getenv(dsnEnv); dsn != "" {
+		ctx := context.Background()
+		database, err := db.NewDB(ctx, dsn)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "connect:", err)
+			os.Exit(1)
+		}
+		if _, err := migrate.NewMigrator(database, slog.New(slog.DiscardHandler)).Up(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, "migrate:", err)
+			os.Exit(1)
+		}
+		testDB = database
+	}
+	code := m.Run()
+	if testDB != nil {
+		_ = testDB.Close()
+	}
+	os.Exit(code)
+}
+
+// newDB returns the test database with every table emptied, or skips t if
+// there is none.
+func newDB(t *testing.T) *bun.DB {
+	t.Helper()
+	if testDB == nil {
+		t.Skipf("%s is not set", dsnEnv)
+	}
+	ctx := context.Background()
+	var tables []string
+	err := testDB.NewSelect().
+		TableExpr("information_schema.tables").
+		Column("table_name").
+		Where("table_schema = current_schema()").
+		Where("table_type = 'BASE TABLE'").
+		Where("table_name <> 'schema_migrations'").
+		Scan(ctx, &tables)
+	if err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+	idents := make([]bun.Ident, len(tables))
+	for i, table := range tables {
+		idents[i] = bun.Ident(table)
+	}
+	if _, err := testDB.ExecContext(ctx, "TRUNCATE ? CASCADE", bun.In(idents)); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	return testDB
+}
+
+func TestNews(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage[model.News] {
+		return storage.NewNewsStorage(newDB(t))
+	}, storagetest.News)
+}
+
+func TestExhibitions(t *testing.T) {
+	storagetest.RunExhibitions(t, func(t *testing.T) (storage.Exhibitions, storage.Storage[model.Exhibit]) {
+		db := newDB(t)
+		return storage.NewExhibitionStorage(db), storage.NewExhibitStorage(db)
+	})
+}
+
+func TestExhibits(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage[model.Exhibit] {
+		return storage.NewExhibitStorage(newDB(t))
+	}, storagetest.Exhibit)
+}
+
+func TestTrash(t *testing.T) {
+	storagetest.RunTrash(t, func(t *testing.T) storagetest.TrashStorage[model.News] {
+		return storage.NewNewsStorage(newDB(t))
+	}, storagetest.News)
+	storagetest.RunTrash(t, func(t *testing.T) storagetest.TrashStorage[model.Exhibit] {
+		return storage.NewExhibitStorage(newDB(t))
+	}, storagetest.Exhibit)
+	storagetest.RunTrash(t, func(t *testing.T) storagetest.TrashStorage[model.Exhibition] {
+		return storage.NewExhibitionStorage(newDB(t))
+	}, storagetest.Exhibition)
+	storagetest.RunExhibitionTrash(t, func(t *testing.T) (storage.Exhibitions, storage.ExhibitionTrash, storage.Storage[model.Exhibit]) {
+		db := newDB(t)
+		ex := storage.NewExhibitionStorage(db)
+		return ex, ex, storage.NewExhibitStorage(db)
+	})
+}
+
+func TestTx(t *testing.T) {
+	storagetest.RunTx(t, func(t *testing.T) (storage.Transactor, storage.Exhibitions, storage.Storage[model.Exhibit]) {
+		db := newDB(t)
+		return storage.NewTxManager(db), storage.NewExhibitionStorage(db), storage.NewExhibitStorage(db)
+	})
+}
+
+func TestRevisions(t *testing.T) {
+	storagetest.RunRevisions(t, func(t *testing.T) storage.Revisions {
+		return storage.NewRevisionStorage(newDB(t))
+	})
+}
+
+func TestAssets(t *testing.T) {
+	storagetest.RunAssets(t, func(t *testing.T) storage.Assets {
+		return storage.NewAssetStorage(newDB(t))
+	})
+}
+
+func TestMedia(t *testing.T) {
+	storagetest.RunMedia(t, func(t *testing.T) (storage.MediaLibrary, storage.Storage[model.News]) {
+		db := newDB(t)
+		return storage.NewMediaStorage(db), storage.NewNewsStorage(db)
+	})
+}
+
+func TestResolvedMedia(t *testing.T) {
+	storagetest.RunResolvedMedia(t, func(t *testing.T) storage.ResolvedMedia {
+		return storage.NewResolvedMediaStorage(newDB(t))
+	})
+}
+
+func TestImportJobs(t *testing.T) {
+	storagetest.RunImportJobs(t, func(t *testing.T) storage.ImportJobs {
+		return storage.NewImportJobStorage(newDB(t))
+	})
+}
+
+func TestLinkChecks(t *testing.T) {
+	storagetest.RunLinkChecks(t, func(t *testing.T) storage.LinkChecks {
+		return storage.NewLinkCheckStorage(newDB(t))
+	})
+}
+
+func TestAdminUsers(t *testing.T) {
+	storagetest.RunAdminUsers(t, func(t *testing.T) storage.AdminUsers {
+		return storage.NewAdminUserStorage(newDB(t))
+	})
+}
+
+func TestLockOwners(t *testing.T) {
+	storagetest.RunLockOwners(t, func(t *testing.T) (storage.Transactor, storage.AdminUsers) {
+		db := newDB(t)
+		return storage.NewTxManager(db), storage.NewAdminUserStorage(db)
+	})
+}
+
+func TestAdminSessions(t *testing.T) {
+	storagetest.RunAdminSessions(t, func(t *testing.T) (storage.AdminUsers, storage.AdminSessions) {
+		db := newDB(t)
+		return storage.NewAdminUserStorage(db), storage.NewAdminSessionStorage(db)
+	})
+}
+
+func TestAPITokens(t *testing.T) {
+	storagetest.RunAPITokens(t, func(t *testing.T) (storage.AdminUsers, storage.APITokens) {
+		db := newDB(t)
+		return storage.NewAdminUserStorage(db), storage.NewAPITokenStorage(db)
+	})
+}
+
+func TestLoginFailures(t *testing.T) {
+	storagetest.RunLoginFailures(t, func(t *testing.T) storage.LoginFailures {
+		return storage.NewLoginFailureStorage(newDB(t))
+	})
+}
@@ -0,0 +1,149 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// RunAdminSessions checks that storage.AdminSessions finds sessions by
+// token, lists them by user and deletes them one by one, by user and by
+// expiry.
+func RunAdminSessions(t *testing.T, newStorages func(t *testing.T) (storage.AdminUsers, storage.AdminSessions)) {
+	t.Helper()
+	ctx := context.Background()
+	base := time.Now().Truncate(time.Second)
+
+	setup := func(t *testing.T) (storage.AdminSessions, model.AdminUser, model.AdminUser) {
+		users, sessions := newStorages(t)
+		alice, err := users.Create(ctx, model.AdminUser{Login: "alice", PasswordHash: "hash", Role: model.RoleOwner})
+		if err != nil {
+			t.Fatalf("Create user: %v", err)
+		}
+		bob, err := users.Create(ctx, model.AdminUser{Login: "bob", PasswordHash: "hash", Role: model.RoleEditor})
+		if err != nil {
+			t.Fatalf("Create user: %v", err)
+		}
+		return sessions, alice, bob
+	}
+	session := func(t *testing.T, s storage.AdminSessions, user uuid.UUID, token string, lastSeen time.Duration) model.AdminSession {
+		t.Helper()
+		as, err := s.Create(ctx, model.AdminSession{
+			UserID:     user,
+			TokenHash:  token,
+			CSRFToken:  "csrf-" + token,
+			UserAgent:  "test",
+			IP:         "192.0.2.1",
+			LastSeenAt: base.Add(lastSeen),
+			ExpiresAt:  base.Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("Create session %s: %v", token, err)
+		}
+		return as
+	}
+
+	t.Run("AdminSessionCreate", func(t *testing.T) {
+		s, alice, _ := setup(t)
+		as := session(t, s, alice.ID, "token-1", 0)
+		if as.ID == uuid.Nil || as.CreatedAt.IsZero() {
+			t.Errorf("Create = %+v, want the ID and created_at set", as)
+		}
+		if _, err := s.Create(ctx, model.AdminSession{UserID: alice.ID, TokenHash: "token-1", ExpiresAt: base}); !errors.Is(err, storage.ErrConflict) {
+			t.Errorf("Create with a taken token: %v, want ErrConflict", err)
+		}
+		if _, err := s.Create(ctx, model.AdminSession{UserID: uuid.New(), TokenHash: "token-2", ExpiresAt: base}); !errors.Is(err, storage.ErrInvalid) {
+			t.Errorf("Create for unknown user: %v, want ErrInvalid", err)
+		}
+
+		got, err := s.GetByToken(ctx, "token-1")
+		if err != nil {
+			t.Fatalf("GetByToken: %v", err)
+		}
+		if got.ID != as.ID || got.UserID != alice.ID || got.CSRFToken != "csrf-token-1" || !got.ExpiresAt.Equal(as.ExpiresAt) {
+			t.Errorf("GetByToken = %+v, want %+v", got, as)
+		}
+		if _, err := s.GetByToken(ctx, "unknown"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("GetByToken of unknown token: %v, want ErrNotFound", err)
+		}
+
+		if err := s.Touch(ctx, as.ID, base.Add(time.Minute)); err != nil {
+			t.Fatalf("Touch: %v", err)
+		}
+		if got, _ := s.GetByToken(ctx, "token-1"); !got.LastSeenAt.Equal(base.Add(time.Minute)) {
+			t.Errorf("LastSeenAt after Touch = %v, want %v", got.LastSeenAt, base.Add(time.Minute))
+		}
+		if err := s.Touch(ctx, uuid.New(), base); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Touch of unknown session: %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("AdminSessionDelete", func(t *testing.T) {
+		s, alice, bob := setup(t)
+		old := session(t, s, alice.ID, "old", -time.Minute)
+		recent := session(t, s, alice.ID, "recent", 0)
+		third := session(t, s, alice.ID, "third", -2*time.Minute)
+		other := session(t, s, bob.ID, "other", 0)
+
+		list, err := s.ListByUser(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("ListByUser: %v", err)
+		}
+		if len(list) != 3 || list[0].ID != recent.ID || list[1].ID != old.ID || list[2].ID != third.ID {
+			t.Errorf("ListByUser = %+v, want recent, old, third", list)
+		}
+
+		if err := s.Delete(ctx, alice.ID, other.ID); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Delete of a session of another user: %v, want ErrNotFound", err)
+		}
+		if err := s.Delete(ctx, alice.ID, third.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := s.GetByToken(ctx, "third"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("GetByToken of a deleted session: %v, want ErrNotFound", err)
+		}
+
+		n, err := s.DeleteByUser(ctx, alice.ID, recent.ID)
+		if err != nil || n != 1 {
+			t.Fatalf("DeleteByUser = %d, %v, want 1", n, err)
+		}
+		if _, err := s.GetByToken(ctx, "recent"); err != nil {
+			t.Errorf("GetByToken of the kept session: %v", err)
+		}
+		if n, err := s.DeleteByUser(ctx, alice.ID, uuid.Nil); err != nil || n != 1 {
+			t.Errorf("DeleteByUser without exception = %d, %v, want 1", n, err)
+		}
+		if _, err := s.GetByToken(ctx, "other"); err != nil {
+			t.Errorf("GetByToken of the session of another user: %v", err)
+		}
+	})
+
+	t.Run("AdminSessionDeleteExpired", func(t *testing.T) {
+		s, alice, _ := setup(t)
+		session(t, s, alice.ID, "idle", -time.Hour)
+		session(t, s, alice.ID, "active", 0)
+		ended, err := s.Create(ctx, model.AdminSession{
+			UserID: alice.ID, TokenHash: "ended", LastSeenAt: base, ExpiresAt: base.Add(-time.Second),
+		})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		n, err := s.DeleteExpired(ctx, base.Add(-30*time.Minute), base)
+		if err != nil || n != 2 {
+			t.Fatalf("DeleteExpired = %d, %v, want 2", n, err)
+		}
+		list, err := s.ListByUser(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("ListByUser: %v", err)
+		}
+		if len(list) != 1 || list[0].TokenHash != "active" || list[0].ID == ended.ID {
+			t.Errorf("ListByUser after DeleteExpired = %+v, want only the active session", list)
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// RunAdminUsers checks that storage.AdminUsers keeps logins unique, finds
+// users by ID and login, updates their credentials and role and uses their
+// TOTP steps and recovery codes once.
+func RunAdminUsers(t *testing.T, newStorage func(t *testing.T) storage.AdminUsers) {
+	t.Helper()
+	ctx := context.Background()
+
+	user := func(login string, role model.Role) model.AdminUser {
+		return model.AdminUser{Login: login, PasswordHash: "hash-" + login, Role: role}
+	}
+
+	t.Run("AdminUserCreate", func(t *testing.T) {
+		s := newStorage(t)
+		u, err := s.Create(ctx, user("teacher", model.RoleEditor))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if u.ID == uuid.Nil || u.CreatedAt.IsZero() || u.UpdatedAt.IsZero() {
+			t.Errorf("Create = %+v, want the ID and timestamps set", u)
+		}
+		if _, err := s.Create(ctx, user("teacher", model.RoleViewer)); !errors.Is(err, storage.ErrConflict) {
+			t.Errorf("Create of a taken login: %v, want ErrConflict", err)
+		}
+
+		byLogin, err := s.GetByLogin(ctx, "teacher")
+		if err != nil {
+			t.Fatalf("GetByLogin: %v", err)
+		}
+		byID, err := s.Get(ctx, u.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if byLogin.ID != u.ID || byID.Login != "teacher" || byID.PasswordHash != "hash-teacher" || byID.Role != model.RoleEditor {
+			t.Errorf("GetByLogin = %+v, Get = %+v, want the created user", byLogin, byID)
+		}
+
+		if _, err := s.GetByLogin(ctx, "nobody"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("GetByLogin of unknown login: %v, want ErrNotFound", err)
+		}
+		if _, err := s.Get(ctx, uuid.New()); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Get of unknown user: %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("AdminUserUpdate", func(t *testing.T) {
+		s := newStorage(t)
+		u, err := s.Create(ctx, user("teacher", model.RoleEditor))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if _, err := s.Create(ctx, user("analyst", model.RoleViewer)); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		u.PasswordHash, u.Role, u.Disabled = "new-hash", model.RoleOwner, true
+		got, err := s.Update(ctx, u)
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if got.PasswordHash != "new-hash" || got.Role != model.RoleOwner || !got.Disabled || !got.UpdatedAt.After(u.UpdatedAt) {
+			t.Errorf("Update = %+v, want the new hash, role and flag and a later updated_at than %v", got, u.UpdatedAt)
+		}
+		if _, err := s.Update(ctx, model.AdminUser{ID: uuid.New()}); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Update of unknown user: %v, want ErrNotFound", err)
+		}
+
+		users, err := s.List(ctx)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(users) != 2 || users[0].Login != "analyst" || users[1].Login != "teacher" {
+			t.Errorf("List = %+v, want analyst and teacher", users)
+		}
+	})
+
+	t.Run("AdminUserTOTP", func(t *testing.T) {
+		s := newStorage(t)
+		u, err := s.Create(ctx, user("teacher", model.RoleEditor))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if u.TOTPEnabled || len(u.RecoveryCodes) != 0 {
+			t.Errorf("Create = %+v, want two-factor authentication off", u)
+		}
+
+		u.TOTPSecret, u.TOTPEnabled, u.RecoveryCodes = "SECRET", true, []string{"code-a", "code-b"}
+		got, err := s.UpdateTOTP(ctx, u)
+		if err != nil {
+			t.Fatalf("UpdateTOTP: %v", err)
+		}
+		if got.TOTPSecret != "SECRET" || !got.TOTPEnabled || len(got.RecoveryCodes) != 2 || !got.UpdatedAt.After(u.UpdatedAt) {
+			t.Errorf("UpdateTOTP = %+v, want the secret, flag and codes and a later updated_at than %v", got, u.UpdatedAt)
+		}
+		if _, err := s.UpdateTOTP(ctx, model.AdminUser{ID: uuid.New()}); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("UpdateTOTP of unknown user: %v, want ErrNotFound", err)
+		}
+
+		if err := s.UseTOTPStep(ctx, u.ID, 100); err != nil {
+			t.Fatalf("UseTOTPStep: %v", err)
+		}
+		for _, step := range []int64{100, 99} {
+			if err := s.UseTOTPStep(ctx, u.ID, step); !errors.Is(err, storage.ErrConflict) {
+				t.Errorf("UseTOTPStep(%d) after 100: %v, want ErrConflict", step, err)
+			}
+		}
+		if err := s.UseTOTPStep(ctx, u.ID, 101); err != nil {
+			t.Errorf("UseTOTPStep(101): %v", err)
+		}
+		if err := s.UseTOTPStep(ctx, uuid.New(), 1); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("UseTOTPStep of unknown user: %v, want ErrNotFound", err)
+		}
+
+		if err := s.UseRecoveryCode(ctx, u.ID, "code-a"); err != nil {
+			t.Fatalf("UseRecoveryCode: %v", err)
+		}
+		if err := s.UseRecoveryCode(ctx, u.ID, "code-a"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("UseRecoveryCode of a used code: %v, want ErrNotFound", err)
+		}
+		got, err = s.Get(ctx, u.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got.TOTPLastStep != 101 || len(got.RecoveryCodes) != 1 || got.RecoveryCodes[0] != "code-b" {
+			t.Errorf("Get = %+v, want the last step 101 and code-b left", got)
+		}
+	})
+}
+
+// RunLockOwners checks that storage.AdminUsers.LockOwners returns the enabled
+// owners and keeps them from a concurrent unit of work until its own ends, so
+// that one sees the owners as they are after it.
+func RunLockOwners(t *testing.T, newStorages func(t *testing.T) (storage.Transactor, storage.AdminUsers)) {
+	t.Helper()
+	ctx := context.Background()
+	tx, s := newStorages(t)
+
+	var anna model.AdminUser
+	for _, u := range []model.AdminUser{
+		{Login: "boris", Role: model.RoleOwner},
+		{Login: "anna", Role: model.RoleOwner},
+		{Login: "teacher", Role: model.RoleEditor},
+		{Login: "former", Role: model.RoleOwner, Disabled: true},
+	} {
+		u.PasswordHash = "hash-" + u.Login
+		created, err := s.Create(ctx, u)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if u.Login == "anna" {
+			anna = created
+		}
+	}
+	logins := func(users []model.AdminUser) []string {
+		out := make([]string, len(users))
+		for i, u := range users {
+			out[i] = u.Login
+		}
+		return out
+	}
+
+	owners, err := s.LockOwners(ctx)
+	if err != nil {
+		t.Fatalf("LockOwners: %v", err)
+	}
+	if got := logins(owners); len(got) != 2 || got[0] != "anna" || got[1] != "boris" {
+		t.Fatalf("LockOwners = %v, want anna and boris", got)
+	}
+
+	type result struct {
+		owners []model.AdminUser
+		err    error
+	}
+	second := make(chan result, 1)
+	err = tx.InTx(ctx, func(txCtx context.Context) error {
+		if _, err := s.LockOwners(txCtx); err != nil {
+			return err
+		}
+		go func() {
+			var r result
+			r.err = tx.InTx(ctx, func(ctx context.Context) error {
+				var err error
+				r.owners, err = s.LockOwners(ctx)
+				return err
+			})
+			second <- r
+		}()
+		// Give the second unit of work the time to reach the lock.
+		time.Sleep(100 * time.Millisecond)
+		anna.Role = model.RoleEditor
+		_, err := s.Update(txCtx, anna)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("InTx: %v", err)
+	}
+	r := <-second
+	if r.err != nil {
+		t.Fatalf("LockOwners of the second unit of work: %v", r.err)
+	}
+	if got := logins(r.owners); len(got) != 1 || got[0] != "boris" {
+		t.Errorf("LockOwners of the second unit of work = %v, want boris, as after the first", got)
+	}
+}
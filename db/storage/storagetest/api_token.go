@@ -0,0 +1,130 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// RunAPITokens checks that storage.APITokens finds tokens by hash, lists
+// them by user, records their use and deletes them only for their user.
+func RunAPITokens(t *testing.T, newStorages func(t *testing.T) (storage.AdminUsers, storage.APITokens)) {
+	t.Helper()
+	ctx := context.Background()
+	base := time.Now().Truncate(time.Second)
+
+	setup := func(t *testing.T) (storage.APITokens, model.AdminUser, model.AdminUser) {
+		users, tokens := newStorages(t)
+		alice, err := users.Create(ctx, model.AdminUser{Login: "alice", PasswordHash: "hash", Role: model.RoleOwner})
+		if err != nil {
+			t.Fatalf("Create user: %v", err)
+		}
+		bob, err := users.Create(ctx, model.AdminUser{Login: "bob", PasswordHash: "hash", Role: model.RoleEditor})
+		if err != nil {
+			t.Fatalf("Create user: %v", err)
+		}
+		return tokens, alice, bob
+	}
+	token := func(t *testing.T, s storage.APITokens, user uuid.UUID, hash string, created time.Duration) model.APIToken {
+		t.Helper()
+		tok, err := s.Create(ctx, model.APIToken{
+			UserID:    user,
+			Name:      "script " + hash,
+			Prefix:    "smt_" + hash,
+			TokenHash: hash,
+			Scopes:    []model.Scope{model.ScopeNewsWrite, model.ScopeStatsRead},
+			CreatedAt: base.Add(created),
+		})
+		if err != nil {
+			t.Fatalf("Create token %s: %v", hash, err)
+		}
+		return tok
+	}
+
+	t.Run("APITokenCreate", func(t *testing.T) {
+		s, alice, _ := setup(t)
+		tok := token(t, s, alice.ID, "hash-1", 0)
+		if tok.ID == uuid.Nil || tok.CreatedAt.IsZero() || tok.ExpiresAt != nil || tok.LastUsedAt != nil {
+			t.Errorf("Create = %+v, want the ID and created_at set and no expiry or use", tok)
+		}
+		if _, err := s.Create(ctx, model.APIToken{UserID: alice.ID, Name: "dup", TokenHash: "hash-1", Scopes: []model.Scope{model.ScopeNewsRead}}); !errors.Is(err, storage.ErrConflict) {
+			t.Errorf("Create with a taken hash: %v, want ErrConflict", err)
+		}
+		if _, err := s.Create(ctx, model.APIToken{UserID: uuid.New(), Name: "orphan", TokenHash: "hash-2", Scopes: []model.Scope{model.ScopeNewsRead}}); !errors.Is(err, storage.ErrInvalid) {
+			t.Errorf("Create for unknown user: %v, want ErrInvalid", err)
+		}
+
+		expires := base.Add(time.Hour)
+		tok2, err := s.Create(ctx, model.APIToken{UserID: alice.ID, Name: "expiring", TokenHash: "hash-3", Scopes: []model.Scope{model.ScopeMediaRead}, ExpiresAt: &expires})
+		if err != nil {
+			t.Fatalf("Create with expiry: %v", err)
+		}
+		if tok2.ExpiresAt == nil || !tok2.ExpiresAt.Equal(expires) {
+			t.Errorf("ExpiresAt = %v, want %v", tok2.ExpiresAt, expires)
+		}
+
+		got, err := s.GetByHash(ctx, "hash-1")
+		if err != nil {
+			t.Fatalf("GetByHash: %v", err)
+		}
+		if got.ID != tok.ID || got.UserID != alice.ID || got.Name != tok.Name || got.Prefix != tok.Prefix ||
+			!slices.Equal(got.Scopes, tok.Scopes) {
+			t.Errorf("GetByHash = %+v, want %+v", got, tok)
+		}
+		if _, err := s.GetByHash(ctx, "unknown"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("GetByHash of unknown hash: %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("APITokenTouch", func(t *testing.T) {
+		s, alice, _ := setup(t)
+		tok := token(t, s, alice.ID, "hash-1", 0)
+		if err := s.Touch(ctx, tok.ID, base.Add(time.Minute), "192.0.2.1"); err != nil {
+			t.Fatalf("Touch: %v", err)
+		}
+		got, _ := s.GetByHash(ctx, "hash-1")
+		if got.LastUsedAt == nil || !got.LastUsedAt.Equal(base.Add(time.Minute)) || got.LastUsedIP != "192.0.2.1" {
+			t.Errorf("last use after Touch = %v from %q, want %v from 192.0.2.1", got.LastUsedAt, got.LastUsedIP, base.Add(time.Minute))
+		}
+		if err := s.Touch(ctx, uuid.New(), base, ""); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Touch of unknown token: %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("APITokenDelete", func(t *testing.T) {
+		s, alice, bob := setup(t)
+		old := token(t, s, alice.ID, "old", -time.Minute)
+		recent := token(t, s, alice.ID, "recent", 0)
+		other := token(t, s, bob.ID, "other", 0)
+
+		list, err := s.ListByUser(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("ListByUser: %v", err)
+		}
+		if len(list) != 2 || list[0].ID != recent.ID || list[1].ID != old.ID {
+			t.Errorf("ListByUser = %+v, want recent then old", list)
+		}
+		if list, _ := s.ListByUser(ctx, uuid.New()); list == nil || len(list) != 0 {
+			t.Errorf("ListByUser of unknown user = %#v, want an empty list", list)
+		}
+
+		if err := s.Delete(ctx, alice.ID, other.ID); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Delete of the token of another user: %v, want ErrNotFound", err)
+		}
+		if err := s.Delete(ctx, alice.ID, old.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := s.GetByHash(ctx, "old"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("GetByHash after Delete: %v, want ErrNotFound", err)
+		}
+		if _, err := s.GetByHash(ctx, "other"); err != nil {
+			t.Errorf("GetByHash of the token of another user: %v", err)
+		}
+	})
+}
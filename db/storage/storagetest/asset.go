@@ -0,0 +1,163 @@
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+)
+
+// RunAssets checks that storage.Assets keeps the first record of a hash,
+// lists assets newest first with their total, finds them by URL, tracks the
+// assets pending variant generation and reports storage.ErrNotFound for
+// unknown hashes.
+func RunAssets(t *testing.T, newStorage func(t *testing.T) storage.Assets) {
+	t.Helper()
+	ctx := context.Background()
+
+	t.Run("AssetsSave", func(t *testing.T) {
+		s := newStorage(t)
+		a := model.Asset{
+			Hash: "aa11", URL: "/media/aa/aa11.png", MIMEType: "image/png", Size: 42, Name: "first.png",
+			Metadata: &model.MediaMetadata{Width: 4, Height: 3, Orientation: 6, Rotated: true, Removed: []string{"exif", "gps"}},
+		}
+
+		saved, created, err := s.Save(ctx, a)
+		if err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		if !created {
+			t.Error("Save of a new hash reported created = false")
+		}
+		if saved.CreatedAt.IsZero() {
+			t.Error("Save did not fill created_at")
+		}
+
+		dup := a
+		dup.Name = "second.png"
+		again, created, err := s.Save(ctx, dup)
+		if err != nil {
+			t.Fatalf("Save duplicate: %v", err)
+		}
+		if created {
+			t.Error("Save of a known hash reported created = true")
+		}
+		if again.Name != "first.png" || !again.CreatedAt.Equal(saved.CreatedAt) {
+			t.Errorf("Save duplicate returned %+v, want the first record %+v", again, saved)
+		}
+
+		got, err := s.Get(ctx, a.Hash)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got.URL != a.URL || got.MIMEType != a.MIMEType || got.Size != a.Size || got.Name != "first.png" {
+			t.Errorf("Get returned %+v, want %+v", got, saved)
+		}
+		if m := got.Metadata; m == nil || m.Width != 4 || m.Orientation != 6 || !m.Rotated || len(m.Removed) != 2 {
+			t.Errorf("Get returned metadata %+v, want %+v", m, a.Metadata)
+		}
+
+		_, err = s.Get(ctx, "missing")
+		requireNotFound(t, "Get of unknown hash", err)
+	})
+
+	t.Run("AssetsList", func(t *testing.T) {
+		s := newStorage(t)
+		base := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+		for i := range 3 {
+			_, _, err := s.Save(ctx, model.Asset{
+				Hash:      fmt.Sprintf("h%d", i),
+				URL:       fmt.Sprintf("/media/h%d", i),
+				MIMEType:  "image/jpeg",
+				CreatedAt: base.Add(time.Duration(i) * time.Minute),
+			})
+			if err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+		}
+
+		all, total, err := s.List(ctx, 0, 0)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if total != 3 || len(all) != 3 {
+			t.Fatalf("List returned %d assets of %d, want 3 of 3", len(all), total)
+		}
+		for i, want := range []string{"h2", "h1", "h0"} {
+			if all[i].Hash != want {
+				t.Errorf("List[%d] = %s, want %s", i, all[i].Hash, want)
+			}
+		}
+
+		pg, total, err := s.List(ctx, 1, 1)
+		if err != nil {
+			t.Fatalf("List page: %v", err)
+		}
+		if total != 3 || len(pg) != 1 || pg[0].Hash != "h1" {
+			t.Errorf("List(1, 1) returned %v of %d, want [h1] of 3", pg, total)
+		}
+	})
+
+	t.Run("AssetsVariants", func(t *testing.T) {
+		s := newStorage(t)
+		base := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+		for i := range 2 {
+			_, _, err := s.Save(ctx, model.Asset{
+				Hash:      fmt.Sprintf("v%d", i),
+				URL:       fmt.Sprintf("/media/v%d.jpg", i),
+				MIMEType:  "image/jpeg",
+				CreatedAt: base.Add(time.Duration(i) * time.Minute),
+			})
+			if err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+		}
+
+		pending, err := s.Pending(ctx)
+		if err != nil {
+			t.Fatalf("Pending: %v", err)
+		}
+		if len(pending) != 2 || pending[0].Hash != "v0" || pending[1].Hash != "v1" {
+			t.Fatalf("Pending returned %v, want [v0 v1]", pending)
+		}
+
+		variants := []model.ImageVariant{{Name: "thumb", URL: "/media/v0_thumb.jpg", Width: 320, Height: 240}}
+		if err := s.SetVariants(ctx, "v0", 1024, 768, variants); err != nil {
+			t.Fatalf("SetVariants: %v", err)
+		}
+		if err := s.SetVariants(ctx, "v1", 0, 0, nil); err != nil {
+			t.Fatalf("SetVariants without variants: %v", err)
+		}
+		requireNotFound(t, "SetVariants of unknown hash", s.SetVariants(ctx, "missing", 0, 0, nil))
+
+		pending, err = s.Pending(ctx)
+		if err != nil {
+			t.Fatalf("Pending: %v", err)
+		}
+		if len(pending) != 0 {
+			t.Errorf("Pending after SetVariants returned %v, want none", pending)
+		}
+
+		got, err := s.ByURLs(ctx, []string{"/media/v0.jpg", "/media/unknown.jpg"})
+		if err != nil {
+			t.Fatalf("ByURLs: %v", err)
+		}
+		if len(got) != 1 || got[0].Hash != "v0" {
+			t.Fatalf("ByURLs returned %v, want [v0]", got)
+		}
+		if got[0].Width != 1024 || got[0].Height != 768 || len(got[0].Variants) != 1 || got[0].Variants[0] != variants[0] {
+			t.Errorf("ByURLs returned %+v, want the variants %+v of a 1024x768 image", got[0], variants)
+		}
+
+		none, err := s.ByURLs(ctx, nil)
+		if err != nil {
+			t.Fatalf("ByURLs without URLs: %v", err)
+		}
+		if len(none) != 0 {
+			t.Errorf("ByURLs without URLs returned %v, want none", none)
+		}
+	})
+}
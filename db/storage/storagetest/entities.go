@@ -0,0 +1,134 @@
+package storagetest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/google/uuid"
+)
+
+// News describes model.News to Run.
+var News = Entity[model.News]{
+	New: func(i int) model.News {
+		return model.News{
+			Title:     title("новость", i),
+			Content:   fmt.Sprintf("Содержание %d", i),
+			ImageURLs: []string{fmt.Sprintf("https://example.com/news/%d.jpg", i)},
+		}
+	},
+	Patch: func(id uuid.UUID) model.News {
+		return model.News{ID: id, Title: "Обновлённая новость"}
+	},
+	Apply: func(n model.News) model.News {
+		n.Title = "Обновлённая новость"
+		return n
+	},
+	Blank: func(id uuid.UUID) model.News {
+		return model.News{ID: id}
+	},
+	Expect: func(n model.News, updatedAt time.Time) model.News {
+		n.UpdatedAt = updatedAt
+		return n
+	},
+	ID:    func(n model.News) uuid.UUID { return n.ID },
+	Title: func(n model.News) string { return n.Title },
+	Timestamps: func(n model.News) (time.Time, time.Time) {
+		return n.CreatedAt, n.UpdatedAt
+	},
+	Strip: func(n model.News) model.News {
+		n.ID = uuid.Nil
+		n.CreatedAt, n.UpdatedAt, n.DeletedAt = time.Time{}, time.Time{}, time.Time{}
+		n.ImageURLs = nonNil(n.ImageURLs)
+		return n
+	},
+}
+
+// Exhibition describes model.Exhibition to Run. Exhibits are a relation and
+// are ignored here; RunExhibitions covers them.
+var Exhibition = Entity[model.Exhibition]{
+	New: func(i int) model.Exhibition {
+		return model.Exhibition{
+			Title:       title("экспозиция", i),
+			Description: fmt.Sprintf("Описание %d", i),
+		}
+	},
+	Patch: func(id uuid.UUID) model.Exhibition {
+		return model.Exhibition{ID: id, Description: "Новое описание"}
+	},
+	Apply: func(ex model.Exhibition) model.Exhibition {
+		ex.Description = "Новое описание"
+		return ex
+	},
+	Blank: func(id uuid.UUID) model.Exhibition {
+		return model.Exhibition{ID: id}
+	},
+	Expect: func(ex model.Exhibition, updatedAt time.Time) model.Exhibition {
+		ex.UpdatedAt = updatedAt
+		return ex
+	},
+	ID:    func(ex model.Exhibition) uuid.UUID { return ex.ID },
+	Title: func(ex model.Exhibition) string { return ex.Title },
+	Timestamps: func(ex model.Exhibition) (time.Time, time.Time) {
+		return ex.CreatedAt, ex.UpdatedAt
+	},
+	Strip: func(ex model.Exhibition) model.Exhibition {
+		ex.ID = uuid.Nil
+		ex.CreatedAt, ex.UpdatedAt, ex.DeletedAt = time.Time{}, time.Time{}, time.Time{}
+		ex.Exhibits = nil
+		return ex
+	},
+}
+
+// Exhibit describes model.Exhibit to Run.
+var Exhibit = Entity[model.Exhibit]{
+	New: func(i int) model.Exhibit {
+		return model.Exhibit{
+			ExhibitionID: uuid.New(),
+			Title:        title("экспонат", i),
+			Description:  fmt.Sprintf("Описание %d", i),
+			ImageURLs:    []string{fmt.Sprintf("https://example.com/exhibit/%d.jpg", i)},
+		}
+	},
+	Patch: func(id uuid.UUID) model.Exhibit {
+		return model.Exhibit{ID: id, ImageURLs: []string{"https://example.com/new.jpg", "https://example.com/new2.jpg"}}
+	},
+	Apply: func(e model.Exhibit) model.Exhibit {
+		e.ImageURLs = []string{"https://example.com/new.jpg", "https://example.com/new2.jpg"}
+		return e
+	},
+	Blank: func(id uuid.UUID) model.Exhibit {
+		return model.Exhibit{ID: id}
+	},
+	Expect: func(e model.Exhibit, updatedAt time.Time) model.Exhibit {
+		e.UpdatedAt = updatedAt
+		return e
+	},
+	ID:    func(e model.Exhibit) uuid.UUID { return e.ID },
+	Title: func(e model.Exhibit) string { return e.Title },
+	Timestamps: func(e model.Exhibit) (time.Time, time.Time) {
+		return e.CreatedAt, e.UpdatedAt
+	},
+	Strip: func(e model.Exhibit) model.Exhibit {
+		e.ID = uuid.Nil
+		e.CreatedAt, e.UpdatedAt, e.DeletedAt = time.Time{}, time.Time{}, time.Time{}
+		e.ImageURLs = nonNil(e.ImageURLs)
+		return e
+	},
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// titleWords start the titles of the fixtures in mixed case, so that sorting
+// by title in byte order, which puts capitals first, fails the suite.
+var titleWords = []string{"Весна", "арфа", "Дом", "галерея", "Бал"}
+
+// title returns the title of the fixture i of kind.
+func title(kind string, i int) string {
+	return fmt.Sprintf("%s: %s %d", titleWords[i%len(titleWords)], kind, i)
+}
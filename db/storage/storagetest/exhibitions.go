@@ -0,0 +1,153 @@
+package storagetest
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// RunExhibitions checks the exhibition-specific semantics on top of Run:
+//   - Read, List and First load the non-deleted exhibits of an exhibition,
+//     Query only when ListOptions.WithRelations is set;
+//   - Delete soft-deletes the exhibition together with its exhibits;
+//   - SetPreview sets and clears the preview exhibit, and reports
+//     storage.ErrNotFound for missing or soft-deleted exhibitions.
+//
+// newStorages must return empty storages sharing the same dataset.
+func RunExhibitions(t *testing.T, newStorages func(t *testing.T) (storage.Exhibitions, storage.Storage[model.Exhibit])) {
+	t.Helper()
+	ctx := context.Background()
+
+	Run(t, func(t *testing.T) storage.Storage[model.Exhibition] {
+		exhibitions, _ := newStorages(t)
+		return exhibitions
+	}, Exhibition)
+
+	// seed creates an exhibition with two exhibits, one of them deleted.
+	seed := func(t *testing.T, exhibitions storage.Exhibitions, exhibits storage.Storage[model.Exhibit]) (exID, liveID, deletedID uuid.UUID) {
+		t.Helper()
+		exID, err := exhibitions.Create(ctx, Exhibition.New(1))
+		if err != nil {
+			t.Fatalf("Create exhibition: %v", err)
+		}
+		ids := make([]uuid.UUID, 2)
+		for i := range ids {
+			e := Exhibit.New(i)
+			e.ExhibitionID = exID
+			if ids[i], err = exhibits.Create(ctx, e); err != nil {
+				t.Fatalf("Create exhibit: %v", err)
+			}
+		}
+		if err := exhibits.Delete(ctx, ids[1]); err != nil {
+			t.Fatalf("Delete exhibit: %v", err)
+		}
+		return exID, ids[0], ids[1]
+	}
+
+	exhibitIDs := func(ex model.Exhibition) []uuid.UUID {
+		ids := make([]uuid.UUID, 0, len(ex.Exhibits))
+		for _, e := range ex.Exhibits {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	t.Run("LoadsExhibits", func(t *testing.T) {
+		exhibitions, exhibits := newStorages(t)
+		exID, liveID, _ := seed(t, exhibitions, exhibits)
+		want := []uuid.UUID{liveID}
+
+		read, err := exhibitions.Read(ctx, exID)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if got := exhibitIDs(read); !slices.Equal(got, want) {
+			t.Errorf("Read exhibits = %v, want %v", got, want)
+		}
+
+		list, err := exhibitions.List(ctx)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(list) != 1 {
+			t.Fatalf("List returned %d rows, want 1", len(list))
+		}
+		if got := exhibitIDs(list[0]); !slices.Equal(got, want) {
+			t.Errorf("List exhibits = %v, want %v", got, want)
+		}
+
+		page, _, err := exhibitions.Query(ctx, storage.ListOptions{WithRelations: true})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		if len(page) != 1 {
+			t.Fatalf("Query returned %d rows, want 1", len(page))
+		}
+		if got := exhibitIDs(page[0]); !slices.Equal(got, want) {
+			t.Errorf("Query exhibits = %v, want %v", got, want)
+		}
+		page, _, err = exhibitions.Query(ctx, storage.ListOptions{})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		if len(page) != 1 || page[0].Exhibits != nil {
+			t.Errorf("Query without relations loaded exhibits")
+		}
+
+		first, err := exhibitions.First(ctx, func(ex model.Exhibition) bool { return ex.ID == exID })
+		if err != nil {
+			t.Fatalf("First: %v", err)
+		}
+		if got := exhibitIDs(first); !slices.Equal(got, want) {
+			t.Errorf("First exhibits = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("DeleteCascades", func(t *testing.T) {
+		exhibitions, exhibits := newStorages(t)
+		exID, liveID, _ := seed(t, exhibitions, exhibits)
+
+		if err := exhibitions.Delete(ctx, exID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		_, err := exhibits.Read(ctx, liveID)
+		requireNotFound(t, "Read exhibit of deleted exhibition", err)
+	})
+
+	t.Run("SetPreview", func(t *testing.T) {
+		exhibitions, exhibits := newStorages(t)
+		exID, liveID, _ := seed(t, exhibitions, exhibits)
+
+		if err := exhibitions.SetPreview(ctx, exID, &liveID); err != nil {
+			t.Fatalf("SetPreview: %v", err)
+		}
+		ex, err := exhibitions.Read(ctx, exID)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if ex.PreviewExhibitID == nil || *ex.PreviewExhibitID != liveID {
+			t.Errorf("preview = %v, want %s", ex.PreviewExhibitID, liveID)
+		}
+
+		if err := exhibitions.SetPreview(ctx, exID, nil); err != nil {
+			t.Fatalf("SetPreview(nil): %v", err)
+		}
+		ex, err = exhibitions.Read(ctx, exID)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if ex.PreviewExhibitID != nil {
+			t.Errorf("preview = %s, want nil", *ex.PreviewExhibitID)
+		}
+
+		requireNotFound(t, "SetPreview of missing exhibition", exhibitions.SetPreview(ctx, uuid.New(), &liveID))
+		if err := exhibitions.Delete(ctx, exID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		requireNotFound(t, "SetPreview of deleted exhibition", exhibitions.SetPreview(ctx, exID, nil))
+	})
+}
@@ -0,0 +1,105 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// RunImportJobs checks that storage.ImportJobs stores jobs with their items,
+// updates their progress and lists pending jobs oldest first and the jobs
+// of an owner newest first.
+func RunImportJobs(t *testing.T, newStorage func(t *testing.T) storage.ImportJobs) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	owner := uuid.New()
+
+	job := func(created time.Time, status model.ImportStatus) model.ImportJob {
+		return model.ImportJob{
+			OwnerType: model.EntityExhibit,
+			OwnerID:   owner,
+			Status:    status,
+			Items:     []model.ImportItem{{SourceURL: "https://a/1.jpg", Status: model.ImportPending}},
+			CreatedAt: created,
+		}
+	}
+
+	t.Run("ImportJobUpdate", func(t *testing.T) {
+		s := newStorage(t)
+		j, err := s.Create(ctx, job(now, model.ImportPending))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if j.ID == uuid.Nil {
+			t.Fatal("Create returned no ID")
+		}
+
+		j.Items[0].Status, j.Items[0].URL = model.ImportDone, "/media/ab/ab.jpg"
+		finished := now.Add(time.Second)
+		j.Status, j.FinishedAt = model.ImportDone, &finished
+		if err := s.Update(ctx, j); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		got, err := s.Get(ctx, j.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got.Status != model.ImportDone || got.FinishedAt == nil || !got.FinishedAt.Equal(finished) ||
+			len(got.Items) != 1 || got.Items[0].URL != "/media/ab/ab.jpg" {
+			t.Errorf("Get = %+v, want the updated job", got)
+		}
+
+		if _, err := s.Get(ctx, uuid.New()); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Get of unknown job: %v, want ErrNotFound", err)
+		}
+		if err := s.Update(ctx, model.ImportJob{ID: uuid.New()}); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Update of unknown job: %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("ImportJobPending", func(t *testing.T) {
+		s := newStorage(t)
+		var ids []uuid.UUID
+		for i, status := range []model.ImportStatus{model.ImportRunning, model.ImportDone, model.ImportPending, model.ImportFailed} {
+			j, err := s.Create(ctx, job(now.Add(time.Duration(i)*time.Second), status))
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			ids = append(ids, j.ID)
+		}
+		if _, err := s.Create(ctx, model.ImportJob{OwnerType: model.EntityNews, OwnerID: uuid.New(), Status: model.ImportDone}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		pending, err := s.Pending(ctx)
+		if err != nil {
+			t.Fatalf("Pending: %v", err)
+		}
+		if len(pending) != 2 || pending[0].ID != ids[0] || pending[1].ID != ids[2] {
+			t.Errorf("Pending = %v, want the running and the pending job, oldest first", jobIDs(pending))
+		}
+
+		byOwner, err := s.ByOwner(ctx, model.EntityExhibit, owner)
+		if err != nil {
+			t.Fatalf("ByOwner: %v", err)
+		}
+		if len(byOwner) != 4 || byOwner[0].ID != ids[3] || byOwner[3].ID != ids[0] {
+			t.Errorf("ByOwner = %v, want the 4 jobs of the exhibit, newest first", jobIDs(byOwner))
+		}
+	})
+}
+
+func jobIDs(jobs []model.ImportJob) []uuid.UUID {
+	ids := make([]uuid.UUID, len(jobs))
+	for i, j := range jobs {
+		ids[i] = j.ID
+	}
+	return ids
+}
@@ -0,0 +1,107 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// RunLinkChecks checks that storage.LinkChecks replaces the results of an
+// owner as a whole, filters them by status and removes the stale ones.
+func RunLinkChecks(t *testing.T, newStorage func(t *testing.T) storage.LinkChecks) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	news, exhibit := uuid.New(), uuid.New()
+
+	check := func(url string, status model.LinkStatus, at time.Time) model.LinkCheck {
+		return model.LinkCheck{URL: url, Status: status, CheckedAt: at}
+	}
+
+	t.Run("LinkCheckSave", func(t *testing.T) {
+		s := newStorage(t)
+		if err := s.Save(ctx, model.EntityNews, news, []model.LinkCheck{
+			check("https://a/1.jpg", model.LinkOK, now),
+			check("https://a/2.jpg", model.LinkNotFound, now),
+		}); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		if err := s.Save(ctx, model.EntityNews, news, []model.LinkCheck{
+			check("https://a/2.jpg", model.LinkOK, now),
+			check("https://a/3.jpg", model.LinkTimeout, now),
+		}); err != nil {
+			t.Fatalf("second Save: %v", err)
+		}
+
+		got, err := s.List(ctx)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(got) != 2 || got[0].URL != "https://a/2.jpg" || got[0].Status != model.LinkOK || got[1].URL != "https://a/3.jpg" {
+			t.Fatalf("List = %+v, want the results of the second Save", got)
+		}
+		if got[0].OwnerType != model.EntityNews || got[0].OwnerID != news || !got[0].CheckedAt.Equal(now) {
+			t.Errorf("List()[0] = %+v, want it to belong to news %s checked at %v", got[0], news, now)
+		}
+
+		if err := s.Save(ctx, model.EntityNews, news, nil); err != nil {
+			t.Fatalf("Save of no results: %v", err)
+		}
+		if got, err := s.List(ctx); err != nil || len(got) != 0 {
+			t.Errorf("List after Save of no results = %+v, %v, want none", got, err)
+		}
+	})
+
+	t.Run("LinkCheckList", func(t *testing.T) {
+		s := newStorage(t)
+		if err := s.Save(ctx, model.EntityExhibit, exhibit, []model.LinkCheck{
+			check("https://b/1.jpg", model.LinkError, now),
+			check("https://b/2.jpg", model.LinkRedirect, now),
+		}); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		if err := s.Save(ctx, model.EntityNews, news, []model.LinkCheck{
+			check("https://a/1.jpg", model.LinkNotFound, now),
+		}); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+
+		broken, err := s.List(ctx, model.BrokenLinkStatuses...)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(broken) != 2 || broken[0].OwnerType != model.EntityExhibit || broken[0].URL != "https://b/1.jpg" ||
+			broken[1].OwnerType != model.EntityNews {
+			t.Errorf("List of broken = %+v, want the exhibit link, then the news link", broken)
+		}
+	})
+
+	t.Run("LinkCheckDeleteBefore", func(t *testing.T) {
+		s := newStorage(t)
+		if err := s.Save(ctx, model.EntityNews, news, []model.LinkCheck{
+			check("https://a/1.jpg", model.LinkOK, now.Add(-time.Hour)),
+		}); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		if err := s.Save(ctx, model.EntityExhibit, exhibit, []model.LinkCheck{
+			check("https://b/1.jpg", model.LinkOK, now),
+		}); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+
+		n, err := s.DeleteBefore(ctx, now)
+		if err != nil {
+			t.Fatalf("DeleteBefore: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("DeleteBefore = %d, want 1", n)
+		}
+		if got, err := s.List(ctx); err != nil || len(got) != 1 || got[0].OwnerID != exhibit {
+			t.Errorf("List after DeleteBefore = %+v, %v, want the exhibit result", got, err)
+		}
+	})
+}
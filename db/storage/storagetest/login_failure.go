@@ -0,0 +1,71 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// RunLoginFailures checks that storage.LoginFailures pages the failures by
+// time, filters them by login and deletes the old ones.
+func RunLoginFailures(t *testing.T, newStorage func(t *testing.T) storage.LoginFailures) {
+	t.Helper()
+	ctx := context.Background()
+	base := time.Now().Truncate(time.Second)
+
+	failure := func(t *testing.T, s storage.LoginFailures, login string, at time.Duration) model.LoginFailure {
+		t.Helper()
+		f, err := s.Create(ctx, model.LoginFailure{Login: login, IP: "192.0.2.1", UserAgent: "test", CreatedAt: base.Add(at)})
+		if err != nil {
+			t.Fatalf("Create %s: %v", login, err)
+		}
+		return f
+	}
+
+	t.Run("LoginFailureQuery", func(t *testing.T) {
+		s := newStorage(t)
+		first := failure(t, s, "alice", -3*time.Minute)
+		second := failure(t, s, "bob", -2*time.Minute)
+		third := failure(t, s, "alice", -time.Minute)
+		if first.ID == uuid.Nil || first.CreatedAt.IsZero() {
+			t.Errorf("Create = %+v, want the ID and created_at set", first)
+		}
+
+		page, total, err := s.Query(ctx, "", storage.ListOptions{Limit: 2, Desc: true})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		if total != 3 || len(page) != 2 || page[0].ID != third.ID || page[1].ID != second.ID {
+			t.Errorf("Query = %+v (total %d), want third and second of 3", page, total)
+		}
+		page, total, _ = s.Query(ctx, "alice", storage.ListOptions{})
+		if total != 2 || len(page) != 2 || page[0].ID != first.ID || page[1].ID != third.ID {
+			t.Errorf("Query of alice = %+v (total %d), want first and third", page, total)
+		}
+		if page, total, _ := s.Query(ctx, "carol", storage.ListOptions{}); page == nil || len(page) != 0 || total != 0 {
+			t.Errorf("Query of unknown login = %#v (total %d), want an empty page", page, total)
+		}
+	})
+
+	t.Run("LoginFailureDeleteBefore", func(t *testing.T) {
+		s := newStorage(t)
+		failure(t, s, "alice", -2*time.Hour)
+		failure(t, s, "alice", -90*time.Minute)
+		recent := failure(t, s, "alice", 0)
+
+		n, err := s.DeleteBefore(ctx, base.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("DeleteBefore: %v", err)
+		}
+		if n != 2 {
+			t.Errorf("DeleteBefore = %d, want 2", n)
+		}
+		if page, _, _ := s.Query(ctx, "", storage.ListOptions{}); len(page) != 1 || page[0].ID != recent.ID {
+			t.Errorf("Query after DeleteBefore = %+v, want the recent failure", page)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// RunMedia checks that storage.MediaLibrary keeps media items with an
+// updated_at precondition on Update, and attaches them to news in order:
+// Attach replaces the previous attachments, rejects unknown items and
+// Delete of an item detaches it.
+func RunMedia(t *testing.T, newStorages func(t *testing.T) (storage.MediaLibrary, storage.Storage[model.News])) {
+	t.Helper()
+	ctx := context.Background()
+
+	t.Run("MediaCRUD", func(t *testing.T) {
+		s, _ := newStorages(t)
+		m, err := s.Create(ctx, model.Media{URL: "/media/aa/aa.jpg", Caption: "Кубок", Alt: "Кубок на полке"})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if m.ID == uuid.Nil || m.CreatedAt.IsZero() || m.UpdatedAt.IsZero() {
+			t.Fatalf("Create returned %+v, want id and timestamps filled", m)
+		}
+
+		got, err := s.Read(ctx, m.ID)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		requireContent(t, "Read", m, got)
+
+		upd := m
+		upd.Credit = "Фото: И. Иванов"
+		upd.License = "CC BY 4.0"
+		upd, err = s.Update(ctx, upd)
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if upd.Credit != "Фото: И. Иванов" || upd.Caption != "Кубок" || !upd.UpdatedAt.After(m.UpdatedAt) {
+			t.Errorf("Update returned %+v, want credit set and updated_at bumped", upd)
+		}
+		if _, err := s.Update(ctx, m); !errors.Is(err, storage.ErrStale) {
+			t.Errorf("Update with a stale updated_at: got error %v, want storage.ErrStale", err)
+		}
+
+		if _, total, err := s.Query(ctx, storage.ListOptions{}); err != nil || total != 1 {
+			t.Errorf("Query returned total %d, error %v; want 1", total, err)
+		}
+
+		if err := s.Delete(ctx, m.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		_, err = s.Read(ctx, m.ID)
+		requireNotFound(t, "Read after Delete", err)
+		requireNotFound(t, "Delete of unknown item", s.Delete(ctx, m.ID))
+	})
+
+	t.Run("MediaAttach", func(t *testing.T) {
+		s, news := newStorages(t)
+		newsID, err := news.Create(ctx, model.News{Title: "С вложениями"})
+		if err != nil {
+			t.Fatalf("Create news: %v", err)
+		}
+		var ids []uuid.UUID
+		for _, u := range []string{"/media/a.jpg", "/media/b.jpg", "/media/c.jpg"} {
+			m, err := s.Create(ctx, model.Media{URL: u})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			ids = append(ids, m.ID)
+		}
+
+		attachedURLs := func() []string {
+			t.Helper()
+			byOwner, err := s.Attached(ctx, model.EntityNews, []uuid.UUID{newsID, uuid.New()})
+			if err != nil {
+				t.Fatalf("Attached: %v", err)
+			}
+			var urls []string
+			for i, a := range byOwner[newsID] {
+				if a.Position != i || a.OwnerID != newsID {
+					t.Errorf("Attached[%d] has position %d, owner %s; want %d, %s", i, a.Position, a.OwnerID, i, newsID)
+				}
+				urls = append(urls, a.URL)
+			}
+			return urls
+		}
+
+		if err := s.Attach(ctx, model.EntityNews, newsID, []uuid.UUID{ids[2], ids[0]}); err != nil {
+			t.Fatalf("Attach: %v", err)
+		}
+		requireContent(t, "Attached", []string{"/media/c.jpg", "/media/a.jpg"}, attachedURLs())
+
+		if err := s.Attach(ctx, model.EntityNews, newsID, []uuid.UUID{ids[1], ids[2]}); err != nil {
+			t.Fatalf("Attach again: %v", err)
+		}
+		requireContent(t, "Attached after replace", []string{"/media/b.jpg", "/media/c.jpg"}, attachedURLs())
+
+		err = s.Attach(ctx, model.EntityNews, newsID, []uuid.UUID{ids[0], uuid.New()})
+		if storage.Classify(err) != storage.ErrInvalid {
+			t.Errorf("Attach of an unknown item: got error %v, want storage.ErrInvalid", err)
+		}
+
+		if err := s.Delete(ctx, ids[1]); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		requireContent(t, "Attached after Delete", []string{"/media/c.jpg"}, attachedURLs())
+
+		if err := s.Attach(ctx, model.EntityNews, newsID, nil); err != nil {
+			t.Fatalf("Attach nothing: %v", err)
+		}
+		if urls := attachedURLs(); len(urls) != 0 {
+			t.Errorf("Attached after detaching all returned %v, want none", urls)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+)
+
+// RunResolvedMedia checks that storage.ResolvedMedia replaces entries of the
+// same source URL, hides and removes expired entries and reports
+// storage.ErrNotFound for unknown ones.
+func RunResolvedMedia(t *testing.T, newStorage func(t *testing.T) storage.ResolvedMedia) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	entry := func(source string, expires time.Time) model.ResolvedMedia {
+		return model.ResolvedMedia{
+			SourceURL: source, URL: source + "/file.jpg", Type: "image", Provider: "direct",
+			ResolvedAt: now, ExpiresAt: expires,
+		}
+	}
+
+	t.Run("ResolvedMediaPut", func(t *testing.T) {
+		s := newStorage(t)
+		if _, err := s.Get(ctx, "https://a", now); !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("Get of unknown entry: %v, want ErrNotFound", err)
+		}
+
+		if err := s.Put(ctx, entry("https://a", now.Add(time.Hour))); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		e := entry("https://a", now.Add(2*time.Hour))
+		e.URL, e.Title = "https://a/other.jpg", "Other"
+		if err := s.Put(ctx, e); err != nil {
+			t.Fatalf("Put of the same source: %v", err)
+		}
+
+		got, err := s.Get(ctx, "https://a", now)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got.URL != e.URL || got.Title != e.Title || !got.ExpiresAt.Equal(e.ExpiresAt) {
+			t.Errorf("Get = %+v, want the replaced entry %+v", got, e)
+		}
+
+		if err := s.Delete(ctx, "https://a"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := s.Delete(ctx, "https://a"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("second Delete: %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("ResolvedMediaExpiry", func(t *testing.T) {
+		s := newStorage(t)
+		for _, e := range []model.ResolvedMedia{
+			entry("https://old", now.Add(-time.Minute)),
+			entry("https://fresh", now.Add(time.Hour)),
+			entry("https://later", now.Add(2*time.Hour)),
+		} {
+			if err := s.Put(ctx, e); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+		}
+
+		if _, err := s.Get(ctx, "https://old", now); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Get of expired entry: %v, want ErrNotFound", err)
+		}
+		n, err := s.DeleteExpired(ctx, now)
+		if err != nil || n != 1 {
+			t.Errorf("DeleteExpired = %d, %v, want 1", n, err)
+		}
+		n, err = s.DeleteAll(ctx)
+		if err != nil || n != 2 {
+			t.Errorf("DeleteAll = %d, %v, want 2", n, err)
+		}
+		if _, err := s.Get(ctx, "https://fresh", now); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Get after DeleteAll: %v, want ErrNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// RunRevisions checks that storage.Revisions numbers revisions per entity
+// starting from 1, lists them oldest first and reports storage.ErrNotFound
+// for unknown revisions.
+func RunRevisions(t *testing.T, newStorage func(t *testing.T) storage.Revisions) {
+	t.Helper()
+	ctx := context.Background()
+
+	t.Run("RevisionsAppend", func(t *testing.T) {
+		s := newStorage(t)
+		a, b := uuid.New(), uuid.New()
+		appendRev := func(id uuid.UUID, title string) model.Revision {
+			t.Helper()
+			r, err := s.Append(ctx, model.Revision{
+				EntityType: model.EntityNews,
+				EntityID:   id,
+				Action:     model.RevisionUpdate,
+				Author:     "admin",
+				Snapshot:   json.RawMessage(`{"title":"` + title + `"}`),
+				Changes:    []model.FieldChange{{Field: "title", New: json.RawMessage(`"` + title + `"`)}},
+			})
+			if err != nil {
+				t.Fatalf("Append: %v", err)
+			}
+			return r
+		}
+
+		for i, want := range []struct {
+			id  uuid.UUID
+			rev int
+		}{{a, 1}, {a, 2}, {b, 1}, {a, 3}} {
+			r := appendRev(want.id, string(rune('a'+i)))
+			if r.Rev != want.rev {
+				t.Errorf("Append #%d rev = %d, want %d", i, r.Rev, want.rev)
+			}
+			if r.CreatedAt.IsZero() {
+				t.Errorf("Append #%d left created_at zero", i)
+			}
+		}
+
+		revs, err := s.List(ctx, model.EntityNews, a)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(revs) != 3 {
+			t.Fatalf("List returned %d revisions, want 3", len(revs))
+		}
+		for i, r := range revs {
+			if r.Rev != i+1 {
+				t.Errorf("List[%d].Rev = %d, want %d", i, r.Rev, i+1)
+			}
+		}
+
+		got, err := s.Get(ctx, model.EntityNews, a, 2)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if !jsonEqual(got.Snapshot, json.RawMessage(`{"title":"b"}`)) || len(got.Changes) != 1 {
+			t.Errorf("Get returned snapshot %s with %d changes", got.Snapshot, len(got.Changes))
+		}
+
+		_, err = s.Get(ctx, model.EntityNews, a, 4)
+		requireNotFound(t, "Get unknown rev", err)
+		_, err = s.Get(ctx, model.EntityExhibit, a, 1)
+		requireNotFound(t, "Get of another entity type", err)
+
+		revs, err = s.List(ctx, model.EntityExhibit, a)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(revs) != 0 {
+			t.Errorf("List of another entity type returned %d revisions", len(revs))
+		}
+	})
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb any
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	ja, _ := json.Marshal(va)
+	jb, _ := json.Marshal(vb)
+	return string(ja) == string(jb)
+}
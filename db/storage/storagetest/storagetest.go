@@ -0,0 +1,394 @@
+// Package storagetest is a conformance suite for storage.Storage[T] backends.
+//
+// Every backend (Postgres, memory, ...) must pass the same suite so that the
+// services above it behave identically regardless of the configured driver:
+//
+//	func TestNewsStorage(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Storage[model.News] {
+//			return memory.NewNewsStorage(memory.NewDB())
+//		}, storagetest.News)
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// clockSkew is the tolerated difference between the test clock and the
+// backend clock when checking default timestamps.
+const clockSkew = 5 * time.Second
+
+// Entity describes a model type to the generic suite.
+type Entity[T any] struct {
+	// New returns a valid value to create; values for different i must differ.
+	New func(i int) T
+	// Patch returns a partial update of id that sets a single field.
+	Patch func(id uuid.UUID) T
+	// Apply returns the content expected after Patch is applied to before.
+	Apply func(before T) T
+	// Blank returns a value of id with all user-editable fields zero.
+	Blank func(id uuid.UUID) T
+	// Expect sets the UpdatedAt of v, which Update treats as a precondition.
+	Expect func(v T, updatedAt time.Time) T
+
+	ID         func(T) uuid.UUID
+	Title      func(T) string
+	Timestamps func(T) (createdAt, updatedAt time.Time)
+	// Strip zeroes the ID, timestamps and relations of a value and normalises
+	// nil slices, leaving only the user-editable content for comparison.
+	Strip func(T) T
+}
+
+// Run checks a Storage[T] backend against the shared semantics:
+//   - Read, Update and First report storage.ErrNotFound for missing or soft-deleted rows;
+//   - Update is partial: zero fields of the argument keep their stored values;
+//   - Replace is not: it overwrites every user-editable field, zero values included;
+//   - Update and Replace move updated_at forward, and Update with a stale
+//     UpdatedAt reports storage.ErrStale (a storage.ErrConflict) without writing;
+//   - Delete is a soft delete hiding the row from Read, List and First, and is idempotent;
+//   - created_at and updated_at default to the current time on Create;
+//   - Query filters, sorts, pages and counts like storage.ListOptions describes.
+//
+// newStorage is called for every subtest and must return an empty storage.
+func Run[T any](t *testing.T, newStorage func(t *testing.T) storage.Storage[T], e Entity[T]) {
+	t.Helper()
+	ctx := context.Background()
+
+	t.Run("ReadMissing", func(t *testing.T) {
+		s := newStorage(t)
+		_, err := s.Read(ctx, uuid.New())
+		requireNotFound(t, "Read", err)
+	})
+
+	t.Run("CreateRead", func(t *testing.T) {
+		s := newStorage(t)
+		before := time.Now()
+		in := e.New(1)
+		id, err := s.Create(ctx, in)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if id == uuid.Nil {
+			t.Fatal("Create returned nil id")
+		}
+
+		got, err := s.Read(ctx, id)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if e.ID(got) != id {
+			t.Errorf("Read id = %s, want %s", e.ID(got), id)
+		}
+		requireContent(t, "Read", e.Strip(in), e.Strip(got))
+
+		created, updated := e.Timestamps(got)
+		requireNear(t, "created_at", created, before)
+		requireNear(t, "updated_at", updated, before)
+	})
+
+	t.Run("CreateDistinctIDs", func(t *testing.T) {
+		s := newStorage(t)
+		id1, err := s.Create(ctx, e.New(1))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		id2, err := s.Create(ctx, e.New(2))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if id1 == id2 {
+			t.Fatalf("Create returned the same id twice: %s", id1)
+		}
+	})
+
+	t.Run("UpdatePartial", func(t *testing.T) {
+		s := newStorage(t)
+		id, err := s.Create(ctx, e.New(1))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		before, err := s.Read(ctx, id)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+
+		updated, err := s.Update(ctx, e.Patch(id))
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		after, err := s.Read(ctx, id)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+
+		want := e.Strip(e.Apply(before))
+		requireContent(t, "Update result", want, e.Strip(updated))
+		requireContent(t, "Read after Update", want, e.Strip(after))
+		if e.ID(updated) != id {
+			t.Errorf("Update id = %s, want %s", e.ID(updated), id)
+		}
+
+		createdBefore, _ := e.Timestamps(before)
+		createdAfter, _ := e.Timestamps(after)
+		if !createdAfter.Equal(createdBefore) {
+			t.Errorf("Update changed created_at: %v -> %v", createdBefore, createdAfter)
+		}
+		createdUpdated, _ := e.Timestamps(updated)
+		if !createdUpdated.Equal(createdBefore) {
+			t.Errorf("Update result created_at = %v, want %v", createdUpdated, createdBefore)
+		}
+	})
+
+	t.Run("UpdateMissing", func(t *testing.T) {
+		s := newStorage(t)
+		_, err := s.Update(ctx, e.Patch(uuid.New()))
+		requireNotFound(t, "Update", err)
+	})
+
+	t.Run("UpdateVersion", func(t *testing.T) {
+		s := newStorage(t)
+		id, err := s.Create(ctx, e.New(1))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		before, err := s.Read(ctx, id)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		_, stale := e.Timestamps(before)
+
+		updated, err := s.Update(ctx, e.Expect(e.Patch(id), stale))
+		if err != nil {
+			t.Fatalf("Update with current updated_at: %v", err)
+		}
+		_, current := e.Timestamps(updated)
+		if !current.After(stale) {
+			t.Errorf("Update did not move updated_at forward: %v -> %v", stale, current)
+		}
+
+		_, err = s.Update(ctx, e.Expect(e.Blank(id), stale))
+		if !errors.Is(err, storage.ErrStale) || !errors.Is(err, storage.ErrConflict) {
+			t.Errorf("Update with stale updated_at returned %v, want storage.ErrStale", err)
+		}
+		after, err := s.Read(ctx, id)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		requireContent(t, "Read after conflicting Update", e.Strip(updated), e.Strip(after))
+
+		replaced, err := s.Replace(ctx, e.Blank(id))
+		if err != nil {
+			t.Fatalf("Replace: %v", err)
+		}
+		if _, r := e.Timestamps(replaced); !r.After(current) {
+			t.Errorf("Replace did not move updated_at forward: %v -> %v", current, r)
+		}
+
+		_, err = s.Update(ctx, e.Expect(e.Patch(uuid.New()), stale))
+		requireNotFound(t, "Update of missing row with precondition", err)
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		s := newStorage(t)
+		id, err := s.Create(ctx, e.New(1))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		replaced, err := s.Replace(ctx, e.Blank(id))
+		if err != nil {
+			t.Fatalf("Replace: %v", err)
+		}
+		after, err := s.Read(ctx, id)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+
+		want := e.Strip(e.Blank(id))
+		requireContent(t, "Replace result", want, e.Strip(replaced))
+		requireContent(t, "Read after Replace", want, e.Strip(after))
+
+		_, err = s.Replace(ctx, e.Blank(uuid.New()))
+		requireNotFound(t, "Replace missing row", err)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		s := newStorage(t)
+		ids := map[uuid.UUID]bool{}
+		for i := range 3 {
+			id, err := s.Create(ctx, e.New(i))
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			ids[id] = true
+		}
+
+		list, err := s.List(ctx)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(list) != len(ids) {
+			t.Fatalf("List returned %d rows, want %d", len(list), len(ids))
+		}
+		for _, v := range list {
+			if !ids[e.ID(v)] {
+				t.Errorf("List returned unexpected id %s", e.ID(v))
+			}
+		}
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		s := newStorage(t)
+		var deleted uuid.UUID
+		for i := range 5 {
+			id, err := s.Create(ctx, e.New(i))
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if i == 4 {
+				deleted = id
+			}
+		}
+		if err := s.Delete(ctx, deleted); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		all, total, err := s.Query(ctx, storage.ListOptions{Sort: storage.SortTitle})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		if total != 4 || len(all) != 4 {
+			t.Fatalf("Query returned %d rows of %d, want 4 of 4", len(all), total)
+		}
+		titles := make([]string, len(all))
+		for i, v := range all {
+			titles[i] = e.Title(v)
+		}
+		if !slices.IsSortedFunc(titles, storage.CompareTitles) {
+			t.Errorf("Query by title is not sorted: %q", titles)
+		}
+
+		page, total, err := s.Query(ctx, storage.ListOptions{Sort: storage.SortTitle, Desc: true, Limit: 2, Offset: 1})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		if total != 4 {
+			t.Errorf("Query total = %d, want 4", total)
+		}
+		if len(page) != 2 || e.Title(page[0]) != titles[2] || e.Title(page[1]) != titles[1] {
+			t.Errorf("Query page = %d rows, want titles %q, %q", len(page), titles[2], titles[1])
+		}
+
+		created, _ := e.Timestamps(all[0])
+		none, total, err := s.Query(ctx, storage.ListOptions{CreatedBefore: created.Add(-time.Hour)})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		if total != 0 || len(none) != 0 {
+			t.Errorf("Query with created_before in the past returned %d rows of %d", len(none), total)
+		}
+		_, total, err = s.Query(ctx, storage.ListOptions{CreatedAfter: created.Add(-time.Hour), Limit: 1})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		if total != 4 {
+			t.Errorf("Query with created_after in the past counted %d rows, want 4", total)
+		}
+	})
+
+	t.Run("First", func(t *testing.T) {
+		s := newStorage(t)
+		id, err := s.Create(ctx, e.New(1))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if _, err := s.Create(ctx, e.New(2)); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		got, err := s.First(ctx, func(v T) bool { return e.ID(v) == id })
+		if err != nil {
+			t.Fatalf("First: %v", err)
+		}
+		read, err := s.Read(ctx, id)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		requireContent(t, "First", read, got)
+
+		_, err = s.First(ctx, func(T) bool { return false })
+		requireNotFound(t, "First without match", err)
+	})
+
+	t.Run("SoftDelete", func(t *testing.T) {
+		s := newStorage(t)
+		id, err := s.Create(ctx, e.New(1))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		keep, err := s.Create(ctx, e.New(2))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		if err := s.Delete(ctx, id); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		_, err = s.Read(ctx, id)
+		requireNotFound(t, "Read after Delete", err)
+
+		_, err = s.Update(ctx, e.Patch(id))
+		requireNotFound(t, "Update after Delete", err)
+
+		_, err = s.First(ctx, func(v T) bool { return e.ID(v) == id })
+		requireNotFound(t, "First after Delete", err)
+
+		list, err := s.List(ctx)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(list) != 1 || e.ID(list[0]) != keep {
+			t.Errorf("List after Delete returned %d rows, want only %s", len(list), keep)
+		}
+
+		if err := s.Delete(ctx, id); err != nil {
+			t.Errorf("second Delete: %v", err)
+		}
+		if err := s.Delete(ctx, uuid.New()); err != nil {
+			t.Errorf("Delete missing: %v", err)
+		}
+	})
+}
+
+func requireNotFound(t *testing.T, op string, err error) {
+	t.Helper()
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("%s: got error %v, want storage.ErrNotFound", op, err)
+	}
+}
+
+func requireContent[T any](t *testing.T, op string, want, got T) {
+	t.Helper()
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("%s:\n got  %+v\n want %+v", op, got, want)
+	}
+}
+
+func requireNear(t *testing.T, name string, got, ref time.Time) {
+	t.Helper()
+	if got.IsZero() {
+		t.Fatalf("%s is zero", name)
+	}
+	if d := got.Sub(ref); d < -clockSkew || d > clockSkew {
+		t.Fatalf("%s = %v, want close to %v", name, got, ref)
+	}
+}
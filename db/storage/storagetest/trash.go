@@ -0,0 +1,175 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// TrashStorage is a Storage that also exposes its trash.
+type TrashStorage[T any] interface {
+	storage.Storage[T]
+	storage.Trash[T]
+}
+
+// RunTrash checks the storage.Trash semantics of a backend:
+//   - only deleted rows are listed, restored or purged, live rows report storage.ErrNotFound;
+//   - Restore makes the row visible to Read again with its content intact;
+//   - Purge and PurgeDeletedBefore remove rows for good.
+func RunTrash[T any](t *testing.T, newStorage func(t *testing.T) TrashStorage[T], e Entity[T]) {
+	t.Helper()
+	ctx := context.Background()
+
+	t.Run("TrashListRestore", func(t *testing.T) {
+		s := newStorage(t)
+		id, err := s.Create(ctx, e.New(1))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if _, err := s.Create(ctx, e.New(2)); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		orig, err := s.Read(ctx, id)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+
+		err = s.Restore(ctx, id)
+		requireNotFound(t, "Restore live row", err)
+
+		if err := s.Delete(ctx, id); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		deleted, err := s.ListDeleted(ctx)
+		if err != nil {
+			t.Fatalf("ListDeleted: %v", err)
+		}
+		if len(deleted) != 1 || e.ID(deleted[0]) != id {
+			t.Fatalf("ListDeleted returned %d rows, want only %s", len(deleted), id)
+		}
+
+		if err := s.Restore(ctx, id); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		got, err := s.Read(ctx, id)
+		if err != nil {
+			t.Fatalf("Read after Restore: %v", err)
+		}
+		requireContent(t, "Read after Restore", e.Strip(orig), e.Strip(got))
+
+		deleted, err = s.ListDeleted(ctx)
+		if err != nil {
+			t.Fatalf("ListDeleted: %v", err)
+		}
+		if len(deleted) != 0 {
+			t.Errorf("ListDeleted after Restore returned %d rows", len(deleted))
+		}
+	})
+
+	t.Run("TrashPurge", func(t *testing.T) {
+		s := newStorage(t)
+		id, err := s.Create(ctx, e.New(1))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		err = s.Purge(ctx, id)
+		requireNotFound(t, "Purge live row", err)
+
+		if err := s.Delete(ctx, id); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := s.Purge(ctx, id); err != nil {
+			t.Fatalf("Purge: %v", err)
+		}
+		err = s.Restore(ctx, id)
+		requireNotFound(t, "Restore purged row", err)
+		err = s.Purge(ctx, uuid.New())
+		requireNotFound(t, "Purge missing row", err)
+	})
+
+	t.Run("TrashPurgeDeletedBefore", func(t *testing.T) {
+		s := newStorage(t)
+		old, err := s.Create(ctx, e.New(1))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		live, err := s.Create(ctx, e.New(2))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if err := s.Delete(ctx, old); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		n, err := s.PurgeDeletedBefore(ctx, time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("PurgeDeletedBefore: %v", err)
+		}
+		if n != 0 {
+			t.Errorf("PurgeDeletedBefore an hour ago purged %d rows, want 0", n)
+		}
+
+		n, err = s.PurgeDeletedBefore(ctx, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("PurgeDeletedBefore: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("PurgeDeletedBefore purged %d rows, want 1", n)
+		}
+		if _, err := s.Read(ctx, live); err != nil {
+			t.Errorf("PurgeDeletedBefore touched a live row: %v", err)
+		}
+	})
+}
+
+// RunExhibitionTrash checks that RestoreWithExhibits restores exactly the
+// exhibits deleted together with the exhibition.
+func RunExhibitionTrash(t *testing.T, newStorages func(t *testing.T) (storage.Exhibitions, storage.ExhibitionTrash, storage.Storage[model.Exhibit])) {
+	t.Helper()
+	ctx := context.Background()
+
+	t.Run("RestoreWithExhibits", func(t *testing.T) {
+		exhibitions, trash, exhibits := newStorages(t)
+		exID, err := exhibitions.Create(ctx, Exhibition.New(1))
+		if err != nil {
+			t.Fatalf("Create exhibition: %v", err)
+		}
+		ids := make([]uuid.UUID, 2)
+		for i := range ids {
+			e := Exhibit.New(i)
+			e.ExhibitionID = exID
+			if ids[i], err = exhibits.Create(ctx, e); err != nil {
+				t.Fatalf("Create exhibit: %v", err)
+			}
+		}
+		// The first exhibit was deleted on its own before the exhibition.
+		if err := exhibits.Delete(ctx, ids[0]); err != nil {
+			t.Fatalf("Delete exhibit: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+		if err := exhibitions.Delete(ctx, exID); err != nil {
+			t.Fatalf("Delete exhibition: %v", err)
+		}
+
+		n, err := trash.RestoreWithExhibits(ctx, exID)
+		if err != nil {
+			t.Fatalf("RestoreWithExhibits: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("RestoreWithExhibits restored %d exhibits, want 1", n)
+		}
+		if _, err := exhibits.Read(ctx, ids[1]); err != nil {
+			t.Errorf("exhibit deleted with the exhibition was not restored: %v", err)
+		}
+		_, err = exhibits.Read(ctx, ids[0])
+		requireNotFound(t, "Read exhibit deleted on its own", err)
+
+		_, err = trash.RestoreWithExhibits(ctx, exID)
+		requireNotFound(t, "RestoreWithExhibits of live exhibition", err)
+	})
+}
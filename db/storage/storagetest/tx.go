@@ -0,0 +1,87 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// RunTx checks that storage calls made inside storage.Transactor.InTx are
+// committed together when the unit of work succeeds, and rolled back together
+// when it fails, including nested units of work.
+func RunTx(t *testing.T, newStorages func(t *testing.T) (storage.Transactor, storage.Exhibitions, storage.Storage[model.Exhibit])) {
+	t.Helper()
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+
+	t.Run("TxCommit", func(t *testing.T) {
+		tx, exhibitions, exhibits := newStorages(t)
+		var exID, eID uuid.UUID
+		err := tx.InTx(ctx, func(ctx context.Context) error {
+			var err error
+			if exID, err = exhibitions.Create(ctx, Exhibition.New(1)); err != nil {
+				return err
+			}
+			e := Exhibit.New(1)
+			e.ExhibitionID = exID
+			eID, err = exhibits.Create(ctx, e)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("InTx: %v", err)
+		}
+		if _, err := exhibitions.Read(ctx, exID); err != nil {
+			t.Errorf("Read committed exhibition: %v", err)
+		}
+		if _, err := exhibits.Read(ctx, eID); err != nil {
+			t.Errorf("Read committed exhibit: %v", err)
+		}
+	})
+
+	t.Run("TxRollback", func(t *testing.T) {
+		tx, exhibitions, exhibits := newStorages(t)
+		exID, err := exhibitions.Create(ctx, Exhibition.New(1))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		e := Exhibit.New(1)
+		e.ExhibitionID = exID
+		eID, err := exhibits.Create(ctx, e)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		var created uuid.UUID
+		err = tx.InTx(ctx, func(ctx context.Context) error {
+			if err := exhibitions.Delete(ctx, exID); err != nil {
+				return err
+			}
+			// A nested unit of work joins the outer one.
+			err := tx.InTx(ctx, func(ctx context.Context) error {
+				var err error
+				created, err = exhibitions.Create(ctx, Exhibition.New(2))
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("InTx returned %v, want %v", err, errAbort)
+		}
+
+		if _, err := exhibitions.Read(ctx, exID); err != nil {
+			t.Errorf("exhibition deleted in a rolled back unit of work: %v", err)
+		}
+		if _, err := exhibits.Read(ctx, eID); err != nil {
+			t.Errorf("exhibit deleted in a rolled back unit of work: %v", err)
+		}
+		_, err = exhibitions.Read(ctx, created)
+		requireNotFound(t, "Read exhibition created in a rolled back unit of work", err)
+	})
+}
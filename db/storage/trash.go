@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Trash gives access to soft-deleted rows of a Storage.
+// All methods only see rows that are in the trash; live rows report ErrNotFound.
+type Trash[T any] interface {
+	// ListDeleted returns the deleted rows, most recently deleted first.
+	ListDeleted(ctx context.Context) ([]T, error)
+	// Restore moves a row back out of the trash.
+	Restore(ctx context.Context, id uuid.UUID) error
+	// Purge permanently removes a row from the trash.
+	Purge(ctx context.Context, id uuid.UUID) error
+	// PurgeDeletedBefore permanently removes rows deleted before t.
+	PurgeDeletedBefore(ctx context.Context, t time.Time) (int, error)
+}
+
+// ExhibitionTrash is the trash of exhibitions. Deleting an exhibition also
+// deletes its exhibits with the same deleted_at, which is how they are
+// recognised when restoring.
+type ExhibitionTrash interface {
+	Trash[model.Exhibition]
+	// RestoreWithExhibits restores the exhibition and the exhibits deleted
+	// together with it, returning the number of restored exhibits.
+	RestoreWithExhibits(ctx context.Context, id uuid.UUID) (int, error)
+}
+
+func listDeleted[T any](ctx context.Context, db bun.IDB) ([]T, error) {
+	rows := []T{}
+	err := db.NewSelect().
+		Model(&rows).
+		WhereDeleted().
+		OrderExpr("deleted_at DESC").
+		Scan(ctx)
+	return rows, err
+}
+
+func restore[T any](ctx context.Context, db bun.IDB, id uuid.UUID) error {
+	res, err := db.NewUpdate().
+		Model((*T)(nil)).
+		WhereDeleted().
+		Set("deleted_at = NULL").
+		Where("id = ?", id).
+		Exec(ctx)
+	return affected(res, err)
+}
+
+func purge[T any](ctx context.Context, db bun.IDB, id uuid.UUID) error {
+	res, err := db.NewDelete().
+		Model((*T)(nil)).
+		WhereDeleted().
+		Where("id = ?", id).
+		ForceDelete().
+		Exec(ctx)
+	return affected(res, err)
+}
+
+func purgeDeletedBefore[T any](ctx context.Context, db bun.IDB, t time.Time) (int, error) {
+	res, err := db.NewDelete().
+		Model((*T)(nil)).
+		WhereDeleted().
+		Where("deleted_at < ?", t).
+		ForceDelete().
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
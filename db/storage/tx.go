@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// Transactor runs a unit of work: every storage call made with the context
+// passed to f joins the same transaction, so the work either fully succeeds
+// or is fully rolled back when f returns an error.
+// Nested calls join the outer transaction.
+type Transactor interface {
+	InTx(ctx context.Context, f func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+// TxManager is the Transactor of the bun-backed storages.
+type TxManager struct {
+	db *bun.DB
+}
+
+var _ Transactor = (*TxManager)(nil)
+
+func NewTxManager(db *bun.DB) *TxManager {
+	return &TxManager{
+		db: db,
+	}
+}
+
+func (m *TxManager) InTx(ctx context.Context, f func(ctx context.Context) error) error {
+	return inTx(ctx, m.db, f)
+}
+
+func inTx(ctx context.Context, db *bun.DB, f func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(bun.Tx); ok {
+		return f(ctx)
+	}
+	return db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		return f(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn returns the transaction carried by ctx, or db outside of a unit of work.
+func conn(ctx context.Context, db *bun.DB) bun.IDB {
+	if tx, ok := ctx.Value(txKey{}).(bun.Tx); ok {
+		return tx
+	}
+	return db
+}
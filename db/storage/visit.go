@@ -13,6 +13,8 @@ type VisitStorage struct {
 	db *bun.DB
 }
 
+var _ Visits = (*VisitStorage)(nil)
+
 func NewVisitStorage(db *bun.DB) *VisitStorage {
 	return &VisitStorage{db: db}
 }
@@ -27,7 +29,7 @@ func (s *VisitStorage) Record(ctx context.Context, v model.Visitor) error {
 	}
 	v.VisitCount = 1
 
-	_, err := s.db.NewInsert().
+	_, err := conn(ctx, s.db).NewInsert().
 		Model(&v).
 		On("CONFLICT (ip) DO UPDATE").
 		Set("visit_count = vis.visit_count + 1").
@@ -53,27 +55,27 @@ func (s *VisitStorage) Stats(ctx context.Context) (model.VisitStats, error) {
 
 	// ── Active visitors by last_visit_at ──
 
-	total, err := s.db.NewSelect().Model((*model.Visitor)(nil)).Count(ctx)
+	total, err := conn(ctx, s.db).NewSelect().Model((*model.Visitor)(nil)).Count(ctx)
 	if err != nil {
 		return stats, err
 	}
 	stats.TotalVisits = total
 
-	todayCount, err := s.db.NewSelect().Model((*model.Visitor)(nil)).
+	todayCount, err := conn(ctx, s.db).NewSelect().Model((*model.Visitor)(nil)).
 		Where("last_visit_at >= ?", today).Count(ctx)
 	if err != nil {
 		return stats, err
 	}
 	stats.TodayVisits = todayCount
 
-	weekCount, err := s.db.NewSelect().Model((*model.Visitor)(nil)).
+	weekCount, err := conn(ctx, s.db).NewSelect().Model((*model.Visitor)(nil)).
 		Where("last_visit_at >= ?", weekAgo).Count(ctx)
 	if err != nil {
 		return stats, err
 	}
 	stats.WeekVisits = weekCount
 
-	monthCount, err := s.db.NewSelect().Model((*model.Visitor)(nil)).
+	monthCount, err := conn(ctx, s.db).NewSelect().Model((*model.Visitor)(nil)).
 		Where("last_visit_at >= ?", monthAgo).Count(ctx)
 	if err != nil {
 		return stats, err
@@ -82,21 +84,21 @@ func (s *VisitStorage) Stats(ctx context.Context) (model.VisitStats, error) {
 
 	// ── New visitors by first_visit_at ──
 
-	newToday, err := s.db.NewSelect().Model((*model.Visitor)(nil)).
+	newToday, err := conn(ctx, s.db).NewSelect().Model((*model.Visitor)(nil)).
 		Where("first_visit_at >= ?", today).Count(ctx)
 	if err != nil {
 		return stats, err
 	}
 	stats.NewToday = newToday
 
-	newWeek, err := s.db.NewSelect().Model((*model.Visitor)(nil)).
+	newWeek, err := conn(ctx, s.db).NewSelect().Model((*model.Visitor)(nil)).
 		Where("first_visit_at >= ?", weekAgo).Count(ctx)
 	if err != nil {
 		return stats, err
 	}
 	stats.NewWeek = newWeek
 
-	newMonth, err := s.db.NewSelect().Model((*model.Visitor)(nil)).
+	newMonth, err := conn(ctx, s.db).NewSelect().Model((*model.Visitor)(nil)).
 		Where("first_visit_at >= ?", monthAgo).Count(ctx)
 	if err != nil {
 		return stats, err
@@ -105,7 +107,7 @@ func (s *VisitStorage) Stats(ctx context.Context) (model.VisitStats, error) {
 
 	// ── Engagement ──
 
-	returningCount, err := s.db.NewSelect().Model((*model.Visitor)(nil)).
+	returningCount, err := conn(ctx, s.db).NewSelect().Model((*model.Visitor)(nil)).
 		Where("visit_count > 1").Count(ctx)
 	if err != nil {
 		return stats, err
@@ -113,7 +115,7 @@ func (s *VisitStorage) Stats(ctx context.Context) (model.VisitStats, error) {
 	stats.ReturningVisitors = returningCount
 
 	var totalPageViews int
-	err = s.db.NewSelect().Model((*model.Visitor)(nil)).
+	err = conn(ctx, s.db).NewSelect().Model((*model.Visitor)(nil)).
 		ColumnExpr("COALESCE(SUM(visit_count), 0)").
 		Scan(ctx, &totalPageViews)
 	if err != nil {
@@ -132,7 +134,7 @@ func (s *VisitStorage) Stats(ctx context.Context) (model.VisitStats, error) {
 		Count int       `bun:"count"`
 	}
 	var rows []dayRow
-	err = s.db.NewSelect().
+	err = conn(ctx, s.db).NewSelect().
 		TableExpr("generate_series(?::date, ?::date, '1 day'::interval) AS day", weekAgo, today).
 		ColumnExpr("day::date AS day").
 		ColumnExpr("(SELECT COUNT(*) FROM visitors WHERE last_visit_at >= day AND last_visit_at < day + '1 day'::interval) AS count").
@@ -150,15 +152,15 @@ func (s *VisitStorage) Stats(ctx context.Context) (model.VisitStats, error) {
 
 	// ── Entity counts ──
 
-	exCount, _ := s.db.NewSelect().Model((*model.Exhibition)(nil)).
+	exCount, _ := conn(ctx, s.db).NewSelect().Model((*model.Exhibition)(nil)).
 		Where("deleted_at IS NULL").Count(ctx)
 	stats.ExhibitionCount = exCount
 
-	eCount, _ := s.db.NewSelect().Model((*model.Exhibit)(nil)).
+	eCount, _ := conn(ctx, s.db).NewSelect().Model((*model.Exhibit)(nil)).
 		Where("deleted_at IS NULL").Count(ctx)
 	stats.ExhibitCount = eCount
 
-	nCount, _ := s.db.NewSelect().Model((*model.News)(nil)).
+	nCount, _ := conn(ctx, s.db).NewSelect().Model((*model.News)(nil)).
 		Where("deleted_at IS NULL").Count(ctx)
 	stats.NewsCount = nCount
 
@@ -7,13 +7,12 @@ const MUSEUM_API = '/museum';
 
 // ==================== АВТОРИЗАЦИЯ ====================
 
-function getAuthHeader() {
-    const creds = sessionStorage.getItem('admin_auth');
-    return creds ? 'Basic ' + creds : '';
-}
+// Токен сессии хранится в HttpOnly-cookie и скриптам недоступен. CSRF-токен сессии
+// передаётся в заголовке X-CSRF-Token всех запросов, кроме GET.
+let csrfToken = '';
 
-function isLoggedIn() {
-    return !!sessionStorage.getItem('admin_auth');
+function authHeaders(method) {
+    return method === 'GET' || !csrfToken ? {} : { 'X-CSRF-Token': csrfToken };
 }
 
 function showAdmin() {
@@ -26,7 +25,7 @@ function showLogin() {
     document.getElementById('login-screen').style.display = '';
     document.getElementById('admin-main').classList.add('admin-hidden');
     document.getElementById('admin-layout').classList.add('admin-hidden');
-    sessionStorage.removeItem('admin_auth');
+    csrfToken = '';
 }
 
 // Login form
@@ -34,23 +33,37 @@ document.getElementById('login-form').addEventListener('submit', async (e) => {
     e.preventDefault();
     const login = document.getElementById('login-username').value.trim();
     const password = document.getElementById('login-password').value;
+    const code = document.getElementById('login-code').value.trim();
     const errorEl = document.getElementById('login-error');
 
     errorEl.style.display = 'none';
 
-    const creds = btoa(login + ':' + password);
-
     try {
-        const resp = await fetch(`${ADMIN_API}/ping?message=ping`, {
-            headers: { 'Authorization': 'Basic ' + creds }
+        const resp = await fetch(`${ADMIN_API}/login`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(code ? { login, password, code } : { login, password })
         });
 
         if (resp.ok) {
-            sessionStorage.setItem('admin_auth', creds);
+            csrfToken = (await resp.json()).csrf_token;
+            document.getElementById('login-code').value = '';
+            document.getElementById('login-code-group').style.display = 'none';
             showAdmin();
-            initAdminData();
+            startAdmin();
+        } else if (resp.status === 401) {
+            // Пользователям с двухфакторной аутентификацией нужен ещё код
+            const problem = await resp.json().catch(() => ({}));
+            if (problem.code === 'totp_required' || problem.code === 'invalid_totp') {
+                document.getElementById('login-code-group').style.display = '';
+                document.getElementById('login-code').focus();
+                errorEl.textContent = problem.detail;
+            } else {
+                errorEl.textContent = 'Неверный логин или пароль';
+            }
+            errorEl.style.display = 'block';
         } else {
-            errorEl.textContent = 'Неверный логин или пароль';
+            errorEl.textContent = await problemMessage(resp, 'Не удалось войти');
             errorEl.style.display = 'block';
         }
     } catch (err) {
@@ -60,23 +73,38 @@ document.getElementById('login-form').addEventListener('submit', async (e) => {
 });
 
 // Logout
-document.getElementById('btn-logout').addEventListener('click', () => {
+document.getElementById('btn-logout').addEventListener('click', async () => {
+    try {
+        await fetch(`${ADMIN_API}/logout`, { method: 'POST', headers: authHeaders('POST') });
+    } catch {
+        // Сессия закончится сама
+    }
     showLogin();
 });
 
-// Check if already authenticated
-if (isLoggedIn()) {
-    // Verify session is still valid
-    fetch(`${ADMIN_API}/ping?message=ping`, {
-        headers: { 'Authorization': getAuthHeader() }
-    }).then(resp => {
-        if (resp.ok) {
-            showAdmin();
-            initAdminData();
-        } else {
-            showLogin();
+// Restore the session after a page reload
+fetch(`${ADMIN_API}/session`).then(async resp => {
+    if (resp.ok) {
+        csrfToken = (await resp.json()).csrf_token;
+        showAdmin();
+        startAdmin();
+    }
+}).catch(() => {});
+
+// startAdmin loads the data, unless the role of the user requires two-factor
+// authentication they have not enabled yet: the server refuses everything else
+// until they do.
+async function startAdmin() {
+    try {
+        const status = await apiRequest(`${ADMIN_API}/me/totp`);
+        if (status.required && !status.enabled) {
+            showTwoFactorSetup();
+            return;
         }
-    }).catch(() => showLogin());
+    } catch {
+        // Без учётных записей двухфакторной аутентификации нет
+    }
+    initAdminData();
 }
 
 function initAdminData() {
@@ -100,12 +128,21 @@ document.querySelectorAll('.sidebar-item').forEach(item => {
 
 // ==================== Общие утилиты ====================
 
-async function apiRequest(url, method = 'GET', body = null) {
+// etagOf строит If-Match для объекта из кэша: ETag на сервере — это его updated_at в кавычках.
+function etagOf(item) {
+    return item && item.updated_at ? `"${item.updated_at}"` : '*';
+}
+
+async function apiRequest(url, method = 'GET', body = null, ifMatch = null) {
     const opts = { method, headers: {} };
 
-    // Add auth header for admin API calls
+    if (ifMatch) {
+        opts.headers['If-Match'] = ifMatch;
+    }
+
+    // Add the CSRF token for admin API calls
     if (url.startsWith(ADMIN_API)) {
-        opts.headers['Authorization'] = getAuthHeader();
+        Object.assign(opts.headers, authHeaders(method));
     }
 
     if (body) {
@@ -119,17 +156,30 @@ async function apiRequest(url, method = 'GET', body = null) {
         throw new Error('Сессия истекла, войдите снова');
     }
 
-    if (method === 'DELETE') {
-        if (!resp.ok) throw new Error('Ошибка удаления');
-        return null;
+    if (resp.status === 412) {
+        throw new Error('Запись изменил другой пользователь. Обновите страницу и повторите правку.');
     }
+
     if (!resp.ok) {
-        const text = await resp.text();
-        throw new Error(text || 'Ошибка запроса');
+        throw new Error(await problemMessage(resp, method === 'DELETE' ? 'Ошибка удаления' : 'Ошибка запроса'));
+    }
+    if (method === 'DELETE') {
+        return null;
     }
     return resp.json();
 }
 
+// problemMessage extracts the human-readable detail of an RFC 7807 error response.
+async function problemMessage(resp, fallback) {
+    const text = await resp.text();
+    try {
+        const problem = JSON.parse(text);
+        return problem.detail || problem.title || fallback;
+    } catch {
+        return text || fallback;
+    }
+}
+
 function formatDate(dateStr) {
     if (!dateStr) return '';
     const d = new Date(dateStr);
@@ -151,8 +201,8 @@ function buildAdminThumbMedia(url, altText) {
 
     const external = parseExternalEmbed(safe);
     if (external) {
-        if (external.provider === 'imgur') {
-            return `<div class="item-thumb item-thumb-link">Imgur</div>`;
+        if (external.source) {
+            return `<div class="item-thumb item-thumb-link">${escapeHtml(external.label)}</div>`;
         }
 
         const src = buildExternalEmbedSrc(external.base, external.provider, false);
@@ -172,7 +222,7 @@ function addImageInput(containerId, value = '') {
     row.className = 'multi-image-row';
     row.innerHTML = `
         <img class="image-preview-thumb" src="" alt="">
-        <input type="url" class="image-url-input" value="${safeValue}" placeholder="https://...">
+        <input type="text" inputmode="url" class="image-url-input" value="${safeValue}" placeholder="https://... или загрузите файл">
         <button type="button" class="btn btn-small btn-delete" onclick="removeImageRow(this)" title="Удалить">✕</button>
     `;
     const input = row.querySelector('.image-url-input');
@@ -218,21 +268,120 @@ function collectImageUrls(containerId) {
     const urls = [];
     inputs.forEach(input => {
         const val = input.value.trim();
+        // Uploaded files keep their server-relative URL, so they survive a change of domain.
+        if (val.startsWith(MEDIA_PREFIX)) {
+            urls.push(val);
+            return;
+        }
         const safe = sanitizeUrl(val);
         if (safe) urls.push(safe);
     });
     return urls;
 }
 
+// ==================== ЗАГРУЗКА ФАЙЛОВ ====================
+
+const MEDIA_PREFIX = '/media/';
+
+function uploadButton(containerId) {
+    return `<label class="btn btn-small btn-secondary btn-upload">📁 Загрузить файл
+        <input type="file" accept="image/jpeg,image/png,image/gif,image/webp,video/mp4,video/webm" multiple hidden onchange="uploadMediaFiles(this, '${containerId}')">
+    </label>`;
+}
+
+async function uploadMediaFiles(input, containerId) {
+    const files = Array.from(input.files || []);
+    input.value = '';
+    for (const file of files) {
+        try {
+            const asset = await uploadMedia(file);
+            putImageUrl(containerId, asset.url);
+        } catch (e) {
+            alert(`Ошибка загрузки «${file.name}»: ${e.message}`);
+        }
+    }
+}
+
+async function uploadMedia(file) {
+    const form = new FormData();
+    form.append('file', file);
+    const resp = await fetch(`${ADMIN_API}/media`, {
+        method: 'POST',
+        headers: authHeaders('POST'),
+        body: form
+    });
+    if (resp.status === 401) {
+        showLogin();
+        throw new Error('Сессия истекла, войдите снова');
+    }
+    if (!resp.ok) {
+        throw new Error(await problemMessage(resp, 'Ошибка загрузки'));
+    }
+    return resp.json();
+}
+
+// putImageUrl fills the first empty media input of the list or adds a new one.
+function putImageUrl(containerId, url) {
+    const container = document.getElementById(containerId);
+    if (!container) return;
+    const empty = Array.from(container.querySelectorAll('.image-url-input')).find(i => !i.value.trim());
+    if (empty) {
+        empty.value = url;
+        updateImagePreview(empty, empty.parentElement.querySelector('.image-preview-thumb'));
+        return;
+    }
+    addImageInput(containerId, url);
+}
+
+// ==================== СТРАНИЦЫ СПИСКОВ ====================
+
+const ADMIN_PAGE_SIZE = 20;
+
+// loadListPage загружает страницу списка со смещением offset. Если после
+// удаления страница опустела, загружается последняя непустая.
+async function loadListPage(url, offset) {
+    const page = await fetchPage(url, offset, ADMIN_PAGE_SIZE);
+    if (page.items.length === 0 && offset > 0 && page.total > 0) {
+        const last = Math.floor((page.total - 1) / ADMIN_PAGE_SIZE) * ADMIN_PAGE_SIZE;
+        return { ...(await fetchPage(url, last, ADMIN_PAGE_SIZE)), offset: last };
+    }
+    return { ...page, offset };
+}
+
+// renderPager показывает под списком переключатель страниц; go получает
+// смещение выбранной страницы.
+function renderPager(id, offset, total, go) {
+    const pager = document.getElementById(id);
+    if (!pager) return;
+    if (total <= ADMIN_PAGE_SIZE) {
+        pager.innerHTML = '';
+        return;
+    }
+    const page = Math.floor(offset / ADMIN_PAGE_SIZE) + 1;
+    const pages = Math.ceil(total / ADMIN_PAGE_SIZE);
+    pager.innerHTML = `
+        <button type="button" class="btn btn-small btn-secondary" ${page <= 1 ? 'disabled' : ''}>Назад</button>
+        <span class="pager-info">Страница ${page} из ${pages}</span>
+        <button type="button" class="btn btn-small btn-secondary" ${page >= pages ? 'disabled' : ''}>Вперёд</button>
+    `;
+    const [prev, next] = pager.querySelectorAll('button');
+    prev.addEventListener('click', () => go(offset - ADMIN_PAGE_SIZE));
+    next.addEventListener('click', () => go(offset + ADMIN_PAGE_SIZE));
+}
+
 // ==================== ЭКСПОЗИЦИИ ====================
 
 let exhibitionsCache = [];
+let exhibitionsOffset = 0;
 
-async function loadExhibitions() {
+async function loadExhibitions(offset = exhibitionsOffset) {
     const container = document.getElementById('exhibitions-list');
     try {
-        const data = await apiRequest(`${MUSEUM_API}/exhibitions`);
-        exhibitionsCache = Array.isArray(data) ? data : (data && data.exhibitions ? data.exhibitions : []);
+        // Экспонаты нужны только для их количества.
+        const page = await loadListPage(`${MUSEUM_API}/exhibitions?exhibits=true`, offset);
+        exhibitionsCache = page.items;
+        exhibitionsOffset = page.offset;
+        renderPager('exhibitions-pager', page.offset, page.total, loadExhibitions);
 
         if (exhibitionsCache.length === 0) {
             container.innerHTML = '<div class="empty-state">Экспозиций пока нет</div>';
@@ -289,7 +438,8 @@ async function saveExhibition(event, id) {
     };
     try {
         if (id) {
-            await apiRequest(`${ADMIN_API}/exhibitions/${id}`, 'PUT', body);
+            const ex = exhibitionsCache.find(e => e.id === id);
+            await apiRequest(`${ADMIN_API}/exhibitions/${id}`, 'PUT', body, etagOf(ex));
         } else {
             await apiRequest(`${ADMIN_API}/exhibitions`, 'POST', body);
         }
@@ -315,16 +465,20 @@ async function deleteExhibition(id) {
 // ==================== ЭКСПОНАТЫ ====================
 
 let allExhibits = [];
+// Экспозиции текущей страницы раздела; новый экспонат добавляется в одну из них.
+let exhibitGroups = [];
+let exhibitsOffset = 0;
 let previewUpdateInFlight = false;
 
-async function loadAllExhibits() {
+async function loadAllExhibits(offset = exhibitsOffset) {
     const container = document.getElementById('exhibits-list');
     try {
-        // Загружаем все экспозиции с экспонатами
-        const data = await apiRequest(`${MUSEUM_API}/exhibitions`);
-        const exhibitions = (Array.isArray(data) ? data : (data && data.exhibitions ? data.exhibitions : []))
-            .slice()
-            .sort((a, b) => String(a.title || '').localeCompare(String(b.title || ''), 'ru'));
+        // Экспонаты сгруппированы по экспозициям, поэтому страница — это страница экспозиций
+        const page = await loadListPage(`${MUSEUM_API}/exhibitions?exhibits=true&sort=title&direction=asc`, offset);
+        const exhibitions = page.items;
+        exhibitGroups = exhibitions;
+        exhibitsOffset = page.offset;
+        renderPager('exhibits-pager', page.offset, page.total, loadAllExhibits);
 
         allExhibits = [];
         exhibitions.forEach(ex => {
@@ -393,7 +547,7 @@ function showExhibitForm(id = null) {
     const isEdit = !!item;
     const existingUrls = isEdit ? (item.image_urls || []) : [];
 
-    const exhibitionOptions = exhibitionsCache.map(ex =>
+    const exhibitionOptions = exhibitGroups.map(ex =>
         `<option value="${ex.id}" ${item && item.exhibition_id === ex.id ? 'selected' : ''}>${escapeHtml(ex.title || '')}</option>`
     ).join('');
 
@@ -421,6 +575,7 @@ function showExhibitForm(id = null) {
                 <label>Медиа (изображения или видео)</label>
                 <div id="exhibit-images-list" class="multi-image-list"></div>
                 <button type="button" class="btn btn-small btn-secondary" onclick="addImageInput('exhibit-images-list')">+ Добавить медиа</button>
+                ${uploadButton('exhibit-images-list')}
             </div>
             <div class="form-actions">
                 <button type="button" class="btn btn-secondary" onclick="closeModal()">Отмена</button>
@@ -445,7 +600,8 @@ async function saveExhibit(event, id) {
                 description: document.getElementById('exhibit-description').value.trim(),
                 image_urls: collectImageUrls('exhibit-images-list')
             };
-            await apiRequest(`${ADMIN_API}/exhibits/${id}`, 'PUT', body);
+            const item = allExhibits.find(e => e.id === id);
+            await apiRequest(`${ADMIN_API}/exhibits/${id}`, 'PUT', body, etagOf(item));
         } else {
             const body = {
                 exhibition_id: document.getElementById('exhibit-exhibition').value,
@@ -477,12 +633,15 @@ async function deleteExhibit(id) {
 // ==================== НОВОСТИ ====================
 
 let newsCache = [];
+let newsOffset = 0;
 
-async function loadNews() {
+async function loadNews(offset = newsOffset) {
     const container = document.getElementById('news-list');
     try {
-        const data = await apiRequest(`${MUSEUM_API}/news`);
-        newsCache = Array.isArray(data) ? data : (data && data.news ? data.news : []);
+        const page = await loadListPage(`${MUSEUM_API}/news`, offset);
+        newsCache = page.items;
+        newsOffset = page.offset;
+        renderPager('news-pager', page.offset, page.total, loadNews);
 
         if (newsCache.length === 0) {
             container.innerHTML = '<div class="empty-state">Новостей пока нет</div>';
@@ -533,6 +692,7 @@ function showNewsForm(id) {
                 <label>Медиа (изображения или видео)</label>
                 <div id="news-images-list" class="multi-image-list"></div>
                 <button type="button" class="btn btn-small btn-secondary" onclick="addImageInput('news-images-list')">+ Добавить медиа</button>
+                ${uploadButton('news-images-list')}
             </div>
             <div class="form-actions">
                 <button type="button" class="btn btn-secondary" onclick="closeModal()">Отмена</button>
@@ -558,7 +718,8 @@ async function saveNews(event, id) {
     };
     try {
         if (id) {
-            await apiRequest(`${ADMIN_API}/news/${id}`, 'PUT', body);
+            const n = newsCache.find(x => x.id === id);
+            await apiRequest(`${ADMIN_API}/news/${id}`, 'PUT', body, etagOf(n));
         } else {
             await apiRequest(`${ADMIN_API}/news`, 'POST', body);
         }
@@ -667,6 +828,7 @@ async function setPreviewExhibit(exhibitionId, exhibitId, inputEl = null) {
     try {
         await apiRequest(`${ADMIN_API}/exhibitions/${exhibitionId}/preview`, 'PUT', { exhibit_id: exhibitId });
         await loadAllExhibits();
+        await loadExhibitions(); // превью меняет версию экспозиции
     } catch (e) {
         alert('Ошибка установки превью: ' + e.message);
     } finally {
@@ -687,6 +849,62 @@ function toggleGroup(header) {
 
 // ==================== МОДАЛЬНОЕ ОКНО ====================
 
+// ==================== Двухфакторная аутентификация ====================
+
+async function showTwoFactorSetup() {
+    let setup;
+    try {
+        setup = await apiRequest(`${ADMIN_API}/me/totp`, 'POST');
+    } catch (e) {
+        alert(e.message);
+        return;
+    }
+    document.getElementById('modal-title').textContent = 'Двухфакторная аутентификация';
+    document.getElementById('modal-body').innerHTML = `
+        <form id="totp-form" onsubmit="confirmTwoFactor(event)">
+            <p>Ваша роль требует входа с кодом из приложения-аутентификатора (Google Authenticator, Яндекс Ключ и т.п.).
+               Добавьте в него учётную запись по ссылке или введите секрет вручную.</p>
+            <div class="form-group">
+                <label>Ссылка для приложения</label>
+                <a href="${escapeHtml(setup.uri)}">${escapeHtml(setup.uri)}</a>
+            </div>
+            <div class="form-group">
+                <label>Секрет</label>
+                <code>${escapeHtml(setup.secret)}</code>
+            </div>
+            <div class="form-group">
+                <label>Код из приложения *</label>
+                <input type="text" id="totp-code" autocomplete="one-time-code" inputmode="numeric" required>
+            </div>
+            <div class="form-actions">
+                <button type="submit" class="btn btn-primary">Включить</button>
+            </div>
+        </form>
+    `;
+    openModal();
+}
+
+async function confirmTwoFactor(e) {
+    e.preventDefault();
+    const code = document.getElementById('totp-code').value.trim();
+    let result;
+    try {
+        result = await apiRequest(`${ADMIN_API}/me/totp/confirm`, 'POST', { code });
+    } catch (err) {
+        alert(err.message);
+        return;
+    }
+    document.getElementById('modal-body').innerHTML = `
+        <p>Двухфакторная аутентификация включена. Сохраните коды восстановления: каждый из них
+           заменяет код из приложения один раз, если телефон потерян. Больше они показаны не будут.</p>
+        <pre>${result.recovery_codes.map(escapeHtml).join('\n')}</pre>
+        <div class="form-actions">
+            <button type="button" class="btn btn-primary" onclick="closeModal()">Готово</button>
+        </div>
+    `;
+    initAdminData();
+}
+
 function openModal() {
     document.getElementById('modal-overlay').classList.add('active');
 }
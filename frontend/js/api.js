@@ -42,6 +42,17 @@ function isVideoUrl(value) {
     }
 }
 
+// Ссылки этих сервисов бэкенд превращает в файл или плеер (GET /media/resolve).
+const RESOLVED_MEDIA_HOSTS = [
+    { host: 'youtube.com', label: 'YouTube' },
+    { host: 'youtu.be', label: 'YouTube' },
+    { host: 'rutube.ru', label: 'Rutube' },
+    { host: 'drive.google.com', label: 'Google Drive' },
+    { host: 'disk.yandex.ru', label: 'Яндекс Диск' },
+    { host: 'disk.yandex.com', label: 'Яндекс Диск' },
+    { host: 'yadi.sk', label: 'Яндекс Диск' }
+];
+
 function parseExternalEmbed(value) {
     const safe = sanitizeUrl(value);
     if (!safe) return null;
@@ -61,6 +72,7 @@ function parseExternalEmbed(value) {
         if (albumMatch) {
             return {
                 provider: 'imgur',
+                label: 'Imgur',
                 source: safe
             };
         }
@@ -69,6 +81,7 @@ function parseExternalEmbed(value) {
         if (imageMatch) {
             return {
                 provider: 'imgur',
+                label: 'Imgur',
                 source: safe
             };
         }
@@ -97,6 +110,15 @@ function parseExternalEmbed(value) {
         }
     }
 
+    const resolvable = RESOLVED_MEDIA_HOSTS.find(h => host === h.host || host.endsWith('.' + h.host));
+    if (resolvable) {
+        return {
+            provider: 'resolve',
+            label: resolvable.label,
+            source: safe
+        };
+    }
+
     return null;
 }
 
@@ -123,7 +145,37 @@ function normalizeMediaUrls(items) {
         .filter(Boolean);
 }
 
-function buildCardMedia(url, altText, imageClass, videoClass, embedClass = imageClass) {
+// Ширина изображения на странице для выбора варианта из srcset.
+const CARD_IMAGE_SIZES = '(max-width: 600px) 100vw, 400px';
+const MODAL_IMAGE_SIZES = '(max-width: 900px) 100vw, 900px';
+
+/**
+ * Ищет описание изображения (поле images в ответах API) по URL из image_urls.
+ */
+function findImage(images, url) {
+    if (!Array.isArray(images)) return null;
+    return images.find(img => img && sanitizeUrl(img.url) === url) || null;
+}
+
+/**
+ * Возвращает атрибуты srcset и sizes для изображения с вариантами размеров.
+ * Если вариантов нет — пустая строка.
+ */
+function srcsetAttrs(image, sizes) {
+    if (!image || !image.srcset) return '';
+    const srcset = String(image.srcset).split(',')
+        .map(part => {
+            const [url, width] = part.trim().split(/\s+/);
+            const safe = sanitizeUrl(url);
+            return safe && /^\d+w$/.test(width || '') ? `${safe} ${width}` : '';
+        })
+        .filter(Boolean)
+        .join(', ');
+    if (!srcset) return '';
+    return ` srcset="${escapeHtml(srcset)}" sizes="${escapeHtml(sizes)}"`;
+}
+
+function buildCardMedia(url, altText, imageClass, videoClass, embedClass = imageClass, srcset = '') {
     const safeUrl = sanitizeUrl(url);
     if (!safeUrl) return '';
 
@@ -133,18 +185,18 @@ function buildCardMedia(url, altText, imageClass, videoClass, embedClass = image
 
     const external = parseExternalEmbed(safeUrl);
     if (external) {
-        if (external.provider === 'imgur') {
-            return `<div class="${embedClass} external-media-loading" data-imgur-source="${escapeHtml(external.source || safeUrl)}" data-imgur-image-class="${escapeHtml(imageClass)}" data-imgur-video-class="${escapeHtml(videoClass)}" data-imgur-embed-class="${escapeHtml(embedClass)}"><span class="external-media-loading__label">Загрузка медиа...</span></div>`;
+        if (external.source) {
+            return `<div class="${embedClass} external-media-loading" data-resolve-source="${escapeHtml(external.source || safeUrl)}" data-resolve-image-class="${escapeHtml(imageClass)}" data-resolve-video-class="${escapeHtml(videoClass)}" data-resolve-embed-class="${escapeHtml(embedClass)}"><span class="external-media-loading__label">Загрузка медиа...</span></div>`;
         }
 
         const initialSrc = buildExternalEmbedSrc(external.base, external.provider, false);
         return `<iframe class="${embedClass}" src="${escapeHtml(initialSrc)}" data-embed-provider="${external.provider}" data-embed-base="${escapeHtml(external.base)}" loading="lazy" referrerpolicy="strict-origin-when-cross-origin" allow="autoplay; fullscreen; picture-in-picture; encrypted-media" allowfullscreen title="${escapeHtml(altText || 'Медиа')}" tabindex="-1"></iframe>`;
     }
 
-    return `<img src="${safeUrl}" alt="${escapeHtml(altText)}" class="${imageClass}">`;
+    return `<img src="${safeUrl}"${srcset} alt="${escapeHtml(altText)}" class="${imageClass}">`;
 }
 
-async function resolveImgurMedia(source) {
+async function resolveExternalMedia(source) {
     const safe = sanitizeUrl(source);
     if (!safe) return null;
 
@@ -155,23 +207,24 @@ async function resolveImgurMedia(source) {
         if (!data || !data.url) return null;
         return {
             url: sanitizeUrl(data.url),
-            type: String(data.type || '').toLowerCase()
+            type: String(data.type || '').toLowerCase(),
+            title: String(data.title || '')
         };
     } catch (_) {
         return null;
     }
 }
 
-async function hydrateImgurMedia(scope = document) {
-    const placeholders = Array.from(scope.querySelectorAll('[data-imgur-source]'));
+async function hydrateExternalMedia(scope = document) {
+    const placeholders = Array.from(scope.querySelectorAll('[data-resolve-source]'));
     if (placeholders.length === 0) return;
 
     await Promise.all(placeholders.map(async (node) => {
-        if (node.dataset.imgurHydrated === '1') return;
-        node.dataset.imgurHydrated = '1';
+        if (node.dataset.resolveHydrated === '1') return;
+        node.dataset.resolveHydrated = '1';
 
-        const source = node.dataset.imgurSource || '';
-        const resolved = await resolveImgurMedia(source);
+        const source = node.dataset.resolveSource || '';
+        const resolved = await resolveExternalMedia(source);
         if (!resolved || !resolved.url) {
             node.classList.remove('external-media-loading');
             node.classList.add('external-media-error');
@@ -179,29 +232,47 @@ async function hydrateImgurMedia(scope = document) {
             return;
         }
 
-        const imageClass = node.dataset.imgurImageClass || '';
-        const videoClass = node.dataset.imgurVideoClass || '';
-        const embedClass = node.dataset.imgurEmbedClass || '';
+        const imageClass = node.dataset.resolveImageClass || '';
+        const videoClass = node.dataset.resolveVideoClass || '';
+        const embedClass = node.dataset.resolveEmbedClass || '';
         const mediaType = resolved.type || (isVideoUrl(resolved.url) ? 'video' : 'image');
 
-        if (mediaType === 'video') {
-            node.outerHTML = `<video class="${videoClass || embedClass}" src="${resolved.url}" muted loop playsinline preload="metadata"></video>`;
+        // Файлы грузятся через наш сервер: в школьных сетях сами сервисы бывают заблокированы.
+        const proxied = `${API_BASE_URL}/media/proxy?url=${encodeURIComponent(source)}`;
+
+        if (mediaType === 'embed') {
+            node.outerHTML = `<iframe class="${embedClass}" src="${escapeHtml(resolved.url)}" loading="lazy" referrerpolicy="strict-origin-when-cross-origin" allow="autoplay; fullscreen; picture-in-picture; encrypted-media" allowfullscreen title="${escapeHtml(resolved.title || 'Медиа')}" tabindex="-1"></iframe>`;
+        } else if (mediaType === 'video') {
+            node.outerHTML = `<video class="${videoClass || embedClass}" src="${escapeHtml(proxied)}" muted loop playsinline preload="metadata"></video>`;
         } else {
-            node.outerHTML = `<img src="${resolved.url}" alt="Media" class="${imageClass || embedClass}">`;
+            node.outerHTML = `<img src="${escapeHtml(proxied)}" alt="Media" class="${imageClass || embedClass}">`;
         }
     }));
 }
 
+// Размер страницы, которую сайт загружает за один раз; сервер отдаёт не больше 100 записей.
+const PAGE_SIZE = 12;
+
+// fetchPage загружает одну страницу списка. total — общее количество записей из X-Total-Count.
+async function fetchPage(url, offset = 0, limit = PAGE_SIZE) {
+    const sep = url.includes('?') ? '&' : '?';
+    const response = await fetch(`${url}${sep}limit=${limit}&offset=${offset}`);
+    if (!response.ok) throw new Error(`Ошибка загрузки (${response.status})`);
+    const items = await response.json();
+    return {
+        items: Array.isArray(items) ? items : [],
+        total: Number(response.headers.get('X-Total-Count')) || 0
+    };
+}
+
 const api = {
-    async getAllExhibitions() {
+    // Экспонаты нужны карточкам для количества и превью.
+    async getExhibitionsPage(offset = 0) {
         try {
-            const response = await fetch(`${API_BASE_URL}/exhibitions`);
-            if (!response.ok) throw new Error('Ошибка загрузки экспозиций');
-            const data = await response.json();
-            return Array.isArray(data) ? data : (data.exhibitions || []);
+            return await fetchPage(`${API_BASE_URL}/exhibitions?exhibits=true`, offset);
         } catch (error) {
             console.error('API Error (exhibitions):', error);
-            return [];
+            return { items: [], total: 0 };
         }
     },
 
@@ -217,15 +288,12 @@ const api = {
         }
     },
 
-    async getAllNews() {
+    async getNewsPage(offset = 0) {
         try {
-            const response = await fetch(`${API_BASE_URL}/news`);
-            if (!response.ok) throw new Error('Ошибка загрузки новостей');
-            const data = await response.json();
-            return data.news || data || [];
+            return await fetchPage(`${API_BASE_URL}/news`, offset);
         } catch (error) {
             console.error('API Error (news):', error);
-            return [];
+            return { items: [], total: 0 };
         }
     },
 
@@ -262,26 +330,56 @@ function truncateText(text, maxLength) {
 // Карусель изображений (общие функции)
 // ═══════════════════════════════════════════════
 
+/**
+ * Ищет объект медиатеки (поле media в ответах API) по URL из image_urls.
+ */
+function findMediaItem(items, url) {
+    if (!Array.isArray(items)) return null;
+    return items.find(item => item && sanitizeUrl(item.url) === url) || null;
+}
+
+/**
+ * Возвращает HTML подписи к медиа: подпись, автор и лицензия.
+ * Если ничего из этого нет — пустая строка.
+ */
+function buildMediaCaption(item) {
+    if (!item) return '';
+    const credit = [item.credit, item.license].filter(Boolean).map(escapeHtml).join(', ');
+    if (!item.caption && !credit) return '';
+    return `<div class="modal-carousel-caption">
+        ${item.caption ? `<span>${escapeHtml(item.caption)}</span>` : ''}
+        ${credit ? `<span class="modal-carousel-credit">${credit}</span>` : ''}
+    </div>`;
+}
+
 /**
  * Возвращает HTML-строку карусели для массива URL-ов изображений.
  * Если изображений нет — пустая строка. Если одно — просто <img>.
+ * images — описания изображений с вариантами размеров из ответа API,
+ * mediaItems — объекты медиатеки с подписями и альтернативным текстом.
  */
-function buildImageCarousel(imgs, altText) {
+function buildImageCarousel(imgs, altText, images = [], mediaItems = []) {
     const media = normalizeMediaUrls(imgs);
     if (media.length === 0) return '';
 
+    const slide = url => {
+        const item = findMediaItem(mediaItems, url);
+        return buildCardMedia(url, (item && item.alt) || altText || '', 'modal-carousel-media', 'modal-carousel-media', 'modal-carousel-embed', srcsetAttrs(findImage(images, url), MODAL_IMAGE_SIZES)) +
+            buildMediaCaption(item);
+    };
+
     if (media.length === 1) {
         return `<div class="modal-carousel">
             <div class="modal-carousel-track">
                 <div class="modal-carousel-slide">
-                    ${buildCardMedia(media[0], altText || '', 'modal-carousel-media', 'modal-carousel-media', 'modal-carousel-embed')}
+                    ${slide(media[0])}
                 </div>
             </div>
         </div>`;
     }
 
     const slides = media.map(url =>
-        `<div class="modal-carousel-slide">${buildCardMedia(url, altText || '', 'modal-carousel-media', 'modal-carousel-media', 'modal-carousel-embed')}</div>`
+        `<div class="modal-carousel-slide">${slide(url)}</div>`
     ).join('');
 
     const dots = media.map((_, i) =>
@@ -156,7 +156,7 @@ async function renderExhibits(exhibits) {
         const title = escapeHtml(exhibit.title || '');
         const desc = escapeHtml(truncateText(exhibit.description || '', 100));
         const mediaHtml = firstMedia
-            ? buildCardMedia(firstMedia, exhibit.title || '', 'exhibit-card-media', 'exhibit-card-media', 'exhibit-card-embed')
+            ? buildCardMedia(firstMedia, exhibit.title || '', 'exhibit-card-media', 'exhibit-card-media', 'exhibit-card-embed', srcsetAttrs(findImage(exhibit.images, firstMedia), CARD_IMAGE_SIZES))
             : '<span class="exhibit-card-placeholder">Нет медиа</span>';
 
         return `
@@ -172,7 +172,7 @@ async function renderExhibits(exhibits) {
         </div>
     `}).join('');
 
-    await hydrateImgurMedia(grid);
+    await hydrateExternalMedia(grid);
 
     // Scroll animations
     initExhibitAnimations();
@@ -225,12 +225,12 @@ async function openExhibitModal(exhibitId) {
     const description = escapeHtml(exhibit.description || 'Описание экспоната отсутствует');
 
     body.innerHTML = `
-        ${buildImageCarousel(exhibit.image_urls || [], exhibit.title || '')}
+        ${buildImageCarousel(exhibit.image_urls || [], exhibit.title || '', exhibit.images, exhibit.media)}
         <h2 class="modal-title">${title}</h2>
         <p class="modal-description">${description}</p>
     `;
 
-    await hydrateImgurMedia(body);
+    await hydrateExternalMedia(body);
 
     initModalCarousel(body);
 
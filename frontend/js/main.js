@@ -141,24 +141,14 @@ function initHoverMediaPlayback(scope = document) {
 }
 
 // ── Load news highlight (dark strip) ──
-async function loadNewsHighlight() {
-    const track = document.getElementById('news-hl-track');
-    if (!track) return;
-
-    const newsData = await api.getAllNews();
-    if (!newsData || newsData.length === 0) {
-        track.innerHTML = '<div class="empty-state" style="color:rgba(255,255,255,.5)">Новости пока отсутствуют</div>';
-        return;
-    }
-
-    track.innerHTML = newsData.map(n => {
-        const media = normalizeMediaUrls(n.image_urls || []);
-        const firstMedia = media.length > 0 ? media[0] : '';
-        const title = escapeHtml(n.title || '');
-        return `
+function newsHighlightCard(n) {
+    const media = normalizeMediaUrls(n.image_urls || []);
+    const firstMedia = media.length > 0 ? media[0] : '';
+    const title = escapeHtml(n.title || '');
+    return `
         <div class="news-hl-card" onclick="openNewsModal('${n.id}')">
             ${firstMedia
-                ? buildCardMedia(firstMedia, n.title || '', 'news-hl-image', 'news-hl-image', 'news-hl-embed')
+                ? buildCardMedia(firstMedia, n.title || '', 'news-hl-image', 'news-hl-image', 'news-hl-embed', srcsetAttrs(findImage(n.images, firstMedia), CARD_IMAGE_SIZES))
                 : `<div class="news-hl-image-placeholder"><span>Нет медиа</span></div>`
             }
             <div class="news-hl-body">
@@ -166,14 +156,48 @@ async function loadNewsHighlight() {
                 <h3 class="news-hl-title">${title}</h3>
             </div>
         </div>
-    `}).join('');
+    `;
+}
 
-    await hydrateImgurMedia(track);
+async function loadNewsHighlight() {
+    const track = document.getElementById('news-hl-track');
+    if (!track) return;
+
+    const first = await api.getNewsPage(0);
+    if (first.items.length === 0) {
+        track.innerHTML = '<div class="empty-state" style="color:rgba(255,255,255,.5)">Новости пока отсутствуют</div>';
+        return;
+    }
+
+    track.innerHTML = first.items.map(newsHighlightCard).join('');
+    await hydrateExternalMedia(track);
 
-    const ctrl = initCarousel('news-hl-carousel', 'news-hl-dots');
+    const ctrl = initCarousel('news-hl-carousel', 'news-hl-dots', {
+        loadMore: pageLoader(first, api.getNewsPage, async (items) => {
+            track.insertAdjacentHTML('beforeend', items.map(newsHighlightCard).join(''));
+            await hydrateExternalMedia(track);
+        })
+    });
     if (ctrl) ctrl.refresh();
 }
 
+// pageLoader возвращает функцию, которая загружает следующую страницу списка
+// после first и передаёт её записи в append. Она возвращает false, когда
+// загружать больше нечего.
+function pageLoader(first, getPage, append) {
+    let loaded = first.items.length;
+    let total = first.total;
+    return async () => {
+        if (loaded >= total) return false;
+        const page = await getPage(loaded);
+        if (page.items.length === 0) return false;
+        loaded += page.items.length;
+        total = page.total;
+        await append(page.items);
+        return true;
+    };
+}
+
 // ═══════════════════════════════════════════════
 // Carousel controller (generic)
 // ═══════════════════════════════════════════════
@@ -188,6 +212,8 @@ function initCarousel(carouselId, dotsId, options = {}) {
     const nextBtn = carousel.querySelector('.carousel-arrow--next');
 
     const fixedPerView = options.perView || null; // null = responsive
+    const loadMore = options.loadMore || null; // appends the next page, at the last slides
+    let loadingMore = false;
 
     let currentIndex = 0;
     let perView = fixedPerView || getPerView();
@@ -251,6 +277,21 @@ function initCarousel(carouselId, dotsId, options = {}) {
         currentIndex = Math.max(0, Math.min(index, maxIndex));
         slide();
         resetAuto();
+        maybeLoadMore();
+    }
+
+    async function maybeLoadMore() {
+        if (!loadMore || loadingMore || currentIndex < maxIndex) return;
+        loadingMore = true;
+        try {
+            if (await loadMore()) {
+                recalc();
+                updateArrows();
+                updateDots();
+            }
+        } finally {
+            loadingMore = false;
+        }
     }
 
     function next() { goTo(currentIndex + 1); }
@@ -328,60 +369,74 @@ function initCarousel(carouselId, dotsId, options = {}) {
             recalc();
             slide(false);
             startAuto();
+            maybeLoadMore();
         }
     };
 }
 
 // ── Load Exhibitions ──
+function exhibitionCard(ex) {
+    const exhibitCount = (ex.exhibits || []).length;
+    const title = escapeHtml(ex.title || '');
+    const desc = escapeHtml(truncateText(ex.description || '', 140));
+
+    // Find preview media from the selected preview exhibit
+    let previewMedia = '';
+    let previewSrcset = '';
+    if (ex.preview_exhibit_id && ex.exhibits) {
+        const previewExhibit = ex.exhibits.find(e => e.id === ex.preview_exhibit_id);
+        if (previewExhibit) {
+            const media = normalizeMediaUrls(previewExhibit.image_urls || []);
+            if (media.length > 0) {
+                previewMedia = media[0];
+                previewSrcset = srcsetAttrs(findImage(previewExhibit.images, previewMedia), CARD_IMAGE_SIZES);
+            }
+        }
+    }
+
+    const previewHtml = previewMedia
+        ? buildCardMedia(previewMedia, ex.title || '', 'exhibition-card-preview-img', 'exhibition-card-preview-video', 'exhibition-card-preview-embed', previewSrcset)
+        : '<span class="exhibition-card-placeholder">Музей</span>';
+
+    return `
+        <div class="exhibition-card" data-hover-play-video="1" onclick="openExhibition('${ex.id}')">
+            <div class="exhibition-card-image">
+                ${previewHtml}
+                ${exhibitCount > 0 ? `<span class="exhibition-card-count">${exhibitCount} экспонатов</span>` : ''}
+            </div>
+            <div class="exhibition-card-body">
+                <h3 class="exhibition-card-title">${title}</h3>
+                <p class="exhibition-card-desc">${desc}</p>
+                <span class="exhibition-card-link">Подробнее</span>
+            </div>
+        </div>
+    `;
+}
+
 async function loadExhibitions() {
     const grid = document.getElementById('exhibitions-grid');
     if (!grid) return;
 
-    const exhibitions = await api.getAllExhibitions();
+    const first = await api.getExhibitionsPage(0);
 
-    if (!exhibitions || exhibitions.length === 0) {
+    if (first.items.length === 0) {
         grid.innerHTML = '<div class="empty-state">Экспозиции пока не добавлены</div>';
         return;
     }
 
-    grid.innerHTML = exhibitions.map((ex) => {
-        const exhibitCount = (ex.exhibits || []).length;
-        const title = escapeHtml(ex.title || '');
-        const desc = escapeHtml(truncateText(ex.description || '', 140));
-
-        // Find preview media from the selected preview exhibit
-        let previewMedia = '';
-        if (ex.preview_exhibit_id && ex.exhibits) {
-            const previewExhibit = ex.exhibits.find(e => e.id === ex.preview_exhibit_id);
-            if (previewExhibit) {
-                const media = normalizeMediaUrls(previewExhibit.image_urls || []);
-                if (media.length > 0) previewMedia = media[0];
-            }
-        }
+    grid.innerHTML = first.items.map(exhibitionCard).join('');
 
-        const previewHtml = previewMedia
-            ? buildCardMedia(previewMedia, ex.title || '', 'exhibition-card-preview-img', 'exhibition-card-preview-video', 'exhibition-card-preview-embed')
-            : '<span class="exhibition-card-placeholder">Музей</span>';
-
-        return `
-            <div class="exhibition-card" data-hover-play-video="1" onclick="openExhibition('${ex.id}')">
-                <div class="exhibition-card-image">
-                    ${previewHtml}
-                    ${exhibitCount > 0 ? `<span class="exhibition-card-count">${exhibitCount} экспонатов</span>` : ''}
-                </div>
-                <div class="exhibition-card-body">
-                    <h3 class="exhibition-card-title">${title}</h3>
-                    <p class="exhibition-card-desc">${desc}</p>
-                    <span class="exhibition-card-link">Подробнее</span>
-                </div>
-            </div>
-        `;
-    }).join('');
-
-    await hydrateImgurMedia(grid);
+    await hydrateExternalMedia(grid);
     initHoverMediaPlayback(grid);
 
-    const ctrl = initCarousel('exhibitions-carousel', 'exhibitions-dots', { perView: 1 });
+    const ctrl = initCarousel('exhibitions-carousel', 'exhibitions-dots', {
+        perView: 1,
+        loadMore: pageLoader(first, api.getExhibitionsPage, async (items) => {
+            grid.insertAdjacentHTML('beforeend', items.map(exhibitionCard).join(''));
+            await hydrateExternalMedia(grid);
+            initHoverMediaPlayback(grid);
+        })
+    });
     if (ctrl) ctrl.refresh();
 }
 
@@ -414,7 +469,7 @@ async function openNewsModal(id) {
     const news = await api.getNewsById(id);
     if (!news) return;
 
-    const imagesHtml = buildImageCarousel(news.image_urls || [], news.title || '');
+    const imagesHtml = buildImageCarousel(news.image_urls || [], news.title || '', news.images, news.media);
     const title = escapeHtml(news.title || '');
     const content = escapeHtml(news.content || 'Содержание новости отсутствует');
 
@@ -425,7 +480,7 @@ async function openNewsModal(id) {
         <p class="modal-description">${content}</p>
     `;
 
-    await hydrateImgurMedia(body);
+    await hydrateExternalMedia(body);
 
     initModalCarousel(body);
 
@@ -0,0 +1,272 @@
+// Package auth manages the accounts of the admin: their bcrypt-hashed
+// passwords, roles and sign-in.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCredentials = storage.NewError(storage.ErrInvalid, "invalid_credentials", "invalid login or password")
+	ErrInvalidLogin       = storage.NewError(storage.ErrInvalid, "invalid_login", "login must be 1 to 64 characters without spaces or colons")
+	ErrInvalidPassword    = storage.NewError(storage.ErrInvalid, "invalid_password", "password must be 8 to 72 bytes long")
+	ErrInvalidRole        = storage.NewError(storage.ErrInvalid, "invalid_role", "unknown role")
+	ErrLoginTaken         = storage.NewError(storage.ErrConflict, "login_taken", "login is already taken")
+	// ErrLastOwner keeps the admin from locking itself out.
+	ErrLastOwner = storage.NewError(storage.ErrConflict, "last_owner", "the last active owner cannot be demoted or disabled")
+)
+
+const (
+	minPassword = 8
+	// maxPassword is the most bcrypt hashes; it refuses longer passwords.
+	maxPassword = 72
+	maxLogin    = 64
+)
+
+// Users manages the admin users.
+type Users struct {
+	store    storage.AdminUsers
+	sessions storage.AdminSessions
+	tx       storage.Transactor
+	// lockout throttles the sign-ins; nil signs in without limits.
+	lockout *Lockout
+	log     *slog.Logger
+	// dummyHash is compared with the passwords of unknown logins, so they
+	// take as long to reject as wrong passwords.
+	dummyHash func() []byte
+}
+
+func NewUsers(store storage.AdminUsers, sessions storage.AdminSessions, tx storage.Transactor, lockout *Lockout, log *slog.Logger) *Users {
+	return &Users{
+		store:    store,
+		sessions: sessions,
+		tx:       tx,
+		lockout:  lockout,
+		log:      log,
+		dummyHash: sync.OnceValue(func() []byte {
+			h, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+			return h
+		}),
+	}
+}
+
+// Authenticate returns the enabled user with login and password. Unknown
+// logins, wrong passwords and disabled users are all ErrInvalidCredentials.
+// With a lockout, the logins and addresses of the request context with too
+// many failures are refused with a LockedError before the password is even
+// checked.
+func (u *Users) Authenticate(ctx context.Context, login, password string) (model.AdminUser, error) {
+	login = normalizeLogin(login)
+	if u.lockout == nil {
+		return u.authenticate(ctx, login, password)
+	}
+
+	ip, _ := ctx.Value(model.CtxKeyVisitorIP).(string)
+	attempt, err := u.lockout.Check(login, ip)
+	if err != nil {
+		return model.AdminUser{}, err
+	}
+	defer attempt.Release()
+	user, err := u.authenticate(ctx, login, password)
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		ua, _ := ctx.Value(model.CtxKeyVisitorUA).(string)
+		if lerr := attempt.Fail(ctx, ua); lerr != nil {
+			return model.AdminUser{}, lerr
+		}
+	case err == nil && !user.TOTPEnabled:
+		// With two-factor authentication the failures are forgotten only
+		// after the code, see TwoFactor.Verify, or they would be reset by
+		// every guess of the code.
+		attempt.Succeed()
+	}
+	return user, err
+}
+
+func (u *Users) authenticate(ctx context.Context, login, password string) (model.AdminUser, error) {
+	user, err := u.store.GetByLogin(ctx, login)
+	if errors.Is(err, storage.ErrNotFound) {
+		_ = bcrypt.CompareHashAndPassword(u.dummyHash(), []byte(password))
+		return model.AdminUser{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return model.AdminUser{}, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil || user.Disabled {
+		return model.AdminUser{}, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+func (u *Users) List(ctx context.Context) ([]model.AdminUser, error) {
+	return u.store.List(ctx)
+}
+
+func (u *Users) Get(ctx context.Context, id uuid.UUID) (model.AdminUser, error) {
+	return u.store.Get(ctx, id)
+}
+
+func (u *Users) GetByLogin(ctx context.Context, login string) (model.AdminUser, error) {
+	return u.store.GetByLogin(ctx, normalizeLogin(login))
+}
+
+// Add creates a user. Logins are case-insensitive and stored in lower case.
+func (u *Users) Add(ctx context.Context, login, password string, role model.Role) (model.AdminUser, error) {
+	login = normalizeLogin(login)
+	if err := checkLogin(login); err != nil {
+		return model.AdminUser{}, err
+	}
+	if !role.Valid() {
+		return model.AdminUser{}, fmt.Errorf("%q: %w", role, ErrInvalidRole)
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return model.AdminUser{}, err
+	}
+	return u.create(ctx, model.AdminUser{Login: login, PasswordHash: hash, Role: role})
+}
+
+func (u *Users) create(ctx context.Context, user model.AdminUser) (model.AdminUser, error) {
+	created, err := u.store.Create(ctx, user)
+	if errors.Is(err, storage.ErrConflict) {
+		return model.AdminUser{}, fmt.Errorf("%q: %w", user.Login, ErrLoginTaken)
+	}
+	return created, err
+}
+
+// Update sets the role of a user and disables or enables them. The last
+// enabled owner can be neither demoted nor disabled. Disabling a user ends
+// their sessions.
+func (u *Users) Update(ctx context.Context, id uuid.UUID, role model.Role, disabled bool) (model.AdminUser, error) {
+	if !role.Valid() {
+		return model.AdminUser{}, fmt.Errorf("%q: %w", role, ErrInvalidRole)
+	}
+	var user model.AdminUser
+	err := u.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if user, err = u.store.Get(ctx, id); err != nil {
+			return err
+		}
+		if isActiveOwner(user) && (role != model.RoleOwner || disabled) {
+			if err := u.keepOwner(ctx, user.ID); err != nil {
+				return err
+			}
+		}
+		user.Role, user.Disabled = role, disabled
+		if user, err = u.store.Update(ctx, user); err != nil {
+			return err
+		}
+		if disabled {
+			_, err = u.sessions.DeleteByUser(ctx, user.ID, uuid.Nil)
+		}
+		return err
+	})
+	if err != nil {
+		return model.AdminUser{}, err
+	}
+	return user, nil
+}
+
+// SetPassword replaces the password of a user and ends their sessions but
+// the one of the request, so a user changing their password stays signed
+// in.
+func (u *Users) SetPassword(ctx context.Context, id uuid.UUID, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	user, err := u.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	if _, err := u.store.Update(ctx, user); err != nil {
+		return err
+	}
+	current, _ := ctx.Value(model.CtxKeyAdminSession).(uuid.UUID)
+	_, err = u.sessions.DeleteByUser(ctx, user.ID, current)
+	return err
+}
+
+// Bootstrap creates an owner with login and password if there are no users
+// yet, so the admin is reachable with the credentials of the configuration
+// right after the upgrade to accounts. The password is not checked against
+// the length limits, as it worked before. It reports whether the owner was
+// created.
+func (u *Users) Bootstrap(ctx context.Context, login, password string) (bool, error) {
+	users, err := u.store.List(ctx)
+	if err != nil || len(users) > 0 {
+		return false, err
+	}
+	login = normalizeLogin(login)
+	if login == "" || password == "" {
+		u.log.Warn("there are no admin users, add one with `school-museum user add`")
+		return false, nil
+	}
+	if err := checkLogin(login); err != nil {
+		return false, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalidPassword, err)
+	}
+	if _, err := u.create(ctx, model.AdminUser{Login: login, PasswordHash: string(hash), Role: model.RoleOwner}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// keepOwner fails if there is no enabled owner but the user id. It locks
+// the owners, so concurrent demotions of two owners cannot both pass.
+func (u *Users) keepOwner(ctx context.Context, id uuid.UUID) error {
+	owners, err := u.store.LockOwners(ctx)
+	if err != nil {
+		return err
+	}
+	for _, other := range owners {
+		if other.ID != id {
+			return nil
+		}
+	}
+	return ErrLastOwner
+}
+
+func isActiveOwner(u model.AdminUser) bool {
+	return u.Role == model.RoleOwner && !u.Disabled
+}
+
+func normalizeLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
+
+// checkLogin rejects the logins Basic Auth cannot carry and the ones that
+// would be hard to tell apart in the history of changes.
+func checkLogin(login string) error {
+	if login == "" || len([]rune(login)) > maxLogin ||
+		strings.ContainsFunc(login, func(r rune) bool { return r == ':' || unicode.IsSpace(r) || !unicode.IsPrint(r) }) {
+		return fmt.Errorf("%q: %w", login, ErrInvalidLogin)
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPassword || len(password) > maxPassword {
+		return "", ErrInvalidPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
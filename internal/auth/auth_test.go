@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage/memory"
+)
+
+func TestUpdateLastOwner(t *testing.T) {
+	ctx := context.Background()
+	db := memory.NewDB()
+	users := NewUsers(memory.NewAdminUserStorage(db), memory.NewAdminSessionStorage(db), db, nil, slog.New(slog.DiscardHandler))
+	add := func(login string, role model.Role) model.AdminUser {
+		t.Helper()
+		u, err := users.Add(ctx, login, "correct horse", role)
+		if err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		return u
+	}
+	anna, boris := add("anna", model.RoleOwner), add("boris", model.RoleOwner)
+	add("teacher", model.RoleEditor)
+
+	// Two owners demoted at once: one of them stays.
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, u := range []model.AdminUser{anna, boris} {
+		wg.Go(func() {
+			_, errs[i] = users.Update(ctx, u.ID, model.RoleEditor, false)
+		})
+	}
+	wg.Wait()
+	if (errs[0] == nil) == (errs[1] == nil) || !errors.Is(errors.Join(errs...), ErrLastOwner) {
+		t.Fatalf("concurrent demotions = %v, want one ErrLastOwner", errs)
+	}
+	last := anna
+	if errs[0] == nil {
+		last = boris
+	}
+
+	if _, err := users.Update(ctx, last.ID, model.RoleOwner, true); !errors.Is(err, ErrLastOwner) {
+		t.Errorf("disabling the last owner: %v, want ErrLastOwner", err)
+	}
+	// The last owner may still be updated as long as they stay an owner.
+	if _, err := users.Update(ctx, last.ID, model.RoleOwner, false); err != nil {
+		t.Errorf("updating the last owner: %v", err)
+	}
+	if _, err := users.Update(ctx, last.ID, model.Role("boss"), false); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("unknown role: %v, want ErrInvalidRole", err)
+	}
+}
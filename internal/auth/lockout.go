@@ -0,0 +1,378 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+)
+
+// ErrLocked is returned for sign-ins to a login or from an address locked
+// out after too many failures; errors.As finds the LockedError with the
+// time left.
+var ErrLocked = storage.NewError(storage.ErrRateLimited, "locked", "too many failed sign-ins, try again later")
+
+// LockedError is ErrLocked with the time left until the next try.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s in %s", ErrLocked.Msg, e.RetryAfter)
+}
+
+// Seconds returns RetryAfter in whole seconds, rounded up, for the
+// Retry-After header.
+func (e *LockedError) Seconds() int {
+	return int((e.RetryAfter + time.Second - 1) / time.Second)
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrLocked
+}
+
+// maxFailureLogin caps the logins written to the security log, which come
+// straight from the requests.
+const maxFailureLogin = 100
+
+// pendingRetry is the Retry-After of the sign-ins refused while an attempt
+// that can lock out is in flight; it takes about one bcrypt comparison.
+const pendingRetry = time.Second
+
+// LockoutOptions configures Lockout. Zero values take the defaults.
+type LockoutOptions struct {
+	// Threshold is the number of failures allowed for a login before it is
+	// locked out, 5 by default.
+	Threshold int
+	// IPThreshold is the same for an address, 20 by default; it is higher
+	// as offices and schools share addresses.
+	IPThreshold int
+	// Duration is the first lockout, 30 seconds by default. Every further
+	// failure doubles it.
+	Duration time.Duration
+	// MaxDuration caps the lockouts, 15 minutes by default. Failures are
+	// forgotten that long after the last one.
+	MaxDuration time.Duration
+	// Retention is how long the failures stay in the security log, 30
+	// days by default.
+	Retention time.Duration
+	// MaxCounters caps the logins and addresses counted at once, 10000 by
+	// default, as both come from the requests.
+	MaxCounters int
+}
+
+// Lockout slows down password guessing. It counts the failed sign-ins of
+// every login and address and, past a threshold, locks them out for a time
+// that doubles with every failure. A login or an address with no
+// failures left gets one attempt in flight at a time, so concurrent
+// requests cannot slip past the lockout. The counters live in the process, up to
+// MaxCounters of them; the failures are also written to the security log.
+type Lockout struct {
+	store storage.LoginFailures
+	opts  LockoutOptions
+	log   *slog.Logger
+	now   func() time.Time
+
+	mu       sync.Mutex
+	counters map[string]*failures
+}
+
+// failures counts the recent failed sign-ins of a login or an address.
+type failures struct {
+	n           int
+	last        time.Time
+	lockedUntil time.Time
+	// pending is the number of attempts in flight.
+	pending int
+}
+
+func NewLockout(store storage.LoginFailures, opts LockoutOptions, log *slog.Logger) *Lockout {
+	if opts.Threshold <= 0 {
+		opts.Threshold = 5
+	}
+	if opts.IPThreshold <= 0 {
+		opts.IPThreshold = 20
+	}
+	if opts.Duration <= 0 {
+		opts.Duration = 30 * time.Second
+	}
+	if opts.MaxDuration <= 0 {
+		opts.MaxDuration = 15 * time.Minute
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = 30 * 24 * time.Hour
+	}
+	if opts.MaxCounters <= 0 {
+		opts.MaxCounters = 10000
+	}
+	return &Lockout{
+		store:    store,
+		opts:     opts,
+		log:      log,
+		now:      time.Now,
+		counters: make(map[string]*failures),
+	}
+}
+
+// Check reserves a sign-in attempt to login from ip, or returns a
+// LockedError if either is locked out, or has no failures left and an
+// attempt in flight already. The attempt must be ended with Fail, Succeed
+// or Release.
+func (l *Lockout) Check(login, ip string) (*Attempt, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var left time.Duration
+	for _, key := range keys(login, ip) {
+		f, ok := l.counters[key]
+		if !ok {
+			continue
+		}
+		left = max(left, f.lockedUntil.Sub(now))
+		n := f.n
+		if l.stale(f, now) {
+			n = 0
+		}
+		// Only failures that happened count; but once one more locks
+		// out, as after a lockout ends, one attempt is let through at a
+		// time, so concurrent guesses cannot all slip past the lockout.
+		if f.pending > 0 && n >= l.threshold(key, ip) {
+			left = max(left, pendingRetry)
+		}
+	}
+	if left > 0 {
+		return nil, &LockedError{RetryAfter: left}
+	}
+	for _, key := range keys(login, ip) {
+		l.counter(key, now).pending++
+	}
+	return &Attempt{l: l, login: login, ip: ip}, nil
+}
+
+// Attempt is a sign-in reserved by Lockout.Check. The methods of a nil
+// Attempt do nothing, and those of an ended one as well.
+type Attempt struct {
+	l         *Lockout
+	login, ip string
+	ended     bool
+}
+
+// Fail counts the attempt as a failed sign-in and writes it to the
+// security log. It returns a LockedError if the failure locked the login
+// or the address out.
+func (a *Attempt) Fail(ctx context.Context, userAgent string) error {
+	if a == nil || a.ended {
+		return nil
+	}
+	a.ended = true
+	return a.l.fail(ctx, a.login, a.ip, userAgent)
+}
+
+// Succeed ends the attempt and forgets the failures of its login. Those
+// of the address are kept, so signing in to one account does not reset
+// guessing at others.
+func (a *Attempt) Succeed() {
+	if a == nil || a.ended {
+		return
+	}
+	a.ended = true
+	a.l.mu.Lock()
+	defer a.l.mu.Unlock()
+	if f, ok := a.l.counters[loginKey(a.login)]; ok {
+		f.n, f.lockedUntil = 0, time.Time{}
+	}
+	a.l.release(a.login, a.ip)
+}
+
+// Release ends the attempt without counting it, for attempts that neither
+// failed nor succeeded yet, like a right password waiting for its code.
+func (a *Attempt) Release() {
+	if a == nil || a.ended {
+		return
+	}
+	a.ended = true
+	a.l.mu.Lock()
+	defer a.l.mu.Unlock()
+	a.l.release(a.login, a.ip)
+}
+
+func (l *Lockout) fail(ctx context.Context, login, ip, userAgent string) error {
+	l.mu.Lock()
+	now := l.now()
+	var left time.Duration
+	for _, key := range keys(login, ip) {
+		f := l.counter(key, now)
+		f.pending = max(f.pending-1, 0)
+		f.n++
+		f.last = now
+		if over := f.n - l.threshold(key, ip); over > 0 {
+			f.lockedUntil = now.Add(l.backoff(over))
+			left = max(left, f.lockedUntil.Sub(now))
+		}
+	}
+	l.mu.Unlock()
+
+	if len(login) > maxFailureLogin {
+		login = login[:maxFailureLogin]
+		for !utf8.ValidString(login) {
+			login = login[:len(login)-1]
+		}
+	}
+	_, err := l.store.Create(ctx, model.LoginFailure{
+		Login:     login,
+		IP:        ip,
+		UserAgent: userAgent,
+		Locked:    left > 0,
+		CreatedAt: now,
+	})
+	if err != nil {
+		l.log.Error("failed to log a failed sign-in", slog.String("error", err.Error()))
+	}
+	if left > 0 {
+		l.log.Warn("sign-in locked out",
+			slog.String("login", login),
+			slog.String("ip", ip),
+			slog.Duration("for", left))
+		return &LockedError{RetryAfter: left}
+	}
+	return nil
+}
+
+// release ends an attempt in flight, dropping the counters left without
+// failures. l.mu must be held.
+func (l *Lockout) release(login, ip string) {
+	for _, key := range keys(login, ip) {
+		f, ok := l.counters[key]
+		if !ok {
+			continue
+		}
+		f.pending = max(f.pending-1, 0)
+		if f.pending == 0 && f.n == 0 {
+			delete(l.counters, key)
+		}
+	}
+}
+
+// counter returns the counter of key, started over if its failures are
+// stale. l.mu must be held.
+func (l *Lockout) counter(key string, now time.Time) *failures {
+	f, ok := l.counters[key]
+	if !ok {
+		if len(l.counters) >= l.opts.MaxCounters {
+			l.evict(now)
+		}
+		f = &failures{}
+		l.counters[key] = f
+	} else if l.stale(f, now) {
+		f.n, f.lockedUntil = 0, time.Time{}
+	}
+	return f
+}
+
+// evict makes room for a counter by dropping the stale ones or, if none
+// are, the one with the oldest failure. Counters with attempts in flight
+// are kept. l.mu must be held.
+func (l *Lockout) evict(now time.Time) {
+	var oldest string
+	for key, f := range l.counters {
+		switch {
+		case f.pending > 0:
+		case l.stale(f, now):
+			delete(l.counters, key)
+		case oldest == "" || f.last.Before(l.counters[oldest].last):
+			oldest = key
+		}
+	}
+	if len(l.counters) >= l.opts.MaxCounters && oldest != "" {
+		delete(l.counters, oldest)
+	}
+}
+
+// stale reports whether the failures of f are forgotten: the last one is
+// MaxDuration old and no lockout is running.
+func (l *Lockout) stale(f *failures, now time.Time) bool {
+	return now.Sub(f.last) >= l.opts.MaxDuration && !now.Before(f.lockedUntil)
+}
+
+// threshold returns the failures allowed for the counter key.
+func (l *Lockout) threshold(key, ip string) int {
+	if key == ipKey(ip) {
+		return l.opts.IPThreshold
+	}
+	return l.opts.Threshold
+}
+
+// List returns a page of the security log: the failures of login, or of
+// all logins if it is empty, with their total number.
+func (l *Lockout) List(ctx context.Context, login string, opts storage.ListOptions) ([]model.LoginFailure, int, error) {
+	return l.store.Query(ctx, normalizeLogin(login), opts)
+}
+
+// RunPurge forgets stale counters and deletes the failures older than the
+// retention every interval until ctx is done.
+func (l *Lockout) RunPurge(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		l.log.Info("login failure purge job disabled")
+		return nil
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			now := l.now()
+			l.forget(now)
+			n, err := l.store.DeleteBefore(ctx, now.Add(-l.opts.Retention))
+			if err != nil {
+				if ctx.Err() == nil {
+					l.log.Error("failed to purge login failures", slog.String("error", err.Error()))
+				}
+				continue
+			}
+			if n > 0 {
+				l.log.Info("purged login failures", slog.Int("failures", n))
+			}
+		}
+	}
+}
+
+// forget drops the counters without failures for MaxDuration and without
+// attempts in flight.
+func (l *Lockout) forget(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, f := range l.counters {
+		if f.pending == 0 && l.stale(f, now) {
+			delete(l.counters, key)
+		}
+	}
+}
+
+// backoff returns the lockout after over failures past the threshold.
+func (l *Lockout) backoff(over int) time.Duration {
+	d := l.opts.Duration
+	for i := 1; i < over && d < l.opts.MaxDuration; i++ {
+		d *= 2
+	}
+	return min(d, l.opts.MaxDuration)
+}
+
+func keys(login, ip string) []string {
+	return []string{loginKey(login), ipKey(ip)}
+}
+
+func loginKey(login string) string {
+	return "login:" + login
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage/memory"
+)
+
+// clock is a settable time for the lockout.
+type clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *clock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newLockout(t *testing.T, opts LockoutOptions) (*Lockout, *clock) {
+	t.Helper()
+	l := NewLockout(memory.NewLoginFailureStorage(memory.NewDB()), opts, slog.New(slog.DiscardHandler))
+	c := &clock{t: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)}
+	l.now = c.now
+	return l, c
+}
+
+// fail makes a failed sign-in to login from ip and returns the lockout
+// it caused, if any.
+func fail(t *testing.T, l *Lockout, login, ip string) time.Duration {
+	t.Helper()
+	a, err := l.Check(login, ip)
+	if err != nil {
+		t.Fatalf("Check(%q, %q): %v", login, ip, err)
+	}
+	var locked *LockedError
+	if err := a.Fail(context.Background(), "test"); errors.As(err, &locked) {
+		return locked.RetryAfter
+	} else if err != nil {
+		t.Fatalf("Fail: %v", err)
+	}
+	return 0
+}
+
+// lockedFor returns the time left of the lockout of login from ip, or 0.
+func lockedFor(t *testing.T, l *Lockout, login, ip string) time.Duration {
+	t.Helper()
+	a, err := l.Check(login, ip)
+	var locked *LockedError
+	if errors.As(err, &locked) {
+		if !errors.Is(err, ErrLocked) {
+			t.Errorf("Check: %v, want ErrLocked", err)
+		}
+		return locked.RetryAfter
+	}
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	a.Release()
+	return 0
+}
+
+func TestLockoutThreshold(t *testing.T) {
+	l, _ := newLockout(t, LockoutOptions{Threshold: 3, Duration: 30 * time.Second})
+	for i := range 3 {
+		if d := fail(t, l, "boss", "10.0.0.1"); d != 0 {
+			t.Fatalf("failure %d locked out for %s, want no lockout up to the threshold", i+1, d)
+		}
+	}
+	if d := lockedFor(t, l, "boss", "10.0.0.2"); d != 0 {
+		t.Fatalf("locked out for %s at the threshold", d)
+	}
+	if d := fail(t, l, "boss", "10.0.0.1"); d != 30*time.Second {
+		t.Fatalf("failure past the threshold locked out for %s, want 30s", d)
+	}
+	// Even a right password waits, from any address.
+	if d := lockedFor(t, l, "boss", "10.0.0.2"); d != 30*time.Second {
+		t.Errorf("Check locked out for %s, want 30s", d)
+	}
+	if d := lockedFor(t, l, "editor", "10.0.0.2"); d != 0 {
+		t.Errorf("another login locked out for %s", d)
+	}
+}
+
+func TestLockoutIPThreshold(t *testing.T) {
+	l, _ := newLockout(t, LockoutOptions{Threshold: 100, IPThreshold: 3})
+	for i := range 3 {
+		fail(t, l, fmt.Sprint("user", i), "10.0.0.1")
+	}
+	if d := fail(t, l, "user3", "10.0.0.1"); d == 0 {
+		t.Fatal("failure past the address threshold did not lock out")
+	}
+	if d := lockedFor(t, l, "user4", "10.0.0.1"); d == 0 {
+		t.Error("another login from the address is not locked out")
+	}
+	if d := lockedFor(t, l, "user4", "10.0.0.2"); d != 0 {
+		t.Errorf("another address locked out for %s", d)
+	}
+}
+
+func TestLockoutBackoff(t *testing.T) {
+	l, c := newLockout(t, LockoutOptions{Threshold: 1, Duration: 30 * time.Second, MaxDuration: 3 * time.Minute})
+	fail(t, l, "boss", "10.0.0.1")
+	want := []time.Duration{
+		30 * time.Second,
+		time.Minute,
+		2 * time.Minute,
+		// Capped by MaxDuration.
+		3 * time.Minute,
+	}
+	for i, w := range want {
+		d := fail(t, l, "boss", "10.0.0.1")
+		if d != w {
+			t.Fatalf("lockout %d = %s, want %s", i+1, d, w)
+		}
+		c.advance(d - time.Second)
+		if left := lockedFor(t, l, "boss", "10.0.0.1"); left != time.Second {
+			t.Fatalf("lockout %d: %s left a second before its end", i+1, left)
+		}
+		c.advance(time.Second)
+	}
+
+	// The failures are forgotten MaxDuration after the last one, which is
+	// when the longest lockout ends.
+	if d := fail(t, l, "boss", "10.0.0.1"); d != 0 {
+		t.Errorf("first failure after MaxDuration locked out for %s", d)
+	}
+}
+
+func TestLockoutSucceed(t *testing.T) {
+	l, _ := newLockout(t, LockoutOptions{Threshold: 2, IPThreshold: 3})
+	fail(t, l, "boss", "10.0.0.1")
+	fail(t, l, "boss", "10.0.0.1")
+
+	a, err := l.Check("boss", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	a.Succeed()
+	if _, ok := l.counters[loginKey("boss")]; ok {
+		t.Error("the login counter is kept after a success")
+	}
+	if f := l.counters[ipKey("10.0.0.1")]; f == nil || f.n != 2 {
+		t.Fatalf("address counter = %+v, want its 2 failures kept", f)
+	}
+
+	// The login starts over, the address does not.
+	if d := fail(t, l, "boss", "10.0.0.1"); d != 0 {
+		t.Fatalf("first failure after a success locked out for %s", d)
+	}
+	if d := fail(t, l, "editor", "10.0.0.1"); d == 0 {
+		t.Error("the address threshold was reset by a success")
+	}
+}
+
+func TestLockoutRelease(t *testing.T) {
+	l, _ := newLockout(t, LockoutOptions{Threshold: 1})
+	fail(t, l, "boss", "10.0.0.1")
+	a, err := l.Check("boss", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	a.Release()
+	a.Fail(context.Background(), "test")
+	if f := l.counters[loginKey("boss")]; f == nil || f.n != 1 || f.pending != 0 {
+		t.Errorf("login counter = %+v, want the failure kept and nothing in flight", f)
+	}
+}
+
+func TestLockoutMaxCounters(t *testing.T) {
+	l, c := newLockout(t, LockoutOptions{MaxCounters: 4})
+	for i := range 10 {
+		fail(t, l, fmt.Sprint("user", i), fmt.Sprint("10.0.0.", i))
+		c.advance(time.Second)
+		if len(l.counters) > 4 {
+			t.Fatalf("%d counters after %d failures, want at most 4", len(l.counters), i+1)
+		}
+	}
+	// The oldest are evicted.
+	for _, key := range []string{loginKey("user9"), ipKey("10.0.0.9"), loginKey("user8"), ipKey("10.0.0.8")} {
+		if _, ok := l.counters[key]; !ok {
+			t.Errorf("counter %q of a recent failure was evicted", key)
+		}
+	}
+
+	// Counters with attempts in flight are not.
+	a, err := l.Check("user9", "10.0.0.9")
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	for i := range 4 {
+		fail(t, l, fmt.Sprint("other", i), fmt.Sprint("10.0.1.", i))
+	}
+	if f := l.counters[loginKey("user9")]; f == nil || f.pending != 1 {
+		t.Errorf("counter with an attempt in flight = %+v, want it kept", f)
+	}
+	a.Release()
+}
+
+func TestLockoutPending(t *testing.T) {
+	l, _ := newLockout(t, LockoutOptions{Threshold: 2})
+
+	// Below the threshold attempts run side by side.
+	var attempts []*Attempt
+	for i := range 10 {
+		a, err := l.Check("boss", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("attempt %d in flight: %v", i+1, err)
+		}
+		attempts = append(attempts, a)
+	}
+	for _, a := range attempts {
+		a.Succeed()
+	}
+
+	// With one failure left, one at a time.
+	fail(t, l, "boss", "10.0.0.1")
+	fail(t, l, "boss", "10.0.0.1")
+	a, err := l.Check("boss", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if d := lockedFor(t, l, "boss", "10.0.0.2"); d != pendingRetry {
+		t.Errorf("second attempt in flight locked out for %s, want %s", d, pendingRetry)
+	}
+	a.Release()
+	if d := lockedFor(t, l, "boss", "10.0.0.2"); d != 0 {
+		t.Errorf("attempt after the release locked out for %s", d)
+	}
+}
+
+func TestAuthenticateConcurrent(t *testing.T) {
+	ctx := context.Background()
+	db := memory.NewDB()
+	l, _ := newLockout(t, LockoutOptions{Threshold: 5, IPThreshold: 20})
+	users := NewUsers(memory.NewAdminUserStorage(db), memory.NewAdminSessionStorage(db), db, l, slog.New(slog.DiscardHandler))
+	if _, err := users.Add(ctx, "boss", "correct horse", model.RoleOwner); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	ctx = context.WithValue(ctx, model.CtxKeyVisitorIP, "10.0.0.1")
+
+	// A script signing in with Basic Auth from a shared address.
+	var wg sync.WaitGroup
+	errs := make(chan error, 25)
+	for range 25 {
+		wg.Go(func() {
+			_, err := users.Authenticate(ctx, "boss", "correct horse")
+			errs <- err
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent sign-in with the right password: %v", err)
+		}
+	}
+	if len(l.counters) != 0 {
+		t.Errorf("counters = %v, want none after successful sign-ins", l.counters)
+	}
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	// CookieName is the cookie of the session token.
+	CookieName = "museum_session"
+	// CSRFHeader carries the CSRF token of the session in the requests
+	// changing something.
+	CSRFHeader = "X-CSRF-Token"
+	// cookiePath limits the cookie to the admin API.
+	cookiePath = "/admin"
+)
+
+// ErrInvalidSession is returned for unknown and ended sessions and for the
+// sessions of disabled users.
+var ErrInvalidSession = storage.NewError(storage.ErrInvalid, "invalid_session", "session is invalid or has ended")
+
+// touchInterval is how often the last request of a session is stored.
+const touchInterval = time.Minute
+
+// SessionOptions configures Sessions. Zero values take the defaults.
+type SessionOptions struct {
+	// IdleTimeout ends a session without requests for that long, 30
+	// minutes by default.
+	IdleTimeout time.Duration
+	// MaxAge ends a session that long after the sign-in, 12 hours by
+	// default.
+	MaxAge time.Duration
+	// SecureCookie sends the cookie over HTTPS only.
+	SecureCookie bool
+}
+
+// Sessions manages the sessions of the admin login form. The token of a
+// session is a random string only its hash is stored of, so a leaked
+// database opens no sessions.
+type Sessions struct {
+	store storage.AdminSessions
+	users storage.AdminUsers
+	opts  SessionOptions
+	log   *slog.Logger
+	now   func() time.Time
+}
+
+func NewSessions(store storage.AdminSessions, users storage.AdminUsers, opts SessionOptions, log *slog.Logger) *Sessions {
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = 30 * time.Minute
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = 12 * time.Hour
+	}
+	return &Sessions{
+		store: store,
+		users: users,
+		opts:  opts,
+		log:   log,
+		now:   time.Now,
+	}
+}
+
+// Start signs user in and returns the session with its token.
+func (s *Sessions) Start(ctx context.Context, user model.AdminUser, userAgent, ip string) (model.AdminSession, string, error) {
+	token, csrf := randomToken(), randomToken()
+	now := s.now()
+	as, err := s.store.Create(ctx, model.AdminSession{
+		UserID:     user.ID,
+		TokenHash:  hashToken(token),
+		CSRFToken:  csrf,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.opts.MaxAge),
+	})
+	if err != nil {
+		return model.AdminSession{}, "", err
+	}
+	return as, token, nil
+}
+
+// Authenticate returns the session with token and its user. Sessions idle
+// for too long or past their expiry are deleted.
+func (s *Sessions) Authenticate(ctx context.Context, token string) (model.AdminSession, model.AdminUser, error) {
+	as, err := s.store.GetByToken(ctx, hashToken(token))
+	if errors.Is(err, storage.ErrNotFound) {
+		return model.AdminSession{}, model.AdminUser{}, ErrInvalidSession
+	}
+	if err != nil {
+		return model.AdminSession{}, model.AdminUser{}, err
+	}
+
+	now := s.now()
+	if !s.active(as, now) {
+		if err := s.store.Delete(ctx, as.UserID, as.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return model.AdminSession{}, model.AdminUser{}, err
+		}
+		return model.AdminSession{}, model.AdminUser{}, ErrInvalidSession
+	}
+	user, err := s.users.Get(ctx, as.UserID)
+	if errors.Is(err, storage.ErrNotFound) || err == nil && user.Disabled {
+		return model.AdminSession{}, model.AdminUser{}, ErrInvalidSession
+	}
+	if err != nil {
+		return model.AdminSession{}, model.AdminUser{}, err
+	}
+
+	if now.Sub(as.LastSeenAt) >= touchInterval {
+		if err := s.store.Touch(ctx, as.ID, now); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return model.AdminSession{}, model.AdminUser{}, err
+		}
+		as.LastSeenAt = now
+	}
+	return as, user, nil
+}
+
+// Cookie returns the cookie carrying token of a session expiring at expires.
+// It is sent to the admin API only and is hidden from scripts and from the
+// requests of other sites.
+func (s *Sessions) Cookie(token string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     CookieName,
+		Value:    token,
+		Path:     cookiePath,
+		Expires:  expires,
+		Secure:   s.opts.SecureCookie,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+// ClearCookie returns the cookie that removes the session cookie.
+func (s *Sessions) ClearCookie() *http.Cookie {
+	c := s.Cookie("", time.Time{})
+	c.MaxAge = -1
+	return c
+}
+
+// CheckCSRF reports whether token is the CSRF token of as.
+func CheckCSRF(as model.AdminSession, token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(as.CSRFToken)) == 1
+}
+
+// List returns the active sessions of a user, the last used first.
+func (s *Sessions) List(ctx context.Context, userID uuid.UUID) ([]model.AdminSession, error) {
+	sessions, err := s.store.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	active := sessions[:0]
+	for _, as := range sessions {
+		if s.active(as, now) {
+			active = append(active, as)
+		}
+	}
+	return active, nil
+}
+
+// Revoke ends a session of a user.
+func (s *Sessions) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	return s.store.Delete(ctx, userID, id)
+}
+
+// RevokeAll ends the sessions of a user but except, which may be uuid.Nil.
+func (s *Sessions) RevokeAll(ctx context.Context, userID, except uuid.UUID) (int, error) {
+	return s.store.DeleteByUser(ctx, userID, except)
+}
+
+// RunPurge deletes ended sessions every interval until ctx is done.
+func (s *Sessions) RunPurge(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		s.log.Info("session purge job disabled")
+		return nil
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			now := s.now()
+			n, err := s.store.DeleteExpired(ctx, now.Add(-s.opts.IdleTimeout), now)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.log.Error("failed to purge admin sessions", slog.String("error", err.Error()))
+				}
+				continue
+			}
+			if n > 0 {
+				s.log.Info("purged admin sessions", slog.Int("sessions", n))
+			}
+		}
+	}
+}
+
+func (s *Sessions) active(as model.AdminSession, now time.Time) bool {
+	return now.Before(as.ExpiresAt) && now.Sub(as.LastSeenAt) < s.opts.IdleTimeout
+}
+
+// randomToken returns a random string of 260 bits.
+func randomToken() string {
+	return rand.Text() + rand.Text()
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
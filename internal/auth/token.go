@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	// TokenPrefix starts every API token, so leaked tokens are easy to find.
+	TokenPrefix = "smt_"
+	// shownPrefix is how much of a token is kept to tell tokens apart.
+	shownPrefix  = len(TokenPrefix) + 8
+	maxTokenName = 100
+)
+
+var (
+	// ErrInvalidToken is returned for unknown and expired tokens and for
+	// the tokens of disabled users.
+	ErrInvalidToken     = storage.NewError(storage.ErrInvalid, "invalid_token", "token is invalid or has expired")
+	ErrInvalidTokenName = storage.NewError(storage.ErrInvalid, "invalid_token_name", "token name must be 1 to 100 characters")
+	ErrInvalidScopes    = storage.NewError(storage.ErrInvalid, "invalid_scopes", "token needs at least one known scope")
+	ErrInvalidExpiry    = storage.NewError(storage.ErrInvalid, "invalid_expiry", "token expiry must be in the future")
+	// ErrScopeNotAllowed is returned for write scopes asked by users who
+	// may only read.
+	ErrScopeNotAllowed = storage.NewError(storage.ErrInvalid, "scope_not_allowed", "the role of the user does not allow the scope")
+)
+
+// Tokens manages the personal API tokens of the admin users. A token acts
+// for its user, limited to its scopes and to the current role of the user.
+// Like session tokens, only their hashes are stored.
+type Tokens struct {
+	store storage.APITokens
+	users storage.AdminUsers
+	now   func() time.Time
+}
+
+func NewTokens(store storage.APITokens, users storage.AdminUsers) *Tokens {
+	return &Tokens{
+		store: store,
+		users: users,
+		now:   time.Now,
+	}
+}
+
+// Create issues a token of user and returns it with its secret, which is
+// not stored and cannot be shown again. A nil expiresAt never expires.
+func (t *Tokens) Create(ctx context.Context, user model.AdminUser, name string, scopes []model.Scope, expiresAt *time.Time) (model.APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxTokenName {
+		return model.APIToken{}, "", ErrInvalidTokenName
+	}
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+	if len(scopes) == 0 {
+		return model.APIToken{}, "", ErrInvalidScopes
+	}
+	for _, s := range scopes {
+		if !s.Valid() {
+			return model.APIToken{}, "", ErrInvalidScopes
+		}
+		if s.Write() && !user.Role.Allows(model.RoleEditor) {
+			return model.APIToken{}, "", ErrScopeNotAllowed
+		}
+	}
+	now := t.now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return model.APIToken{}, "", ErrInvalidExpiry
+	}
+
+	secret := TokenPrefix + randomToken()
+	tok, err := t.store.Create(ctx, model.APIToken{
+		UserID:    user.ID,
+		Name:      name,
+		Prefix:    secret[:shownPrefix],
+		TokenHash: hashToken(secret),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return model.APIToken{}, "", err
+	}
+	return tok, secret, nil
+}
+
+// Authenticate returns the token with secret and its user, and records
+// its use from ip.
+func (t *Tokens) Authenticate(ctx context.Context, secret, ip string) (model.APIToken, model.AdminUser, error) {
+	if !strings.HasPrefix(secret, TokenPrefix) {
+		return model.APIToken{}, model.AdminUser{}, ErrInvalidToken
+	}
+	tok, err := t.store.GetByHash(ctx, hashToken(secret))
+	if errors.Is(err, storage.ErrNotFound) {
+		return model.APIToken{}, model.AdminUser{}, ErrInvalidToken
+	}
+	if err != nil {
+		return model.APIToken{}, model.AdminUser{}, err
+	}
+
+	now := t.now()
+	if tok.ExpiresAt != nil && !now.Before(*tok.ExpiresAt) {
+		return model.APIToken{}, model.AdminUser{}, ErrInvalidToken
+	}
+	user, err := t.users.Get(ctx, tok.UserID)
+	if errors.Is(err, storage.ErrNotFound) || err == nil && user.Disabled {
+		return model.APIToken{}, model.AdminUser{}, ErrInvalidToken
+	}
+	if err != nil {
+		return model.APIToken{}, model.AdminUser{}, err
+	}
+
+	if tok.LastUsedAt == nil || now.Sub(*tok.LastUsedAt) >= touchInterval || tok.LastUsedIP != ip {
+		if err := t.store.Touch(ctx, tok.ID, now, ip); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return model.APIToken{}, model.AdminUser{}, err
+		}
+		tok.LastUsedAt, tok.LastUsedIP = &now, ip
+	}
+	return tok, user, nil
+}
+
+// List returns the tokens of a user, the newest first.
+func (t *Tokens) List(ctx context.Context, userID uuid.UUID) ([]model.APIToken, error) {
+	return t.store.ListByUser(ctx, userID)
+}
+
+// Revoke deletes a token of a user.
+func (t *Tokens) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	return t.store.Delete(ctx, userID, id)
+}
@@ -0,0 +1,340 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTOTPRequired is returned by Verify without a code for users with
+	// two-factor authentication.
+	ErrTOTPRequired = storage.NewError(storage.ErrInvalid, "totp_required", "a one-time code is required")
+	ErrInvalidTOTP  = storage.NewError(storage.ErrInvalid, "invalid_totp", "invalid one-time or recovery code")
+	ErrTOTPEnabled  = storage.NewError(storage.ErrConflict, "totp_enabled", "two-factor authentication is already enabled")
+	ErrTOTPDisabled = storage.NewError(storage.ErrConflict, "totp_disabled", "two-factor authentication is not enabled")
+	// ErrTOTPNotStarted is returned by Confirm before Begin.
+	ErrTOTPNotStarted = storage.NewError(storage.ErrConflict, "totp_not_started", "two-factor enrollment has not been started")
+	// ErrTOTPEnforced keeps the users whose role requires two-factor
+	// authentication from turning it off.
+	ErrTOTPEnforced = storage.NewError(storage.ErrConflict, "totp_enforced", "the role of the user requires two-factor authentication")
+)
+
+const (
+	// totpPeriod, totpDigits and SHA-1 are what authenticator apps
+	// assume when the otpauth URI does not say otherwise.
+	totpPeriod = 30
+	totpDigits = 6
+	// totpModulo is 10^totpDigits.
+	totpModulo = 1_000_000
+	// totpSkew is how many steps a code may be off, for clocks running
+	// apart.
+	totpSkew = 1
+	// secretBytes is the size of the TOTP secrets, as RFC 4226 recommends.
+	secretBytes = 20
+	// recoveryCodes is the number of recovery codes of a user.
+	recoveryCodes = 10
+)
+
+var base32NoPad = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorOptions configures TwoFactor.
+type TwoFactorOptions struct {
+	// Issuer names the admin in authenticator apps.
+	Issuer string
+	// Roles must use two-factor authentication: until they enroll, their
+	// users can only enroll.
+	Roles []model.Role
+}
+
+// TwoFactor manages the two-factor authentication of the admin users with
+// time-based one-time codes (TOTP, RFC 6238) of authenticator apps, and
+// one-time recovery codes for lost phones. Wrong codes count towards the
+// lockout like wrong passwords.
+type TwoFactor struct {
+	store   storage.AdminUsers
+	lockout *Lockout
+	opts    TwoFactorOptions
+	log     *slog.Logger
+	now     func() time.Time
+}
+
+func NewTwoFactor(store storage.AdminUsers, lockout *Lockout, opts TwoFactorOptions, log *slog.Logger) *TwoFactor {
+	if opts.Issuer == "" {
+		opts.Issuer = "School Museum"
+	}
+	return &TwoFactor{
+		store:   store,
+		lockout: lockout,
+		opts:    opts,
+		log:     log,
+		now:     time.Now,
+	}
+}
+
+// Required reports whether users with role must use two-factor
+// authentication.
+func (t *TwoFactor) Required(role model.Role) bool {
+	return slices.Contains(t.opts.Roles, role)
+}
+
+// Begin starts the enrollment of user with a new secret and returns it
+// with its otpauth URI, for a QR code. Signing in needs no codes until
+// Confirm.
+func (t *TwoFactor) Begin(ctx context.Context, user model.AdminUser) (string, string, error) {
+	if user.TOTPEnabled {
+		return "", "", ErrTOTPEnabled
+	}
+	key := make([]byte, secretBytes)
+	_, _ = rand.Read(key)
+	user.TOTPSecret = base32NoPad.EncodeToString(key)
+	user.TOTPLastStep = 0
+	user.RecoveryCodes = nil
+	if _, err := t.store.UpdateTOTP(ctx, user); err != nil {
+		return "", "", err
+	}
+	return user.TOTPSecret, t.uri(user), nil
+}
+
+// Confirm enables two-factor authentication for user once code matches
+// the secret of Begin, and returns the recovery codes, which are not
+// stored and cannot be shown again.
+func (t *TwoFactor) Confirm(ctx context.Context, user model.AdminUser, code string) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, ErrTOTPEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTOTPNotStarted
+	}
+	attempt, err := t.checkLockout(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	defer attempt.Release()
+	step, ok := matchTOTP(user.TOTPSecret, code, t.now())
+	if !ok {
+		return nil, t.fail(ctx, attempt)
+	}
+	codes, hashes := newRecoveryCodes()
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	user.RecoveryCodes = hashes
+	if _, err := t.store.UpdateTOTP(ctx, user); err != nil {
+		return nil, err
+	}
+	t.log.Info("two-factor authentication enabled", slog.String("login", user.Login))
+	return codes, nil
+}
+
+// Verify checks the one-time code or a recovery code of user, who has
+// two-factor authentication, while signing in. Accepted codes cannot be
+// used again.
+func (t *TwoFactor) Verify(ctx context.Context, user model.AdminUser, code string) error {
+	if !user.TOTPEnabled {
+		return nil
+	}
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ErrTOTPRequired
+	}
+	attempt, err := t.checkLockout(ctx, user)
+	if err != nil {
+		return err
+	}
+	defer attempt.Release()
+
+	if step, ok := matchTOTP(user.TOTPSecret, code, t.now()); ok {
+		err = t.store.UseTOTPStep(ctx, user.ID, step)
+		if errors.Is(err, storage.ErrConflict) {
+			err = ErrInvalidTOTP
+		}
+	} else {
+		err = t.store.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(code))
+		if errors.Is(err, storage.ErrNotFound) {
+			err = ErrInvalidTOTP
+		}
+		if err == nil {
+			t.log.Warn("recovery code used",
+				slog.String("login", user.Login),
+				slog.Int("left", len(user.RecoveryCodes)-1))
+		}
+	}
+	if errors.Is(err, ErrInvalidTOTP) {
+		return t.fail(ctx, attempt)
+	}
+	if err == nil {
+		attempt.Succeed()
+	}
+	return err
+}
+
+// Disable turns two-factor authentication of user off if code is valid,
+// unless their role requires it.
+func (t *TwoFactor) Disable(ctx context.Context, user model.AdminUser, code string) error {
+	if !user.TOTPEnabled {
+		return ErrTOTPDisabled
+	}
+	if t.Required(user.Role) {
+		return ErrTOTPEnforced
+	}
+	if err := t.verifyEnabled(ctx, user, code); err != nil {
+		return err
+	}
+	if err := t.reset(ctx, user); err != nil {
+		return err
+	}
+	t.log.Info("two-factor authentication disabled", slog.String("login", user.Login))
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of user if code is
+// valid and returns the new ones.
+func (t *TwoFactor) RegenerateRecoveryCodes(ctx context.Context, user model.AdminUser, code string) ([]string, error) {
+	if !user.TOTPEnabled {
+		return nil, ErrTOTPDisabled
+	}
+	if err := t.verifyEnabled(ctx, user, code); err != nil {
+		return nil, err
+	}
+	// Verify may have used a recovery code or a time step.
+	user, err := t.store.Get(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes := newRecoveryCodes()
+	user.RecoveryCodes = hashes
+	if _, err := t.store.UpdateTOTP(ctx, user); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Reset turns two-factor authentication of a user off without a code, for
+// owners helping users who lost their phone and recovery codes.
+func (t *TwoFactor) Reset(ctx context.Context, id uuid.UUID) (model.AdminUser, error) {
+	user, err := t.store.Get(ctx, id)
+	if err != nil {
+		return model.AdminUser{}, err
+	}
+	if err := t.reset(ctx, user); err != nil {
+		return model.AdminUser{}, err
+	}
+	return t.store.Get(ctx, id)
+}
+
+// verifyEnabled is Verify for users already signed in, where a missing
+// code is as wrong as a bad one.
+func (t *TwoFactor) verifyEnabled(ctx context.Context, user model.AdminUser, code string) error {
+	err := t.Verify(ctx, user, code)
+	if errors.Is(err, ErrTOTPRequired) {
+		return ErrInvalidTOTP
+	}
+	return err
+}
+
+func (t *TwoFactor) reset(ctx context.Context, user model.AdminUser) error {
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	user.TOTPLastStep = 0
+	user.RecoveryCodes = nil
+	_, err := t.store.UpdateTOTP(ctx, user)
+	return err
+}
+
+// checkLockout reserves an attempt to enter a code of user; without a
+// lockout the attempt is nil.
+func (t *TwoFactor) checkLockout(ctx context.Context, user model.AdminUser) (*Attempt, error) {
+	if t.lockout == nil {
+		return nil, nil
+	}
+	ip, _ := ctx.Value(model.CtxKeyVisitorIP).(string)
+	return t.lockout.Check(user.Login, ip)
+}
+
+// fail counts a wrong code of the attempt and returns ErrInvalidTOTP, or
+// the lockout it caused.
+func (t *TwoFactor) fail(ctx context.Context, attempt *Attempt) error {
+	ua, _ := ctx.Value(model.CtxKeyVisitorUA).(string)
+	if err := attempt.Fail(ctx, ua); err != nil {
+		return err
+	}
+	return ErrInvalidTOTP
+}
+
+// uri returns the otpauth URI of the secret of user, which authenticator
+// apps read from a QR code.
+func (t *TwoFactor) uri(user model.AdminUser) string {
+	q := url.Values{}
+	q.Set("secret", user.TOTPSecret)
+	q.Set("issuer", t.opts.Issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + t.opts.Issuer + ":" + user.Login,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+// matchTOTP returns the time step within the skew of now whose code is
+// code.
+func matchTOTP(secret, code string, now time.Time) (int64, bool) {
+	key, err := base32NoPad.DecodeString(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// hotp returns the code of counter, see RFC 4226.
+func hotp(key []byte, counter int64) string {
+	mac := hmac.New(sha1.New, key)
+	_ = binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	n := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, n%totpModulo)
+}
+
+// newRecoveryCodes returns new recovery codes, like abcde-fghij, and their
+// hashes.
+func newRecoveryCodes() ([]string, []string) {
+	codes := make([]string, recoveryCodes)
+	hashes := make([]string, recoveryCodes)
+	for i := range codes {
+		text := strings.ToLower(rand.Text()[:10])
+		codes[i] = text[:5] + "-" + text[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes
+}
+
+// hashRecoveryCode hashes code ignoring case, spaces and dashes.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return hashToken(code)
+}
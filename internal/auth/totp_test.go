@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage/memory"
+)
+
+// rfcKey is the secret of the test vectors of RFC 4226 and RFC 6238.
+var rfcKey = []byte("12345678901234567890")
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226, appendix D.
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range want {
+		if got := hotp(rfcKey, int64(counter)); got != code {
+			t.Errorf("hotp(%d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret := base32NoPad.EncodeToString(rfcKey)
+	// RFC 6238, appendix B, SHA-1; the codes there have 8 digits, ours
+	// are their last 6.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		now := time.Unix(tt.unix, 0)
+		step, ok := matchTOTP(secret, tt.code, now)
+		if !ok || step != tt.unix/totpPeriod {
+			t.Errorf("matchTOTP(%s) at %d = %d, %v, want step %d", tt.code, tt.unix, step, ok, tt.unix/totpPeriod)
+		}
+	}
+
+	// One step of skew either way, and no more.
+	now := time.Unix(1111111109, 0)
+	step := now.Unix() / totpPeriod
+	for d := int64(-2); d <= 2; d++ {
+		got, ok := matchTOTP(secret, hotp(rfcKey, step+d), now)
+		if want := d >= -totpSkew && d <= totpSkew; ok != want || ok && got != step+d {
+			t.Errorf("code %+d steps away = %d, %v, want match %v", d, got, ok, want)
+		}
+	}
+
+	for _, code := range []string{"", "08180", "0818040", "081805", "abcdef"} {
+		if _, ok := matchTOTP(secret, code, now); ok {
+			t.Errorf("matchTOTP(%q) matched", code)
+		}
+	}
+	if _, ok := matchTOTP("not base32!", "081804", now); ok {
+		t.Error("matchTOTP with an invalid secret matched")
+	}
+}
+
+// enroll returns two-factor authentication and a user with it enabled,
+// with its recovery codes. Codes are entered from 10.0.0.1.
+func enroll(t *testing.T, opts LockoutOptions) (context.Context, *TwoFactor, *Lockout, *clock, model.AdminUser, []string) {
+	t.Helper()
+	ctx := context.WithValue(context.Background(), model.CtxKeyVisitorIP, "10.0.0.1")
+	db := memory.NewDB()
+	store := memory.NewAdminUserStorage(db)
+	l, c := newLockout(t, opts)
+	users := NewUsers(store, memory.NewAdminSessionStorage(db), db, l, slog.New(slog.DiscardHandler))
+	user, err := users.Add(ctx, "boss", "correct horse", model.RoleOwner)
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	tf := NewTwoFactor(store, l, TwoFactorOptions{}, slog.New(slog.DiscardHandler))
+	tf.now = c.now
+	if _, _, err := tf.Begin(ctx, user); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if user, err = store.Get(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	codes, err := tf.Confirm(ctx, user, code(t, user, c.now()))
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if user, err = store.Get(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	return ctx, tf, l, c, user, codes
+}
+
+// code returns the one-time code of user at now.
+func code(t *testing.T, user model.AdminUser, now time.Time) string {
+	t.Helper()
+	key, err := base32NoPad.DecodeString(user.TOTPSecret)
+	if err != nil {
+		t.Fatalf("secret: %v", err)
+	}
+	return hotp(key, now.Unix()/totpPeriod)
+}
+
+func TestVerifyReplay(t *testing.T) {
+	ctx, tf, _, c, user, _ := enroll(t, LockoutOptions{Threshold: 100})
+	if len(user.RecoveryCodes) != recoveryCodes || !user.TOTPEnabled {
+		t.Fatalf("user after Confirm = %+v, want two-factor enabled with %d recovery codes", user, recoveryCodes)
+	}
+
+	// The code of Confirm is used up.
+	if err := tf.Verify(ctx, user, code(t, user, c.now())); !errors.Is(err, ErrInvalidTOTP) {
+		t.Errorf("Verify with the code of Confirm: %v, want ErrInvalidTOTP", err)
+	}
+	c.advance(totpPeriod * time.Second)
+	next := code(t, user, c.now())
+	if err := tf.Verify(ctx, user, next); err != nil {
+		t.Fatalf("Verify with the next code: %v", err)
+	}
+	if err := tf.Verify(ctx, user, next); !errors.Is(err, ErrInvalidTOTP) {
+		t.Errorf("Verify with the code again: %v, want ErrInvalidTOTP", err)
+	}
+	// An earlier code within the skew is no good either once a later
+	// one is used.
+	c.advance(totpPeriod * time.Second)
+	later := code(t, user, c.now().Add(totpPeriod*time.Second))
+	if err := tf.Verify(ctx, user, later); err != nil {
+		t.Fatalf("Verify with a code a step ahead: %v", err)
+	}
+	if err := tf.Verify(ctx, user, code(t, user, c.now())); !errors.Is(err, ErrInvalidTOTP) {
+		t.Errorf("Verify with a code before the used one: %v, want ErrInvalidTOTP", err)
+	}
+
+	if err := tf.Verify(ctx, user, " "); !errors.Is(err, ErrTOTPRequired) {
+		t.Errorf("Verify without a code: %v, want ErrTOTPRequired", err)
+	}
+}
+
+func TestVerifyRecoveryCode(t *testing.T) {
+	ctx, tf, _, _, user, codes := enroll(t, LockoutOptions{Threshold: 100})
+	if err := tf.Verify(ctx, user, codes[3]); err != nil {
+		t.Fatalf("Verify with a recovery code: %v", err)
+	}
+	if err := tf.Verify(ctx, user, codes[3]); !errors.Is(err, ErrInvalidTOTP) {
+		t.Errorf("Verify with a used recovery code: %v, want ErrInvalidTOTP", err)
+	}
+	// Case, spaces and dashes do not matter.
+	loose := " " + strings.ToUpper(strings.ReplaceAll(codes[4], "-", " ")) + " "
+	if err := tf.Verify(ctx, user, loose); err != nil {
+		t.Errorf("Verify with %q: %v", loose, err)
+	}
+	user, err := tf.store.Get(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(user.RecoveryCodes) != recoveryCodes-2 {
+		t.Errorf("%d recovery codes left, want %d", len(user.RecoveryCodes), recoveryCodes-2)
+	}
+}
+
+func TestVerifyLockout(t *testing.T) {
+	ctx, tf, l, c, user, codes := enroll(t, LockoutOptions{Threshold: 2, Duration: totpPeriod * time.Second})
+	c.advance(totpPeriod * time.Second)
+	for i := range 2 {
+		if err := tf.Verify(ctx, user, "000000"); !errors.Is(err, ErrInvalidTOTP) {
+			t.Fatalf("wrong code %d: %v, want ErrInvalidTOTP", i+1, err)
+		}
+	}
+	var locked *LockedError
+	if err := tf.Verify(ctx, user, "wrong-code"); !errors.As(err, &locked) || locked.RetryAfter != totpPeriod*time.Second {
+		t.Fatalf("wrong code past the threshold: %v, want a lockout of 30s", err)
+	}
+	// The right code waits too, and it is not used up: it is still good a
+	// step later, when the lockout ends.
+	right := code(t, user, c.now())
+	if err := tf.Verify(ctx, user, right); !errors.Is(err, ErrLocked) {
+		t.Errorf("right code while locked out: %v, want ErrLocked", err)
+	}
+	if d := lockedFor(t, l, "boss", "10.0.0.2"); d != totpPeriod*time.Second {
+		t.Errorf("password sign-in locked out for %s, want 30s", d)
+	}
+
+	c.advance(totpPeriod * time.Second)
+	if err := tf.Verify(ctx, user, right); err != nil {
+		t.Fatalf("right code after the lockout: %v", err)
+	}
+	if _, ok := l.counters[loginKey("boss")]; ok {
+		t.Error("the login counter is kept after a right code")
+	}
+	if err := tf.Verify(ctx, user, codes[0]); err != nil {
+		t.Errorf("recovery code: %v", err)
+	}
+}
@@ -12,14 +12,25 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT" env-default:"10s" koanf:"read_timeout"`
 	WriteTimeout    time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" env-default:"30s" koanf:"write_timeout"`
 	IdleTimeout     time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" env-default:"30s" koanf:"idle_timeout"`
+	// TrustedProxies are the reverse proxies, as addresses or CIDR
+	// prefixes, whose X-Forwarded-For and X-Real-Ip headers tell the
+	// address of the client. The headers of other peers are ignored.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES" koanf:"trusted_proxies"`
 }
 
+// Storage drivers supported by StorageConfig.Driver.
+const (
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
+)
+
 type StorageConfig struct {
-	Host string `yaml:"host" env:"DB_HOST" env-default:"localhost" koanf:"host"`
-	Port string `yaml:"port" env:"DB_PORT" env-default:"5432" koanf:"port"`
-	User string `yaml:"user" env:"DB_USER" env-default:"user" koanf:"user"`
-	Pass string `yaml:"pass" env:"DB_PASS" env-default:"password" koanf:"pass"`
-	Name string `yaml:"name" env:"DB_NAME" env-default:"school_museum" koanf:"name"`
+	Driver string `yaml:"driver" env:"DB_DRIVER" env-default:"postgres" koanf:"driver"`
+	Host   string `yaml:"host" env:"DB_HOST" env-default:"localhost" koanf:"host"`
+	Port   string `yaml:"port" env:"DB_PORT" env-default:"5432" koanf:"port"`
+	User   string `yaml:"user" env:"DB_USER" env-default:"user" koanf:"user"`
+	Pass   string `yaml:"pass" env:"DB_PASS" env-default:"password" koanf:"pass"`
+	Name   string `yaml:"name" env:"DB_NAME" env-default:"school_museum" koanf:"name"`
 }
 
 func (s StorageConfig) DSN() string {
@@ -40,11 +51,135 @@ type Config struct {
 	Storage StorageConfig `yaml:"storage" env:"STORAGE" koanf:"storage"`
 	Logger  LoggerConfig  `yaml:"logger" env:"LOGGER" koanf:"logger"`
 	Admin   AdminConfig   `yaml:"admin" env:"ADMIN" koanf:"admin"`
+	Trash   TrashConfig   `yaml:"trash" env:"TRASH" koanf:"trash"`
+	Media   MediaConfig   `yaml:"media" env:"MEDIA" koanf:"media"`
+	Egress  EgressConfig  `yaml:"egress" env:"EGRESS" koanf:"egress"`
 }
 
+// AdminConfig controls the sign-in to the admin.
 type AdminConfig struct {
+	// Login and Password are the owner created on the first start, when
+	// there are no admin users yet. Later the users are managed in the
+	// admin or with the user command, and these credentials are not used.
 	Login    string `yaml:"login" env:"ADMIN_LOGIN" env-default:"admin" koanf:"login"`
 	Password string `yaml:"password" env:"ADMIN_PASSWORD" env-default:"admin" koanf:"password"`
+
+	// SessionIdleTimeout ends a login form session without requests for that long.
+	SessionIdleTimeout time.Duration `yaml:"session_idle_timeout" env:"ADMIN_SESSION_IDLE_TIMEOUT" env-default:"30m" koanf:"session_idle_timeout"`
+	// SessionMaxAge ends a login form session that long after the sign-in.
+	SessionMaxAge time.Duration `yaml:"session_max_age" env:"ADMIN_SESSION_MAX_AGE" env-default:"12h" koanf:"session_max_age"`
+	// SessionPurgeInterval is how often ended sessions and the failed
+	// sign-ins past LoginFailureRetention are deleted.
+	SessionPurgeInterval time.Duration `yaml:"session_purge_interval" env:"ADMIN_SESSION_PURGE_INTERVAL" env-default:"1h" koanf:"session_purge_interval"`
+	// SecureCookie sends the session cookie over HTTPS only. Browsers
+	// accept such cookies from http://localhost as well; installs served
+	// over plain HTTP on other addresses must turn it off, or signing in
+	// to the admin fails.
+	SecureCookie bool `yaml:"secure_cookie" env:"ADMIN_SECURE_COOKIE" env-default:"true" koanf:"secure_cookie"`
+
+	// LockoutThreshold is the number of failed sign-ins to a login before
+	// it is locked out; LockoutIPThreshold is the same for an address.
+	LockoutThreshold   int `yaml:"lockout_threshold" env:"ADMIN_LOCKOUT_THRESHOLD" env-default:"5" koanf:"lockout_threshold"`
+	LockoutIPThreshold int `yaml:"lockout_ip_threshold" env:"ADMIN_LOCKOUT_IP_THRESHOLD" env-default:"20" koanf:"lockout_ip_threshold"`
+	// LockoutDuration is the first lockout; every further failure doubles
+	// it up to LockoutMaxDuration.
+	LockoutDuration    time.Duration `yaml:"lockout_duration" env:"ADMIN_LOCKOUT_DURATION" env-default:"30s" koanf:"lockout_duration"`
+	LockoutMaxDuration time.Duration `yaml:"lockout_max_duration" env:"ADMIN_LOCKOUT_MAX_DURATION" env-default:"15m" koanf:"lockout_max_duration"`
+	// LoginFailureRetention is how long failed sign-ins stay in the
+	// security log.
+	LoginFailureRetention time.Duration `yaml:"login_failure_retention" env:"ADMIN_LOGIN_FAILURE_RETENTION" env-default:"720h" koanf:"login_failure_retention"`
+
+	// TOTPIssuer names the admin in authenticator apps.
+	TOTPIssuer string `yaml:"totp_issuer" env:"ADMIN_TOTP_ISSUER" env-default:"School Museum" koanf:"totp_issuer"`
+	// TOTPRoles must use two-factor authentication; until they enroll,
+	// their users can only enroll.
+	TOTPRoles []string `yaml:"totp_roles" env:"ADMIN_TOTP_ROLES" koanf:"totp_roles"`
+}
+
+// TrashConfig controls how long soft-deleted content is kept.
+// A zero Retention keeps it forever and disables the purge job.
+type TrashConfig struct {
+	Retention     time.Duration `yaml:"retention" env:"TRASH_RETENTION" env-default:"720h" koanf:"retention"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"TRASH_PURGE_INTERVAL" env-default:"1h" koanf:"purge_interval"`
+}
+
+// MediaConfig controls the local storage of uploaded media files.
+type MediaConfig struct {
+	Dir string `yaml:"dir" env:"MEDIA_DIR" env-default:"media" koanf:"dir"`
+	// MaxSize is the upload size limit in megabytes.
+	MaxSize int64 `yaml:"max_size" env:"MEDIA_MAX_SIZE" env-default:"20" koanf:"max_size"`
+	// VariantWorkers is the number of workers generating resized image
+	// variants. Zero disables the generation.
+	VariantWorkers int `yaml:"variant_workers" env:"MEDIA_VARIANT_WORKERS" env-default:"2" koanf:"variant_workers"`
+	// VariantInterval is how often images left without variants are retried.
+	VariantInterval time.Duration `yaml:"variant_interval" env:"MEDIA_VARIANT_INTERVAL" env-default:"10m" koanf:"variant_interval"`
+
+	// ResolveCacheSize is the number of resolved external links kept in memory.
+	ResolveCacheSize int `yaml:"resolve_cache_size" env:"MEDIA_RESOLVE_CACHE_SIZE" env-default:"1000" koanf:"resolve_cache_size"`
+	// ResolveTTL is how long a resolved external link is reused.
+	ResolveTTL time.Duration `yaml:"resolve_ttl" env:"MEDIA_RESOLVE_TTL" env-default:"6h" koanf:"resolve_ttl"`
+	// ResolveNegativeTTL is how long a link that failed to resolve is not retried.
+	ResolveNegativeTTL time.Duration `yaml:"resolve_negative_ttl" env:"MEDIA_RESOLVE_NEGATIVE_TTL" env-default:"5m" koanf:"resolve_negative_ttl"`
+	// ResolvePersist keeps resolved links in the database across restarts.
+	ResolvePersist bool `yaml:"resolve_persist" env:"MEDIA_RESOLVE_PERSIST" env-default:"true" koanf:"resolve_persist"`
+
+	// ProxyDir is the disk cache of external media served by the proxy.
+	ProxyDir string `yaml:"proxy_dir" env:"MEDIA_PROXY_DIR" env-default:"cache/media-proxy" koanf:"proxy_dir"`
+	// ProxyMaxSize is the size limit of a proxied file in megabytes.
+	ProxyMaxSize int64 `yaml:"proxy_max_size" env:"MEDIA_PROXY_MAX_SIZE" env-default:"50" koanf:"proxy_max_size"`
+	// ProxyCacheSize is the size the proxy cache is trimmed to in megabytes.
+	ProxyCacheSize int64 `yaml:"proxy_cache_size" env:"MEDIA_PROXY_CACHE_SIZE" env-default:"1024" koanf:"proxy_cache_size"`
+	// ProxyTTL is how long a proxied file is served before it is downloaded again.
+	ProxyTTL time.Duration `yaml:"proxy_ttl" env:"MEDIA_PROXY_TTL" env-default:"24h" koanf:"proxy_ttl"`
+	// ProxyTimeout bounds the download of a proxied file, which may be a
+	// large video.
+	ProxyTimeout time.Duration `yaml:"proxy_timeout" env:"MEDIA_PROXY_TIMEOUT" env-default:"2m" koanf:"proxy_timeout"`
+	// ProxyPurgeInterval is how often the proxy cache is cleaned up.
+	ProxyPurgeInterval time.Duration `yaml:"proxy_purge_interval" env:"MEDIA_PROXY_PURGE_INTERVAL" env-default:"1h" koanf:"proxy_purge_interval"`
+	// ProxyHosts are the hosts, with their subdomains, that visitors may
+	// proxy direct links to. Links to the supported services need not be
+	// listed.
+	ProxyHosts []string `yaml:"proxy_hosts" env:"MEDIA_PROXY_HOSTS" koanf:"proxy_hosts"`
+	// ProxyRate is the number of proxy requests allowed from one address
+	// per minute. Zero disables the limit.
+	ProxyRate int `yaml:"proxy_rate" env:"MEDIA_PROXY_RATE" env-default:"300" koanf:"proxy_rate"`
+	// ImportInterval is how often pending imports of external images are
+	// retried when they cannot be listed.
+	ImportInterval time.Duration `yaml:"import_interval" env:"MEDIA_IMPORT_INTERVAL" env-default:"1m" koanf:"import_interval"`
+
+	// LinkCheckInterval is how often the image links of news and exhibits
+	// are checked for broken ones. Zero disables the job.
+	LinkCheckInterval time.Duration `yaml:"link_check_interval" env:"MEDIA_LINK_CHECK_INTERVAL" env-default:"24h" koanf:"link_check_interval"`
+	// LinkCheckWorkers is the number of links checked at once.
+	LinkCheckWorkers int `yaml:"link_check_workers" env:"MEDIA_LINK_CHECK_WORKERS" env-default:"4" koanf:"link_check_workers"`
+}
+
+// MaxBytes returns the upload size limit in bytes.
+func (m MediaConfig) MaxBytes() int64 {
+	return m.MaxSize << 20
+}
+
+// EgressConfig limits the requests the server makes to external links.
+type EgressConfig struct {
+	Timeout      time.Duration `yaml:"timeout" env:"EGRESS_TIMEOUT" env-default:"10s" koanf:"timeout"`
+	MaxRedirects int           `yaml:"max_redirects" env:"EGRESS_MAX_REDIRECTS" env-default:"5" koanf:"max_redirects"`
+	// MaxResponseSize is the response size limit in megabytes.
+	MaxResponseSize int64 `yaml:"max_response_size" env:"EGRESS_MAX_RESPONSE_SIZE" env-default:"3" koanf:"max_response_size"`
+	// MaxPerHost is the number of concurrent requests to one host.
+	MaxPerHost int `yaml:"max_per_host" env:"EGRESS_MAX_PER_HOST" env-default:"4" koanf:"max_per_host"`
+	// ContentTypes are the accepted Content-Type prefixes of responses.
+	ContentTypes []string `yaml:"content_types" env:"EGRESS_CONTENT_TYPES" koanf:"content_types"`
+	// AllowPrivate lets the server fetch loopback and private addresses.
+	// Only for development.
+	AllowPrivate bool `yaml:"allow_private" env:"EGRESS_ALLOW_PRIVATE" env-default:"false" koanf:"allow_private"`
+	// AllowedNetworks are internal networks, in CIDR notation, the server
+	// may fetch anyway.
+	AllowedNetworks []string `yaml:"allowed_networks" env:"EGRESS_ALLOWED_NETWORKS" koanf:"allowed_networks"`
+}
+
+// MaxResponseBytes returns the response size limit in bytes.
+func (e EgressConfig) MaxResponseBytes() int64 {
+	return e.MaxResponseSize << 20
 }
 
 func (srv *ServerConfig) ServerAddr() string {
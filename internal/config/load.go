@@ -83,6 +83,12 @@ func Load[T any](ft filetype) (*T, error) {
 				newKey = strings.Replace(strings.ToLower(k), "log_", "logger.", 1)
 			case strings.HasPrefix(k, "ADMIN_"):
 				newKey = strings.Replace(strings.ToLower(k), "admin_", "admin.", 1)
+			case strings.HasPrefix(k, "TRASH_"):
+				newKey = strings.Replace(strings.ToLower(k), "trash_", "trash.", 1)
+			case strings.HasPrefix(k, "MEDIA_"):
+				newKey = strings.Replace(strings.ToLower(k), "media_", "media.", 1)
+			case strings.HasPrefix(k, "EGRESS_"):
+				newKey = strings.Replace(strings.ToLower(k), "egress_", "egress.", 1)
 			default:
 				return "", nil
 			}
@@ -0,0 +1,218 @@
+// Package egress builds the HTTP client for requests the server makes on
+// behalf of visitors: resolving external media links, proxying and
+// importing files. Such requests go to addresses taken from user input, so
+// the client refuses to connect to loopback, private and other internal
+// addresses, limits redirects, response sizes and content types, and caps
+// the number of concurrent requests to one host.
+package egress
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/storage"
+)
+
+var (
+	ErrBlocked          = storage.NewError(storage.ErrInvalid, "blocked_destination", "destination is not allowed")
+	ErrTooManyRedirects = storage.NewError(storage.ErrInvalid, "too_many_redirects", "too many redirects")
+	ErrTooLarge         = storage.NewError(storage.ErrInvalid, "response_too_large", "response is too large")
+	ErrContentType      = storage.NewError(storage.ErrInvalid, "unexpected_content_type", "unexpected content type")
+)
+
+// Options configures the client. Zero values take the defaults.
+type Options struct {
+	// Timeout bounds a whole request including the body, 10 seconds by default.
+	Timeout time.Duration
+	// MaxRedirects is the number of redirects followed, 5 by default.
+	MaxRedirects int
+	// MaxResponseSize is the body size limit in bytes, 3 MB by default.
+	// WithMaxSize overrides it for a request.
+	MaxResponseSize int64
+	// MaxPerHost is the number of concurrent requests to one host, 4 by default.
+	MaxPerHost int
+	// ContentTypes are the accepted Content-Type prefixes of successful
+	// responses, by default HTML, JSON, images and videos.
+	// WithContentTypes overrides them for a request.
+	ContentTypes []string
+	// AllowPrivate lets the client connect to internal addresses, for
+	// development setups that serve media locally.
+	AllowPrivate bool
+	// AllowedNetworks are internal networks the client may connect to
+	// anyway, for example an internal media server.
+	AllowedNetworks []netip.Prefix
+}
+
+func (o Options) withDefaults() Options {
+	if o.Timeout <= 0 {
+		o.Timeout = 10 * time.Second
+	}
+	if o.MaxRedirects <= 0 {
+		o.MaxRedirects = 5
+	}
+	if o.MaxResponseSize <= 0 {
+		o.MaxResponseSize = 3 << 20
+	}
+	if o.MaxPerHost <= 0 {
+		o.MaxPerHost = 4
+	}
+	if len(o.ContentTypes) == 0 {
+		o.ContentTypes = []string{"text/html", "application/json", "image/", "video/"}
+	}
+	return o
+}
+
+// ParseNetworks parses CIDR prefixes or single addresses, such as
+// Options.AllowedNetworks.
+func ParseNetworks(networks []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(networks))
+	for _, n := range networks {
+		if p, err := netip.ParsePrefix(n); err == nil {
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		a, err := netip.ParseAddr(n)
+		if err != nil {
+			return nil, fmt.Errorf("network %q: %w", n, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(a, a.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// NewClient returns an HTTP client that enforces opts.
+func NewClient(opts Options) *http.Client {
+	opts = opts.withDefaults()
+
+	dialer := &net.Dialer{
+		Timeout:   opts.Timeout,
+		KeepAlive: 30 * time.Second,
+		// The address is checked after name resolution, right before the
+		// connection, so a name that resolves to a public address for a
+		// check and to an internal one for the request cannot get through.
+		Control: opts.control,
+	}
+
+	return &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &transport{
+			next: &http.Transport{
+				// A proxy would connect on our behalf, past the dialer.
+				Proxy:                 nil,
+				DialContext:           dialer.DialContext,
+				ForceAttemptHTTP2:     true,
+				MaxIdleConns:          100,
+				IdleConnTimeout:       90 * time.Second,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ExpectContinueTimeout: time.Second,
+			},
+			opts:  opts,
+			hosts: newHostLimiter(opts.MaxPerHost),
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// Every hop goes through the transport and the dialer again.
+			if len(via) > opts.MaxRedirects {
+				return fmt.Errorf("%d redirects: %w", len(via), ErrTooManyRedirects)
+			}
+			return nil
+		},
+	}
+}
+
+// control refuses connections to addresses opts does not allow.
+func (o Options) control(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%s: %w", address, ErrBlocked)
+	}
+	if !o.allowed(ap.Addr().Unmap()) {
+		return fmt.Errorf("%s: %w", ap.Addr(), ErrBlocked)
+	}
+	return nil
+}
+
+// reserved are the special-purpose networks not covered by the netip
+// predicates: shared address space, benchmarking, documentation, the
+// reserved class E, and the IPv6 transition networks NAT64, 6to4 and
+// Teredo, whose addresses embed IPv4 ones that may be internal.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("2001::/32"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+func (o Options) allowed(a netip.Addr) bool {
+	if slices.ContainsFunc(o.AllowedNetworks, func(p netip.Prefix) bool { return p.Contains(a) }) {
+		return true
+	}
+	return o.AllowPrivate || public(a)
+}
+
+// public reports whether a is a global unicast address.
+func public(a netip.Addr) bool {
+	if !a.IsValid() || a.IsUnspecified() || a.IsLoopback() || a.IsPrivate() ||
+		a.IsLinkLocalUnicast() || a.IsLinkLocalMulticast() || a.IsInterfaceLocalMulticast() || a.IsMulticast() {
+		return false
+	}
+	return !slices.ContainsFunc(reserved, func(p netip.Prefix) bool { return p.Contains(a) })
+}
+
+type ctxKey int
+
+const (
+	hostsKey ctxKey = iota
+	maxSizeKey
+	contentTypesKey
+)
+
+// WithHosts limits the requests made with ctx, redirects included, to
+// hosts and their subdomains.
+func WithHosts(ctx context.Context, hosts ...string) context.Context {
+	return context.WithValue(ctx, hostsKey, hosts)
+}
+
+// WithMaxSize sets the body size limit of the requests made with ctx.
+func WithMaxSize(ctx context.Context, n int64) context.Context {
+	return context.WithValue(ctx, maxSizeKey, n)
+}
+
+// WithContentTypes sets the accepted Content-Type prefixes of the requests
+// made with ctx.
+func WithContentTypes(ctx context.Context, types ...string) context.Context {
+	return context.WithValue(ctx, contentTypesKey, types)
+}
+
+// HostAllowed reports whether host is one of hosts or their subdomain, as
+// WithHosts checks it.
+func HostAllowed(host string, hosts []string) bool {
+	host = strings.ToLower(host)
+	return slices.ContainsFunc(hosts, func(h string) bool {
+		return host == h || strings.HasSuffix(host, "."+h)
+	})
+}
+
+// contentTypeAllowed reports whether the media type of ct starts with one
+// of types. A missing Content-Type is not allowed.
+func contentTypeAllowed(ct string, types []string) bool {
+	mt, _, _ := strings.Cut(strings.ToLower(ct), ";")
+	mt = strings.TrimSpace(mt)
+	return mt != "" && slices.ContainsFunc(types, func(t string) bool { return strings.HasPrefix(mt, t) })
+}
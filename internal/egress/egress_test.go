@@ -0,0 +1,240 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestControl(t *testing.T) {
+	tests := []struct {
+		addr string
+		opts Options
+		ok   bool
+	}{
+		{addr: "93.184.216.34:443", ok: true},
+		{addr: "[2606:2800:220:1:248:1893:25c8:1946]:443", ok: true},
+
+		{addr: "127.0.0.1:80"},
+		{addr: "[::1]:80"},
+		{addr: "0.0.0.0:80"},
+		{addr: "10.1.2.3:80"},
+		{addr: "172.16.0.1:80"},
+		{addr: "192.168.1.1:80"},
+		{addr: "[fd00::1]:80"},
+		{addr: "169.254.1.1:80"},
+		{addr: "[fe80::1]:80"},
+		// Cloud metadata services.
+		{addr: "169.254.169.254:80"},
+		{addr: "[fd00:ec2::254]:80"},
+		{addr: "100.100.100.200:80"},
+		{addr: "224.0.0.1:80"},
+		{addr: "255.255.255.255:80"},
+		// Internal IPv4 addresses embedded in IPv6 ones.
+		{addr: "[::ffff:127.0.0.1]:80"},
+		{addr: "[::ffff:169.254.169.254]:80"},
+		{addr: "[64:ff9b::a9fe:a9fe]:80"},
+		{addr: "[64:ff9b::7f00:1]:80"},
+		{addr: "[2002:7f00:1::]:80"},
+		{addr: "[2002:a9fe:a9fe::1]:80"},
+		{addr: "[2001:0:4136:e378:8000:63bf:80ff:fffe]:80"},
+		{addr: "not an address"},
+
+		{addr: "127.0.0.1:80", opts: Options{AllowPrivate: true}, ok: true},
+		{addr: "10.1.2.3:80", opts: Options{AllowedNetworks: []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}}, ok: true},
+		{addr: "10.2.2.3:80", opts: Options{AllowedNetworks: []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}}},
+		{addr: "[::ffff:10.1.2.3]:80", opts: Options{AllowedNetworks: []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}}, ok: true},
+	}
+	for _, tt := range tests {
+		err := tt.opts.control("tcp", tt.addr, nil)
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v, want allowed", tt.addr, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrBlocked) {
+			t.Errorf("%s: %v, want ErrBlocked", tt.addr, err)
+		}
+	}
+}
+
+func TestParseNetworks(t *testing.T) {
+	got, err := ParseNetworks([]string{"10.1.2.3/16", "192.168.1.10", "fd00::1"})
+	if err != nil {
+		t.Fatalf("ParseNetworks: %v", err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.1.0.0/16"),
+		netip.MustParsePrefix("192.168.1.10/32"),
+		netip.MustParsePrefix("fd00::1/128"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ParseNetworks = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ParseNetworks = %v, want %v", got, want)
+		}
+	}
+	if _, err := ParseNetworks([]string{"school.local"}); err == nil {
+		t.Error("ParseNetworks of a name succeeded")
+	}
+}
+
+// newServer returns a server on 127.0.0.1 and a client allowed to reach
+// it, and only it.
+func newServer(t *testing.T, opts Options, h http.HandlerFunc) (*httptest.Server, *http.Client) {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	opts.AllowedNetworks = []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}
+	return srv, NewClient(opts)
+}
+
+func get(ctx context.Context, c *http.Client, rawURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	return string(b), err
+}
+
+func TestRedirects(t *testing.T) {
+	var port string
+	srv, c := newServer(t, Options{MaxRedirects: 3}, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/internal":
+			// The same server, by an address the client may not reach.
+			http.Redirect(w, r, "http://[::1]:"+port+"/ok", http.StatusFound)
+		case "/other-host":
+			http.Redirect(w, r, "http://localhost:"+port+"/ok", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/chain":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, "ok")
+		}
+	})
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port = u.Port()
+	ctx := context.Background()
+
+	if body, err := get(ctx, c, srv.URL+"/chain"); err != nil || body != "ok" {
+		t.Errorf("redirect to an allowed address = %q, %v", body, err)
+	}
+	// Every hop is checked again.
+	if _, err := get(ctx, c, srv.URL+"/internal"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("redirect to an internal address: %v, want ErrBlocked", err)
+	}
+	if _, err := get(WithHosts(ctx, "127.0.0.1"), c, srv.URL+"/other-host"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("redirect to a host out of WithHosts: %v, want ErrBlocked", err)
+	}
+	if _, err := get(ctx, c, srv.URL+"/loop"); !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("redirect loop: %v, want ErrTooManyRedirects", err)
+	}
+	if _, err := get(ctx, c, "ftp://127.0.0.1/"); err == nil {
+		t.Error("request with another scheme succeeded")
+	}
+}
+
+func TestWithHosts(t *testing.T) {
+	srv, c := newServer(t, Options{}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+	})
+	ctx := context.Background()
+	if _, err := get(WithHosts(ctx, "media.example"), c, srv.URL); !errors.Is(err, ErrBlocked) {
+		t.Errorf("host out of WithHosts: %v, want ErrBlocked", err)
+	}
+	if _, err := get(WithHosts(ctx, "127.0.0.1"), c, srv.URL); err != nil {
+		t.Errorf("host in WithHosts: %v", err)
+	}
+	if !HostAllowed("cdn.Media.example", []string{"media.example"}) || HostAllowed("evilmedia.example", []string{"media.example"}) {
+		t.Error("HostAllowed does not match subdomains only")
+	}
+}
+
+func TestWithMaxSize(t *testing.T) {
+	body := strings.Repeat("x", 100)
+	srv, c := newServer(t, Options{MaxResponseSize: 1000}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/chunked" {
+			// Flushing before the end leaves the length unknown.
+			io.WriteString(w, body[:50])
+			w.(http.Flusher).Flush()
+			io.WriteString(w, body[50:])
+			return
+		}
+		io.WriteString(w, body)
+	})
+	ctx := context.Background()
+
+	if got, err := get(ctx, c, srv.URL); err != nil || got != body {
+		t.Errorf("body under the limit = %d bytes, %v", len(got), err)
+	}
+	if got, err := get(WithMaxSize(ctx, 100), c, srv.URL+"/chunked"); err != nil || got != body {
+		t.Errorf("body of the size of the limit = %d bytes, %v", len(got), err)
+	}
+	if _, err := get(WithMaxSize(ctx, 99), c, srv.URL); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("body with a length over the limit: %v, want ErrTooLarge", err)
+	}
+	if _, err := get(WithMaxSize(ctx, 99), c, srv.URL+"/chunked"); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("body without a length over the limit: %v, want ErrTooLarge", err)
+	}
+}
+
+func TestWithContentTypes(t *testing.T) {
+	srv, c := newServer(t, Options{}, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		// Without a type the server would sniff one.
+		w.Header()["Content-Type"] = nil
+		if ct := r.URL.Query().Get("type"); ct != "" {
+			w.Header().Set("Content-Type", ct)
+		}
+		io.WriteString(w, "x")
+	})
+	ctx := context.Background()
+	media := WithContentTypes(ctx, "image/", "video/")
+	tests := []struct {
+		ctx   context.Context
+		path  string
+		ctype string
+		ok    bool
+	}{
+		{ctx, "/", "text/html; charset=utf-8", true},
+		{ctx, "/", "IMAGE/PNG", true},
+		{ctx, "/", "application/octet-stream", false},
+		{ctx, "/", "", false},
+		{media, "/", "image/png", true},
+		{media, "/", "video/mp4", true},
+		{media, "/", "text/html", false},
+		{media, "/", "application/json", false},
+		// Errors carry no content and are not checked.
+		{media, "/missing", "", true},
+	}
+	for _, tt := range tests {
+		_, err := get(tt.ctx, c, srv.URL+tt.path+"?type="+url.QueryEscape(tt.ctype))
+		if tt.ok && err != nil {
+			t.Errorf("%s %q: %v", tt.path, tt.ctype, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrContentType) {
+			t.Errorf("%s %q: %v, want ErrContentType", tt.path, tt.ctype, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package egress
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// transport checks every request, redirects included, against the host
+// allowlist of its context, limits concurrent requests per host and
+// enforces the content type and size limits on responses.
+type transport struct {
+	next  http.RoundTripper
+	opts  Options
+	hosts *hostLimiter
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("scheme %q: %w", req.URL.Scheme, ErrBlocked)
+	}
+	host := strings.ToLower(req.URL.Hostname())
+	if hosts, ok := ctx.Value(hostsKey).([]string); ok && !HostAllowed(host, hosts) {
+		return nil, fmt.Errorf("host %q: %w", host, ErrBlocked)
+	}
+
+	release, err := t.hosts.acquire(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	// Redirects and errors carry no content the caller reads.
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		types := t.opts.ContentTypes
+		if v, ok := ctx.Value(contentTypesKey).([]string); ok {
+			types = v
+		}
+		if ct := resp.Header.Get("Content-Type"); !contentTypeAllowed(ct, types) {
+			resp.Body.Close()
+			release()
+			return nil, fmt.Errorf("%q: %w", ct, ErrContentType)
+		}
+	}
+
+	limit := t.opts.MaxResponseSize
+	if v, ok := ctx.Value(maxSizeKey).(int64); ok {
+		limit = v
+	}
+	if resp.ContentLength > limit {
+		resp.Body.Close()
+		release()
+		return nil, fmt.Errorf("%d bytes: %w", resp.ContentLength, ErrTooLarge)
+	}
+	resp.Body = &limitedBody{rc: resp.Body, n: limit, release: release}
+	return resp, nil
+}
+
+// limitedBody fails reads past n bytes instead of cutting the body short
+// silently, and frees the host slot when closed.
+type limitedBody struct {
+	rc      io.ReadCloser
+	n       int64
+	release func()
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.n <= 0 {
+		var one [1]byte
+		n, err := b.rc.Read(one[:])
+		if n > 0 {
+			return 0, ErrTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.n {
+		p = p[:b.n]
+	}
+	n, err := b.rc.Read(p)
+	b.n -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	b.release()
+	return b.rc.Close()
+}
+
+// hostLimiter caps concurrent requests per host. Hosts without requests
+// in progress are dropped from the map.
+type hostLimiter struct {
+	max   int
+	mu    sync.Mutex
+	slots map[string]*hostSlot
+}
+
+type hostSlot struct {
+	sem  chan struct{}
+	refs int
+}
+
+func newHostLimiter(max int) *hostLimiter {
+	return &hostLimiter{max: max, slots: make(map[string]*hostSlot)}
+}
+
+// acquire waits for a free slot for host and returns the function that
+// frees it; calling it more than once is safe.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	l.mu.Lock()
+	s, ok := l.slots[host]
+	if !ok {
+		s = &hostSlot{sem: make(chan struct{}, l.max)}
+		l.slots[host] = s
+	}
+	s.refs++
+	l.mu.Unlock()
+
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		l.unref(host, s)
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-s.sem
+			l.unref(host, s)
+		})
+	}, nil
+}
+
+func (l *hostLimiter) unref(host string, s *hostSlot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s.refs--; s.refs == 0 {
+		delete(l.slots, host)
+	}
+}
@@ -0,0 +1,335 @@
+// Package linkcheck finds the image links of news and exhibits that no
+// longer lead to media, so editors can fix them.
+//
+// A link is resolved the way the site resolves it (see package resolver),
+// but past the cache, and the file it leads to is requested with HEAD.
+// Every full check replaces the stored results (see storage.LinkChecks).
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/egress"
+	"github.com/WhiCu/school-museum/internal/resolver"
+	"github.com/google/uuid"
+)
+
+// Options configures a Checker. Zero values take the defaults.
+type Options struct {
+	// Workers is the number of links checked at once, 4 by default. The
+	// egress client limits the requests to each host on its own.
+	Workers int
+}
+
+// Checker checks the image links of news and exhibits.
+type Checker struct {
+	news      storage.Storage[model.News]
+	exhibits  storage.Storage[model.Exhibit]
+	checks    storage.LinkChecks
+	client    *resolver.Client
+	resolvers *resolver.Registry
+	opts      Options
+	log       *slog.Logger
+	now       func() time.Time
+
+	// mu lets one full check run at a time.
+	mu sync.Mutex
+}
+
+// New returns a checker making requests with client, which should be an
+// egress client.
+func New(
+	news storage.Storage[model.News],
+	exhibits storage.Storage[model.Exhibit],
+	checks storage.LinkChecks,
+	client *resolver.Client,
+	opts Options,
+	log *slog.Logger) *Checker {
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	return &Checker{
+		news:      news,
+		exhibits:  exhibits,
+		checks:    checks,
+		client:    client,
+		resolvers: resolver.New(client),
+		opts:      opts,
+		log:       log,
+		now:       time.Now,
+	}
+}
+
+// Summary counts the links of a full check by status. A link used by
+// several news items or exhibits is counted for each of them.
+type Summary struct {
+	Links    int
+	Statuses map[model.LinkStatus]int
+}
+
+// Broken returns the number of broken links.
+func (s Summary) Broken() int {
+	n := 0
+	for _, st := range model.BrokenLinkStatuses {
+		n += s.Statuses[st]
+	}
+	return n
+}
+
+// Run checks all links every interval until ctx is done.
+func (c *Checker) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		c.log.Info("link check job disabled")
+		return nil
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			sum, err := c.CheckAll(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					c.log.Error("failed to check image links", slog.String("error", err.Error()))
+				}
+				continue
+			}
+			c.log.Info("checked image links", slog.Int("links", sum.Links), slog.Int("broken", sum.Broken()))
+		}
+	}
+}
+
+// owner is a news item or an exhibit with its external image links.
+type owner struct {
+	typ  model.EntityType
+	id   uuid.UUID
+	urls []string
+}
+
+// CheckAll checks the external image links of all news and exhibits, each
+// distinct link once, and replaces the stored results. The results of
+// deleted news and exhibits are removed. Nothing is stored if ctx is done
+// before all links are checked.
+func (c *Checker) CheckAll(ctx context.Context) (Summary, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	start := c.now()
+	owners, err := c.owners(ctx)
+	if err != nil {
+		return Summary{}, err
+	}
+	var urls []string
+	seen := make(map[string]bool)
+	for _, o := range owners {
+		for _, u := range o.urls {
+			if !seen[u] {
+				seen[u] = true
+				urls = append(urls, u)
+			}
+		}
+	}
+
+	results := c.checkURLs(ctx, urls)
+	if err := ctx.Err(); err != nil {
+		return Summary{}, err
+	}
+
+	sum := Summary{Statuses: make(map[model.LinkStatus]int)}
+	for _, o := range owners {
+		if len(o.urls) == 0 {
+			// Earlier results, if any, go with the stale ones below.
+			continue
+		}
+		checks := make([]model.LinkCheck, len(o.urls))
+		for i, u := range o.urls {
+			checks[i] = results[u]
+			sum.Links++
+			sum.Statuses[checks[i].Status]++
+		}
+		if err := c.checks.Save(ctx, o.typ, o.id, checks); err != nil {
+			return Summary{}, err
+		}
+	}
+	if _, err := c.checks.DeleteBefore(ctx, start); err != nil {
+		return Summary{}, err
+	}
+	return sum, nil
+}
+
+// owners returns the news and exhibits with their distinct external links.
+func (c *Checker) owners(ctx context.Context) ([]owner, error) {
+	news, err := c.news.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exhibits, err := c.exhibits.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make([]owner, 0, len(news)+len(exhibits))
+	for _, n := range news {
+		owners = append(owners, owner{typ: model.EntityNews, id: n.ID, urls: externalURLs(n.ImageURLs)})
+	}
+	for _, e := range exhibits {
+		owners = append(owners, owner{typ: model.EntityExhibit, id: e.ID, urls: externalURLs(e.ImageURLs)})
+	}
+	return owners, nil
+}
+
+// checkURLs checks urls with a pool of workers. The links left unchecked
+// when ctx is done are missing from the result.
+func (c *Checker) checkURLs(ctx context.Context, urls []string) map[string]model.LinkCheck {
+	var (
+		mu      sync.Mutex
+		results = make(map[string]model.LinkCheck, len(urls))
+		queue   = make(chan string)
+		wg      sync.WaitGroup
+	)
+	for range min(c.opts.Workers, len(urls)) {
+		wg.Go(func() {
+			for u := range queue {
+				res := c.Check(ctx, u)
+				mu.Lock()
+				results[u] = res
+				mu.Unlock()
+			}
+		})
+	}
+
+	defer wg.Wait()
+	defer close(queue)
+	for _, u := range urls {
+		select {
+		case <-ctx.Done():
+			return results
+		case queue <- u:
+		}
+	}
+	return results
+}
+
+// Check checks one link. Links to players are only resolved: the services
+// report missing videos then.
+func (c *Checker) Check(ctx context.Context, rawURL string) model.LinkCheck {
+	check := model.LinkCheck{URL: rawURL, Status: model.LinkOK}
+
+	res, err := c.resolvers.Resolve(ctx, rawURL)
+	if err == nil && res.Type != resolver.TypeEmbed {
+		// The body is not read, so a large file is no failure.
+		resp, herr := c.client.Head(egress.WithMaxSize(ctx, math.MaxInt64), res.URL)
+		if herr == nil {
+			resp.Body.Close()
+			// Only the requests made to follow a redirect have a Response.
+			if resp.Request.Response != nil {
+				check.Status, check.FinalURL = model.LinkRedirect, resp.Request.URL.String()
+			}
+		}
+		err = herr
+	}
+	if err != nil {
+		check.Status, check.Error = outcome(err)
+	}
+	check.CheckedAt = c.now()
+	return check
+}
+
+// outcome returns the status of a failed check and the code of its error.
+func outcome(err error) (model.LinkStatus, string) {
+	var ne net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &ne) && ne.Timeout() {
+		return model.LinkTimeout, "timeout"
+	}
+	code := "request_failed"
+	var se *storage.Error
+	if errors.As(err, &se) {
+		code = se.Code
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		return model.LinkNotFound, code
+	}
+	return model.LinkError, code
+}
+
+// Broken returns the stored results with one of statuses, the broken
+// links by default, grouped by news item and exhibit. Links removed from
+// their news item or exhibit since the last check are left out, and so
+// are deleted news and exhibits.
+func (c *Checker) Broken(ctx context.Context, statuses ...model.LinkStatus) ([]model.BrokenLinks, error) {
+	if len(statuses) == 0 {
+		statuses = model.BrokenLinkStatuses
+	}
+	checks, err := c.checks.List(ctx, statuses...)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []model.BrokenLinks{}
+	for i := 0; i < len(checks); {
+		j := i + 1
+		for j < len(checks) && checks[j].OwnerType == checks[i].OwnerType && checks[j].OwnerID == checks[i].OwnerID {
+			j++
+		}
+		g, err := c.group(ctx, checks[i:j])
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+		case err != nil:
+			return nil, err
+		case len(g.Links) > 0:
+			groups = append(groups, g)
+		}
+		i = j
+	}
+	return groups, nil
+}
+
+// group describes the owner of checks, which all belong to it, and keeps
+// the checks of the links it still has.
+func (c *Checker) group(ctx context.Context, checks []model.LinkCheck) (model.BrokenLinks, error) {
+	g := model.BrokenLinks{OwnerType: checks[0].OwnerType, OwnerID: checks[0].OwnerID}
+	var urls []string
+	switch g.OwnerType {
+	case model.EntityNews:
+		n, err := c.news.Read(ctx, g.OwnerID)
+		if err != nil {
+			return g, err
+		}
+		g.Title, urls = n.Title, n.ImageURLs
+	case model.EntityExhibit:
+		e, err := c.exhibits.Read(ctx, g.OwnerID)
+		if err != nil {
+			return g, err
+		}
+		g.Title, urls = e.Title, e.ImageURLs
+	}
+	for _, ch := range checks {
+		if slices.Contains(urls, ch.URL) {
+			g.Links = append(g.Links, ch)
+		}
+	}
+	return g, nil
+}
+
+// externalURLs returns the distinct links to other sites among urls.
+func externalURLs(urls []string) []string {
+	var out []string
+	for _, u := range urls {
+		if (strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://")) && !slices.Contains(out, u) {
+			out = append(out, u)
+		}
+	}
+	return out
+}
@@ -0,0 +1,352 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"time"
+)
+
+// rotatedJPEGQuality is the quality JPEG originals are re-encoded with
+// when they are rotated upright. It is higher than that of the variants,
+// since the result replaces the original.
+const rotatedJPEGQuality = 92
+
+// Metadata is what Save learns from an uploaded image. The metadata blocks
+// it is read from are removed from the stored file.
+type Metadata struct {
+	// Width and Height are the dimensions of the stored image.
+	Width  int
+	Height int
+	// CapturedAt is the capture date from EXIF, zero if unknown.
+	CapturedAt time.Time
+	// Orientation is the EXIF orientation of the upload, 1 to 8, or zero
+	// if the upload had none.
+	Orientation int
+	// Rotated reports that the image was turned upright according to Orientation.
+	Rotated bool
+	// Removed lists the kinds of metadata removed from the file:
+	// exif, gps, device, xmp, iptc, text.
+	Removed []string
+}
+
+// cleaned is the result of stripping metadata from an image.
+type cleaned struct {
+	data []byte
+	exif exifInfo
+	// removed lists the kinds of metadata found in the file, see Metadata.Removed.
+	removed []string
+}
+
+func (c *cleaned) remove(kind string) {
+	for _, k := range c.removed {
+		if k == kind {
+			return
+		}
+	}
+	c.removed = append(c.removed, kind)
+}
+
+// readEXIF records an EXIF block being removed. A block that cannot be
+// parsed is removed all the same.
+func (c *cleaned) readEXIF(b []byte) {
+	c.remove("exif")
+	info, err := parseEXIF(b)
+	if err != nil {
+		return
+	}
+	c.exif = info
+	if info.gps {
+		c.remove("gps")
+	}
+	if info.device {
+		c.remove("device")
+	}
+}
+
+// sanitize strips the metadata that may reveal where and with what an
+// image was taken from the uploaded file f, rewriting it in place, and
+// turns JPEG and PNG images upright. It returns nil for files that are not
+// images. Images with a broken structure are rejected with ErrUndecodable:
+// their metadata cannot be reliably removed.
+func sanitize(f *os.File, mimeType string) (*Metadata, error) {
+	var clean func([]byte) (cleaned, error)
+	switch mimeType {
+	case "image/jpeg":
+		clean = cleanJPEG
+	case "image/png":
+		clean = cleanPNG
+	case "image/webp":
+		clean = cleanWebP
+	case "image/gif":
+		// GIF has no EXIF; comment extensions are kept.
+		clean = func(b []byte) (cleaned, error) { return cleaned{data: b}, nil }
+	default:
+		return nil, nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	orig, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	c, err := clean(orig)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", mimeType, ErrUndecodable, err)
+	}
+
+	meta := &Metadata{
+		CapturedAt:  c.exif.captured,
+		Orientation: c.exif.orientation,
+		Removed:     c.removed,
+	}
+	if c.exif.orientation > 1 && mimeType != "image/webp" {
+		// There is no pure Go WebP encoder, so WebP images keep their pixels as they are.
+		if c.data, err = rotate(c.data, mimeType, c.exif.orientation); err != nil {
+			return nil, err
+		}
+		meta.Rotated = true
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(c.data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", mimeType, ErrUndecodable, err)
+	}
+	meta.Width, meta.Height = cfg.Width, cfg.Height
+
+	if bytes.Equal(c.data, orig) {
+		return meta, nil
+	}
+	if err := f.Truncate(0); err != nil {
+		return nil, err
+	}
+	if _, err := f.WriteAt(c.data, 0); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// rotate decodes an image, turns it upright for the EXIF orientation o and
+// encodes it again in the same format.
+func rotate(data []byte, mimeType string, o int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", mimeType, ErrUndecodable, err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%dx%d: %w", cfg.Width, cfg.Height, ErrTooLarge)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", mimeType, ErrUndecodable, err)
+	}
+
+	var buf bytes.Buffer
+	upright := orient(img, o)
+	if mimeType == "image/jpeg" {
+		err = jpeg.Encode(&buf, upright, &jpeg.Options{Quality: rotatedJPEGQuality})
+	} else {
+		err = png.Encode(&buf, upright)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// orient returns img transformed so that it displays upright, for the EXIF
+// orientation o (2 to 8: mirrored and rotated by multiples of 90°).
+func orient(img image.Image, o int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	if o >= 5 {
+		dst = image.NewNRGBA(image.Rect(0, 0, h, w))
+	}
+	for y := range h {
+		for x := range w {
+			var dx, dy int
+			switch o {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise to be upright
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counterclockwise to be upright
+				dx, dy = y, w-1-x
+			default:
+				return img
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// JPEG APP segment signatures of the metadata cleanJPEG removes.
+var (
+	xmpHeader         = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	xmpExtendedHeader = []byte("http://ns.adobe.com/xmp/extension/\x00")
+	photoshopHeader   = []byte("Photoshop 3.0\x00")
+)
+
+// cleanJPEG removes the EXIF and XMP (APP1) and Photoshop/IPTC (APP13)
+// segments of a JPEG file. The other segments, e.g. JFIF and the ICC colour
+// profile, and the image data are copied as they are.
+func cleanJPEG(b []byte) (cleaned, error) {
+	if len(b) < 4 || b[0] != 0xff || b[1] != 0xd8 {
+		return cleaned{}, fmt.Errorf("no start of image")
+	}
+	c := cleaned{data: make([]byte, 0, len(b))}
+	c.data = append(c.data, b[:2]...)
+
+	for i := 2; ; {
+		if i+2 > len(b) || b[i] != 0xff {
+			return cleaned{}, fmt.Errorf("bad marker at %d", i)
+		}
+		switch marker := b[i+1]; {
+		case marker == 0xff:
+			// Fill byte.
+			i++
+			continue
+		case marker == 0xd9 || marker == 0xda:
+			// End of image or start of scan: the rest is image data.
+			c.data = append(c.data, b[i:]...)
+			return c, nil
+		case marker == 0x01 || marker >= 0xd0 && marker <= 0xd7:
+			// Markers without a segment.
+			c.data = append(c.data, b[i:i+2]...)
+			i += 2
+			continue
+		}
+		if i+4 > len(b) {
+			return cleaned{}, fmt.Errorf("truncated segment at %d", i)
+		}
+		marker := b[i+1]
+		n := int(binary.BigEndian.Uint16(b[i+2:]))
+		if n < 2 || i+2+n > len(b) {
+			return cleaned{}, fmt.Errorf("bad segment length at %d", i)
+		}
+		seg, payload := b[i:i+2+n], b[i+4:i+2+n]
+		i += 2 + n
+
+		switch {
+		case marker == 0xe1 && bytes.HasPrefix(payload, exifHeader):
+			c.readEXIF(payload[len(exifHeader):])
+		case marker == 0xe1 && (bytes.HasPrefix(payload, xmpHeader) || bytes.HasPrefix(payload, xmpExtendedHeader)):
+			c.remove("xmp")
+		case marker == 0xed && bytes.HasPrefix(payload, photoshopHeader):
+			c.remove("iptc")
+		default:
+			c.data = append(c.data, seg...)
+		}
+	}
+}
+
+// pngSignature starts every PNG file.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// cleanPNG removes the eXIf chunk and the text chunks of a PNG file.
+// Text chunks hold XMP and the raw EXIF profiles some editors write, as
+// well as free-form comments.
+func cleanPNG(b []byte) (cleaned, error) {
+	if !bytes.HasPrefix(b, pngSignature) {
+		return cleaned{}, fmt.Errorf("no png signature")
+	}
+	c := cleaned{data: make([]byte, 0, len(b))}
+	c.data = append(c.data, pngSignature...)
+
+	for i := len(pngSignature); i < len(b); {
+		if i+12 > len(b) {
+			return cleaned{}, fmt.Errorf("truncated chunk at %d", i)
+		}
+		n := binary.BigEndian.Uint32(b[i:])
+		if uint64(i)+12+uint64(n) > uint64(len(b)) {
+			return cleaned{}, fmt.Errorf("bad chunk length at %d", i)
+		}
+		typ := string(b[i+4 : i+8])
+		chunk, data := b[i:i+12+int(n)], b[i+8:i+8+int(n)]
+		i += 12 + int(n)
+
+		switch typ {
+		case "eXIf":
+			c.readEXIF(data)
+		case "iTXt", "tEXt", "zTXt":
+			if bytes.HasPrefix(data, []byte("XML:com.adobe.xmp\x00")) {
+				c.remove("xmp")
+			} else {
+				c.remove("text")
+			}
+		default:
+			c.data = append(c.data, chunk...)
+		}
+		if typ == "IEND" {
+			break
+		}
+	}
+	return c, nil
+}
+
+// VP8X feature flags of the metadata cleanWebP removes.
+const (
+	vp8xFlagEXIF = 0x08
+	vp8xFlagXMP  = 0x04
+)
+
+// cleanWebP removes the EXIF and XMP chunks of a WebP file and clears their
+// flags in the VP8X header.
+func cleanWebP(b []byte) (cleaned, error) {
+	if len(b) < 12 || string(b[:4]) != "RIFF" || string(b[8:12]) != "WEBP" {
+		return cleaned{}, fmt.Errorf("no riff webp header")
+	}
+	c := cleaned{data: make([]byte, 0, len(b))}
+	c.data = append(c.data, b[:12]...)
+
+	vp8x := -1
+	for i := 12; i < len(b); {
+		if i+8 > len(b) {
+			return cleaned{}, fmt.Errorf("truncated chunk at %d", i)
+		}
+		n := uint64(binary.LittleEndian.Uint32(b[i+4:]))
+		size := 8 + n + n&1 // chunks are padded to an even size
+		if uint64(i)+8+n > uint64(len(b)) {
+			return cleaned{}, fmt.Errorf("bad chunk length at %d", i)
+		}
+		end := min(uint64(i)+size, uint64(len(b)))
+		fourcc := string(b[i : i+4])
+		chunk, data := b[i:end], b[i+8:uint64(i)+8+n]
+		i = int(end)
+
+		switch fourcc {
+		case "EXIF":
+			c.readEXIF(data)
+		case "XMP ":
+			c.remove("xmp")
+		default:
+			if fourcc == "VP8X" && n > 0 {
+				vp8x = len(c.data) + 8
+			}
+			c.data = append(c.data, chunk...)
+		}
+	}
+
+	if vp8x >= 0 {
+		c.data[vp8x] &^= vp8xFlagEXIF | vp8xFlagXMP
+	}
+	binary.LittleEndian.PutUint32(c.data[4:], uint32(len(c.data)-8))
+	return c, nil
+}
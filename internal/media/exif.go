@@ -0,0 +1,194 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+// EXIF tags read by parseEXIF.
+const (
+	tagMake             = 0x010f
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagSoftware         = 0x0131
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+	tagOffsetOriginal   = 0x9011
+	tagBodySerial       = 0xa431
+	tagLensMake         = 0xa433
+	tagLensModel        = 0xa434
+)
+
+// exifDateLayout is the layout of EXIF date and time values.
+const exifDateLayout = "2006:01:02 15:04:05"
+
+// maxIFDEntries bounds the entries read from a single IFD of a broken or
+// hostile file.
+const maxIFDEntries = 1024
+
+var errBadEXIF = errors.New("malformed exif")
+
+// exifHeader prefixes the TIFF data of an EXIF block in JPEG APP1 segments.
+var exifHeader = []byte("Exif\x00\x00")
+
+// exifInfo is what parseEXIF learns from an EXIF block.
+type exifInfo struct {
+	// orientation is the EXIF orientation, 1 to 8, or 0 if absent.
+	orientation int
+	captured    time.Time
+	// gps and device report whether the block has location or camera tags.
+	gps    bool
+	device bool
+}
+
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// parseEXIF reads the TIFF structure of an EXIF block. A leading exifHeader
+// is skipped, as some WebP encoders keep it.
+func parseEXIF(b []byte) (exifInfo, error) {
+	b = bytes.TrimPrefix(b, exifHeader)
+	if len(b) < 8 {
+		return exifInfo{}, errBadEXIF
+	}
+	var bo binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return exifInfo{}, errBadEXIF
+	}
+	if bo.Uint16(b[2:]) != 42 {
+		return exifInfo{}, errBadEXIF
+	}
+
+	ifd0, err := readIFD(b, bo, bo.Uint32(b[4:]))
+	if err != nil {
+		return exifInfo{}, err
+	}
+	var info exifInfo
+	if e, ok := ifd0[tagOrientation]; ok {
+		if o := e.uint(bo); o >= 1 && o <= 8 {
+			info.orientation = int(o)
+		}
+	}
+	_, info.gps = ifd0[tagGPSIFD]
+	for _, tag := range []uint16{tagMake, tagModel, tagSoftware} {
+		if _, ok := ifd0[tag]; ok {
+			info.device = true
+		}
+	}
+
+	date, offset := ifd0[tagDateTime].string(), ""
+	if e, ok := ifd0[tagExifIFD]; ok {
+		exif, err := readIFD(b, bo, e.uint(bo))
+		if err != nil {
+			return exifInfo{}, err
+		}
+		if d := exif[tagDateTimeOriginal].string(); d != "" {
+			date, offset = d, exif[tagOffsetOriginal].string()
+		}
+		for _, tag := range []uint16{tagBodySerial, tagLensMake, tagLensModel} {
+			if _, ok := exif[tag]; ok {
+				info.device = true
+			}
+		}
+	}
+	info.captured = parseEXIFDate(date, offset)
+	return info, nil
+}
+
+// parseEXIFDate parses an EXIF date with its optional "+03:00" offset.
+// Without an offset the time is taken as UTC. Unset dates, which cameras
+// write as blanks or zeros, give the zero time.
+func parseEXIFDate(date, offset string) time.Time {
+	if offset != "" {
+		if t, err := time.Parse(exifDateLayout+"-07:00", date+offset); err == nil {
+			return t
+		}
+	}
+	t, err := time.Parse(exifDateLayout, date)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// readIFD reads the entries of the IFD at offset off of the TIFF data b.
+func readIFD(b []byte, bo binary.ByteOrder, off uint32) (map[uint16]ifdEntry, error) {
+	if uint64(off)+2 > uint64(len(b)) {
+		return nil, errBadEXIF
+	}
+	n := int(bo.Uint16(b[off:]))
+	if n > maxIFDEntries || int(off)+2+n*12 > len(b) {
+		return nil, errBadEXIF
+	}
+
+	entries := make(map[uint16]ifdEntry, n)
+	for i := range n {
+		raw := b[int(off)+2+i*12:][:12]
+		e := ifdEntry{
+			typ:   bo.Uint16(raw[2:]),
+			count: bo.Uint32(raw[4:]),
+		}
+		size := uint64(typeSize(e.typ)) * uint64(e.count)
+		switch {
+		case size == 0:
+			// Unknown type: the value cannot be located, but the tag is present.
+		case size <= 4:
+			e.value = raw[8 : 8+size]
+		default:
+			start := uint64(bo.Uint32(raw[8:]))
+			if start+size > uint64(len(b)) {
+				continue
+			}
+			e.value = b[start : start+size]
+		}
+		entries[bo.Uint16(raw)] = e
+	}
+	return entries, nil
+}
+
+// typeSize is the size in bytes of a value of a TIFF field type.
+func typeSize(typ uint16) int {
+	switch typ {
+	case 1, 2, 6, 7: // BYTE, ASCII, SBYTE, UNDEFINED
+		return 1
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	}
+	return 0
+}
+
+// uint returns the first value of a SHORT or LONG entry.
+func (e ifdEntry) uint(bo binary.ByteOrder) uint32 {
+	switch {
+	case e.typ == 3 && len(e.value) >= 2:
+		return uint32(bo.Uint16(e.value))
+	case e.typ == 4 && len(e.value) >= 4:
+		return bo.Uint32(e.value)
+	}
+	return 0
+}
+
+// string returns the value of an ASCII entry without the trailing NULs.
+func (e ifdEntry) string() string {
+	if e.typ != 2 {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(e.value), "\x00"))
+}
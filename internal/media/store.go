@@ -0,0 +1,180 @@
+// Package media keeps files uploaded through the admin API on the local disk
+// and serves them under URLPrefix.
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/WhiCu/school-museum/db/storage"
+)
+
+// URLPrefix is the path stored files are served under.
+const URLPrefix = "/media/"
+
+var (
+	ErrTooLarge    = storage.NewError(storage.ErrInvalid, "file_too_large", "file is too large")
+	ErrUnsupported = storage.NewError(storage.ErrInvalid, "unsupported_media_type", "unsupported media type")
+)
+
+// Types maps the accepted MIME types, as sniffed by http.DetectContentType,
+// to the extensions files of that type are stored with.
+var Types = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"video/mp4":  ".mp4",
+	"video/webm": ".webm",
+}
+
+// reStoredPath matches the paths Store.Save and Store.Variants produce.
+var reStoredPath = regexp.MustCompile(`^[0-9a-f]{2}/[0-9a-f]{64}(_[a-z]+)?\.[a-z0-9]+$`)
+
+// File is a file saved in a Store.
+type File struct {
+	// Hash is the hex SHA-256 of the content.
+	Hash string
+	// Path is the slash-separated path of the file relative to the store directory.
+	Path     string
+	MIMEType string
+	Size     int64
+	// Meta is what was learned from an image, nil for other files.
+	Meta *Metadata
+}
+
+// URL is the stable URL the file is served under.
+func (f File) URL() string {
+	return URLPrefix + f.Path
+}
+
+// Store is a content-addressed file store: a file is kept once under
+// <dir>/<hash[:2]>/<hash><ext>, however many times it is saved.
+type Store struct {
+	dir     string
+	maxSize int64
+}
+
+// NewStore creates the store directory if needed. Files over maxSize bytes
+// are rejected; a zero maxSize means no limit.
+func NewStore(dir string, maxSize int64) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Store{
+		dir:     dir,
+		maxSize: maxSize,
+	}, nil
+}
+
+// Save copies r into the store. The type of the content is sniffed, never
+// taken from the client, and must be one of Types. Images are stored
+// without the metadata that may reveal where and with what they were taken
+// (see Metadata), so the hash is that of the cleaned content.
+// The content is written to a temporary file first and renamed into place,
+// so a file under its final path is always complete.
+func (s *Store) Save(r io.Reader) (f File, err error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		if errors.Is(err, io.EOF) {
+			return File{}, fmt.Errorf("empty file: %w", ErrUnsupported)
+		}
+		return File{}, err
+	}
+	head = head[:n]
+	mimeType := http.DetectContentType(head)
+	ext, ok := Types[mimeType]
+	if !ok {
+		return File{}, fmt.Errorf("%s: %w", mimeType, ErrUnsupported)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return File{}, err
+	}
+	defer func() {
+		tmp.Close()
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	src := io.MultiReader(bytes.NewReader(head), r)
+	if s.maxSize > 0 {
+		// One byte over the limit is enough to tell the file is too large.
+		src = io.LimitReader(src, s.maxSize+1)
+	}
+	size, err := io.Copy(tmp, src)
+	if err != nil {
+		return File{}, err
+	}
+	if s.maxSize > 0 && size > s.maxSize {
+		return File{}, fmt.Errorf("more than %d bytes: %w", s.maxSize, ErrTooLarge)
+	}
+
+	meta, err := sanitize(tmp, mimeType)
+	if err != nil {
+		return File{}, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return File{}, err
+	}
+	h := sha256.New()
+	if size, err = io.Copy(h, tmp); err != nil {
+		return File{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return File{}, err
+	}
+
+	hash := hex.EncodeToString(h.Sum(nil))
+	f = File{
+		Hash:     hash,
+		Path:     hash[:2] + "/" + hash + ext,
+		MIMEType: mimeType,
+		Size:     size,
+		Meta:     meta,
+	}
+	dst := s.path(f.Path)
+	if _, err := os.Stat(dst); err == nil {
+		// Same content is already stored.
+		return f, os.Remove(tmp.Name())
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return File{}, err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return File{}, err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return File{}, err
+	}
+	return f, nil
+}
+
+// ServeHTTP serves the stored files under URLPrefix. Files never change once
+// stored, so they are cached as immutable. Directory listings are not served.
+func (s *Store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := strings.TrimPrefix(r.URL.Path, URLPrefix)
+	if !reStoredPath.MatchString(p) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeFile(w, r, s.path(p))
+}
+
+func (s *Store) path(p string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(p))
+}
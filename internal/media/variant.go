@@ -0,0 +1,180 @@
+package media
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/WhiCu/school-museum/db/storage"
+	"golang.org/x/image/draw"
+
+	// Decoders of the formats variants are generated from.
+	_ "golang.org/x/image/webp"
+)
+
+// ErrUndecodable is returned by Variants for image files that cannot be decoded.
+var ErrUndecodable = storage.NewError(storage.ErrInvalid, "undecodable_image", "image cannot be decoded")
+
+// maxPixels bounds the images Variants decodes: a small file can declare
+// huge dimensions, and decoding allocates memory for every pixel.
+const maxPixels = 50_000_000
+
+// jpegQuality is the quality JPEG variants are encoded with.
+const jpegQuality = 85
+
+// Size is a size class of image variants.
+type Size struct {
+	Name string
+	// Width is the width images are scaled down to, keeping the aspect ratio.
+	Width int
+}
+
+// Sizes are the variants generated for every image, smallest first.
+// A variant is only generated if the original is wider than its size.
+var Sizes = []Size{
+	{Name: "thumb", Width: 320},
+	{Name: "medium", Width: 800},
+	{Name: "large", Width: 1600},
+}
+
+// Variant is a resized copy of a stored image, kept next to the original
+// as <hash[:2]>/<hash>_<size><ext>.
+type Variant struct {
+	Name   string
+	Path   string
+	Width  int
+	Height int
+}
+
+// URL is the stable URL the variant is served under.
+func (v Variant) URL() string {
+	return URLPrefix + v.Path
+}
+
+// Resized describes an image and the variants generated from it.
+type Resized struct {
+	Width    int
+	Height   int
+	Variants []Variant
+}
+
+// Variants generates the variants of an image file saved in the store.
+// JPEG originals get JPEG variants and PNG originals PNG ones; WebP, which
+// has no pure Go encoder, is converted to JPEG, or to PNG if it has
+// transparency. GIFs and videos get no variants: the zero Resized is
+// returned for them. Variants that already exist are kept as they are.
+// The variants of an image Save could not turn upright (f.Meta) are
+// turned upright instead, and Resized has their dimensions.
+func (s *Store) Variants(f File) (Resized, error) {
+	switch f.MIMEType {
+	case "image/jpeg", "image/png", "image/webp":
+	default:
+		return Resized{}, nil
+	}
+
+	src, err := os.Open(s.path(f.Path))
+	if err != nil {
+		return Resized{}, err
+	}
+	defer src.Close()
+
+	cfg, _, err := image.DecodeConfig(src)
+	if err != nil {
+		return Resized{}, fmt.Errorf("%s: %w: %v", f.Path, ErrUndecodable, err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return Resized{}, fmt.Errorf("%s: %dx%d: %w", f.Path, cfg.Width, cfg.Height, ErrTooLarge)
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return Resized{}, err
+	}
+	img, _, err := image.Decode(src)
+	if err != nil {
+		return Resized{}, fmt.Errorf("%s: %w: %v", f.Path, ErrUndecodable, err)
+	}
+
+	if m := f.Meta; m != nil && m.Orientation > 1 && !m.Rotated {
+		img = orient(img, m.Orientation)
+	}
+
+	b := img.Bounds()
+	res := Resized{Width: b.Dx(), Height: b.Dy()}
+	ext, encode := encoderFor(f.MIMEType, img)
+	base := strings.TrimSuffix(f.Path, filepath.Ext(f.Path))
+	for _, size := range Sizes {
+		if size.Width >= res.Width {
+			break
+		}
+		v := Variant{
+			Name:   size.Name,
+			Path:   base + "_" + size.Name + ext,
+			Width:  size.Width,
+			Height: max(1, res.Height*size.Width/res.Width),
+		}
+		if err := s.writeVariant(v, img, encode); err != nil {
+			return Resized{}, err
+		}
+		res.Variants = append(res.Variants, v)
+	}
+	return res, nil
+}
+
+// encoderFor picks the format the variants of an image of the given type are
+// encoded in and returns its extension and encoder.
+func encoderFor(mimeType string, img image.Image) (string, func(io.Writer, image.Image) error) {
+	encodeJPEG := func(w io.Writer, m image.Image) error {
+		return jpeg.Encode(w, m, &jpeg.Options{Quality: jpegQuality})
+	}
+	switch {
+	case mimeType == "image/jpeg":
+		return Types["image/jpeg"], encodeJPEG
+	case mimeType == "image/webp" && opaque(img):
+		return Types["image/jpeg"], encodeJPEG
+	}
+	return Types["image/png"], png.Encode
+}
+
+func opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// writeVariant scales img down to the size of v and writes it under v.Path,
+// through a temporary file like Save.
+func (s *Store) writeVariant(v Variant, img image.Image, encode func(io.Writer, image.Image) error) (err error) {
+	dst := s.path(v.Path)
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, v.Width, v.Height))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".variant-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tmp.Close()
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err := encode(tmp, scaled); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
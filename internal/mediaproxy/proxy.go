@@ -0,0 +1,355 @@
+// Package mediaproxy serves external images and videos from the site's own
+// origin, for networks that block the services they are hosted on.
+//
+// A link is resolved (see package resolver), the file it points to is
+// downloaded through the egress client and kept in a disk cache, and served
+// from there with Range and conditional request support.
+package mediaproxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/egress"
+	"github.com/WhiCu/school-museum/internal/media"
+	"github.com/WhiCu/school-museum/internal/resolver"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	ErrNotProxyable   = storage.NewError(storage.ErrInvalid, "media_not_proxyable", "only images and videos can be proxied")
+	ErrHostNotAllowed = storage.NewError(storage.ErrInvalid, "media_host_not_allowed", "direct links to this host cannot be proxied")
+)
+
+// Resolver resolves links to external media services.
+type Resolver interface {
+	Resolve(ctx context.Context, rawURL string) (resolver.Resolved, error)
+	// Hosts returns the hosts the resolver of rawURL is limited to, or nil
+	// for direct links, which may point anywhere.
+	Hosts(rawURL string) ([]string, error)
+}
+
+// Options configures a Proxy. Zero values take the defaults.
+type Options struct {
+	// MaxFileSize is the size limit of a proxied file in bytes, 50 MB by default.
+	MaxFileSize int64
+	// MaxCacheSize is the size the cache is trimmed to by RunPurge, in
+	// bytes, 1 GB by default. Least recently served files go first.
+	MaxCacheSize int64
+	// TTL is how long a downloaded file is served before it is downloaded
+	// again, 24 hours by default. Browsers may cache it as long.
+	TTL time.Duration
+	// Hosts are the hosts, with their subdomains, that visitors may proxy
+	// direct links to with OpenPublic. Links to the services of package
+	// resolver need not be listed. None by default.
+	Hosts []string
+}
+
+// Proxy downloads and caches external media files.
+type Proxy struct {
+	dir       string
+	client    *resolver.Client
+	resolvers Resolver
+	opts      Options
+	log       *slog.Logger
+	now       func() time.Time
+
+	calls singleflight.Group
+}
+
+// New creates the cache directory if needed. Files are downloaded with
+// client, which should be an egress client.
+func New(dir string, client *resolver.Client, resolvers Resolver, opts Options, log *slog.Logger) (*Proxy, error) {
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = 50 << 20
+	}
+	if opts.MaxCacheSize <= 0 {
+		opts.MaxCacheSize = 1 << 30
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+	opts.Hosts = slices.Clone(opts.Hosts)
+	for i, h := range opts.Hosts {
+		opts.Hosts[i] = strings.ToLower(h)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Proxy{
+		dir:       dir,
+		client:    client,
+		resolvers: resolvers,
+		opts:      opts,
+		log:       log,
+		now:       time.Now,
+	}, nil
+}
+
+// File is a cached file opened for serving. It must be closed.
+type File struct {
+	io.ReadSeeker
+	ContentType string
+	Size        int64
+	ETag        string
+	FetchedAt   time.Time
+	maxAge      time.Duration
+	f           *os.File
+}
+
+func (f *File) Close() error {
+	return f.f.Close()
+}
+
+// ServeHTTP serves the file with Range and conditional request support.
+// The content type was sniffed when the file was downloaded, and browsers
+// must not guess another one.
+func (f *File) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", f.ContentType)
+	w.Header().Set("ETag", f.ETag)
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(f.maxAge.Seconds())))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", f.FetchedAt, f)
+}
+
+// meta describes a cached file. It is stored after the content, followed by
+// its length as a big-endian uint32, so a file and its description are
+// replaced together by one rename.
+type meta struct {
+	Source      string    `json:"source"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	Hash        string    `json:"hash"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// Open returns the file behind rawURL from the cache, downloading it if it
+// is not cached or expired. Concurrent requests for one link download it once.
+func (p *Proxy) Open(ctx context.Context, rawURL string) (*File, error) {
+	return p.get(ctx, rawURL, nil)
+}
+
+// OpenPublic is Open for links given by visitors, which must not turn the
+// proxy into an open one: rawURL must be a link to one of the services of
+// package resolver, or a direct link to one of Options.Hosts, and the file
+// of a direct link is downloaded only from those hosts.
+func (p *Proxy) OpenPublic(ctx context.Context, rawURL string) (*File, error) {
+	hosts, err := p.resolvers.Hosts(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if hosts != nil {
+		return p.get(ctx, rawURL, nil)
+	}
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, resolver.ErrInvalidURL
+	}
+	if !egress.HostAllowed(u.Hostname(), p.opts.Hosts) {
+		return nil, fmt.Errorf("host %q: %w", u.Hostname(), ErrHostNotAllowed)
+	}
+	return p.get(ctx, rawURL, p.opts.Hosts)
+}
+
+// get returns the file behind rawURL, downloading it only from hosts if
+// they are set.
+func (p *Proxy) get(ctx context.Context, rawURL string, hosts []string) (*File, error) {
+	key := resolver.Key(rawURL)
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+
+	f, err := p.open(name)
+	if err == nil {
+		return f, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		p.log.Error("failed to open cached media", slog.String("url", key), slog.String("error", err.Error()))
+	}
+
+	// The download is shared by every request waiting for the link, so it
+	// must not end when the first of them goes away. Restricted downloads
+	// are not shared with unrestricted ones, which must not fail for them.
+	call := name
+	if hosts != nil {
+		call += " restricted"
+	}
+	ch := p.calls.DoChan(call, func() (any, error) {
+		return nil, p.fetch(context.WithoutCancel(ctx), name, key, rawURL, hosts)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-ch:
+		if r.Err != nil {
+			return nil, r.Err
+		}
+	}
+	return p.open(name)
+}
+
+// open opens a cached file that has not expired. Missing and expired files
+// are os.ErrNotExist.
+func (p *Proxy) open(name string) (*File, error) {
+	path := p.path(name)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	m, size, err := readMeta(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	now := p.now()
+	if !m.FetchedAt.Add(p.opts.TTL).After(now) {
+		f.Close()
+		return nil, fmt.Errorf("%s expired: %w", name, os.ErrNotExist)
+	}
+	// The modification time tells RunPurge when the file was last served.
+	if err := os.Chtimes(path, now, now); err != nil {
+		p.log.Warn("failed to touch cached media", slog.String("path", path), slog.String("error", err.Error()))
+	}
+	return &File{
+		ReadSeeker:  io.NewSectionReader(f, 0, size),
+		ContentType: m.ContentType,
+		Size:        size,
+		ETag:        `"` + m.Hash + `"`,
+		FetchedAt:   m.FetchedAt,
+		maxAge:      m.FetchedAt.Add(p.opts.TTL).Sub(now),
+		f:           f,
+	}, nil
+}
+
+// fetch resolves rawURL, downloads the file, only from hosts if they are
+// set, and puts it into the cache.
+func (p *Proxy) fetch(ctx context.Context, name, key, rawURL string, hosts []string) (err error) {
+	res, err := p.resolvers.Resolve(ctx, rawURL)
+	if err != nil {
+		return err
+	}
+	if res.Type != resolver.TypeImage && res.Type != resolver.TypeVideo {
+		return fmt.Errorf("%s: %w", res.Type, ErrNotProxyable)
+	}
+
+	ctx = egress.WithContentTypes(egress.WithMaxSize(ctx, p.opts.MaxFileSize), "image/", "video/")
+	if hosts != nil {
+		ctx = egress.WithHosts(ctx, hosts...)
+	}
+	resp, err := p.client.Open(ctx, res.URL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Only the types accepted for uploads are served, whatever the source
+	// claims: an SVG or HTML page served from our origin could run scripts.
+	head := make([]byte, 512)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("empty file: %w", media.ErrUnsupported)
+		}
+		return fmt.Errorf("%w: %w", resolver.ErrSource, err)
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if _, ok := media.Types[contentType]; !ok {
+		return fmt.Errorf("%s: %w", contentType, media.ErrUnsupported)
+	}
+
+	tmp, err := os.CreateTemp(p.dir, ".fetch-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tmp.Close()
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), io.MultiReader(bytes.NewReader(head), resp.Body)); err != nil {
+		var se *storage.Error
+		if errors.As(err, &se) {
+			return err
+		}
+		return fmt.Errorf("%w: %w", resolver.ErrSource, err)
+	}
+	if err := writeMeta(tmp, meta{
+		Source:      key,
+		URL:         res.URL,
+		ContentType: contentType,
+		Hash:        hex.EncodeToString(h.Sum(nil)),
+		FetchedAt:   p.now().UTC(),
+	}); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	dst := p.path(name)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func (p *Proxy) path(name string) string {
+	return filepath.Join(p.dir, name[:2], name)
+}
+
+func writeMeta(w io.Writer, m meta) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(b)))
+	_, err = w.Write(b)
+	return err
+}
+
+// readMeta returns the description of a cached file and the size of its
+// content.
+func readMeta(f *os.File) (meta, int64, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return meta{}, 0, err
+	}
+	var m meta
+	var l [4]byte
+	if _, err := f.ReadAt(l[:], fi.Size()-4); err != nil {
+		return m, 0, fmt.Errorf("%s: read meta length: %w", f.Name(), err)
+	}
+	n := int64(binary.BigEndian.Uint32(l[:]))
+	size := fi.Size() - 4 - n
+	if size < 0 {
+		return m, 0, fmt.Errorf("%s: invalid meta length %d", f.Name(), n)
+	}
+	b := make([]byte, n)
+	if _, err := f.ReadAt(b, size); err != nil {
+		return m, 0, fmt.Errorf("%s: read meta: %w", f.Name(), err)
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return m, 0, fmt.Errorf("%s: decode meta: %w", f.Name(), err)
+	}
+	return m, size, nil
+}
@@ -0,0 +1,291 @@
+package mediaproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/internal/egress"
+	"github.com/WhiCu/school-museum/internal/media"
+	"github.com/WhiCu/school-museum/internal/resolver"
+)
+
+// fakeResolver treats every link as a direct one, except the links in
+// providers, which belong to a service limited to the hosts given.
+type fakeResolver struct {
+	providers map[string][]string
+}
+
+func (r fakeResolver) Resolve(_ context.Context, rawURL string) (resolver.Resolved, error) {
+	typ := resolver.TypeImage
+	if strings.Contains(rawURL, "/player") {
+		typ = resolver.TypeEmbed
+	}
+	return resolver.Resolved{Type: typ, URL: rawURL}, nil
+}
+
+func (r fakeResolver) Hosts(rawURL string) ([]string, error) {
+	return r.providers[rawURL], nil
+}
+
+// file is a file of the test server.
+type file struct {
+	ctype string
+	body  []byte
+}
+
+// server serves files and counts the requests to each path. /redirect
+// redirects to /photo.png on localhost, another host than the one of the
+// server's URL.
+type server struct {
+	*httptest.Server
+	files map[string]file
+	hits  map[string]*atomic.Int32
+}
+
+func newServer(t *testing.T, files map[string]file) *server {
+	s := &server{files: files, hits: make(map[string]*atomic.Int32)}
+	for path := range files {
+		s.hits[path] = new(atomic.Int32)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			_, port, _ := net.SplitHostPort(r.Host)
+			http.Redirect(w, r, "http://localhost:"+port+"/photo.png", http.StatusFound)
+			return
+		}
+		f, ok := s.files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		s.hits[r.URL.Path].Add(1)
+		w.Header().Set("Content-Type", f.ctype)
+		w.Write(f.body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newProxy returns a proxy with a settable clock that downloads from
+// local servers.
+func newProxy(t *testing.T, res Resolver, opts Options) (*Proxy, *time.Time) {
+	t.Helper()
+	client := resolver.NewClient(egress.NewClient(egress.Options{AllowPrivate: true}))
+	p, err := New(t.TempDir(), client, res, opts, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	return p, &now
+}
+
+func encodePNG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func open(t *testing.T, p *Proxy, rawURL string) *File {
+	t.Helper()
+	f, err := p.Open(context.Background(), rawURL)
+	if err != nil {
+		t.Fatalf("Open(%q): %v", rawURL, err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestOpenCache(t *testing.T) {
+	img := encodePNG(t)
+	srv := newServer(t, map[string]file{"/photo.png": {"image/png", img}})
+	p, now := newProxy(t, fakeResolver{}, Options{TTL: time.Hour})
+	link := srv.URL + "/photo.png"
+
+	f := open(t, p, link)
+	if f.ContentType != "image/png" || f.Size != int64(len(img)) {
+		t.Errorf("file = %s, %d bytes, want image/png, %d bytes", f.ContentType, f.Size, len(img))
+	}
+	if b, _ := io.ReadAll(f); !bytes.Equal(b, img) {
+		t.Error("content differs from the source")
+	}
+
+	// Served from the cache until the TTL ends.
+	*now = now.Add(time.Hour - time.Second)
+	open(t, p, link)
+	if n := srv.hits["/photo.png"].Load(); n != 1 {
+		t.Errorf("%d downloads before the TTL ended, want 1", n)
+	}
+	*now = now.Add(time.Second)
+	f = open(t, p, link)
+	if n := srv.hits["/photo.png"].Load(); n != 2 {
+		t.Errorf("%d downloads after the TTL ended, want 2", n)
+	}
+	if !f.FetchedAt.Equal(*now) {
+		t.Errorf("FetchedAt = %s, want the time of the new download", f.FetchedAt)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	img := encodePNG(t)
+	srv := newServer(t, map[string]file{"/photo.png": {"image/png", img}})
+	p, _ := newProxy(t, fakeResolver{}, Options{TTL: time.Hour})
+	link := srv.URL + "/photo.png"
+
+	serve := func(header http.Header) *httptest.ResponseRecorder {
+		f := open(t, p, link)
+		r := httptest.NewRequest(http.MethodGet, "/museum/media/proxy", nil)
+		r.Header = header
+		w := httptest.NewRecorder()
+		f.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(http.Header{})
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" || !bytes.Equal(w.Body.Bytes(), img) {
+		t.Fatalf("GET = %d %s, %d bytes, want the whole image", w.Code, w.Header().Get("Content-Type"), w.Body.Len())
+	}
+	if etag == "" || w.Header().Get("X-Content-Type-Options") != "nosniff" || w.Header().Get("Cache-Control") != "public, max-age=3600" {
+		t.Errorf("headers = %v", w.Header())
+	}
+
+	w = serve(http.Header{"Range": {"bytes=0-7"}})
+	if w.Code != http.StatusPartialContent || !bytes.Equal(w.Body.Bytes(), img[:8]) {
+		t.Errorf("Range = %d, %q, want 206 with the first 8 bytes", w.Code, w.Body.Bytes())
+	}
+	if got, want := w.Header().Get("Content-Range"), fmt.Sprintf("bytes 0-7/%d", len(img)); got != want {
+		t.Errorf("Content-Range = %q, want %q", got, want)
+	}
+
+	if w = serve(http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match with the ETag = %d, want 304", w.Code)
+	}
+	if w = serve(http.Header{"If-None-Match": {`"other"`}}); w.Code != http.StatusOK {
+		t.Errorf("If-None-Match with another ETag = %d, want 200", w.Code)
+	}
+}
+
+func TestOpenTypes(t *testing.T) {
+	srv := newServer(t, map[string]file{
+		"/photo.png": {"image/png", encodePNG(t)},
+		"/photo.jpg": {"image/jpeg", encodeJPEG(t)},
+		// Sources may claim anything; the sniffed type is what counts.
+		"/icon.svg":  {"image/svg+xml", []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)},
+		"/page.png":  {"image/png", []byte("<!DOCTYPE html><html><script>alert(1)</script></html>")},
+		"/empty.png": {"image/png", nil},
+		"/page.html": {"text/html", []byte("<!DOCTYPE html><html></html>")},
+	})
+	p, _ := newProxy(t, fakeResolver{}, Options{})
+
+	tests := []struct {
+		path  string
+		ctype string
+		err   error
+	}{
+		{path: "/photo.png", ctype: "image/png"},
+		{path: "/photo.jpg", ctype: "image/jpeg"},
+		{path: "/icon.svg", err: media.ErrUnsupported},
+		{path: "/page.png", err: media.ErrUnsupported},
+		{path: "/empty.png", err: media.ErrUnsupported},
+		// Refused by the egress client before anything is read.
+		{path: "/page.html", err: egress.ErrContentType},
+		{path: "/player", err: ErrNotProxyable},
+	}
+	for _, tt := range tests {
+		f, err := p.Open(context.Background(), srv.URL+tt.path)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: %v, want %v", tt.path, err, tt.err)
+			}
+			if err == nil {
+				f.Close()
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		if f.ContentType != tt.ctype {
+			t.Errorf("%s: type %s, want %s", tt.path, f.ContentType, tt.ctype)
+		}
+		f.Close()
+	}
+}
+
+func TestOpenPublic(t *testing.T) {
+	img := encodePNG(t)
+	srv := newServer(t, map[string]file{
+		"/photo.png":         {"image/png", img},
+		"/service/photo.png": {"image/png", img},
+	})
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := srv.URL + "/service/photo.png"
+	res := fakeResolver{providers: map[string][]string{service: {"video.example"}}}
+	ctx := context.Background()
+
+	// Direct links are refused without allowed hosts, even if cached.
+	p, _ := newProxy(t, res, Options{})
+	open(t, p, srv.URL+"/photo.png")
+	if _, err := p.OpenPublic(ctx, srv.URL+"/photo.png"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("direct link without allowed hosts: %v, want ErrHostNotAllowed", err)
+	}
+	if _, err := p.OpenPublic(ctx, "http://[::1"); !errors.Is(err, resolver.ErrInvalidURL) {
+		t.Errorf("invalid link: %v, want ErrInvalidURL", err)
+	}
+
+	// Links of services are resolved and proxied as usual; their files
+	// may be hosted elsewhere.
+	f, err := p.OpenPublic(ctx, service)
+	if err != nil {
+		t.Fatalf("link of a service: %v", err)
+	}
+	f.Close()
+
+	p, _ = newProxy(t, res, Options{Hosts: []string{strings.ToUpper(u.Hostname())}})
+	f, err = p.OpenPublic(ctx, srv.URL+"/photo.png")
+	if err != nil {
+		t.Fatalf("direct link to an allowed host: %v", err)
+	}
+	f.Close()
+
+	// Redirects may not leave the allowed hosts.
+	if _, err := p.OpenPublic(ctx, srv.URL+"/redirect"); !errors.Is(err, egress.ErrBlocked) {
+		t.Errorf("redirect to another host: %v, want ErrBlocked", err)
+	}
+	// Admin imports are not limited.
+	if f, err := p.Open(ctx, srv.URL+"/redirect"); err != nil {
+		t.Errorf("Open of a redirect to another host: %v", err)
+	} else {
+		f.Close()
+	}
+}
@@ -0,0 +1,108 @@
+package mediaproxy
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RunPurge removes expired files from the cache every interval until ctx
+// is done, then trims it to MaxCacheSize. Between runs the cache may grow
+// past the limit.
+func (p *Proxy) RunPurge(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		p.log.Info("media proxy purge job disabled")
+		return nil
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			n, err := p.Purge()
+			if err != nil {
+				p.log.Error("failed to purge media proxy cache", slog.String("error", err.Error()))
+				continue
+			}
+			if n > 0 {
+				p.log.Info("purged media proxy cache", slog.Int("count", n))
+			}
+		}
+	}
+}
+
+type cachedFile struct {
+	path     string
+	size     int64
+	servedAt time.Time
+}
+
+// Purge removes expired and unreadable files and the downloads left
+// unfinished by a crash, then the least recently served files until the
+// cache fits MaxCacheSize. It returns the number of removed files.
+func (p *Proxy) Purge() (int, error) {
+	now := p.now()
+	var (
+		files   []cachedFile
+		total   int64
+		removed int
+	)
+	remove := func(path string) {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			p.log.Error("failed to remove cached media", slog.String("path", path), slog.String("error", err.Error()))
+			return
+		}
+		removed++
+	}
+
+	err := filepath.WalkDir(p.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".fetch-") {
+			// Downloads take minutes at most.
+			if now.Sub(fi.ModTime()) > time.Hour {
+				remove(path)
+			}
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		m, _, err := readMeta(f)
+		f.Close()
+		if err != nil || !m.FetchedAt.Add(p.opts.TTL).After(now) {
+			remove(path)
+			return nil
+		}
+		files = append(files, cachedFile{path: path, size: fi.Size(), servedAt: fi.ModTime()})
+		total += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	slices.SortFunc(files, func(a, b cachedFile) int { return a.servedAt.Compare(b.servedAt) })
+	for _, f := range files {
+		if total <= p.opts.MaxCacheSize {
+			break
+		}
+		remove(f.path)
+		total -= f.size
+	}
+	return removed, nil
+}
@@ -0,0 +1,140 @@
+// Package problem translates errors into RFC 7807 problem details responses.
+// It is the single place that decides which HTTP status and machine-readable
+// code an error gets; handlers only choose the human-readable message.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Problem is huma's RFC 7807 error model extended with a machine-readable code.
+type Problem struct {
+	huma.ErrorModel
+	Code string `json:"code,omitempty" example:"not_found" doc:"Машиночитаемый код ошибки"`
+}
+
+// New builds a Problem for the given status. It replaces huma.NewError, so
+// the errors huma produces itself (request validation, huma.ErrorXXX helpers)
+// carry a code as well. An error of a known kind that a handler returned as
+// is reaches New with status 500 and is translated by From instead.
+func New(status int, msg string, errs ...error) huma.StatusError {
+	if status == http.StatusInternalServerError && len(errs) == 1 && storage.Classify(errs[0]) != nil {
+		return From(errs[0], msg)
+	}
+
+	details := make([]*huma.ErrorDetail, 0, len(errs))
+	for _, err := range errs {
+		var d huma.ErrorDetailer
+		switch {
+		case err == nil:
+			continue
+		case errors.As(err, &d):
+			details = append(details, d.ErrorDetail())
+		default:
+			details = append(details, &huma.ErrorDetail{Message: err.Error()})
+		}
+	}
+	return newProblem(status, code(status), msg, details)
+}
+
+// Detail overrides the message of the problem for errors matching Err and,
+// if Status is set, the status that follows from the kind of the error.
+type Detail struct {
+	Err    error
+	Msg    string
+	Status int
+}
+
+// From translates an error returned by a service into a Problem.
+// The status and code follow the kind of err (see storage.Classify):
+//
+//	storage.ErrStale       412 stale_version
+//	storage.ErrNotFound    404 not_found
+//	storage.ErrConflict    409 conflict
+//	storage.ErrInvalid     422 invalid
+//	storage.ErrUnavailable 503 unavailable
+//	storage.ErrRateLimited 429 rate_limited
+//	anything else          500 internal
+//
+// unless err wraps a storage.Error, whose code is used instead.
+// The message is that of the first Detail matching err, or msg.
+// The text of err itself is never exposed to the client.
+func From(err error, msg string, details ...Detail) huma.StatusError {
+	var status int
+	for _, d := range details {
+		if errors.Is(err, d.Err) {
+			msg, status = d.Msg, d.Status
+			break
+		}
+	}
+
+	switch kind := storage.Classify(err); {
+	case status != 0:
+	case errors.Is(err, storage.ErrStale):
+		status = http.StatusPreconditionFailed
+	case kind == storage.ErrNotFound:
+		status = http.StatusNotFound
+	case kind == storage.ErrConflict:
+		status = http.StatusConflict
+	case kind == storage.ErrInvalid:
+		status = http.StatusUnprocessableEntity
+	case kind == storage.ErrUnavailable:
+		status = http.StatusServiceUnavailable
+	case kind == storage.ErrRateLimited:
+		status = http.StatusTooManyRequests
+	default:
+		return newProblem(http.StatusInternalServerError, code(http.StatusInternalServerError), msg, nil)
+	}
+	return newProblem(status, storage.Code(err), msg, nil)
+}
+
+// Write writes a Problem for the given status to w. It serves the
+// middlewares that answer before a request reaches huma.
+func Write(w http.ResponseWriter, status int, msg string) {
+	write(w, New(status, msg))
+}
+
+// WriteError writes the Problem of err, as From translates it, to w.
+func WriteError(w http.ResponseWriter, err error, msg string, details ...Detail) {
+	write(w, From(err, msg, details...))
+}
+
+func write(w http.ResponseWriter, p huma.StatusError) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.GetStatus())
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+func newProblem(status int, code, msg string, details []*huma.ErrorDetail) *Problem {
+	return &Problem{
+		ErrorModel: huma.ErrorModel{
+			Status: status,
+			Title:  http.StatusText(status),
+			Detail: msg,
+			Errors: details,
+		},
+		Code: code,
+	}
+}
+
+// code is the machine-readable code of a problem raised by status alone:
+// the snake_case status text, aligned with storage.Code where they overlap.
+func code(status int) string {
+	switch status {
+	case http.StatusUnprocessableEntity:
+		return "invalid"
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	case http.StatusInternalServerError:
+		return "internal"
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
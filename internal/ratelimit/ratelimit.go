@@ -0,0 +1,98 @@
+// Package ratelimit limits how often one client may call an endpoint, with
+// a token bucket per client address.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Options configures a Limiter. Zero values take the defaults.
+type Options struct {
+	// Rate is the number of requests allowed per minute. Required.
+	Rate int
+	// Burst is the number of requests allowed at once, Rate by default.
+	Burst int
+	// MaxKeys is the number of clients tracked at once, 10000 by default.
+	// When it is reached, the client seen least recently is forgotten.
+	MaxKeys int
+}
+
+// Limiter allows each key Rate requests a minute, in bursts of up to Burst.
+// It is safe for concurrent use.
+type Limiter struct {
+	opts    Options
+	every   time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// bucket holds the tokens of a key as of last.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New returns a limiter with opts.
+func New(opts Options) *Limiter {
+	if opts.Burst <= 0 {
+		opts.Burst = opts.Rate
+	}
+	if opts.MaxKeys <= 0 {
+		opts.MaxKeys = 10000
+	}
+	return &Limiter{
+		opts:    opts,
+		every:   time.Minute / time.Duration(max(opts.Rate, 1)),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a request of key. If key is over its limit, Allow returns
+// false and the time until the next request is allowed.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= l.opts.MaxKeys {
+			l.evict(now)
+		}
+		b = &bucket{tokens: float64(l.opts.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.tokens(b, now)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(l.every))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// tokens returns the tokens of b at now.
+func (l *Limiter) tokens(b *bucket, now time.Time) float64 {
+	return min(float64(l.opts.Burst), b.tokens+float64(now.Sub(b.last))/float64(l.every))
+}
+
+// evict makes room for a bucket by dropping the full ones, which are the
+// same as new ones, or, if none are, the one used least recently. l.mu must
+// be held.
+func (l *Limiter) evict(now time.Time) {
+	var oldest string
+	for key, b := range l.buckets {
+		switch {
+		case l.tokens(b, now) >= float64(l.opts.Burst):
+			delete(l.buckets, key)
+		case oldest == "" || b.last.Before(l.buckets[oldest].last):
+			oldest = key
+		}
+	}
+	if len(l.buckets) >= l.opts.MaxKeys && oldest != "" {
+		delete(l.buckets, oldest)
+	}
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newLimiter(opts Options) (*Limiter, *time.Time) {
+	l := New(opts)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestAllow(t *testing.T) {
+	l, now := newLimiter(Options{Rate: 60, Burst: 3})
+	for i := range 3 {
+		if ok, _ := l.Allow("10.0.0.1"); !ok {
+			t.Fatalf("request %d of the burst refused", i+1)
+		}
+	}
+	ok, wait := l.Allow("10.0.0.1")
+	if ok || wait != time.Second {
+		t.Fatalf("Allow past the burst = %v, %s, want false, 1s", ok, wait)
+	}
+	if ok, _ := l.Allow("10.0.0.2"); !ok {
+		t.Error("another address refused")
+	}
+
+	*now = now.Add(time.Second)
+	if ok, _ := l.Allow("10.0.0.1"); !ok {
+		t.Error("request a token later refused")
+	}
+	if ok, _ := l.Allow("10.0.0.1"); ok {
+		t.Error("second request a token later allowed")
+	}
+
+	// The bucket does not fill past the burst.
+	*now = now.Add(time.Hour)
+	for range 3 {
+		l.Allow("10.0.0.1")
+	}
+	if ok, _ := l.Allow("10.0.0.1"); ok {
+		t.Error("request past the burst allowed after an hour")
+	}
+}
+
+func TestMaxKeys(t *testing.T) {
+	l, now := newLimiter(Options{Rate: 60, Burst: 2, MaxKeys: 4})
+	for i := range 10 {
+		l.Allow(fmt.Sprint("10.0.0.", i))
+		*now = now.Add(time.Millisecond)
+		if len(l.buckets) > 4 {
+			t.Fatalf("%d buckets after %d clients, want at most 4", len(l.buckets), i+1)
+		}
+	}
+	for _, key := range []string{"10.0.0.9", "10.0.0.8", "10.0.0.7"} {
+		if _, ok := l.buckets[key]; !ok {
+			t.Errorf("bucket of recent client %s was evicted", key)
+		}
+	}
+}
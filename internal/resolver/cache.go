@@ -0,0 +1,278 @@
+package resolver
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheOptions configures a Cache. Zero values take the defaults.
+type CacheOptions struct {
+	// Size is the number of links kept in memory, 1000 by default.
+	Size int
+	// TTL is how long a resolved link is served from the cache, 6 hours by
+	// default. Some services hand out file URLs that expire, so it should
+	// stay within hours.
+	TTL time.Duration
+	// NegativeTTL is how long a failure is remembered, 5 minutes by default.
+	NegativeTTL time.Duration
+}
+
+// Purged counts the entries removed from a Cache.
+type Purged struct {
+	Memory int `json:"memory" doc:"Удалено записей из памяти"`
+	Stored int `json:"stored" doc:"Удалено записей из базы данных"`
+}
+
+// Cache remembers what links resolved to. Recently used links are kept in
+// memory; successes are also kept in an optional store, so they survive
+// restarts and are shared by replicas. Failures are remembered for a short
+// time in memory only. Concurrent lookups of the same link make one request.
+type Cache struct {
+	reg   *Registry
+	store storage.ResolvedMedia
+	opts  CacheOptions
+	log   *slog.Logger
+	now   func() time.Time
+
+	mu    sync.Mutex
+	order *list.List // of *cacheEntry, most recently used first
+	items map[string]*list.Element
+
+	calls singleflight.Group
+}
+
+type cacheEntry struct {
+	key     string
+	res     Resolved
+	err     error
+	expires time.Time
+}
+
+// NewCache returns a cache of the links resolved by reg. A nil store keeps
+// the cache in memory only.
+func NewCache(reg *Registry, store storage.ResolvedMedia, opts CacheOptions, log *slog.Logger) *Cache {
+	if opts.Size <= 0 {
+		opts.Size = 1000
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 6 * time.Hour
+	}
+	if opts.NegativeTTL <= 0 {
+		opts.NegativeTTL = 5 * time.Minute
+	}
+	return &Cache{
+		reg:   reg,
+		store: store,
+		opts:  opts,
+		log:   log,
+		now:   time.Now,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Resolve returns the media behind rawURL from the cache, resolving the
+// link if it is not cached.
+func (c *Cache) Resolve(ctx context.Context, rawURL string) (Resolved, error) {
+	key := Key(rawURL)
+	if e, ok := c.get(key); ok {
+		return e.res, e.err
+	}
+
+	// The lookup is shared by every caller waiting for the link, so it must
+	// not end when the first of them goes away.
+	ch := c.calls.DoChan(key, func() (any, error) {
+		return c.load(context.WithoutCancel(ctx), key, rawURL)
+	})
+	select {
+	case <-ctx.Done():
+		return Resolved{}, ctx.Err()
+	case r := <-ch:
+		if r.Err != nil {
+			return Resolved{}, r.Err
+		}
+		e := r.Val.(*cacheEntry)
+		return e.res, e.err
+	}
+}
+
+// Hosts returns the hosts the resolver of rawURL is limited to, see
+// Registry.Hosts.
+func (c *Cache) Hosts(rawURL string) ([]string, error) {
+	return c.reg.Hosts(rawURL)
+}
+
+// load looks key up in the store or resolves it, and caches the result.
+func (c *Cache) load(ctx context.Context, key, rawURL string) (*cacheEntry, error) {
+	now := c.now()
+	if c.store != nil {
+		m, err := c.store.Get(ctx, key, now)
+		switch {
+		case err == nil:
+			e := &cacheEntry{key: key, res: fromModel(m), expires: m.ExpiresAt}
+			c.put(e)
+			return e, nil
+		case !errors.Is(err, storage.ErrNotFound):
+			c.log.Error("failed to get resolved media", slog.String("url", key), slog.String("error", err.Error()))
+		}
+	}
+
+	res, err := c.reg.Resolve(ctx, rawURL)
+	if err != nil {
+		// Only failures of the link itself or of the service are remembered.
+		if storage.Classify(err) == nil {
+			return nil, err
+		}
+		e := &cacheEntry{key: key, err: err, expires: now.Add(c.opts.NegativeTTL)}
+		c.put(e)
+		return e, nil
+	}
+
+	e := &cacheEntry{key: key, res: res, expires: now.Add(c.opts.TTL)}
+	c.put(e)
+	if c.store != nil {
+		if err := c.store.Put(ctx, toModel(key, res, now, e.expires)); err != nil {
+			c.log.Error("failed to store resolved media", slog.String("url", key), slog.String("error", err.Error()))
+		}
+	}
+	return e, nil
+}
+
+func (c *Cache) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if !e.expires.After(c.now()) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e, true
+}
+
+func (c *Cache) put(e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[e.key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[e.key] = c.order.PushFront(e)
+	for c.order.Len() > c.opts.Size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.items, last.Value.(*cacheEntry).key)
+	}
+}
+
+// Invalidate forgets what rawURL resolved to, so the next lookup resolves
+// it again. An empty rawURL forgets every link.
+func (c *Cache) Invalidate(ctx context.Context, rawURL string) (Purged, error) {
+	var p Purged
+	if rawURL == "" {
+		c.mu.Lock()
+		for key := range c.items {
+			c.calls.Forget(key)
+		}
+		p.Memory = c.order.Len()
+		c.order.Init()
+		clear(c.items)
+		c.mu.Unlock()
+
+		if c.store != nil {
+			n, err := c.store.DeleteAll(ctx)
+			if err != nil {
+				return p, err
+			}
+			p.Stored = n
+		}
+		return p, nil
+	}
+
+	key := Key(rawURL)
+	// A lookup in flight would bring the old result back.
+	c.calls.Forget(key)
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.order.Remove(el)
+		delete(c.items, key)
+		p.Memory = 1
+	}
+	c.mu.Unlock()
+
+	if c.store != nil {
+		switch err := c.store.Delete(ctx, key); {
+		case err == nil:
+			p.Stored = 1
+		case !errors.Is(err, storage.ErrNotFound):
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// RunPurge removes expired links from the store every interval until ctx
+// is done. Expired links in memory are dropped when they are looked up or
+// pushed out by newer ones.
+func (c *Cache) RunPurge(ctx context.Context, interval time.Duration) error {
+	if c.store == nil || interval <= 0 {
+		c.log.Info("resolved media purge job disabled")
+		return nil
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			n, err := c.store.DeleteExpired(ctx, c.now())
+			if err != nil {
+				c.log.Error("failed to purge resolved media", slog.String("error", err.Error()))
+				continue
+			}
+			if n > 0 {
+				c.log.Info("purged resolved media", slog.Int("count", n))
+			}
+		}
+	}
+}
+
+func fromModel(m model.ResolvedMedia) Resolved {
+	return Resolved{
+		URL:       m.URL,
+		Type:      m.Type,
+		Provider:  m.Provider,
+		Title:     m.Title,
+		Thumbnail: m.Thumbnail,
+	}
+}
+
+func toModel(key string, res Resolved, now, expires time.Time) model.ResolvedMedia {
+	return model.ResolvedMedia{
+		SourceURL:  key,
+		URL:        res.URL,
+		Type:       res.Type,
+		Provider:   res.Provider,
+		Title:      res.Title,
+		Thumbnail:  res.Thumbnail,
+		ResolvedAt: now,
+		ExpiresAt:  expires,
+	}
+}
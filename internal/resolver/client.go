@@ -0,0 +1,123 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/WhiCu/school-museum/db/storage"
+)
+
+const userAgent = "school-museum/1.0"
+
+// Client makes the requests of resolvers. Size and address limits are left
+// to the HTTP client, see package egress.
+type Client struct {
+	hc *http.Client
+}
+
+func NewClient(hc *http.Client) *Client {
+	return &Client{
+		hc: hc,
+	}
+}
+
+// do sends a request and checks the response status: 401, 403, 404 and 410
+// mean the media is gone or not public (ErrNotFound), other failures are
+// ErrSource. The caller must close the body.
+func (c *Client) do(ctx context.Context, method, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, sourceError(err)
+	}
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return resp, nil
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden,
+		resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusGone:
+		resp.Body.Close()
+		return nil, fmt.Errorf("external source returned status %d: %w", resp.StatusCode, ErrNotFound)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("external source returned status %d: %w", resp.StatusCode, ErrSource)
+	}
+}
+
+// Open requests the file at rawURL with the same error handling as the
+// resolvers. The caller must close the body.
+func (c *Client) Open(ctx context.Context, rawURL string) (*http.Response, error) {
+	return c.do(ctx, http.MethodGet, rawURL)
+}
+
+// page returns the body of the page at rawURL.
+func (c *Client) page(ctx context.Context, rawURL string) (string, error) {
+	resp, err := c.do(ctx, http.MethodGet, rawURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", sourceError(err)
+	}
+	return string(body), nil
+}
+
+// json decodes the JSON response at rawURL into v.
+func (c *Client) json(ctx context.Context, rawURL string, v any) error {
+	resp, err := c.do(ctx, http.MethodGet, rawURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		var se *storage.Error
+		if errors.As(err, &se) {
+			return err
+		}
+		return fmt.Errorf("decode response: %w", ErrSource)
+	}
+	return nil
+}
+
+// sourceError marks a failed request as ErrSource, unless the egress
+// policy refused it: that is a problem of the link, not of the service.
+func sourceError(err error) error {
+	var se *storage.Error
+	if errors.As(err, &se) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrSource, err)
+}
+
+// Head requests the headers of the resource at rawURL with the same error
+// handling as the resolvers, asking with HEAD and falling back to GET for
+// servers that do not allow HEAD. The caller must close the body.
+func (c *Client) Head(ctx context.Context, rawURL string) (*http.Response, error) {
+	resp, err := c.do(ctx, http.MethodHead, rawURL)
+	if errors.Is(err, ErrSource) {
+		resp, err = c.do(ctx, http.MethodGet, rawURL)
+	}
+	return resp, err
+}
+
+// contentType returns the Content-Type of the resource at rawURL.
+func (c *Client) contentType(ctx context.Context, rawURL string) (string, error) {
+	resp, err := c.Head(ctx, rawURL)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("Content-Type"), nil
+}
@@ -0,0 +1,38 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+func init() {
+	register(Provider{
+		Name: "direct",
+		// Any link can be a direct one, so the other resolvers go first.
+		Order: 100,
+		New:   func(c *Client) Resolver { return direct{c: c} },
+	})
+}
+
+// direct accepts links straight to an image or video file, telling them
+// apart by the Content-Type the server reports.
+type direct struct {
+	c *Client
+}
+
+func (direct) Match(u *url.URL) bool {
+	return true
+}
+
+func (r direct) Resolve(ctx context.Context, u *url.URL) (Resolved, error) {
+	ct, err := r.c.contentType(ctx, u.String())
+	if err != nil {
+		return Resolved{}, err
+	}
+	typ := mediaType(ct)
+	if typ == "" {
+		return Resolved{}, fmt.Errorf("content type %q: %w", ct, ErrUnsupportedURL)
+	}
+	return Resolved{URL: u.String(), Type: typ}, nil
+}
@@ -0,0 +1,64 @@
+package resolver
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	register(Provider{
+		Name:  "google-drive",
+		Order: 20,
+		Hosts: []string{"drive.google.com", "drive.usercontent.google.com", "accounts.google.com", "googleusercontent.com"},
+		New:   func(c *Client) Resolver { return drive{c: c} },
+	})
+}
+
+var reDriveID = regexp.MustCompile(`^[A-Za-z0-9_-]{10,}$`)
+
+// drive resolves links to files shared on Google Drive. Images are served
+// by the download URL of the file, videos by the Drive player. Files that
+// are not shared publicly answer with a login page and are not found.
+type drive struct {
+	c *Client
+}
+
+// driveID returns the file ID of a Google Drive link, or "".
+func driveID(u *url.URL) string {
+	if host(u) != "drive.google.com" {
+		return ""
+	}
+	var id string
+	if rest, ok := strings.CutPrefix(u.Path, "/file/d/"); ok {
+		id, _, _ = strings.Cut(rest, "/")
+	} else if u.Path == "/open" || u.Path == "/uc" {
+		id = u.Query().Get("id")
+	}
+	if !reDriveID.MatchString(id) {
+		return ""
+	}
+	return id
+}
+
+func (drive) Match(u *url.URL) bool {
+	return driveID(u) != ""
+}
+
+func (r drive) Resolve(ctx context.Context, u *url.URL) (Resolved, error) {
+	id := driveID(u)
+	file := "https://drive.google.com/uc?export=view&id=" + url.QueryEscape(id)
+
+	ct, err := r.c.contentType(ctx, file)
+	if err != nil {
+		return Resolved{}, err
+	}
+	switch mediaType(ct) {
+	case TypeImage:
+		return Resolved{URL: file, Type: TypeImage}, nil
+	case TypeVideo:
+		return Resolved{URL: "https://drive.google.com/file/d/" + id + "/preview", Type: TypeEmbed}, nil
+	}
+	return Resolved{}, ErrNotFound
+}
@@ -0,0 +1,128 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	register(Provider{
+		Name:  "imgur",
+		Order: 10,
+		Hosts: []string{"imgur.com"},
+		New:   func(c *Client) Resolver { return imgur{c: c} },
+	})
+}
+
+var (
+	rePostDataJSON = regexp.MustCompile(`window\.postDataJSON=\"([\s\S]*?)\"</script>`)
+	reOgVideo      = regexp.MustCompile(`<meta\s+property=\"og:video\"[^>]*content=\"([^\"]+)\"`)
+	reOgImage      = regexp.MustCompile(`<meta\s+property=\"og:image\"[^>]*content=\"([^\"]+)\"`)
+	reOgTitle      = regexp.MustCompile(`<meta\s+property=\"og:title\"[^>]*content=\"([^\"]+)\"`)
+	reTwitterImage = regexp.MustCompile(`<meta\s+name=\"twitter:image\"[^>]*content=\"([^\"]+)\"`)
+)
+
+// imgur resolves imgur.com posts, albums and galleries to the file of
+// their first image or video. Direct i.imgur.com links are left to direct.
+type imgur struct {
+	c *Client
+}
+
+type imgurPostData struct {
+	Cover struct {
+		URL      string `json:"url"`
+		MimeType string `json:"mime_type"`
+	} `json:"cover"`
+	Media []struct {
+		URL      string `json:"url"`
+		MimeType string `json:"mime_type"`
+	} `json:"media"`
+}
+
+func (imgur) Match(u *url.URL) bool {
+	return host(u) == "imgur.com"
+}
+
+func (r imgur) Resolve(ctx context.Context, u *url.URL) (Resolved, error) {
+	page, err := r.c.page(ctx, u.String())
+	if err != nil {
+		return Resolved{}, err
+	}
+
+	if res, ok := extractFromPostDataJSON(page); ok {
+		return res, nil
+	}
+
+	if v, ok := firstSubmatch(reOgVideo, page); ok {
+		return Resolved{URL: v, Type: TypeVideo}, nil
+	}
+
+	if v, ok := firstSubmatch(reTwitterImage, page); ok {
+		return Resolved{URL: strings.ReplaceAll(v, "?fbplay", ""), Type: TypeImage}, nil
+	}
+
+	if v, ok := firstSubmatch(reOgImage, page); ok {
+		return Resolved{URL: strings.ReplaceAll(v, "?fbplay", ""), Type: TypeImage}, nil
+	}
+
+	return Resolved{}, ErrNotFound
+}
+
+func extractFromPostDataJSON(page string) (Resolved, bool) {
+	m := rePostDataJSON.FindStringSubmatch(page)
+	if len(m) < 2 {
+		return Resolved{}, false
+	}
+
+	decoded, err := strconv.Unquote(`"` + m[1] + `"`)
+	if err != nil {
+		return Resolved{}, false
+	}
+
+	var data imgurPostData
+	if err := json.Unmarshal([]byte(decoded), &data); err != nil {
+		return Resolved{}, false
+	}
+
+	if len(data.Media) > 0 && data.Media[0].URL != "" {
+		return Resolved{URL: data.Media[0].URL, Type: classifyMedia(data.Media[0].URL, data.Media[0].MimeType)}, true
+	}
+
+	if data.Cover.URL != "" {
+		return Resolved{URL: data.Cover.URL, Type: classifyMedia(data.Cover.URL, data.Cover.MimeType)}, true
+	}
+
+	return Resolved{}, false
+}
+
+// classifyMedia tells videos from images by the MIME type or, failing
+// that, by the file extension.
+func classifyMedia(sourceURL, mimeType string) string {
+	if mediaType(mimeType) == TypeVideo {
+		return TypeVideo
+	}
+
+	ext := strings.ToLower(path.Ext(sourceURL))
+	switch ext {
+	case ".mp4", ".webm", ".mov", ".m4v", ".ogv", ".ogg":
+		return TypeVideo
+	default:
+		return TypeImage
+	}
+}
+
+// firstSubmatch returns the first group of re in body, with HTML entities
+// of attribute values decoded.
+func firstSubmatch(re *regexp.Regexp, body string) (string, bool) {
+	m := re.FindStringSubmatch(body)
+	if len(m) < 2 {
+		return "", false
+	}
+	return html.UnescapeString(m[1]), true
+}
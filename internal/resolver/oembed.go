@@ -0,0 +1,124 @@
+package resolver
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	register(Provider{
+		Name:  "youtube",
+		Order: 20,
+		Hosts: []string{"youtube.com"},
+		New: func(c *Client) Resolver {
+			return oembed{
+				c:        c,
+				endpoint: "https://www.youtube.com/oembed",
+				videoID:  youTubeID,
+				page:     "https://www.youtube.com/watch?v=",
+				embed:    "https://www.youtube-nocookie.com/embed/",
+			}
+		},
+	})
+	register(Provider{
+		Name:  "rutube",
+		Order: 20,
+		Hosts: []string{"rutube.ru"},
+		New: func(c *Client) Resolver {
+			return oembed{
+				c:        c,
+				endpoint: "https://rutube.ru/api/oembed/",
+				videoID:  rutubeID,
+				page:     "https://rutube.ru/video/",
+				embed:    "https://rutube.ru/play/embed/",
+			}
+		},
+	})
+}
+
+var (
+	reYouTubeID = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	reRutubeID  = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+// oembed resolves links of video hostings to their embedded player. The
+// video ID is taken from the link; the oEmbed endpoint of the hosting
+// confirms that the video exists and is public, and gives its title and
+// preview.
+type oembed struct {
+	c        *Client
+	endpoint string
+	// videoID returns the video ID of a link of the hosting, or "".
+	videoID func(u *url.URL) string
+	// page and embed are the canonical page and player URLs without the ID.
+	page  string
+	embed string
+}
+
+type oembedResponse struct {
+	Title        string `json:"title"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+func (r oembed) Match(u *url.URL) bool {
+	return r.videoID(u) != ""
+}
+
+func (r oembed) Resolve(ctx context.Context, u *url.URL) (Resolved, error) {
+	id := r.videoID(u)
+
+	q := url.Values{}
+	q.Set("format", "json")
+	q.Set("url", r.page+id)
+	var resp oembedResponse
+	if err := r.c.json(ctx, r.endpoint+"?"+q.Encode(), &resp); err != nil {
+		return Resolved{}, err
+	}
+
+	return Resolved{
+		URL:       r.embed + id,
+		Type:      TypeEmbed,
+		Title:     resp.Title,
+		Thumbnail: resp.ThumbnailURL,
+	}, nil
+}
+
+// youTubeID accepts watch, short, live, embed and youtu.be links.
+func youTubeID(u *url.URL) string {
+	var id string
+	switch {
+	case host(u) == "youtu.be":
+		id = strings.Trim(u.Path, "/")
+	case hostIs(u, "youtube.com", "youtube-nocookie.com"):
+		if u.Path == "/watch" {
+			id = u.Query().Get("v")
+			break
+		}
+		for _, prefix := range []string{"/shorts/", "/live/", "/embed/"} {
+			if rest, ok := strings.CutPrefix(u.Path, prefix); ok {
+				id = strings.TrimSuffix(rest, "/")
+			}
+		}
+	}
+	if !reYouTubeID.MatchString(id) {
+		return ""
+	}
+	return id
+}
+
+// rutubeID accepts video, shorts and player links.
+func rutubeID(u *url.URL) string {
+	if !hostIs(u, "rutube.ru") {
+		return ""
+	}
+	for _, prefix := range []string{"/video/", "/shorts/", "/play/embed/"} {
+		if rest, ok := strings.CutPrefix(u.Path, prefix); ok {
+			if id := strings.TrimSuffix(rest, "/"); reRutubeID.MatchString(id) {
+				return id
+			}
+		}
+	}
+	return ""
+}
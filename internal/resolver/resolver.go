@@ -0,0 +1,199 @@
+// Package resolver turns links to pages of external media services into
+// something the site can show: a direct image or video URL, or the URL of
+// an embeddable player.
+//
+// Each service is a Resolver in its own file, registered from init() the
+// same way as database migrations; New builds them all around one HTTP
+// client, so the tests serve the pages and API responses recorded in
+// testdata through its transport. In the server that client is the egress
+// client, and each resolver is limited to the hosts of its service.
+package resolver
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/egress"
+)
+
+var (
+	ErrInvalidURL     = storage.NewError(storage.ErrInvalid, "invalid_media_url", "invalid media URL")
+	ErrUnsupportedURL = storage.NewError(storage.ErrInvalid, "unsupported_media_url", "unsupported media URL")
+	ErrNotFound       = storage.NewError(storage.ErrNotFound, "media_not_found", "no media found on the page")
+	ErrSource         = storage.NewError(storage.ErrUnavailable, "media_source_unavailable", "media source is unavailable")
+)
+
+// Kinds of resolved media.
+const (
+	TypeImage = "image"
+	TypeVideo = "video"
+	// TypeEmbed is a player page to be shown in an iframe.
+	TypeEmbed = "embed"
+)
+
+// Resolved is the media behind an external link.
+type Resolved struct {
+	URL  string `json:"url"`
+	Type string `json:"type" enum:"image,video,embed"`
+	// Provider is the name of the resolver that handled the link.
+	Provider string `json:"provider"`
+	// Title and Thumbnail are filled when the service reports them.
+	Title     string `json:"title,omitempty"`
+	Thumbnail string `json:"thumbnail_url,omitempty"`
+}
+
+// Resolver handles the links of one service.
+type Resolver interface {
+	// Match reports whether u is a link of the service.
+	Match(u *url.URL) bool
+	// Resolve returns the media behind u, which Match accepted.
+	Resolve(ctx context.Context, u *url.URL) (Resolved, error)
+}
+
+// Provider describes a resolver for the registry. Resolvers are tried in
+// ascending Order, so the catch-all direct links come last.
+type Provider struct {
+	Name  string
+	Order int
+	// Hosts are the only hosts, with their subdomains, the resolver may
+	// send requests to, redirects included. Empty means any public host.
+	Hosts []string
+	New   func(c *Client) Resolver
+}
+
+// providers holds all resolvers of the application, filled from init()
+// functions of the files in this package.
+var providers []Provider
+
+func register(p Provider) {
+	providers = append(providers, p)
+}
+
+type entry struct {
+	name  string
+	hosts []string
+	r     Resolver
+}
+
+// Registry resolves links with the first resolver that matches them.
+type Registry struct {
+	resolvers []entry
+}
+
+// New returns a registry of all providers, making requests with c.
+func New(c *Client) *Registry {
+	sorted := slices.Clone(providers)
+	slices.SortStableFunc(sorted, func(a, b Provider) int { return cmp.Compare(a.Order, b.Order) })
+
+	reg := &Registry{}
+	for _, p := range sorted {
+		reg.resolvers = append(reg.resolvers, entry{name: p.Name, hosts: p.Hosts, r: p.New(c)})
+	}
+	return reg
+}
+
+// Providers returns the names of the resolvers in the order they are tried.
+func (reg *Registry) Providers() []string {
+	names := make([]string, len(reg.resolvers))
+	for i, e := range reg.resolvers {
+		names[i] = e.name
+	}
+	return names
+}
+
+// Resolve returns the media behind rawURL.
+func (reg *Registry) Resolve(ctx context.Context, rawURL string) (Resolved, error) {
+	u, err := parse(rawURL)
+	if err != nil {
+		return Resolved{}, err
+	}
+
+	for _, e := range reg.resolvers {
+		if !e.r.Match(u) {
+			continue
+		}
+		rctx := ctx
+		if len(e.hosts) > 0 {
+			rctx = egress.WithHosts(ctx, e.hosts...)
+		}
+		res, err := e.r.Resolve(rctx, u)
+		if err != nil {
+			return Resolved{}, fmt.Errorf("%s: %w", e.name, err)
+		}
+		res.Provider = e.name
+		return res, nil
+	}
+	return Resolved{}, fmt.Errorf("host %q: %w", u.Hostname(), ErrUnsupportedURL)
+}
+
+// Hosts returns the hosts the resolver of rawURL is limited to, or nil if
+// it accepts links to any host, like direct links do.
+func (reg *Registry) Hosts(rawURL string) ([]string, error) {
+	u, err := parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range reg.resolvers {
+		if e.r.Match(u) {
+			return e.hosts, nil
+		}
+	}
+	return nil, fmt.Errorf("host %q: %w", u.Hostname(), ErrUnsupportedURL)
+}
+
+// parse parses an http(s) link with the host in lower case.
+func parse(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("scheme %q: %w", u.Scheme, ErrUnsupportedURL)
+	}
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	return u, nil
+}
+
+// Key returns the normalised form of rawURL that identifies it in caches:
+// links with and without "www." are the same.
+func Key(rawURL string) string {
+	u, err := parse(rawURL)
+	if err != nil {
+		return strings.TrimSpace(rawURL)
+	}
+	u.Host = strings.TrimPrefix(u.Host, "www.")
+	return u.String()
+}
+
+// host returns the host name of u without the "www." prefix.
+func host(u *url.URL) string {
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// hostIs reports whether u is on one of hosts or their subdomains.
+func hostIs(u *url.URL, hosts ...string) bool {
+	h := host(u)
+	for _, host := range hosts {
+		if h == host || strings.HasSuffix(h, "."+host) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaType classifies a MIME type as TypeImage or TypeVideo, or returns "".
+func mediaType(mimeType string) string {
+	switch mt, _, _ := strings.Cut(strings.ToLower(mimeType), ";"); {
+	case strings.HasPrefix(mt, "image/"):
+		return TypeImage
+	case strings.HasPrefix(mt, "video/"):
+		return TypeVideo
+	}
+	return ""
+}
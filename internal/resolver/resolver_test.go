@@ -0,0 +1,279 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// route is a recorded response of an external service.
+type route struct {
+	// host and path are those of the original request; query, if set,
+	// lists parameters the request must have; method, if set, is the only
+	// method the route answers.
+	host, path string
+	query      map[string]string
+	method     string
+
+	status   int
+	ctype    string
+	file     string // in testdata
+	location string
+}
+
+// routes are the responses of the services to the links in the tests.
+var routes = []route{
+	{host: "www.youtube.com", path: "/oembed", query: map[string]string{"format": "json", "url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		status: 200, ctype: "application/json", file: "youtube_oembed.json"},
+	// oEmbed of private and deleted videos.
+	{host: "www.youtube.com", path: "/oembed", query: map[string]string{"url": "https://www.youtube.com/watch?v=PrivateVid1"},
+		status: 401, ctype: "text/html"},
+	{host: "rutube.ru", path: "/api/oembed/", query: map[string]string{"format": "json", "url": "https://rutube.ru/video/0123456789abcdef0123456789abcdef"},
+		status: 200, ctype: "application/json", file: "rutube_oembed.json"},
+	{host: "rutube.ru", path: "/api/oembed/", query: map[string]string{"url": "https://rutube.ru/video/ffffffffffffffffffffffffffffffff"},
+		status: 404, ctype: "application/json"},
+
+	{host: "vk.com", path: "/video-22822305_456241864", status: 200, ctype: "text/html; charset=utf-8", file: "vk_video_public.html"},
+	{host: "vk.com", path: "/video-22822305_456241865", status: 200, ctype: "text/html; charset=utf-8", file: "vk_video_private.html"},
+
+	{host: "drive.google.com", path: "/uc", query: map[string]string{"export": "view", "id": "1ImageFileId0123"},
+		status: 200, ctype: "image/jpeg"},
+	{host: "drive.google.com", path: "/uc", query: map[string]string{"export": "view", "id": "1VideoFileId0123"},
+		status: 200, ctype: "video/mp4"},
+	// Files not shared publicly redirect to the login page.
+	{host: "drive.google.com", path: "/uc", query: map[string]string{"export": "view", "id": "1PrivateFileId0123"},
+		status: 302, location: "https://accounts.google.com/ServiceLogin?service=wise&continue=https%3A%2F%2Fdrive.google.com%2Fuc%3Fexport%3Dview%26id%3D1PrivateFileId0123"},
+	{host: "accounts.google.com", path: "/ServiceLogin", status: 200, ctype: "text/html; charset=utf-8", file: "drive_login.html"},
+
+	{host: "cloud-api.yandex.net", path: "/v1/disk/public/resources", query: map[string]string{"public_key": "https://disk.yandex.ru/i/Vd1985AbCdEf"},
+		status: 200, ctype: "application/json", file: "yadisk_file.json"},
+	{host: "cloud-api.yandex.net", path: "/v1/disk/public/resources", query: map[string]string{"public_key": "https://disk.yandex.ru/d/PhotosFolder"},
+		status: 200, ctype: "application/json", file: "yadisk_dir.json"},
+	{host: "cloud-api.yandex.net", path: "/v1/disk/public/resources", query: map[string]string{"public_key": "https://yadi.sk/i/Removed"},
+		status: 404, ctype: "application/json", file: "yadisk_not_found.json"},
+
+	{host: "imgur.com", path: "/gallery/shkolnaya-lineyka-Ab12Cd3", status: 200, ctype: "text/html", file: "imgur_post.html"},
+
+	{host: "museum.example", path: "/photo.jpg", status: 200, ctype: "image/jpeg"},
+	{host: "museum.example", path: "/parade.webm", method: http.MethodHead, status: 405},
+	{host: "museum.example", path: "/parade.webm", method: http.MethodGet, status: 200, ctype: "video/webm"},
+	{host: "museum.example", path: "/about", status: 200, ctype: "text/html; charset=utf-8", file: "page.html"},
+}
+
+// match reports whether rt answers r, sent to rt.host.
+func (rt route) match(r *http.Request) bool {
+	if r.Host != rt.host || r.URL.Path != rt.path || (rt.method != "" && r.Method != rt.method) {
+		return false
+	}
+	q := r.URL.Query()
+	for k, v := range rt.query {
+		if q.Get(k) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// serve answers the requests of resolvers with routes; any other request
+// is not found.
+func serve(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	for _, rt := range routes {
+		if !rt.match(r) {
+			continue
+		}
+		if rt.location != "" {
+			http.Redirect(w, r, rt.location, rt.status)
+			return
+		}
+		var body []byte
+		if rt.file != "" {
+			var err error
+			if body, err = os.ReadFile(filepath.Join("testdata", rt.file)); err != nil {
+				t.Errorf("fixture: %v", err)
+			}
+		}
+		if rt.ctype != "" {
+			w.Header().Set("Content-Type", rt.ctype)
+		}
+		w.WriteHeader(rt.status)
+		w.Write(body)
+		return
+	}
+	t.Logf("no route for %s %s%s", r.Method, r.Host, r.URL.RequestURI())
+	http.NotFound(w, r)
+}
+
+// rewrite sends all requests to a test server, keeping the original host
+// in the Host header.
+type rewrite struct {
+	srv *url.URL
+}
+
+func (rw rewrite) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Host = r.URL.Host
+	r.URL.Scheme, r.URL.Host = rw.srv.Scheme, rw.srv.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// newRegistry returns a registry that gets the responses of the services
+// from routes.
+func newRegistry(t *testing.T) *Registry {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serve(t, w, r)
+	}))
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(NewClient(&http.Client{Transport: rewrite{srv: u}}))
+}
+
+func TestMatch(t *testing.T) {
+	reg := newRegistry(t)
+	tests := []struct {
+		url      string
+		provider string
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42", "youtube"},
+		{"https://youtu.be/dQw4w9WgXcQ", "youtube"},
+		{"https://m.youtube.com/shorts/dQw4w9WgXcQ/", "youtube"},
+		{"https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ", "youtube"},
+		{"https://rutube.ru/video/0123456789abcdef0123456789abcdef/", "rutube"},
+		{"https://rutube.ru/play/embed/0123456789abcdef0123456789abcdef", "rutube"},
+		{"https://vk.com/video-22822305_456241864", "vk"},
+		{"https://vkvideo.ru/clip-22822305_456241864", "vk"},
+		{"https://vk.com/museum?z=video-22822305_456241864%2Fpl_-22822305_-2", "vk"},
+		{"https://vk.com/video_ext.php?oid=-22822305&id=456241864&hash=8c4f1b7a2d9e3f60", "vk"},
+		{"https://drive.google.com/file/d/1ImageFileId0123/view?usp=sharing", "google-drive"},
+		{"https://drive.google.com/open?id=1ImageFileId0123", "google-drive"},
+		{"https://disk.yandex.ru/i/Vd1985AbCdEf", "yandex-disk"},
+		{"https://yadi.sk/d/PhotosFolder", "yandex-disk"},
+		{"https://imgur.com/gallery/shkolnaya-lineyka-Ab12Cd3", "imgur"},
+		// Links that look like a service but are not a video or a file.
+		{"https://www.youtube.com/watch?v=short", "direct"},
+		{"https://www.youtube.com/@school-museum", "direct"},
+		{"https://rutube.ru/channel/123/", "direct"},
+		{"https://vk.com/museum", "direct"},
+		{"https://drive.google.com/drive/folders/1FolderId0123", "direct"},
+		{"https://disk.yandex.ru/client/disk", "direct"},
+		{"https://i.imgur.com/Ab12Cd3.jpg", "direct"},
+		{"https://museum.example/photo.jpg", "direct"},
+	}
+	for _, tt := range tests {
+		u, err := parse(tt.url)
+		if err != nil {
+			t.Fatalf("parse(%q): %v", tt.url, err)
+		}
+		var got string
+		for _, e := range reg.resolvers {
+			if e.r.Match(u) {
+				got = e.name
+				break
+			}
+		}
+		if got != tt.provider {
+			t.Errorf("%s: matched by %q, want %q", tt.url, got, tt.provider)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	reg := newRegistry(t)
+	tests := []struct {
+		url  string
+		want Resolved
+		err  error
+	}{
+		{url: "https://youtu.be/dQw4w9WgXcQ", want: Resolved{
+			URL: "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ", Type: TypeEmbed, Provider: "youtube",
+			Title: "Парад Победы 1945 года", Thumbnail: "https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg",
+		}},
+		{url: "https://www.youtube.com/watch?v=PrivateVid1", err: ErrNotFound},
+		{url: "https://rutube.ru/video/0123456789abcdef0123456789abcdef/", want: Resolved{
+			URL: "https://rutube.ru/play/embed/0123456789abcdef0123456789abcdef", Type: TypeEmbed, Provider: "rutube",
+			Title: "Экскурсия по музею лицея", Thumbnail: "https://pic.rutubelist.ru/video/3f/0a/3f0a1b2c3d4e5f60718293a4b5c6d7e8.jpg",
+		}},
+		{url: "https://rutube.ru/video/ffffffffffffffffffffffffffffffff/", err: ErrNotFound},
+
+		{url: "https://vk.com/video_ext.php?oid=-22822305&id=456241864", want: Resolved{
+			URL: "https://vk.com/video_ext.php?oid=-22822305&id=456241864", Type: TypeEmbed, Provider: "vk",
+			Title: "Встреча с ветеранами", Thumbnail: "https://sun9-1.userapi.com/impg/preview.jpg?size=800x450&quality=95",
+		}},
+		// The access hash of a private video comes from its page.
+		{url: "https://vk.com/museum?z=video-22822305_456241865%2Fpl_-22822305_-2", want: Resolved{
+			URL: "https://vk.com/video_ext.php?oid=-22822305&id=456241865&hash=8c4f1b7a2d9e3f60", Type: TypeEmbed, Provider: "vk",
+			Title: "Репетиция", Thumbnail: "https://sun9-2.userapi.com/impg/private.jpg?size=800x450",
+		}},
+		{url: "https://vk.com/video-22822305_1", err: ErrNotFound},
+
+		{url: "https://drive.google.com/file/d/1ImageFileId0123/view?usp=sharing", want: Resolved{
+			URL: "https://drive.google.com/uc?export=view&id=1ImageFileId0123", Type: TypeImage, Provider: "google-drive",
+		}},
+		{url: "https://drive.google.com/open?id=1VideoFileId0123", want: Resolved{
+			URL: "https://drive.google.com/file/d/1VideoFileId0123/preview", Type: TypeEmbed, Provider: "google-drive",
+		}},
+		{url: "https://drive.google.com/file/d/1PrivateFileId0123/view", err: ErrNotFound},
+
+		{url: "https://disk.yandex.ru/i/Vd1985AbCdEf", want: Resolved{
+			URL:  "https://downloader.disk.yandex.ru/disk/4b1c2d3e/6a7b8c9d?uid=0&filename=%D0%92%D1%8B%D0%BF%D1%83%D1%81%D0%BA%201985.mp4&disposition=attachment",
+			Type: TypeVideo, Provider: "yandex-disk",
+			Title: "Выпуск 1985.mp4", Thumbnail: "https://downloader.disk.yandex.ru/preview/4b1c2d3e?size=L",
+		}},
+		{url: "https://disk.yandex.ru/d/PhotosFolder", err: ErrNotFound},
+		{url: "https://yadi.sk/i/Removed", err: ErrNotFound},
+
+		{url: "https://imgur.com/gallery/shkolnaya-lineyka-Ab12Cd3", want: Resolved{
+			URL: "https://i.imgur.com/Xy98Zw7.mp4", Type: TypeVideo, Provider: "imgur",
+		}},
+
+		{url: "https://museum.example/photo.jpg", want: Resolved{
+			URL: "https://museum.example/photo.jpg", Type: TypeImage, Provider: "direct",
+		}},
+		// A server that does not allow HEAD is asked with GET.
+		{url: "https://museum.example/parade.webm", want: Resolved{
+			URL: "https://museum.example/parade.webm", Type: TypeVideo, Provider: "direct",
+		}},
+		{url: "https://museum.example/about", err: ErrUnsupportedURL},
+		{url: "https://museum.example/missing.jpg", err: ErrNotFound},
+
+		{url: "ftp://museum.example/photo.jpg", err: ErrUnsupportedURL},
+		{url: "museum.example/photo.jpg", err: ErrInvalidURL},
+	}
+	for _, tt := range tests {
+		got, err := reg.Resolve(context.Background(), tt.url)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Resolve(%q): %v, want %v", tt.url, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", tt.url, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%q) = %+v, want %+v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestHosts(t *testing.T) {
+	reg := newRegistry(t)
+	hosts, err := reg.Hosts("https://youtu.be/dQw4w9WgXcQ")
+	if err != nil || len(hosts) == 0 {
+		t.Errorf("Hosts of a YouTube link = %q, %v, want the YouTube hosts", hosts, err)
+	}
+	if hosts, err := reg.Hosts("https://museum.example/photo.jpg"); err != nil || hosts != nil {
+		t.Errorf("Hosts of a direct link = %q, %v, want none", hosts, err)
+	}
+	if _, err := reg.Hosts("ftp://museum.example/photo.jpg"); !errors.Is(err, ErrUnsupportedURL) {
+		t.Errorf("Hosts of an ftp link: %v, want ErrUnsupportedURL", err)
+	}
+}
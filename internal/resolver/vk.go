@@ -0,0 +1,74 @@
+package resolver
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	register(Provider{
+		Name:  "vk",
+		Order: 20,
+		Hosts: []string{"vk.com", "vk.ru", "vkvideo.ru"},
+		New:   func(c *Client) Resolver { return vk{c: c} },
+	})
+}
+
+var reVKVideo = regexp.MustCompile(`^(?:video|clip)(-?\d+)_(\d+)$`)
+
+// vk resolves VK video and clip links to the VK player. Private videos can
+// only be played with the access hash of the player URL, which the video
+// page gives in og:video.
+type vk struct {
+	c *Client
+}
+
+// vkVideo returns the owner and video IDs of a VK video link.
+func vkVideo(u *url.URL) (oid, id string, ok bool) {
+	if !hostIs(u, "vk.com", "vk.ru", "vkvideo.ru") {
+		return "", "", false
+	}
+	if u.Path == "/video_ext.php" {
+		q := u.Query()
+		oid, id = q.Get("oid"), q.Get("id")
+		return oid, id, reVKVideo.MatchString("video" + oid + "_" + id)
+	}
+	// Videos opened over a feed or a group page are in the z parameter.
+	for _, s := range []string{u.Path, u.Query().Get("z")} {
+		s, _, _ = strings.Cut(strings.TrimPrefix(s, "/"), "/")
+		if m := reVKVideo.FindStringSubmatch(s); m != nil {
+			return m[1], m[2], true
+		}
+	}
+	return "", "", false
+}
+
+func (vk) Match(u *url.URL) bool {
+	_, _, ok := vkVideo(u)
+	return ok
+}
+
+func (r vk) Resolve(ctx context.Context, u *url.URL) (Resolved, error) {
+	oid, id, _ := vkVideo(u)
+
+	q := url.Values{}
+	q.Set("oid", oid)
+	q.Set("id", id)
+	if hash := u.Query().Get("hash"); hash != "" && u.Path == "/video_ext.php" {
+		q.Set("hash", hash)
+	}
+	res := Resolved{URL: "https://vk.com/video_ext.php?" + q.Encode(), Type: TypeEmbed}
+
+	page, err := r.c.page(ctx, "https://vk.com/video"+oid+"_"+id)
+	if err != nil {
+		return Resolved{}, err
+	}
+	if v, ok := firstSubmatch(reOgVideo, page); ok && strings.Contains(v, "/video_ext.php") {
+		res.URL = v
+	}
+	res.Title, _ = firstSubmatch(reOgTitle, page)
+	res.Thumbnail, _ = firstSubmatch(reOgImage, page)
+	return res, nil
+}
@@ -0,0 +1,54 @@
+package resolver
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	register(Provider{
+		Name:  "yandex-disk",
+		Order: 20,
+		Hosts: []string{"cloud-api.yandex.net"},
+		New:   func(c *Client) Resolver { return yandexDisk{c: c} },
+	})
+}
+
+// yandexDisk resolves public links to files on Yandex Disk with the public
+// resources API. The download URL it returns expires after some hours, so
+// callers should not store it in place of the public link.
+type yandexDisk struct {
+	c *Client
+}
+
+type yandexResource struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type"`
+	File     string `json:"file"`
+	Preview  string `json:"preview"`
+}
+
+func (yandexDisk) Match(u *url.URL) bool {
+	// /i/ links are single files, /d/ links are files or folders.
+	public := strings.HasPrefix(u.Path, "/i/") || strings.HasPrefix(u.Path, "/d/")
+	return public && hostIs(u, "yadi.sk", "disk.yandex.ru", "disk.yandex.com", "disk.yandex.by", "disk.yandex.kz")
+}
+
+func (r yandexDisk) Resolve(ctx context.Context, u *url.URL) (Resolved, error) {
+	q := url.Values{}
+	q.Set("public_key", u.String())
+	q.Set("fields", "type,name,mime_type,file,preview")
+	var res yandexResource
+	if err := r.c.json(ctx, "https://cloud-api.yandex.net/v1/disk/public/resources?"+q.Encode(), &res); err != nil {
+		return Resolved{}, err
+	}
+
+	// Folders have no single file to show.
+	typ := mediaType(res.MimeType)
+	if res.Type != "file" || res.File == "" || typ == "" {
+		return Resolved{}, ErrNotFound
+	}
+	return Resolved{URL: res.File, Type: typ, Title: res.Name, Thumbnail: res.Preview}, nil
+}
@@ -2,23 +2,36 @@ package server
 
 import (
 	"context"
-	"crypto/subtle"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
+	"net/netip"
 	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/WhiCu/school-museum/db"
+	"github.com/WhiCu/school-museum/db/migrate"
 	"github.com/WhiCu/school-museum/db/model"
 	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/db/storage/memory"
+	"github.com/WhiCu/school-museum/internal/auth"
 	"github.com/WhiCu/school-museum/internal/config"
+	"github.com/WhiCu/school-museum/internal/egress"
+	"github.com/WhiCu/school-museum/internal/linkcheck"
+	"github.com/WhiCu/school-museum/internal/media"
+	"github.com/WhiCu/school-museum/internal/mediaproxy"
+	"github.com/WhiCu/school-museum/internal/problem"
+	"github.com/WhiCu/school-museum/internal/ratelimit"
+	"github.com/WhiCu/school-museum/internal/resolver"
 	webadmin "github.com/WhiCu/school-museum/internal/web-admin"
+	adminclient "github.com/WhiCu/school-museum/internal/web-admin/client"
+	adminservice "github.com/WhiCu/school-museum/internal/web-admin/service"
 	webmuseum "github.com/WhiCu/school-museum/internal/web-museum"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humabunrouter"
@@ -32,6 +45,9 @@ type App struct {
 	log *slog.Logger
 
 	shutdownTimeout time.Duration
+
+	// jobs run in the background for the lifetime of the server.
+	jobs []func(ctx context.Context) error
 }
 
 func (a *App) gracefulShutdownCtx(ctx context.Context) error {
@@ -65,39 +81,177 @@ func NewApp(ctx context.Context, cfg *config.Config, log *slog.Logger) *App {
 	}
 
 	// ----- Database -----
-	database, err := db.NewDB(ctx, cfg.Storage.DSN(), db.WithDebug(true))
+	stg := newStorages(ctx, cfg, log)
+
+	// ----- Admin users -----
+	lockout := auth.NewLockout(stg.loginFailures, auth.LockoutOptions{
+		Threshold:   cfg.Admin.LockoutThreshold,
+		IPThreshold: cfg.Admin.LockoutIPThreshold,
+		Duration:    cfg.Admin.LockoutDuration,
+		MaxDuration: cfg.Admin.LockoutMaxDuration,
+		Retention:   cfg.Admin.LoginFailureRetention,
+	}, log.WithGroup("auth"))
+	users := auth.NewUsers(stg.users, stg.sessions, stg.tx, lockout, log.WithGroup("auth"))
+	totpRoles := make([]model.Role, 0, len(cfg.Admin.TOTPRoles))
+	for _, r := range cfg.Admin.TOTPRoles {
+		role := model.Role(r)
+		if !role.Valid() {
+			log.Error("unknown role in admin.totp_roles", slog.String("role", r))
+			panic(fmt.Sprintf("unknown role in admin.totp_roles: %s", r))
+		}
+		totpRoles = append(totpRoles, role)
+	}
+	twoFactor := auth.NewTwoFactor(stg.users, lockout, auth.TwoFactorOptions{
+		Issuer: cfg.Admin.TOTPIssuer,
+		Roles:  totpRoles,
+	}, log.WithGroup("auth"))
+	tokens := auth.NewTokens(stg.tokens, stg.users)
+	sessions := auth.NewSessions(stg.sessions, stg.users, auth.SessionOptions{
+		IdleTimeout:  cfg.Admin.SessionIdleTimeout,
+		MaxAge:       cfg.Admin.SessionMaxAge,
+		SecureCookie: cfg.Admin.SecureCookie,
+	}, log.WithGroup("auth"))
+	// The server itself speaks plain HTTP and cannot tell whether a proxy
+	// in front of it serves HTTPS.
+	if cfg.Admin.SecureCookie {
+		log.Warn("admin.secure_cookie is on: browsers keep the session cookie only over HTTPS and on http://localhost, " +
+			"set it to false to sign in to the admin over plain HTTP")
+	}
+	created, err := users.Bootstrap(ctx, cfg.Admin.Login, cfg.Admin.Password)
 	if err != nil {
-		log.Error("failed to create database connection", slog.String("error", err.Error()))
+		log.Error("failed to create the first admin user", slog.String("error", err.Error()))
 		panic(err)
 	}
-
-	news := storage.NewNewsStorage(database)
-	exhibits := storage.NewExhibitStorage(database)
-	exhibitions := storage.NewExhibitionStorage(database)
-	visits := storage.NewVisitStorage(database)
+	if created {
+		log.Info("created the first admin user from the configuration", slog.String("login", cfg.Admin.Login))
+	}
 
 	// ----- Router -----
+	// Every error response, including huma's own, is an RFC 7807 problem with a code.
+	huma.NewError = problem.New
+
 	r := bunrouter.New()
 
 	api := humabunrouter.New(r, huma.DefaultConfig("school-museum", "0.1.0"))
 	pingHandler(api)
 
+	egressOpts, err := EgressOptions(cfg.Egress)
+	if err != nil {
+		log.Error("failed to parse egress allowed networks", slog.String("error", err.Error()))
+		panic(err)
+	}
+	// Every request to an external link goes through this client.
+	outbound := egress.NewClient(egressOpts)
+
+	var resolvedStore storage.ResolvedMedia
+	if cfg.Media.ResolvePersist {
+		resolvedStore = stg.resolved
+	}
+	resolved := resolver.NewCache(
+		resolver.New(resolver.NewClient(outbound)),
+		resolvedStore,
+		resolver.CacheOptions{
+			Size:        cfg.Media.ResolveCacheSize,
+			TTL:         cfg.Media.ResolveTTL,
+			NegativeTTL: cfg.Media.ResolveNegativeTTL,
+		},
+		log.WithGroup("resolver"))
+
+	// Proxied files may be large videos, so their downloads get more time.
+	proxyEgress := egressOpts
+	proxyEgress.Timeout = cfg.Media.ProxyTimeout
+	proxy, err := mediaproxy.New(
+		cfg.Media.ProxyDir,
+		resolver.NewClient(egress.NewClient(proxyEgress)),
+		resolved,
+		mediaproxy.Options{
+			MaxFileSize:  cfg.Media.ProxyMaxSize << 20,
+			MaxCacheSize: cfg.Media.ProxyCacheSize << 20,
+			TTL:          cfg.Media.ProxyTTL,
+			Hosts:        cfg.Media.ProxyHosts,
+		},
+		log.WithGroup("media-proxy"))
+	if err != nil {
+		log.Error("failed to create media proxy", slog.String("dir", cfg.Media.ProxyDir), slog.String("error", err.Error()))
+		panic(err)
+	}
+
+	links := NewLinkChecker(stg.news, stg.exhibits, stg.links, outbound, cfg.Media, log.WithGroup("link-check"))
+
 	museum := huma.NewGroup(api, "/museum")
 	webmuseum.RegisterHandlers(
-		museum, news, exhibitions, exhibits, visits, log.WithGroup("web-museum"))
+		museum, stg.news, stg.exhibitions, stg.exhibits, stg.visits, stg.search, stg.assets, stg.media, resolved, proxy, log.WithGroup("web-museum"))
+
+	files, err := media.NewStore(cfg.Media.Dir, cfg.Media.MaxBytes())
+	if err != nil {
+		log.Error("failed to create media store", slog.String("dir", cfg.Media.Dir), slog.String("error", err.Error()))
+		panic(err)
+	}
+	r.GET(media.URLPrefix+"*path", bunrouter.HTTPHandler(files))
 
 	admin := huma.NewGroup(api, "/admin")
-	webadmin.RegisterHandlers(
-		admin, news, exhibitions, exhibits, visits, log.WithGroup("web-admin"))
+	adminSrv := webadmin.RegisterHandlers(
+		admin, stg.news, stg.exhibitions, stg.exhibits, stg.visits, stg.trash, stg.tx, stg.revisions, stg.assets, stg.media, stg.imports, log.WithGroup("web-admin"),
+		adminservice.WithTrashRetention(cfg.Trash.Retention),
+		adminservice.WithFiles(files),
+		adminservice.WithResolvedMedia(resolved),
+		adminservice.WithImports(proxy),
+		adminservice.WithLinkChecks(links),
+		adminservice.WithUsers(users),
+		adminservice.WithSessions(sessions),
+		adminservice.WithTokens(tokens),
+		adminservice.WithLoginFailures(lockout),
+		adminservice.WithTwoFactor(twoFactor))
+
+	// ----- Background jobs -----
+	app.jobs = append(app.jobs, func(ctx context.Context) error {
+		return adminSrv.RunTrashPurge(ctx, cfg.Trash.PurgeInterval)
+	})
+	app.jobs = append(app.jobs, func(ctx context.Context) error {
+		return adminSrv.RunVariants(ctx, cfg.Media.VariantWorkers, cfg.Media.VariantInterval)
+	})
+	app.jobs = append(app.jobs, func(ctx context.Context) error {
+		return resolved.RunPurge(ctx, cfg.Media.ResolveTTL)
+	})
+	app.jobs = append(app.jobs, func(ctx context.Context) error {
+		return proxy.RunPurge(ctx, cfg.Media.ProxyPurgeInterval)
+	})
+	app.jobs = append(app.jobs, func(ctx context.Context) error {
+		return adminSrv.RunImports(ctx, cfg.Media.ImportInterval)
+	})
+	app.jobs = append(app.jobs, func(ctx context.Context) error {
+		return links.Run(ctx, cfg.Media.LinkCheckInterval)
+	})
+	app.jobs = append(app.jobs, func(ctx context.Context) error {
+		return sessions.RunPurge(ctx, cfg.Admin.SessionPurgeInterval)
+	})
+	app.jobs = append(app.jobs, func(ctx context.Context) error {
+		return lockout.RunPurge(ctx, cfg.Admin.SessionPurgeInterval)
+	})
 
 	// ----- HTTP handler chain -----
 	var handler http.Handler = r
 
-	// Basic Auth middleware for /admin/ routes
-	handler = adminAuthMiddleware(handler, cfg.Admin.Login, cfg.Admin.Password)
+	// Basic Auth and session middleware for /admin/ routes
+	handler = adminAuthMiddleware(handler, users, sessions, tokens, twoFactor, log.WithGroup("auth"))
+
+	// Rate limit of the media proxy, which downloads files for visitors
+	if cfg.Media.ProxyRate > 0 {
+		handler = rateLimitMiddleware(handler, "/museum/media/proxy", ratelimit.New(ratelimit.Options{Rate: cfg.Media.ProxyRate}))
+	}
+
+	// Request body limit — multipart uploads are not limited by huma
+	if limit := cfg.Media.MaxBytes(); limit > 0 {
+		handler = bodyLimitMiddleware(handler, limit+maxFormOverhead)
+	}
 
 	// Visit tracking middleware — extracts IP and User-Agent into request context
-	handler = visitTrackingMiddleware(handler)
+	trustedProxies, err := egress.ParseNetworks(cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Error("failed to parse trusted proxies", slog.String("error", err.Error()))
+		panic(err)
+	}
+	handler = visitTrackingMiddleware(handler, trustedProxies)
 
 	// CORS middleware
 	handler = corsMiddleware(handler)
@@ -113,12 +267,135 @@ func NewApp(ctx context.Context, cfg *config.Config, log *slog.Logger) *App {
 	return app
 }
 
+// storages bundles the storage backends shared by web-museum and web-admin.
+type storages struct {
+	news        storage.Storage[model.News]
+	exhibitions storage.Exhibitions
+	exhibits    storage.Storage[model.Exhibit]
+	visits      storage.Visits
+	search      storage.Searcher
+	trash       adminclient.Trash
+	tx          storage.Transactor
+	revisions   storage.Revisions
+	assets      storage.Assets
+	media       storage.MediaLibrary
+	resolved    storage.ResolvedMedia
+	imports     storage.ImportJobs
+	links       storage.LinkChecks
+	users       storage.AdminUsers
+	sessions    storage.AdminSessions
+	tokens      storage.APITokens
+	// loginFailures is the security log of the failed sign-ins.
+	loginFailures storage.LoginFailures
+}
+
+// newStorages creates the storages for the configured storage driver.
+// For Postgres it also applies pending schema migrations.
+func newStorages(ctx context.Context, cfg *config.Config, log *slog.Logger) storages {
+	switch cfg.Storage.Driver {
+	case config.DriverMemory:
+		log.Warn("using in-memory storage, data will be lost on restart")
+		mem := memory.NewDB()
+		news := memory.NewNewsStorage(mem)
+		exhibitions := memory.NewExhibitionStorage(mem)
+		exhibits := memory.NewExhibitStorage(mem)
+		return storages{
+			news:          news,
+			exhibitions:   exhibitions,
+			exhibits:      exhibits,
+			visits:        memory.NewVisitStorage(mem),
+			search:        memory.NewSearchStorage(mem),
+			trash:         adminclient.Trash{News: news, Exhibitions: exhibitions, Exhibits: exhibits},
+			tx:            mem,
+			revisions:     memory.NewRevisionStorage(mem),
+			assets:        memory.NewAssetStorage(mem),
+			media:         memory.NewMediaStorage(mem),
+			resolved:      memory.NewResolvedMediaStorage(mem),
+			imports:       memory.NewImportJobStorage(mem),
+			links:         memory.NewLinkCheckStorage(mem),
+			users:         memory.NewAdminUserStorage(mem),
+			sessions:      memory.NewAdminSessionStorage(mem),
+			tokens:        memory.NewAPITokenStorage(mem),
+			loginFailures: memory.NewLoginFailureStorage(mem),
+		}
+	case config.DriverPostgres, "":
+	default:
+		log.Error("unsupported storage driver", slog.String("driver", cfg.Storage.Driver))
+		panic(fmt.Sprintf("unsupported storage driver: %s", cfg.Storage.Driver))
+	}
+
+	database, err := db.NewDB(ctx, cfg.Storage.DSN(), db.WithDebug(true))
+	if err != nil {
+		log.Error("failed to create database connection", slog.String("error", err.Error()))
+		panic(err)
+	}
+
+	if _, err := migrate.NewMigrator(database, log.WithGroup("migrate")).Up(ctx); err != nil {
+		log.Error("failed to apply migrations", slog.String("error", err.Error()))
+		panic(err)
+	}
+
+	news := storage.NewNewsStorage(database)
+	exhibitions := storage.NewExhibitionStorage(database)
+	exhibits := storage.NewExhibitStorage(database)
+	return storages{
+		news:          news,
+		exhibitions:   exhibitions,
+		exhibits:      exhibits,
+		visits:        storage.NewVisitStorage(database),
+		search:        storage.NewSearchStorage(database),
+		trash:         adminclient.Trash{News: news, Exhibitions: exhibitions, Exhibits: exhibits},
+		tx:            storage.NewTxManager(database),
+		revisions:     storage.NewRevisionStorage(database),
+		assets:        storage.NewAssetStorage(database),
+		media:         storage.NewMediaStorage(database),
+		resolved:      storage.NewResolvedMediaStorage(database),
+		imports:       storage.NewImportJobStorage(database),
+		links:         storage.NewLinkCheckStorage(database),
+		users:         storage.NewAdminUserStorage(database),
+		sessions:      storage.NewAdminSessionStorage(database),
+		tokens:        storage.NewAPITokenStorage(database),
+		loginFailures: storage.NewLoginFailureStorage(database),
+	}
+}
+
+// EgressOptions returns the policy of the requests to external links
+// configured by cfg.
+func EgressOptions(cfg config.EgressConfig) (egress.Options, error) {
+	allowedNetworks, err := egress.ParseNetworks(cfg.AllowedNetworks)
+	if err != nil {
+		return egress.Options{}, err
+	}
+	return egress.Options{
+		Timeout:         cfg.Timeout,
+		MaxRedirects:    cfg.MaxRedirects,
+		MaxResponseSize: cfg.MaxResponseBytes(),
+		MaxPerHost:      cfg.MaxPerHost,
+		ContentTypes:    cfg.ContentTypes,
+		AllowPrivate:    cfg.AllowPrivate,
+		AllowedNetworks: allowedNetworks,
+	}, nil
+}
+
+// NewLinkChecker returns the checker of the image links of news and
+// exhibits, making requests with the egress client outbound.
+func NewLinkChecker(
+	news storage.Storage[model.News],
+	exhibits storage.Storage[model.Exhibit],
+	checks storage.LinkChecks,
+	outbound *http.Client,
+	cfg config.MediaConfig,
+	log *slog.Logger) *linkcheck.Checker {
+	return linkcheck.New(news, exhibits, checks, resolver.NewClient(outbound),
+		linkcheck.Options{Workers: cfg.LinkCheckWorkers}, log)
+}
+
 // visitTrackingMiddleware extracts the visitor's IP address and User-Agent
 // from the HTTP request and stores them in the request context.
 // Downstream handlers can read them via model.CtxKeyVisitorIP / model.CtxKeyVisitorUA.
-func visitTrackingMiddleware(next http.Handler) http.Handler {
+func visitTrackingMiddleware(next http.Handler, trustedProxies []netip.Prefix) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := extractIP(r)
+		ip := extractIP(r, trustedProxies)
 		ua := r.Header.Get("User-Agent")
 
 		ctx := context.WithValue(r.Context(), model.CtxKeyVisitorIP, ip)
@@ -128,28 +405,61 @@ func visitTrackingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// extractIP retrieves the real client IP, checking proxy headers first.
-func extractIP(r *http.Request) string {
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		parts := strings.Split(xff, ",")
-		return strings.TrimSpace(parts[0])
+// extractIP retrieves the real client IP. Anyone can send proxy headers,
+// so they are only read when the request comes from a trusted proxy: the
+// client is then the right-most address of X-Forwarded-For that is not a
+// trusted proxy itself, or X-Real-Ip without X-Forwarded-For.
+func extractIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
 	}
-	if xri := r.Header.Get("X-Real-Ip"); xri != "" {
-		return xri
+	if !isTrustedProxy(ip, trustedProxies) {
+		return ip
 	}
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		// Every proxy appends the address it got the request from, so the
+		// hops are read from the right, up to the first one not trusted.
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if _, err := netip.ParseAddr(hop); err != nil {
+				// Proxies write valid addresses; anything else came from the client.
+				break
+			}
+			ip = hop
+			if !isTrustedProxy(hop, trustedProxies) {
+				break
+			}
+		}
+		return ip
+	}
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-Ip")); xri != "" {
+		if _, err := netip.ParseAddr(xri); err == nil {
+			return xri
+		}
 	}
 	return ip
 }
 
+// isTrustedProxy reports whether ip is in one of trustedProxies.
+func isTrustedProxy(ip string, trustedProxies []netip.Prefix) bool {
+	a, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	a = a.Unmap()
+	return slices.ContainsFunc(trustedProxies, func(p netip.Prefix) bool { return p.Contains(a) })
+}
+
 // corsMiddleware adds CORS headers to allow cross-origin requests from the frontend.
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, X-CSRF-Token")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count, ETag, X-Import-Job")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
@@ -160,50 +470,133 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// adminAuthMiddleware protects /admin/ routes with Basic Auth.
-// Requests to other paths pass through unchanged.
-func adminAuthMiddleware(next http.Handler, login, password string) http.Handler {
+// maxFormOverhead is the room left in a request body for the multipart
+// headers and fields around an uploaded file.
+const maxFormOverhead = 1 << 20
+
+// bodyLimitMiddleware rejects request bodies larger than limit bytes.
+func bodyLimitMiddleware(next http.Handler, limit int64) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.HasPrefix(r.URL.Path, "/admin/") && r.URL.Path != "/admin" {
-			next.ServeHTTP(w, r)
+		if r.ContentLength > limit {
+			problem.Write(w, http.StatusRequestEntityTooLarge, "слишком большой запрос")
 			return
 		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
 
-		auth := r.Header.Get("Authorization")
-		if auth == "" {
-			http.Error(w, `{"title":"Unauthorized","status":401}`, http.StatusUnauthorized)
+// rateLimitMiddleware answers 429 to requests to path from addresses over
+// the limit of limiter. Requests to other paths pass through unchanged.
+func rateLimitMiddleware(next http.Handler, path string, limiter *ratelimit.Limiter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			next.ServeHTTP(w, r)
 			return
 		}
-
-		if !strings.HasPrefix(auth, "Basic ") {
-			http.Error(w, `{"title":"Unauthorized","status":401}`, http.StatusUnauthorized)
+		ip, _ := r.Context().Value(model.CtxKeyVisitorIP).(string)
+		if ok, wait := limiter.Allow(ip); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+			problem.Write(w, http.StatusTooManyRequests, "слишком много запросов, попробуйте позже")
 			return
 		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		decoded, err := base64.StdEncoding.DecodeString(auth[6:])
-		if err != nil {
-			http.Error(w, `{"title":"Unauthorized","status":401}`, http.StatusUnauthorized)
+// adminLoginPath is the sign-in of the login form, open to everyone.
+const adminLoginPath = "/admin/login"
+
+// adminAuthMiddleware protects /admin/ routes with an API token in the
+// Authorization: Bearer header, with Basic Auth against the admin users or
+// with the session cookie of the login form, and puts the login and role of
+// the user in the request context, with the scopes of the token if any.
+// Requests with a session that change something must carry its CSRF token;
+// tokens and Basic Auth cannot be forged by other sites and need none.
+// Basic Auth from a login or address locked out after too many failures
+// gets 429, and users with two-factor authentication cannot use it at all:
+// it has no room for the code. Users whose role requires two-factor
+// authentication they have not enabled are marked in the context, so the
+// admin lets them only enroll. Requests to other paths pass through
+// unchanged.
+func adminAuthMiddleware(next http.Handler, users *auth.Users, sessions *auth.Sessions, tokens *auth.Tokens, twoFactor *auth.TwoFactor, log *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/admin/") && r.URL.Path != "/admin" || r.URL.Path == adminLoginPath {
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		parts := strings.SplitN(string(decoded), ":", 2)
-		if len(parts) != 2 {
-			http.Error(w, `{"title":"Unauthorized","status":401}`, http.StatusUnauthorized)
+		ctx := r.Context()
+		var (
+			user model.AdminUser
+			err  error
+		)
+		if secret, ok := bearerToken(r); ok {
+			var tok model.APIToken
+			ip, _ := ctx.Value(model.CtxKeyVisitorIP).(string)
+			tok, user, err = tokens.Authenticate(ctx, secret, ip)
+			ctx = context.WithValue(ctx, model.CtxKeyAdminScopes, tok.Scopes)
+		} else if login, password, ok := r.BasicAuth(); ok {
+			user, err = users.Authenticate(ctx, login, password)
+			if err == nil && user.TOTPEnabled {
+				problem.Write(w, http.StatusUnauthorized, "для пользователя включена двухфакторная аутентификация: войдите через форму или используйте API-токен")
+				return
+			}
+		} else if cookie, cerr := r.Cookie(auth.CookieName); cerr == nil {
+			var as model.AdminSession
+			as, user, err = sessions.Authenticate(ctx, cookie.Value)
+			if errors.Is(err, auth.ErrInvalidSession) {
+				http.SetCookie(w, sessions.ClearCookie())
+			}
+			if err == nil && !safeMethod(r.Method) && !auth.CheckCSRF(as, r.Header.Get(auth.CSRFHeader)) {
+				problem.Write(w, http.StatusForbidden, "неверный CSRF-токен")
+				return
+			}
+			ctx = context.WithValue(ctx, model.CtxKeyAdminSession, as.ID)
+		} else {
+			problem.Write(w, http.StatusUnauthorized, "требуется авторизация")
 			return
 		}
 
-		loginOk := subtle.ConstantTimeCompare([]byte(parts[0]), []byte(login)) == 1
-		passOk := subtle.ConstantTimeCompare([]byte(parts[1]), []byte(password)) == 1
-
-		if !loginOk || !passOk {
-			http.Error(w, `{"title":"Unauthorized","status":401}`, http.StatusUnauthorized)
+		var locked *auth.LockedError
+		if errors.As(err, &locked) {
+			w.Header().Set("Retry-After", strconv.Itoa(locked.Seconds()))
+			problem.WriteError(w, err, "слишком много неудачных попыток входа, попробуйте позже")
+			return
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) || errors.Is(err, auth.ErrInvalidSession) || errors.Is(err, auth.ErrInvalidToken) {
+			problem.Write(w, http.StatusUnauthorized, "требуется авторизация")
+			return
+		}
+		if err != nil {
+			log.Error("failed to authenticate admin user", slog.String("error", err.Error()))
+			problem.Write(w, http.StatusServiceUnavailable, "не удалось проверить пользователя")
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx = context.WithValue(ctx, model.CtxKeyAdmin, user.Login)
+		ctx = context.WithValue(ctx, model.CtxKeyAdminRole, user.Role)
+		if twoFactor.Required(user.Role) && !user.TOTPEnabled {
+			ctx = context.WithValue(ctx, model.CtxKeyAdminTOTPSetup, true)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// bearerToken returns the token of the Authorization: Bearer header of r.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// safeMethod reports whether requests with method change nothing.
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
 func (a *App) Run(ctx context.Context) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -219,10 +612,21 @@ func (a *App) Run(ctx context.Context) error {
 		return nil
 	})
 
+	// Background jobs stop as soon as shutdown begins.
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	defer stopJobs()
+
 	eg.Go(func() error {
+		defer stopJobs()
 		return a.gracefulShutdownCtx(ctx)
 	})
 
+	for _, job := range a.jobs {
+		eg.Go(func() error {
+			return job(jobsCtx)
+		})
+	}
+
 	fmt.Println(`
 	==================================
 	=                                =
@@ -0,0 +1,372 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage/memory"
+	"github.com/WhiCu/school-museum/internal/auth"
+	"github.com/WhiCu/school-museum/internal/config"
+	"github.com/WhiCu/school-museum/internal/problem"
+	webadmin "github.com/WhiCu/school-museum/internal/web-admin"
+	adminservice "github.com/WhiCu/school-museum/internal/web-admin/service"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humabunrouter"
+	"github.com/uptrace/bunrouter"
+)
+
+// seen is what adminAuthMiddleware put into the context of a request it
+// let through.
+type seen struct {
+	login     any
+	role      any
+	scopes    any
+	session   any
+	totpSetup any
+}
+
+// authTest is adminAuthMiddleware in front of a handler that records the
+// requests it gets, with users, sessions and tokens in memory.
+type authTest struct {
+	t        *testing.T
+	users    *auth.Users
+	sessions *auth.Sessions
+	tokens   *auth.Tokens
+	store    *memory.AdminUserStorage
+	handler  http.Handler
+	seen     *seen
+}
+
+func newAuthTest(t *testing.T) *authTest {
+	t.Helper()
+	db := memory.NewDB()
+	log := slog.New(slog.DiscardHandler)
+	store := memory.NewAdminUserStorage(db)
+	lockout := auth.NewLockout(memory.NewLoginFailureStorage(db), auth.LockoutOptions{Threshold: 2, Duration: time.Minute}, log)
+	at := &authTest{
+		t:        t,
+		users:    auth.NewUsers(store, memory.NewAdminSessionStorage(db), db, lockout, log),
+		sessions: auth.NewSessions(memory.NewAdminSessionStorage(db), store, auth.SessionOptions{}, log),
+		tokens:   auth.NewTokens(memory.NewAPITokenStorage(db), store),
+		store:    store,
+	}
+	twoFactor := auth.NewTwoFactor(store, lockout, auth.TwoFactorOptions{Roles: []model.Role{model.RoleOwner}}, log)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		at.seen = &seen{
+			login:     ctx.Value(model.CtxKeyAdmin),
+			role:      ctx.Value(model.CtxKeyAdminRole),
+			scopes:    ctx.Value(model.CtxKeyAdminScopes),
+			session:   ctx.Value(model.CtxKeyAdminSession),
+			totpSetup: ctx.Value(model.CtxKeyAdminTOTPSetup),
+		}
+	})
+	at.handler = adminAuthMiddleware(next, at.users, at.sessions, at.tokens, twoFactor, log)
+	return at
+}
+
+func (at *authTest) add(login string, role model.Role) model.AdminUser {
+	at.t.Helper()
+	user, err := at.users.Add(context.Background(), login, "correct horse", role)
+	if err != nil {
+		at.t.Fatalf("Add(%q): %v", login, err)
+	}
+	return user
+}
+
+func (at *authTest) disable(user model.AdminUser) {
+	at.t.Helper()
+	if _, err := at.users.Update(context.Background(), user.ID, user.Role, true); err != nil {
+		at.t.Fatalf("Update: %v", err)
+	}
+}
+
+// do sends a request from 10.0.0.1 and returns the response and what the
+// next handler saw, if it was reached.
+func (at *authTest) do(method, path string, header http.Header) (*httptest.ResponseRecorder, *seen) {
+	at.t.Helper()
+	at.seen = nil
+	r := httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	r = r.WithContext(context.WithValue(r.Context(), model.CtxKeyVisitorIP, "10.0.0.1"))
+	w := httptest.NewRecorder()
+	at.handler.ServeHTTP(w, r)
+	return w, at.seen
+}
+
+func basic(login, password string) http.Header {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth(login, password)
+	return r.Header
+}
+
+func bearer(secret string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + secret}}
+}
+
+// problemCode returns the code of the problem in the body of w.
+func problemCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var p struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("problem %q: %v", w.Body.String(), err)
+	}
+	return p.Code
+}
+
+func TestAdminAuthOpenPaths(t *testing.T) {
+	at := newAuthTest(t)
+	for _, path := range []string{"/museum/news", "/administration", adminLoginPath} {
+		if w, s := at.do(http.MethodPost, path, nil); s == nil || w.Code != http.StatusOK {
+			t.Errorf("POST %s = %d, want it passed through", path, w.Code)
+		}
+	}
+	for _, path := range []string{"/admin", "/admin/news"} {
+		if w, s := at.do(http.MethodGet, path, nil); s != nil || w.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s without credentials = %d, want 401", path, w.Code)
+		}
+	}
+}
+
+func TestAdminAuthBearer(t *testing.T) {
+	at := newAuthTest(t)
+	ctx := context.Background()
+	editor := at.add("editor", model.RoleEditor)
+	scopes := []model.Scope{model.ScopeNewsRead, model.ScopeNewsWrite}
+	_, secret, err := at.tokens.Create(ctx, editor, "import script", scopes, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Tokens cannot be forged by other sites and need no CSRF token.
+	w, s := at.do(http.MethodPost, "/admin/news", bearer(secret))
+	if s == nil {
+		t.Fatalf("request with a token = %d %s", w.Code, w.Body)
+	}
+	if s.login != "editor" || s.role != model.RoleEditor || !slices.Equal(s.scopes.([]model.Scope), scopes) || s.session != nil {
+		t.Errorf("context = %+v, want the login, role and scopes of the token", *s)
+	}
+
+	for _, secret := range []string{"", "not-a-token", secret + "x"} {
+		if w, s := at.do(http.MethodGet, "/admin/news", bearer(secret)); s != nil || w.Code != http.StatusUnauthorized {
+			t.Errorf("token %q = %d, want 401", secret, w.Code)
+		}
+	}
+
+	at.disable(editor)
+	if w, s := at.do(http.MethodGet, "/admin/news", bearer(secret)); s != nil || w.Code != http.StatusUnauthorized {
+		t.Errorf("token of a disabled user = %d, want 401", w.Code)
+	}
+}
+
+func TestAdminAuthBasic(t *testing.T) {
+	at := newAuthTest(t)
+	editor := at.add("editor", model.RoleEditor)
+
+	w, s := at.do(http.MethodPost, "/admin/news", basic("editor", "correct horse"))
+	if s == nil {
+		t.Fatalf("Basic Auth = %d %s", w.Code, w.Body)
+	}
+	if s.login != "editor" || s.role != model.RoleEditor || s.scopes != nil || s.session != nil || s.totpSetup != nil {
+		t.Errorf("context = %+v, want the login and role of the user only", *s)
+	}
+	if w, s := at.do(http.MethodGet, "/admin/news", basic("editor", "wrong")); s != nil || w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password = %d, want 401", w.Code)
+	}
+
+	// Users whose role requires two-factor authentication may only enroll.
+	at.add("boss", model.RoleOwner)
+	if _, s := at.do(http.MethodGet, "/admin/news", basic("boss", "correct horse")); s == nil || s.totpSetup != true {
+		t.Errorf("owner without two-factor authentication: context %+v, want the enrollment mark", s)
+	}
+
+	at.disable(editor)
+	if w, s := at.do(http.MethodGet, "/admin/news", basic("editor", "correct horse")); s != nil || w.Code != http.StatusUnauthorized {
+		t.Errorf("disabled user = %d, want 401", w.Code)
+	}
+}
+
+func TestAdminAuthBasicTOTP(t *testing.T) {
+	at := newAuthTest(t)
+	user := at.add("editor", model.RoleEditor)
+	user.TOTPSecret, user.TOTPEnabled = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", true
+	if _, err := at.store.UpdateTOTP(context.Background(), user); err != nil {
+		t.Fatalf("UpdateTOTP: %v", err)
+	}
+	// Basic Auth has no room for the code.
+	if w, s := at.do(http.MethodGet, "/admin/news", basic("editor", "correct horse")); s != nil || w.Code != http.StatusUnauthorized {
+		t.Errorf("Basic Auth of a user with two-factor authentication = %d, want 401", w.Code)
+	}
+}
+
+func TestAdminAuthLockout(t *testing.T) {
+	at := newAuthTest(t)
+	at.add("editor", model.RoleEditor)
+	for range 2 {
+		at.do(http.MethodGet, "/admin/news", basic("editor", "wrong"))
+	}
+	w, s := at.do(http.MethodGet, "/admin/news", basic("editor", "wrong"))
+	if s != nil || w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("failure past the threshold = %d, Retry-After %q, want 429 after 60", w.Code, w.Header().Get("Retry-After"))
+	}
+	if code := problemCode(t, w); code != "locked" {
+		t.Errorf("code = %q, want locked", code)
+	}
+	// The right password waits too.
+	if w, s := at.do(http.MethodGet, "/admin/news", basic("editor", "correct horse")); s != nil || w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("right password while locked out = %d, want 429 with Retry-After", w.Code)
+	}
+}
+
+func TestAdminAuthSession(t *testing.T) {
+	at := newAuthTest(t)
+	ctx := context.Background()
+	editor := at.add("editor", model.RoleEditor)
+	as, token, err := at.sessions.Start(ctx, editor, "test", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	cookie := func(token string) http.Header {
+		return http.Header{"Cookie": {auth.CookieName + "=" + token}}
+	}
+	withCSRF := func(h http.Header, csrf string) http.Header {
+		h.Set(auth.CSRFHeader, csrf)
+		return h
+	}
+
+	w, s := at.do(http.MethodGet, "/admin/news", cookie(token))
+	if s == nil {
+		t.Fatalf("GET with a session = %d %s", w.Code, w.Body)
+	}
+	if s.login != "editor" || s.session != as.ID || s.scopes != nil {
+		t.Errorf("context = %+v, want the login and session", *s)
+	}
+
+	// Unsafe methods need the CSRF token of the session.
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		if w, s := at.do(method, "/admin/news", cookie(token)); s != nil || w.Code != http.StatusForbidden {
+			t.Errorf("%s without the CSRF token = %d, want 403", method, w.Code)
+		}
+		if w, s := at.do(method, "/admin/news", withCSRF(cookie(token), "forged")); s != nil || w.Code != http.StatusForbidden {
+			t.Errorf("%s with another CSRF token = %d, want 403", method, w.Code)
+		}
+		if w, s := at.do(method, "/admin/news", withCSRF(cookie(token), as.CSRFToken)); s == nil {
+			t.Errorf("%s with the CSRF token = %d, want it passed through", method, w.Code)
+		}
+	}
+
+	// An unknown session is cleared.
+	w, s = at.do(http.MethodGet, "/admin/news", cookie("unknown"))
+	if s != nil || w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown session = %d, want 401", w.Code)
+	}
+	if c := w.Result().Cookies(); len(c) != 1 || c[0].Name != auth.CookieName || c[0].MaxAge >= 0 {
+		t.Errorf("cookies = %v, want the session cookie cleared", c)
+	}
+
+	// Disabling a user ends their sessions.
+	at.disable(editor)
+	if w, s := at.do(http.MethodGet, "/admin/news", cookie(token)); s != nil || w.Code != http.StatusUnauthorized {
+		t.Errorf("session of a disabled user = %d, want 401", w.Code)
+	}
+}
+
+func TestAdminAuthSessionOfDisabledUser(t *testing.T) {
+	at := newAuthTest(t)
+	ctx := context.Background()
+	editor := at.add("editor", model.RoleEditor)
+	_, token, err := at.sessions.Start(ctx, editor, "test", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	// Disabled behind the back of Users, with the session left.
+	editor.Disabled = true
+	if _, err := at.store.Update(ctx, editor); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	h := http.Header{"Cookie": {auth.CookieName + "=" + token}}
+	if w, s := at.do(http.MethodGet, "/admin/news", h); s != nil || w.Code != http.StatusUnauthorized {
+		t.Errorf("session of a disabled user = %d, want 401", w.Code)
+	}
+}
+
+func TestAdminAuthScopes(t *testing.T) {
+	ctx := context.Background()
+	log := slog.New(slog.DiscardHandler)
+	stg := newStorages(ctx, &config.Config{Storage: config.StorageConfig{Driver: config.DriverMemory}}, log)
+	lockout := auth.NewLockout(stg.loginFailures, auth.LockoutOptions{}, log)
+	users := auth.NewUsers(stg.users, stg.sessions, stg.tx, lockout, log)
+	sessions := auth.NewSessions(stg.sessions, stg.users, auth.SessionOptions{}, log)
+	tokens := auth.NewTokens(stg.tokens, stg.users)
+	twoFactor := auth.NewTwoFactor(stg.users, lockout, auth.TwoFactorOptions{}, log)
+
+	huma.NewError = problem.New
+	r := bunrouter.New()
+	api := humabunrouter.New(r, huma.DefaultConfig("school-museum", "test"))
+	webadmin.RegisterHandlers(
+		huma.NewGroup(api, "/admin"), stg.news, stg.exhibitions, stg.exhibits, stg.visits, stg.trash, stg.tx, stg.revisions, stg.assets, stg.media, stg.imports, log,
+		adminservice.WithUsers(users),
+		adminservice.WithSessions(sessions),
+		adminservice.WithTokens(tokens),
+		adminservice.WithTwoFactor(twoFactor))
+	handler := adminAuthMiddleware(r, users, sessions, tokens, twoFactor, log)
+
+	owner, err := users.Add(ctx, "boss", "correct horse", model.RoleOwner)
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	token := func(scopes ...model.Scope) string {
+		_, secret, err := tokens.Create(ctx, owner, "test", scopes, nil)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return secret
+	}
+	newsRead, newsWrite, stats := token(model.ScopeNewsRead), token(model.ScopeNewsWrite), token(model.ScopeStatsRead)
+
+	tests := []struct {
+		method, path, secret string
+		status               int
+	}{
+		{http.MethodGet, "/admin/stats", stats, http.StatusOK},
+		{http.MethodGet, "/admin/stats", newsWrite, http.StatusForbidden},
+		// Reaching validation means the scope let the request through.
+		{http.MethodPost, "/admin/news", newsWrite, http.StatusUnprocessableEntity},
+		{http.MethodPost, "/admin/news", newsRead, http.StatusForbidden},
+		{http.MethodPost, "/admin/news", stats, http.StatusForbidden},
+		// Users and tokens are closed to tokens, even of owners.
+		{http.MethodGet, "/admin/users", newsWrite, http.StatusForbidden},
+		{http.MethodGet, "/admin/tokens", stats, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+tt.secret)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s %s = %d %s, want %d", tt.method, tt.path, w.Code, w.Body, tt.status)
+		}
+	}
+
+	// Without a token the role alone decides.
+	req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+	req.SetBasicAuth("boss", "correct horse")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /admin/users with Basic Auth = %d %s, want 200", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/google/uuid"
+)
+
+// --- Imports ---
+
+func (s *Storage) CreateImportJob(ctx context.Context, j model.ImportJob) (model.ImportJob, error) {
+	created, err := s.Imports.Create(ctx, j)
+	if err != nil {
+		s.log.Error("failed to create import job", slog.String("owner_id", j.OwnerID.String()), slog.String("error", err.Error()))
+		return model.ImportJob{}, err
+	}
+	return created, nil
+}
+
+func (s *Storage) GetImportJob(ctx context.Context, id uuid.UUID) (model.ImportJob, error) {
+	return s.Imports.Get(ctx, id)
+}
+
+func (s *Storage) UpdateImportJob(ctx context.Context, j model.ImportJob) error {
+	if err := s.Imports.Update(ctx, j); err != nil {
+		s.log.Error("failed to update import job", slog.String("id", j.ID.String()), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) PendingImportJobs(ctx context.Context) ([]model.ImportJob, error) {
+	jobs, err := s.Imports.Pending(ctx)
+	if err != nil {
+		s.log.Error("failed to list pending import jobs", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (s *Storage) ImportJobsByOwner(ctx context.Context, owner model.EntityType, ownerID uuid.UUID) ([]model.ImportJob, error) {
+	jobs, err := s.Imports.ByOwner(ctx, owner, ownerID)
+	if err != nil {
+		s.log.Error("failed to list import jobs", slog.String("owner_id", ownerID.String()), slog.String("error", err.Error()))
+		return nil, err
+	}
+	return jobs, nil
+}
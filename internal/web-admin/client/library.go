@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// --- Media library ---
+
+func (s *Storage) CreateMediaItem(ctx context.Context, m model.Media) (model.Media, error) {
+	created, err := s.Media.Create(ctx, m)
+	if err != nil {
+		s.log.Error("failed to create media item", slog.String("error", err.Error()))
+		return model.Media{}, err
+	}
+	return created, nil
+}
+
+func (s *Storage) GetMediaItem(ctx context.Context, id uuid.UUID) (model.Media, error) {
+	return s.Media.Read(ctx, id)
+}
+
+func (s *Storage) UpdateMediaItem(ctx context.Context, m model.Media) (model.Media, error) {
+	updated, err := s.Media.Update(ctx, m)
+	if err != nil {
+		s.log.Error("failed to update media item", slog.String("id", m.ID.String()), slog.String("error", err.Error()))
+		return model.Media{}, err
+	}
+	return updated, nil
+}
+
+func (s *Storage) DeleteMediaItem(ctx context.Context, id uuid.UUID) error {
+	if err := s.Media.Delete(ctx, id); err != nil {
+		s.log.Error("failed to delete media item", slog.String("id", id.String()), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) ListMediaItems(ctx context.Context, opts storage.ListOptions) ([]model.Media, int, error) {
+	media, total, err := s.Media.Query(ctx, opts)
+	if err != nil {
+		s.log.Error("failed to list media items", slog.String("error", err.Error()))
+		return nil, 0, err
+	}
+	return media, total, nil
+}
+
+func (s *Storage) AttachMedia(ctx context.Context, owner model.EntityType, ownerID uuid.UUID, ids []uuid.UUID) error {
+	if err := s.Media.Attach(ctx, owner, ownerID, ids); err != nil {
+		s.log.Error("failed to attach media", slog.String("owner", string(owner)), slog.String("id", ownerID.String()), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) AttachedMedia(ctx context.Context, owner model.EntityType, ownerID uuid.UUID) ([]model.AttachedMedia, error) {
+	byOwner, err := s.Media.Attached(ctx, owner, []uuid.UUID{ownerID})
+	if err != nil {
+		s.log.Error("failed to get attached media", slog.String("owner", string(owner)), slog.String("id", ownerID.String()), slog.String("error", err.Error()))
+		return nil, err
+	}
+	return byOwner[ownerID], nil
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/WhiCu/school-museum/db/model"
+)
+
+// --- Media ---
+
+func (s *Storage) SaveAsset(ctx context.Context, a model.Asset) (model.Asset, bool, error) {
+	saved, created, err := s.Assets.Save(ctx, a)
+	if err != nil {
+		s.log.Error("failed to save asset", slog.String("hash", a.Hash), slog.String("error", err.Error()))
+		return model.Asset{}, false, err
+	}
+	return saved, created, nil
+}
+
+func (s *Storage) GetAsset(ctx context.Context, hash string) (model.Asset, error) {
+	return s.Assets.Get(ctx, hash)
+}
+
+func (s *Storage) ListAssets(ctx context.Context, limit, offset int) ([]model.Asset, int, error) {
+	assets, total, err := s.Assets.List(ctx, limit, offset)
+	if err != nil {
+		s.log.Error("failed to list assets", slog.String("error", err.Error()))
+		return nil, 0, err
+	}
+	return assets, total, nil
+}
+
+func (s *Storage) PendingAssets(ctx context.Context) ([]model.Asset, error) {
+	assets, err := s.Assets.Pending(ctx)
+	if err != nil {
+		s.log.Error("failed to list pending assets", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return assets, nil
+}
+
+func (s *Storage) SetAssetVariants(ctx context.Context, hash string, width, height int, v []model.ImageVariant) error {
+	if err := s.Assets.SetVariants(ctx, hash, width, height, v); err != nil {
+		s.log.Error("failed to set asset variants", slog.String("hash", hash), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
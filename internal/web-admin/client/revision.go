@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/google/uuid"
+)
+
+// InTx runs f as a single unit of work.
+func (s *Storage) InTx(ctx context.Context, f func(ctx context.Context) error) error {
+	return s.Tx.InTx(ctx, f)
+}
+
+func (s *Storage) GetNews(ctx context.Context, id uuid.UUID) (model.News, error) {
+	return s.News.Read(ctx, id)
+}
+
+func (s *Storage) GetExhibition(ctx context.Context, id uuid.UUID) (model.Exhibition, error) {
+	return s.Exhibitions.Read(ctx, id)
+}
+
+func (s *Storage) GetExhibit(ctx context.Context, id uuid.UUID) (model.Exhibit, error) {
+	return s.Exhibits.Read(ctx, id)
+}
+
+func (s *Storage) ReplaceNews(ctx context.Context, n model.News) (model.News, error) {
+	replaced, err := s.News.Replace(ctx, n)
+	if err != nil {
+		s.log.Error("failed to replace news", slog.String("id", n.ID.String()), slog.String("error", err.Error()))
+		return model.News{}, err
+	}
+	return replaced, nil
+}
+
+func (s *Storage) ReplaceExhibition(ctx context.Context, ex model.Exhibition) (model.Exhibition, error) {
+	replaced, err := s.Exhibitions.Replace(ctx, ex)
+	if err != nil {
+		s.log.Error("failed to replace exhibition", slog.String("id", ex.ID.String()), slog.String("error", err.Error()))
+		return model.Exhibition{}, err
+	}
+	return replaced, nil
+}
+
+func (s *Storage) ReplaceExhibit(ctx context.Context, e model.Exhibit) (model.Exhibit, error) {
+	replaced, err := s.Exhibits.Replace(ctx, e)
+	if err != nil {
+		s.log.Error("failed to replace exhibit", slog.String("id", e.ID.String()), slog.String("error", err.Error()))
+		return model.Exhibit{}, err
+	}
+	return replaced, nil
+}
+
+// --- Revisions ---
+
+func (s *Storage) AppendRevision(ctx context.Context, r model.Revision) (model.Revision, error) {
+	out, err := s.Revisions.Append(ctx, r)
+	if err != nil {
+		s.log.Error("failed to append revision",
+			slog.String("entity_type", string(r.EntityType)),
+			slog.String("entity_id", r.EntityID.String()),
+			slog.String("error", err.Error()))
+		return model.Revision{}, err
+	}
+	return out, nil
+}
+
+func (s *Storage) ListRevisions(ctx context.Context, t model.EntityType, id uuid.UUID) ([]model.Revision, error) {
+	revs, err := s.Revisions.List(ctx, t, id)
+	if err != nil {
+		s.log.Error("failed to list revisions", slog.String("entity_id", id.String()), slog.String("error", err.Error()))
+		return nil, err
+	}
+	return revs, nil
+}
+
+func (s *Storage) GetRevision(ctx context.Context, t model.EntityType, id uuid.UUID, rev int) (model.Revision, error) {
+	return s.Revisions.Get(ctx, t, id, rev)
+}
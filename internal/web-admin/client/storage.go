@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 
 	"github.com/WhiCu/school-museum/db/model"
@@ -10,21 +11,40 @@ import (
 )
 
 type Storage struct {
-	News               storage.Storage[model.News]
-	Exhibitions        storage.Storage[model.Exhibition]
-	ExhibitionStorage  *storage.ExhibitionStorage
-	Exhibits           storage.Storage[model.Exhibit]
-	Visits             *storage.VisitStorage
-	log                *slog.Logger
+	News              storage.Storage[model.News]
+	Exhibitions       storage.Storage[model.Exhibition]
+	ExhibitionStorage storage.Exhibitions
+	Exhibits          storage.Storage[model.Exhibit]
+	Visits            storage.Visits
+	Trash             Trash
+	Tx                storage.Transactor
+	Revisions         storage.Revisions
+	Assets            storage.Assets
+	Media             storage.MediaLibrary
+	Imports           storage.ImportJobs
+	log               *slog.Logger
 }
 
-func NewStorage(news storage.Storage[model.News], exhibitions *storage.ExhibitionStorage, exhibits storage.Storage[model.Exhibit], visits *storage.VisitStorage, log *slog.Logger) *Storage {
+// Trash bundles the trash bins of the content storages.
+type Trash struct {
+	News        storage.Trash[model.News]
+	Exhibitions storage.ExhibitionTrash
+	Exhibits    storage.Trash[model.Exhibit]
+}
+
+func NewStorage(news storage.Storage[model.News], exhibitions storage.Exhibitions, exhibits storage.Storage[model.Exhibit], visits storage.Visits, trash Trash, tx storage.Transactor, revisions storage.Revisions, assets storage.Assets, media storage.MediaLibrary, imports storage.ImportJobs, log *slog.Logger) *Storage {
 	return &Storage{
 		News:              news,
 		Exhibitions:       exhibitions,
 		ExhibitionStorage: exhibitions,
 		Exhibits:          exhibits,
 		Visits:            visits,
+		Trash:             trash,
+		Tx:                tx,
+		Revisions:         revisions,
+		Assets:            assets,
+		Media:             media,
+		Imports:           imports,
 		log:               log,
 	}
 }
@@ -87,19 +107,34 @@ func (s *Storage) DeleteExhibition(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-func (s *Storage) ExhibitionExists(ctx context.Context, id uuid.UUID) bool {
+// ExhibitionExists reports whether a live exhibition with the given id exists.
+// Only failures other than a missing exhibition are returned as errors.
+func (s *Storage) ExhibitionExists(ctx context.Context, id uuid.UUID) (bool, error) {
 	_, err := s.Exhibitions.Read(ctx, id)
-	return err == nil
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return false, nil
+	case err != nil:
+		s.log.Error("failed to read exhibition", slog.String("id", id.String()), slog.String("error", err.Error()))
+		return false, err
+	}
+	return true, nil
 }
 
-func (s *Storage) SetExhibitionPreview(ctx context.Context, exhibitionID uuid.UUID, exhibitID *uuid.UUID) (model.Exhibition, error) {
-	if err := s.ExhibitionStorage.SetPreview(ctx, exhibitionID, exhibitID); err != nil {
-		s.log.Error("failed to set exhibition preview", slog.String("id", exhibitionID.String()), slog.String("error", err.Error()))
-		return model.Exhibition{}, err
-	}
-	ex, err := s.Exhibitions.Read(ctx, exhibitionID)
+func (s *Storage) SetExhibitionPreview(ctx context.Context, exhibitionID uuid.UUID, exhibitID *uuid.UUID) (ex model.Exhibition, err error) {
+	err = s.Tx.InTx(ctx, func(ctx context.Context) error {
+		if err := s.ExhibitionStorage.SetPreview(ctx, exhibitionID, exhibitID); err != nil {
+			s.log.Error("failed to set exhibition preview", slog.String("id", exhibitionID.String()), slog.String("error", err.Error()))
+			return err
+		}
+		ex, err = s.Exhibitions.Read(ctx, exhibitionID)
+		if err != nil {
+			s.log.Error("failed to re-read exhibition", slog.String("id", exhibitionID.String()), slog.String("error", err.Error()))
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		s.log.Error("failed to re-read exhibition", slog.String("id", exhibitionID.String()), slog.String("error", err.Error()))
 		return model.Exhibition{}, err
 	}
 	return ex, nil
@@ -134,6 +169,42 @@ func (s *Storage) DeleteExhibit(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// MoveExhibits moves exhibits to another exhibition in one transaction: either
+// all of them are moved or none. An exhibit that was the preview of its old
+// exhibition stops being it. Returns the target exhibition.
+func (s *Storage) MoveExhibits(ctx context.Context, exhibitIDs []uuid.UUID, exhibitionID uuid.UUID) (target model.Exhibition, err error) {
+	err = s.Tx.InTx(ctx, func(ctx context.Context) error {
+		if _, err := s.Exhibitions.Read(ctx, exhibitionID); err != nil {
+			return err
+		}
+		for _, id := range exhibitIDs {
+			e, err := s.Exhibits.Read(ctx, id)
+			if err != nil {
+				return err
+			}
+			if e.ExhibitionID == exhibitionID {
+				continue
+			}
+			src, err := s.Exhibitions.Read(ctx, e.ExhibitionID)
+			if err == nil && src.PreviewExhibitID != nil && *src.PreviewExhibitID == id {
+				if err := s.ExhibitionStorage.SetPreview(ctx, src.ID, nil); err != nil {
+					return err
+				}
+			}
+			if _, err := s.Exhibits.Update(ctx, model.Exhibit{ID: id, ExhibitionID: exhibitionID}); err != nil {
+				return err
+			}
+		}
+		target, err = s.Exhibitions.Read(ctx, exhibitionID)
+		return err
+	})
+	if err != nil {
+		s.log.Error("failed to move exhibits", slog.String("exhibition_id", exhibitionID.String()), slog.String("error", err.Error()))
+		return model.Exhibition{}, err
+	}
+	return target, nil
+}
+
 // --- Stats ---
 
 func (s *Storage) GetStats(ctx context.Context) (model.VisitStats, error) {
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// ListTrash returns the deleted content of the given types, most recently deleted first.
+// Exhibits deleted together with their exhibition are folded into the exhibition item.
+func (s *Storage) ListTrash(ctx context.Context, types []model.EntityType) ([]model.TrashItem, error) {
+	want := func(t model.EntityType) bool {
+		return len(types) == 0 || slices.Contains(types, t)
+	}
+	items := []model.TrashItem{}
+
+	if want(model.EntityNews) {
+		news, err := s.Trash.News.ListDeleted(ctx)
+		if err != nil {
+			s.log.Error("failed to list deleted news", slog.String("error", err.Error()))
+			return nil, err
+		}
+		for _, n := range news {
+			items = append(items, model.TrashItem{
+				Type:      model.EntityNews,
+				ID:        n.ID,
+				Title:     n.Title,
+				DeletedAt: n.DeletedAt,
+			})
+		}
+	}
+
+	var exhibits []model.Exhibit
+	if want(model.EntityExhibition) || want(model.EntityExhibit) {
+		var err error
+		exhibits, err = s.Trash.Exhibits.ListDeleted(ctx)
+		if err != nil {
+			s.log.Error("failed to list deleted exhibits", slog.String("error", err.Error()))
+			return nil, err
+		}
+	}
+
+	// cascaded holds the exhibits deleted by their exhibition's deletion.
+	cascaded := map[uuid.UUID]bool{}
+	if want(model.EntityExhibition) {
+		exhibitions, err := s.Trash.Exhibitions.ListDeleted(ctx)
+		if err != nil {
+			s.log.Error("failed to list deleted exhibitions", slog.String("error", err.Error()))
+			return nil, err
+		}
+		for _, ex := range exhibitions {
+			item := model.TrashItem{
+				Type:      model.EntityExhibition,
+				ID:        ex.ID,
+				Title:     ex.Title,
+				DeletedAt: ex.DeletedAt,
+			}
+			for _, e := range exhibits {
+				if e.ExhibitionID == ex.ID && e.DeletedAt.Equal(ex.DeletedAt) {
+					item.DeletedExhibits++
+					cascaded[e.ID] = true
+				}
+			}
+			items = append(items, item)
+		}
+	}
+
+	if want(model.EntityExhibit) {
+		for _, e := range exhibits {
+			if cascaded[e.ID] {
+				continue
+			}
+			items = append(items, model.TrashItem{
+				Type:         model.EntityExhibit,
+				ID:           e.ID,
+				Title:        e.Title,
+				ExhibitionID: &e.ExhibitionID,
+				DeletedAt:    e.DeletedAt,
+			})
+		}
+	}
+
+	slices.SortStableFunc(items, func(a, b model.TrashItem) int {
+		return b.DeletedAt.Compare(a.DeletedAt)
+	})
+	return items, nil
+}
+
+// DeletedExhibit returns an exhibit from the trash.
+func (s *Storage) DeletedExhibit(ctx context.Context, id uuid.UUID) (model.Exhibit, error) {
+	exhibits, err := s.Trash.Exhibits.ListDeleted(ctx)
+	if err != nil {
+		s.log.Error("failed to list deleted exhibits", slog.String("error", err.Error()))
+		return model.Exhibit{}, err
+	}
+	i := slices.IndexFunc(exhibits, func(e model.Exhibit) bool { return e.ID == id })
+	if i < 0 {
+		return model.Exhibit{}, storage.ErrNotFound
+	}
+	return exhibits[i], nil
+}
+
+func (s *Storage) RestoreNews(ctx context.Context, id uuid.UUID) error {
+	if err := s.Trash.News.Restore(ctx, id); err != nil {
+		s.log.Error("failed to restore news", slog.String("id", id.String()), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// RestoreExhibition restores an exhibition and, if withExhibits is set, the
+// exhibits deleted together with it. It returns the number of restored exhibits.
+func (s *Storage) RestoreExhibition(ctx context.Context, id uuid.UUID, withExhibits bool) (int, error) {
+	var (
+		n   int
+		err error
+	)
+	if withExhibits {
+		n, err = s.Trash.Exhibitions.RestoreWithExhibits(ctx, id)
+	} else {
+		err = s.Trash.Exhibitions.Restore(ctx, id)
+	}
+	if err != nil {
+		s.log.Error("failed to restore exhibition", slog.String("id", id.String()), slog.String("error", err.Error()))
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *Storage) RestoreExhibit(ctx context.Context, id uuid.UUID) error {
+	if err := s.Trash.Exhibits.Restore(ctx, id); err != nil {
+		s.log.Error("failed to restore exhibit", slog.String("id", id.String()), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) PurgeNews(ctx context.Context, id uuid.UUID) error {
+	if err := s.Trash.News.Purge(ctx, id); err != nil {
+		s.log.Error("failed to purge news", slog.String("id", id.String()), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) PurgeExhibition(ctx context.Context, id uuid.UUID) error {
+	if err := s.Trash.Exhibitions.Purge(ctx, id); err != nil {
+		s.log.Error("failed to purge exhibition", slog.String("id", id.String()), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) PurgeExhibit(ctx context.Context, id uuid.UUID) error {
+	if err := s.Trash.Exhibits.Purge(ctx, id); err != nil {
+		s.log.Error("failed to purge exhibit", slog.String("id", id.String()), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// PurgeDeletedBefore permanently removes all content deleted before t.
+func (s *Storage) PurgeDeletedBefore(ctx context.Context, t time.Time) (model.PurgeResult, error) {
+	var (
+		res model.PurgeResult
+		err error
+	)
+	if res.Exhibitions, err = s.Trash.Exhibitions.PurgeDeletedBefore(ctx, t); err != nil {
+		s.log.Error("failed to purge exhibitions", slog.String("error", err.Error()))
+		return res, err
+	}
+	if res.Exhibits, err = s.Trash.Exhibits.PurgeDeletedBefore(ctx, t); err != nil {
+		s.log.Error("failed to purge exhibits", slog.String("error", err.Error()))
+		return res, err
+	}
+	if res.News, err = s.Trash.News.PurgeDeletedBefore(ctx, t); err != nil {
+		s.log.Error("failed to purge news", slog.String("error", err.Error()))
+		return res, err
+	}
+	return res, nil
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// --- Roles ---
+
+const (
+	// roleKey is the key of the role an operation requires in its Metadata.
+	roleKey = "role"
+	// publicKey marks the operations anyone may call in their Metadata.
+	publicKey = "public"
+	// enrollmentKey marks the operations users who must enable two-factor
+	// authentication may call before they do.
+	enrollmentKey = "enrollment"
+)
+
+// requires is the Metadata of an operation only role and the roles above
+// it may call. Without it reading needs a viewer and changing an editor.
+func requires(role model.Role) map[string]any {
+	return map[string]any{roleKey: role}
+}
+
+// public is the Metadata of an operation anyone may call, even signed out.
+func public() map[string]any {
+	return map[string]any{publicKey: true}
+}
+
+// duringEnrollment adds to the Metadata m of an operation that users who
+// must enable two-factor authentication may call it before they do.
+func duringEnrollment(m map[string]any) map[string]any {
+	if m == nil {
+		m = map[string]any{}
+	}
+	m[enrollmentKey] = true
+	return m
+}
+
+// Authorize makes the operations registered on api after it check the role
+// of the admin, which the authentication middleware puts in the request
+// context, and the scopes of the API token of the request, if any. Users
+// who must enable two-factor authentication may only call the operations
+// of the enrollment until they do. It must be called before the operations
+// are registered.
+func (h *Handler) Authorize(api huma.API) {
+	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+		if ctx.Operation().Metadata[publicKey] == true {
+			next(ctx)
+			return
+		}
+		role, _ := ctx.Context().Value(model.CtxKeyAdminRole).(model.Role)
+		if !role.Allows(required(ctx.Operation())) {
+			_ = huma.WriteErr(api, ctx, http.StatusForbidden, "недостаточно прав")
+			return
+		}
+		if setup, _ := ctx.Context().Value(model.CtxKeyAdminTOTPSetup).(bool); setup && ctx.Operation().Metadata[enrollmentKey] != true {
+			_ = huma.WriteErr(api, ctx, http.StatusForbidden, "ваша роль требует двухфакторной аутентификации: включите её, чтобы продолжить")
+			return
+		}
+		if scopes, ok := ctx.Context().Value(model.CtxKeyAdminScopes).([]model.Scope); ok && !scopesAllow(scopes, ctx.Operation()) {
+			_ = huma.WriteErr(api, ctx, http.StatusForbidden, "токен не даёт доступа к этой операции")
+			return
+		}
+		next(ctx)
+	})
+}
+
+// required returns the role op requires.
+func required(op *huma.Operation) model.Role {
+	if role, ok := op.Metadata[roleKey].(model.Role); ok {
+		return role
+	}
+	if op.Method == http.MethodGet {
+		return model.RoleViewer
+	}
+	return model.RoleEditor
+}
+
+// --- Scopes ---
+
+// scopeResources maps the tags of the operations to the resources of the
+// token scopes. An empty resource is open to any token. Operations without
+// a mapped tag, like the users, sessions and tokens, are closed to tokens,
+// so a leaked token cannot grow its own rights.
+var scopeResources = map[string]string{
+	"Test":          "",
+	"News":          "news",
+	"Exhibitions":   "exhibitions",
+	"Exhibits":      "exhibits",
+	"Media":         "media",
+	"Media Library": "media",
+	"Trash":         "trash",
+	"Stats":         "stats",
+}
+
+// scopesAllow reports whether a token with scopes may call op. The first
+// mapped tag of op names its resource; reading needs any scope of the
+// resource and changing a write scope.
+func scopesAllow(scopes []model.Scope, op *huma.Operation) bool {
+	for _, tag := range op.Tags {
+		resource, ok := scopeResources[tag]
+		if !ok {
+			continue
+		}
+		if resource == "" {
+			return true
+		}
+		write := op.Method != http.MethodGet
+		return slices.ContainsFunc(scopes, func(s model.Scope) bool {
+			return s.Allows(resource, write)
+		})
+	}
+	return false
+}
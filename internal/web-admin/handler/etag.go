@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// The ETag of a news item, exhibition or exhibit is its quoted updated_at,
+// which storages move forward on every change. Clients that only have the
+// JSON body can build If-Match from its updated_at field.
+
+func etag(updatedAt time.Time) string {
+	return `"` + updatedAt.UTC().Format(time.RFC3339Nano) + `"`
+}
+
+// maxIfMatch bounds the entity tags of an If-Match list, each of which may
+// cost an update attempt.
+const maxIfMatch = 8
+
+// ifMatch turns an If-Match header into the updated_at values the stored row
+// may still have; a list of entity tags matches if any of them does. "*"
+// matches any version and yields the zero time. If-Match compares strongly
+// (RFC 9110, 13.1.1), so weak tags never match.
+func ifMatch(header string) ([]time.Time, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return nil, huma.NewError(http.StatusPreconditionRequired, "нужен заголовок If-Match с ETag объекта")
+	}
+	if header == "*" {
+		return []time.Time{{}}, nil
+	}
+	tags := strings.Split(header, ",")
+	if len(tags) > maxIfMatch {
+		return nil, huma.Error400BadRequest(fmt.Sprintf("в If-Match не больше %d ETag", maxIfMatch))
+	}
+	var versions []time.Time
+	weak := 0
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			weak++
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339Nano, strings.Trim(tag, `"`)); err == nil {
+			versions = append(versions, t)
+		}
+	}
+	switch {
+	case len(versions) > 0:
+		return versions, nil
+	case weak == len(tags):
+		return nil, huma.Error412PreconditionFailed("слабый ETag (W/) не подходит для If-Match")
+	default:
+		return nil, huma.Error412PreconditionFailed("ETag в If-Match не совпадает с текущей версией объекта")
+	}
+}
+
+// matching runs update with the versions of If-Match in turn until the
+// stored row has one of them. Only one version is current, so at most one
+// run changes anything; the others fail with storage.ErrStale and leave
+// nothing behind.
+func matching[T any](versions []time.Time, update func(expected time.Time) (T, error)) (out T, err error) {
+	for _, v := range versions {
+		if out, err = update(v); !errors.Is(err, storage.ErrStale) {
+			return out, err
+		}
+	}
+	return out, err
+}
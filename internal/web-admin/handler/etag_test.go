@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func TestIfMatch(t *testing.T) {
+	v1 := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	v2 := v1.Add(1500 * time.Microsecond)
+	tests := []struct {
+		header string
+		want   []time.Time
+		status int
+	}{
+		{header: etag(v1), want: []time.Time{v1}},
+		{header: "*", want: []time.Time{{}}},
+		{header: etag(v1) + ", " + etag(v2), want: []time.Time{v1, v2}},
+		{header: `W/` + etag(v1) + "," + etag(v2), want: []time.Time{v2}},
+		{header: `"other",` + etag(v2), want: []time.Time{v2}},
+
+		{header: " ", status: http.StatusPreconditionRequired},
+		{header: `W/` + etag(v1), status: http.StatusPreconditionFailed},
+		{header: `W/` + etag(v1) + `, W/` + etag(v2), status: http.StatusPreconditionFailed},
+		{header: `"other", *`, status: http.StatusPreconditionFailed},
+		{header: `"1","2","3","4","5","6","7","8","9"`, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		got, err := ifMatch(tt.header)
+		if tt.status != 0 {
+			var se huma.StatusError
+			if !errors.As(err, &se) || se.GetStatus() != tt.status {
+				t.Errorf("ifMatch(%q) = %v, %v, want status %d", tt.header, got, err, tt.status)
+			}
+			continue
+		}
+		if err != nil || len(got) != len(tt.want) {
+			t.Errorf("ifMatch(%q) = %v, %v, want %v", tt.header, got, err, tt.want)
+			continue
+		}
+		for i := range got {
+			if !got[i].Equal(tt.want[i]) {
+				t.Errorf("ifMatch(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestMatching(t *testing.T) {
+	current := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	other := current.Add(-time.Hour)
+	var tried []time.Time
+	update := func(expected time.Time) (time.Time, error) {
+		tried = append(tried, expected)
+		if !expected.Equal(current) {
+			return time.Time{}, storage.ErrStale
+		}
+		return expected.Add(time.Second), nil
+	}
+
+	got, err := matching([]time.Time{other, current, other}, update)
+	if err != nil || !got.Equal(current.Add(time.Second)) || len(tried) != 2 {
+		t.Errorf("matching with the current version second = %v, %v after %d tries, want an update after 2", got, err, len(tried))
+	}
+	tried = nil
+	if _, err := matching([]time.Time{other, other}, update); !errors.Is(err, storage.ErrStale) || len(tried) != 2 {
+		t.Errorf("matching without the current version = %v after %d tries, want ErrStale after 2", err, len(tried))
+	}
+
+	// Other errors end the tries.
+	tried = nil
+	_, err = matching([]time.Time{other, current}, func(expected time.Time) (time.Time, error) {
+		tried = append(tried, expected)
+		return time.Time{}, storage.ErrNotFound
+	})
+	if !errors.Is(err, storage.ErrNotFound) || len(tried) != 1 {
+		t.Errorf("matching of a missing row = %v after %d tries, want ErrNotFound after 1", err, len(tried))
+	}
+}
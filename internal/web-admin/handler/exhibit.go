@@ -3,8 +3,12 @@ package handler
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/problem"
+	adminservice "github.com/WhiCu/school-museum/internal/web-admin/service"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/google/uuid"
 )
@@ -13,7 +17,8 @@ import (
 
 // CreateExhibit - создание нового экспоната.
 type createExhibitInput struct {
-	Body struct {
+	Import bool `query:"import" doc:"Скачать внешние изображения в медиатеку сервера в фоне, см. POST {id}/import"`
+	Body   struct {
 		ExhibitionID uuid.UUID `json:"exhibition_id" format:"uuid" doc:"ID экспозиции"`
 		Title        string    `json:"title" minLength:"1" doc:"Название экспоната"`
 		Description  string    `json:"description" doc:"Описание экспоната"`
@@ -22,7 +27,9 @@ type createExhibitInput struct {
 }
 
 type createExhibitOutput struct {
-	Body model.Exhibit
+	ETag      string `header:"ETag"`
+	ImportJob string `header:"X-Import-Job" doc:"ID задания импорта, если он запущен"`
+	Body      model.Exhibit
 }
 
 func (h *Handler) CreateExhibit(api huma.API) {
@@ -49,17 +56,26 @@ func (h *Handler) CreateExhibit(api huma.API) {
 				ImageURLs:    req.Body.ImageURLs,
 			})
 			if err != nil {
-				return nil, huma.Error500InternalServerError("не удалось создать экспонат")
+				return nil, problem.From(err, "не удалось создать экспонат",
+					problem.Detail{Err: adminservice.ErrExhibitionNotFound, Msg: "экспозиция не найдена"})
+			}
+			out := &createExhibitOutput{ETag: etag(ex.UpdatedAt), Body: ex}
+			if req.Import {
+				if out.ImportJob, err = h.importAfterSave(ctx, model.EntityExhibit, ex.ID); err != nil {
+					return nil, err
+				}
 			}
-			return &createExhibitOutput{Body: ex}, nil
+			return out, nil
 		},
 	)
 }
 
 // UpdateExhibit - обновление экспоната.
 type updateExhibitInput struct {
-	ID   uuid.UUID `path:"id" format:"uuid" doc:"ID экспоната"`
-	Body struct {
+	ID      uuid.UUID `path:"id" format:"uuid" doc:"ID экспоната"`
+	IfMatch string    `header:"If-Match" doc:"ETag текущей версии (updated_at в кавычках), несколько ETag через запятую или *"`
+	Import  bool      `query:"import" doc:"Скачать внешние изображения в медиатеку сервера в фоне, см. POST {id}/import"`
+	Body    struct {
 		Title       string   `json:"title" doc:"Название экспоната"`
 		Description string   `json:"description" doc:"Описание экспоната"`
 		ImageURLs   []string `json:"image_urls" doc:"URLs изображений экспоната"`
@@ -67,7 +83,9 @@ type updateExhibitInput struct {
 }
 
 type updateExhibitOutput struct {
-	Body model.Exhibit
+	ETag      string `header:"ETag"`
+	ImportJob string `header:"X-Import-Job" doc:"ID задания импорта, если он запущен"`
+	Body      model.Exhibit
 }
 
 func (h *Handler) UpdateExhibit(api huma.API) {
@@ -78,23 +96,38 @@ func (h *Handler) UpdateExhibit(api huma.API) {
 			Method:      http.MethodPut,
 			Path:        "/exhibits/{id}",
 			Summary:     "Обновить экспонат",
-			Description: "Обновляет данные существующего экспоната.",
+			Description: "Обновляет данные существующего экспоната. Требует If-Match с ETag версии, которую видел редактор; если объект с тех пор изменили, возвращает 412.",
 			Tags: []string{
 				"Admin",
 				"Exhibits",
 			},
 		},
 		func(ctx context.Context, req *updateExhibitInput) (*updateExhibitOutput, error) {
-			ex, err := h.service.UpdateExhibit(ctx, model.Exhibit{
-				ID:          req.ID,
-				Title:       req.Body.Title,
-				Description: req.Body.Description,
-				ImageURLs:   req.Body.ImageURLs,
+			versions, err := ifMatch(req.IfMatch)
+			if err != nil {
+				return nil, err
+			}
+			ex, err := matching(versions, func(expected time.Time) (model.Exhibit, error) {
+				return h.service.UpdateExhibit(ctx, model.Exhibit{
+					ID:          req.ID,
+					UpdatedAt:   expected,
+					Title:       req.Body.Title,
+					Description: req.Body.Description,
+					ImageURLs:   req.Body.ImageURLs,
+				})
 			})
 			if err != nil {
-				return nil, huma.Error500InternalServerError("не удалось обновить экспонат")
+				return nil, problem.From(err, "не удалось обновить экспонат",
+					problem.Detail{Err: storage.ErrStale, Msg: "экспонат был изменён другим пользователем, обновите страницу"},
+					problem.Detail{Err: storage.ErrNotFound, Msg: "экспонат не найден"})
+			}
+			out := &updateExhibitOutput{ETag: etag(ex.UpdatedAt), Body: ex}
+			if req.Import {
+				if out.ImportJob, err = h.importAfterSave(ctx, model.EntityExhibit, ex.ID); err != nil {
+					return nil, err
+				}
 			}
-			return &updateExhibitOutput{Body: ex}, nil
+			return out, nil
 		},
 	)
 }
@@ -117,9 +150,44 @@ func (h *Handler) DeleteExhibit(api huma.API) {
 		},
 		func(ctx context.Context, req *deleteExhibitInput) (*struct{}, error) {
 			if err := h.service.DeleteExhibit(ctx, req.ID); err != nil {
-				return nil, huma.Error500InternalServerError("не удалось удалить экспонат")
+				return nil, problem.From(err, "не удалось удалить экспонат",
+					problem.Detail{Err: storage.ErrNotFound, Msg: "экспонат не найден"})
 			}
 			return nil, nil
 		},
 	)
 }
+
+// MoveExhibits - перенос экспонатов в другую экспозицию.
+type moveExhibitsInput struct {
+	Body struct {
+		ExhibitionID uuid.UUID   `json:"exhibition_id" format:"uuid" doc:"ID экспозиции, в которую переносятся экспонаты"`
+		ExhibitIDs   []uuid.UUID `json:"exhibit_ids" minItems:"1" doc:"ID переносимых экспонатов"`
+	}
+}
+
+type moveExhibitsOutput struct {
+	Body model.Exhibition
+}
+
+func (h *Handler) MoveExhibits(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "move-exhibits",
+			Method:      http.MethodPost,
+			Path:        "/exhibits/move",
+			Summary:     "Перенести экспонаты",
+			Description: "Переносит экспонаты в другую экспозицию. Переносятся либо все экспонаты, либо ни один. Возвращает экспозицию назначения.",
+			Tags:        []string{"Admin", "Exhibits"},
+		},
+		func(ctx context.Context, req *moveExhibitsInput) (*moveExhibitsOutput, error) {
+			ex, err := h.service.MoveExhibits(ctx, req.Body.ExhibitIDs, req.Body.ExhibitionID)
+			if err != nil {
+				return nil, problem.From(err, "не удалось перенести экспонаты",
+					problem.Detail{Err: storage.ErrNotFound, Msg: "экспозиция или экспонат не найдены"})
+			}
+			return &moveExhibitsOutput{Body: ex}, nil
+		},
+	)
+}
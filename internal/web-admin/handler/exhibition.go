@@ -3,8 +3,11 @@ package handler
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/problem"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/google/uuid"
 )
@@ -20,6 +23,7 @@ type createExhibitionInput struct {
 }
 
 type createExhibitionOutput struct {
+	ETag string `header:"ETag"`
 	Body model.Exhibition
 }
 
@@ -40,23 +44,25 @@ func (h *Handler) CreateExhibition(api huma.API) {
 				Description: req.Body.Description,
 			})
 			if err != nil {
-				return nil, huma.Error500InternalServerError("не удалось создать экспозицию")
+				return nil, problem.From(err, "не удалось создать экспозицию")
 			}
-			return &createExhibitionOutput{Body: ex}, nil
+			return &createExhibitionOutput{ETag: etag(ex.UpdatedAt), Body: ex}, nil
 		},
 	)
 }
 
 // UpdateExhibition - обновление экспозиции.
 type updateExhibitionInput struct {
-	ID   uuid.UUID `path:"id" format:"uuid" doc:"ID экспозиции"`
-	Body struct {
+	ID      uuid.UUID `path:"id" format:"uuid" doc:"ID экспозиции"`
+	IfMatch string    `header:"If-Match" doc:"ETag текущей версии (updated_at в кавычках), несколько ETag через запятую или *"`
+	Body    struct {
 		Title       string `json:"title" doc:"Название экспозиции"`
 		Description string `json:"description" doc:"Описание экспозиции"`
 	}
 }
 
 type updateExhibitionOutput struct {
+	ETag string `header:"ETag"`
 	Body model.Exhibition
 }
 
@@ -68,22 +74,31 @@ func (h *Handler) UpdateExhibition(api huma.API) {
 			Method:      http.MethodPut,
 			Path:        "/exhibitions/{id}",
 			Summary:     "Обновить экспозицию",
-			Description: "Обновляет данные существующей экспозиции.",
+			Description: "Обновляет данные существующей экспозиции. Требует If-Match с ETag версии, которую видел редактор; если объект с тех пор изменили, возвращает 412.",
 			Tags: []string{
 				"Admin",
 				"Exhibitions",
 			},
 		},
 		func(ctx context.Context, req *updateExhibitionInput) (*updateExhibitionOutput, error) {
-			ex, err := h.service.UpdateExhibition(ctx, model.Exhibition{
-				ID:          req.ID,
-				Title:       req.Body.Title,
-				Description: req.Body.Description,
+			versions, err := ifMatch(req.IfMatch)
+			if err != nil {
+				return nil, err
+			}
+			ex, err := matching(versions, func(expected time.Time) (model.Exhibition, error) {
+				return h.service.UpdateExhibition(ctx, model.Exhibition{
+					ID:          req.ID,
+					UpdatedAt:   expected,
+					Title:       req.Body.Title,
+					Description: req.Body.Description,
+				})
 			})
 			if err != nil {
-				return nil, huma.Error500InternalServerError("не удалось обновить экспозицию")
+				return nil, problem.From(err, "не удалось обновить экспозицию",
+					problem.Detail{Err: storage.ErrStale, Msg: "экспозиция была изменена другим пользователем, обновите страницу"},
+					problem.Detail{Err: storage.ErrNotFound, Msg: "экспозиция не найдена"})
 			}
-			return &updateExhibitionOutput{Body: ex}, nil
+			return &updateExhibitionOutput{ETag: etag(ex.UpdatedAt), Body: ex}, nil
 		},
 	)
 }
@@ -106,7 +121,8 @@ func (h *Handler) DeleteExhibition(api huma.API) {
 		},
 		func(ctx context.Context, req *deleteExhibitionInput) (*struct{}, error) {
 			if err := h.service.DeleteExhibition(ctx, req.ID); err != nil {
-				return nil, huma.Error500InternalServerError("не удалось удалить экспозицию")
+				return nil, problem.From(err, "не удалось удалить экспозицию",
+					problem.Detail{Err: storage.ErrNotFound, Msg: "экспозиция не найдена"})
 			}
 			return nil, nil
 		},
@@ -147,7 +163,8 @@ func (h *Handler) SetExhibitionPreview(api huma.API) {
 			}
 			ex, err := h.service.SetExhibitionPreview(ctx, req.ID, exhibitID)
 			if err != nil {
-				return nil, huma.Error500InternalServerError("не удалось установить превью")
+				return nil, problem.From(err, "не удалось установить превью",
+					problem.Detail{Err: storage.ErrNotFound, Msg: "экспозиция не найдена"})
 			}
 			return &setExhibitionPreviewOutput{Body: ex}, nil
 		},
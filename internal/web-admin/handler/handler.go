@@ -2,9 +2,15 @@ package handler
 
 import (
 	"context"
+	"io"
 	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/resolver"
+	adminservice "github.com/WhiCu/school-museum/internal/web-admin/service"
 	"github.com/google/uuid"
 )
 
@@ -21,8 +27,65 @@ type service interface {
 	CreateExhibit(ctx context.Context, e model.Exhibit) (model.Exhibit, error)
 	UpdateExhibit(ctx context.Context, e model.Exhibit) (model.Exhibit, error)
 	DeleteExhibit(ctx context.Context, id uuid.UUID) error
+	MoveExhibits(ctx context.Context, exhibitIDs []uuid.UUID, exhibitionID uuid.UUID) (model.Exhibition, error)
 
 	GetStats(ctx context.Context) (model.VisitStats, error)
+
+	ListTrash(ctx context.Context, types []model.EntityType) ([]model.TrashItem, error)
+	Restore(ctx context.Context, t model.EntityType, id uuid.UUID, withExhibits bool) (int, error)
+	Purge(ctx context.Context, t model.EntityType, id uuid.UUID) error
+	PurgeExpired(ctx context.Context) (model.PurgeResult, error)
+
+	ListRevisions(ctx context.Context, t model.EntityType, id uuid.UUID) ([]model.Revision, error)
+	DiffRevisions(ctx context.Context, t model.EntityType, id uuid.UUID, from, to int) ([]model.FieldChange, error)
+	RollbackRevision(ctx context.Context, t model.EntityType, id uuid.UUID, rev int) (model.Revision, error)
+
+	UploadMedia(ctx context.Context, name string, r io.Reader) (model.Asset, bool, error)
+	GetAsset(ctx context.Context, hash string) (model.Asset, error)
+	ListAssets(ctx context.Context, limit, offset int) ([]model.Asset, int, error)
+	InvalidateResolvedMedia(ctx context.Context, rawURL string) (resolver.Purged, error)
+	StartImport(ctx context.Context, owner model.EntityType, id uuid.UUID) (model.ImportJob, error)
+	GetImportJob(ctx context.Context, id uuid.UUID) (model.ImportJob, error)
+	ListImportJobs(ctx context.Context, owner model.EntityType, id uuid.UUID) ([]model.ImportJob, error)
+	ListBrokenLinks(ctx context.Context, statuses []model.LinkStatus) ([]model.BrokenLinks, error)
+
+	CreateMediaItem(ctx context.Context, m model.Media) (model.Media, error)
+	GetMediaItem(ctx context.Context, id uuid.UUID) (model.Media, error)
+	UpdateMediaItem(ctx context.Context, m model.Media) (model.Media, error)
+	DeleteMediaItem(ctx context.Context, id uuid.UUID) error
+	ListMediaItems(ctx context.Context, opts storage.ListOptions) ([]model.Media, int, error)
+	SetAttachedMedia(ctx context.Context, owner model.EntityType, id uuid.UUID, ids []uuid.UUID) ([]model.AttachedMedia, error)
+	GetAttachedMedia(ctx context.Context, owner model.EntityType, id uuid.UUID) ([]model.AttachedMedia, error)
+
+	ListUsers(ctx context.Context) ([]model.AdminUser, error)
+	CreateUser(ctx context.Context, login, password string, role model.Role) (model.AdminUser, error)
+	UpdateUser(ctx context.Context, id uuid.UUID, role model.Role, disabled bool) (model.AdminUser, error)
+	SetUserPassword(ctx context.Context, id uuid.UUID, password string) error
+	CurrentUser(ctx context.Context) (model.AdminUser, error)
+	ChangeOwnPassword(ctx context.Context, current, password string) error
+
+	Login(ctx context.Context, login, password, code string) (adminservice.Login, error)
+	Logout(ctx context.Context) (*http.Cookie, error)
+	CurrentSession(ctx context.Context) (model.AdminUser, model.AdminSession, error)
+	ListSessions(ctx context.Context) ([]model.AdminSession, error)
+	RevokeSession(ctx context.Context, id uuid.UUID) error
+	ListUserSessions(ctx context.Context, userID uuid.UUID) ([]model.AdminSession, error)
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID) (int, error)
+
+	CreateToken(ctx context.Context, name string, scopes []model.Scope, expiresAt *time.Time) (model.APIToken, string, error)
+	ListTokens(ctx context.Context) ([]model.APIToken, error)
+	RevokeToken(ctx context.Context, id uuid.UUID) error
+	ListUserTokens(ctx context.Context, userID uuid.UUID) ([]model.APIToken, error)
+	RevokeUserToken(ctx context.Context, userID, id uuid.UUID) error
+
+	ListLoginFailures(ctx context.Context, login string, opts storage.ListOptions) ([]model.LoginFailure, int, error)
+
+	TwoFactorStatus(ctx context.Context) (adminservice.TwoFactorStatus, error)
+	BeginTwoFactor(ctx context.Context) (string, string, error)
+	ConfirmTwoFactor(ctx context.Context, code string) ([]string, error)
+	DisableTwoFactor(ctx context.Context, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error)
+	ResetUserTwoFactor(ctx context.Context, id uuid.UUID) (model.AdminUser, error)
 }
 
 type Handler struct {
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/problem"
+	adminservice "github.com/WhiCu/school-museum/internal/web-admin/service"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+// --- Imports ---
+
+// StartImport - запуск импорта внешних изображений.
+type startImportInput struct {
+	ID uuid.UUID `path:"id" format:"uuid" doc:"ID объекта"`
+}
+
+type importJobOutput struct {
+	Body model.ImportJob
+}
+
+func (h *Handler) StartImport(api huma.API) {
+	for _, o := range mediaOwners {
+		huma.Register(
+			api,
+			huma.Operation{
+				OperationID:   "import-" + string(o.typ) + "-media",
+				Method:        http.MethodPost,
+				Path:          o.path + "/{id}/import",
+				DefaultStatus: http.StatusAccepted,
+				Summary:       "Импортировать внешние изображения",
+				Description: "Запускает в фоне задание, которое скачивает изображения и видео по внешним ссылкам из image_urls " +
+					"(imgur, Google Диск, прямые ссылки и т. п.) в медиатеку сервера и заменяет ссылки на локальные копии, " +
+					"чтобы коллекция не пропала вместе со сторонним сервисом. Плееры (YouTube, Rutube, VK Видео) пропускаются. " +
+					"Состояние задания — GET /imports/{id}.",
+				Tags: []string{"Admin", o.tag, "Media"},
+			},
+			func(ctx context.Context, req *startImportInput) (*importJobOutput, error) {
+				j, err := h.service.StartImport(ctx, o.typ, req.ID)
+				if err != nil {
+					return nil, importError(err, "не удалось запустить импорт", o.notFound)
+				}
+				return &importJobOutput{Body: j}, nil
+			},
+		)
+	}
+}
+
+// ListImportJobs - задания импорта объекта.
+type listImportJobsInput struct {
+	ID uuid.UUID `path:"id" format:"uuid" doc:"ID объекта"`
+}
+
+type listImportJobsOutput struct {
+	Body []model.ImportJob
+}
+
+func (h *Handler) ListImportJobs(api huma.API) {
+	for _, o := range mediaOwners {
+		huma.Register(
+			api,
+			huma.Operation{
+				OperationID: "list-" + string(o.typ) + "-imports",
+				Method:      http.MethodGet,
+				Path:        o.path + "/{id}/imports",
+				Summary:     "Задания импорта",
+				Description: "Возвращает задания импорта внешних изображений объекта, новые первыми.",
+				Tags:        []string{"Admin", o.tag, "Media"},
+			},
+			func(ctx context.Context, req *listImportJobsInput) (*listImportJobsOutput, error) {
+				jobs, err := h.service.ListImportJobs(ctx, o.typ, req.ID)
+				if err != nil {
+					return nil, problem.From(err, "не удалось получить задания импорта",
+						problem.Detail{Err: storage.ErrNotFound, Msg: o.notFound})
+				}
+				return &listImportJobsOutput{Body: jobs}, nil
+			},
+		)
+	}
+}
+
+// GetImportJob - состояние задания импорта.
+type getImportJobInput struct {
+	ID uuid.UUID `path:"id" format:"uuid" doc:"ID задания"`
+}
+
+func (h *Handler) GetImportJob(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "get-import",
+			Method:      http.MethodGet,
+			Path:        "/imports/{id}",
+			Summary:     "Состояние задания импорта",
+			Description: "Возвращает состояние задания импорта (pending, running, done, failed) и каждой ссылки в нём: " +
+				"done — скачана, url указывает на локальную копию; skipped — это плеер, а не файл; failed — код ошибки в error.",
+			Tags: []string{"Admin", "Media"},
+		},
+		func(ctx context.Context, req *getImportJobInput) (*importJobOutput, error) {
+			j, err := h.service.GetImportJob(ctx, req.ID)
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить задание импорта",
+					problem.Detail{Err: storage.ErrNotFound, Msg: "задание импорта не найдено"})
+			}
+			return &importJobOutput{Body: j}, nil
+		},
+	)
+}
+
+// importAfterSave starts the import of a news item or an exhibit just saved
+// with ?import=true and returns the job ID. Having nothing to import is not
+// an error.
+func (h *Handler) importAfterSave(ctx context.Context, owner model.EntityType, id uuid.UUID) (string, error) {
+	j, err := h.service.StartImport(ctx, owner, id)
+	switch {
+	case errors.Is(err, adminservice.ErrNothingToImport):
+		return "", nil
+	case err != nil:
+		return "", problem.From(err, "изменения сохранены, но импорт изображений не запущен",
+			problem.Detail{Err: adminservice.ErrImportsDisabled, Msg: "изменения сохранены, но импорт изображений отключён"})
+	}
+	return j.ID.String(), nil
+}
+
+func importError(err error, msg, notFound string) error {
+	return problem.From(err, msg,
+		problem.Detail{Err: adminservice.ErrImportsDisabled, Msg: "импорт изображений отключён"},
+		problem.Detail{Err: adminservice.ErrNothingToImport, Msg: "нет внешних ссылок на изображения"},
+		problem.Detail{Err: storage.ErrNotFound, Msg: notFound})
+}
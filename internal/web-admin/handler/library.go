@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/problem"
+	adminservice "github.com/WhiCu/school-museum/internal/web-admin/service"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+// --- Media library ---
+
+type mediaBody struct {
+	URL     string `json:"url" pattern:"^(https?://|/media/)\\S+$" doc:"URL изображения или видео: внешняя ссылка или адрес загруженного файла /media/..."`
+	Caption string `json:"caption,omitempty" doc:"Подпись"`
+	Alt     string `json:"alt,omitempty" doc:"Альтернативный текст для программ чтения с экрана"`
+	Credit  string `json:"credit,omitempty" doc:"Автор или источник"`
+	License string `json:"license,omitempty" doc:"Лицензия, например CC BY-SA 4.0"`
+}
+
+func (b mediaBody) model(id uuid.UUID) model.Media {
+	return model.Media{
+		ID:      id,
+		URL:     b.URL,
+		Caption: b.Caption,
+		Alt:     b.Alt,
+		Credit:  b.Credit,
+		License: b.License,
+	}
+}
+
+// CreateMediaItem - добавление объекта в медиатеку.
+type createMediaItemInput struct {
+	Body mediaBody
+}
+
+type mediaItemOutput struct {
+	ETag string `header:"ETag"`
+	Body model.Media
+}
+
+func (h *Handler) CreateMediaItem(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID:   "create-media-item",
+			Method:        http.MethodPost,
+			Path:          "/library",
+			DefaultStatus: http.StatusCreated,
+			Summary:       "Добавить в медиатеку",
+			Description:   "Создаёт объект медиатеки: изображение или видео с подписью, альтернативным текстом, автором и лицензией.",
+			Tags:          []string{"Admin", "Media Library"},
+		},
+		func(ctx context.Context, req *createMediaItemInput) (*mediaItemOutput, error) {
+			m, err := h.service.CreateMediaItem(ctx, req.Body.model(uuid.Nil))
+			if err != nil {
+				return nil, problem.From(err, "не удалось добавить объект в медиатеку")
+			}
+			return &mediaItemOutput{ETag: etag(m.UpdatedAt), Body: m}, nil
+		},
+	)
+}
+
+// ListMediaItems - объекты медиатеки.
+type listMediaItemsInput struct {
+	Limit     int    `query:"limit" minimum:"1" maximum:"100" doc:"Размер страницы (без параметра — все объекты)"`
+	Offset    int    `query:"offset" minimum:"0" doc:"Смещение от начала списка"`
+	Sort      string `query:"sort" enum:"created_at,caption" default:"created_at" doc:"Поле сортировки"`
+	Direction string `query:"direction" enum:"asc,desc" default:"desc" doc:"Направление сортировки"`
+}
+
+type listMediaItemsOutput struct {
+	Total int `header:"X-Total-Count" doc:"Общее количество объектов"`
+	Body  []model.Media
+}
+
+func (h *Handler) ListMediaItems(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "list-media-items",
+			Method:      http.MethodGet,
+			Path:        "/library",
+			Summary:     "Получить медиатеку",
+			Description: "Возвращает страницу объектов медиатеки. Общее количество — в заголовке X-Total-Count.",
+			Tags:        []string{"Admin", "Media Library"},
+		},
+		func(ctx context.Context, req *listMediaItemsInput) (*listMediaItemsOutput, error) {
+			sort := storage.SortCreatedAt
+			if req.Sort == "caption" {
+				sort = storage.SortTitle
+			}
+			items, total, err := h.service.ListMediaItems(ctx, storage.ListOptions{
+				Limit:  req.Limit,
+				Offset: req.Offset,
+				Sort:   sort,
+				Desc:   req.Direction == "desc",
+			})
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить медиатеку")
+			}
+			return &listMediaItemsOutput{Total: total, Body: items}, nil
+		},
+	)
+}
+
+// GetMediaItem - объект медиатеки по ID.
+type mediaItemIDInput struct {
+	ID uuid.UUID `path:"id" format:"uuid" doc:"ID объекта медиатеки"`
+}
+
+func (h *Handler) GetMediaItem(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "get-media-item",
+			Method:      http.MethodGet,
+			Path:        "/library/{id}",
+			Summary:     "Получить объект медиатеки",
+			Description: "Возвращает объект медиатеки по идентификатору.",
+			Tags:        []string{"Admin", "Media Library"},
+		},
+		func(ctx context.Context, req *mediaItemIDInput) (*mediaItemOutput, error) {
+			m, err := h.service.GetMediaItem(ctx, req.ID)
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить объект медиатеки",
+					problem.Detail{Err: storage.ErrNotFound, Msg: "объект медиатеки не найден"})
+			}
+			return &mediaItemOutput{ETag: etag(m.UpdatedAt), Body: m}, nil
+		},
+	)
+}
+
+// UpdateMediaItem - обновление объекта медиатеки.
+type updateMediaItemInput struct {
+	ID      uuid.UUID `path:"id" format:"uuid" doc:"ID объекта медиатеки"`
+	IfMatch string    `header:"If-Match" doc:"ETag текущей версии (updated_at в кавычках), несколько ETag через запятую или *"`
+	Body    mediaBody
+}
+
+func (h *Handler) UpdateMediaItem(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "update-media-item",
+			Method:      http.MethodPut,
+			Path:        "/library/{id}",
+			Summary:     "Обновить объект медиатеки",
+			Description: "Заменяет URL, подпись, альтернативный текст, автора и лицензию. Требует If-Match с ETag; если объект с тех пор изменили, возвращает 412.",
+			Tags:        []string{"Admin", "Media Library"},
+		},
+		func(ctx context.Context, req *updateMediaItemInput) (*mediaItemOutput, error) {
+			versions, err := ifMatch(req.IfMatch)
+			if err != nil {
+				return nil, err
+			}
+			m, err := matching(versions, func(expected time.Time) (model.Media, error) {
+				m := req.Body.model(req.ID)
+				m.UpdatedAt = expected
+				return h.service.UpdateMediaItem(ctx, m)
+			})
+			if err != nil {
+				return nil, problem.From(err, "не удалось обновить объект медиатеки",
+					problem.Detail{Err: storage.ErrStale, Msg: "объект медиатеки был изменён другим пользователем, обновите страницу"},
+					problem.Detail{Err: storage.ErrNotFound, Msg: "объект медиатеки не найден"})
+			}
+			return &mediaItemOutput{ETag: etag(m.UpdatedAt), Body: m}, nil
+		},
+	)
+}
+
+// DeleteMediaItem - удаление объекта медиатеки.
+func (h *Handler) DeleteMediaItem(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "delete-media-item",
+			Method:      http.MethodDelete,
+			Path:        "/library/{id}",
+			Summary:     "Удалить объект медиатеки",
+			Description: "Удаляет объект медиатеки и открепляет его от новостей и экспонатов. Загруженный файл остаётся на сервере.",
+			Tags:        []string{"Admin", "Media Library"},
+		},
+		func(ctx context.Context, req *mediaItemIDInput) (*struct{}, error) {
+			if err := h.service.DeleteMediaItem(ctx, req.ID); err != nil {
+				return nil, problem.From(err, "не удалось удалить объект медиатеки",
+					problem.Detail{Err: storage.ErrNotFound, Msg: "объект медиатеки не найден"})
+			}
+			return nil, nil
+		},
+	)
+}
+
+// mediaOwners lists the admin collections media can be attached to.
+var mediaOwners = []struct {
+	path     string
+	typ      model.EntityType
+	tag      string
+	notFound string
+}{
+	{"/news", model.EntityNews, "News", "новость не найдена"},
+	{"/exhibits", model.EntityExhibit, "Exhibits", "экспонат не найден"},
+}
+
+// SetAttachedMedia - прикрепление медиа к новости или экспонату.
+type setAttachedMediaInput struct {
+	ID   uuid.UUID `path:"id" format:"uuid" doc:"ID объекта"`
+	Body struct {
+		MediaIDs []uuid.UUID `json:"media_ids" doc:"ID объектов медиатеки в порядке показа"`
+	}
+}
+
+type attachedMediaOutput struct {
+	Body []model.AttachedMedia
+}
+
+func (h *Handler) SetAttachedMedia(api huma.API) {
+	for _, o := range mediaOwners {
+		huma.Register(
+			api,
+			huma.Operation{
+				OperationID: "set-" + string(o.typ) + "-media",
+				Method:      http.MethodPut,
+				Path:        o.path + "/{id}/media",
+				Summary:     "Прикрепить медиа",
+				Description: "Заменяет список прикреплённых объектов медиатеки; порядок в media_ids — порядок показа. Пустой список открепляет все.",
+				Tags:        []string{"Admin", o.tag, "Media Library"},
+			},
+			func(ctx context.Context, req *setAttachedMediaInput) (*attachedMediaOutput, error) {
+				attached, err := h.service.SetAttachedMedia(ctx, o.typ, req.ID, req.Body.MediaIDs)
+				if err != nil {
+					return nil, problem.From(err, "не удалось прикрепить медиа",
+						problem.Detail{Err: storage.ErrNotFound, Msg: o.notFound},
+						problem.Detail{Err: adminservice.ErrDuplicateMedia, Msg: "объект медиатеки указан дважды"},
+						problem.Detail{Err: adminservice.ErrUnknownMedia, Msg: "объект медиатеки не найден"})
+				}
+				return &attachedMediaOutput{Body: attached}, nil
+			},
+		)
+	}
+}
+
+// GetAttachedMedia - медиа, прикреплённые к новости или экспонату.
+type getAttachedMediaInput struct {
+	ID uuid.UUID `path:"id" format:"uuid" doc:"ID объекта"`
+}
+
+func (h *Handler) GetAttachedMedia(api huma.API) {
+	for _, o := range mediaOwners {
+		huma.Register(
+			api,
+			huma.Operation{
+				OperationID: "get-" + string(o.typ) + "-media",
+				Method:      http.MethodGet,
+				Path:        o.path + "/{id}/media",
+				Summary:     "Прикреплённые медиа",
+				Description: "Возвращает объекты медиатеки, прикреплённые к объекту, в порядке показа.",
+				Tags:        []string{"Admin", o.tag, "Media Library"},
+			},
+			func(ctx context.Context, req *getAttachedMediaInput) (*attachedMediaOutput, error) {
+				attached, err := h.service.GetAttachedMedia(ctx, o.typ, req.ID)
+				if err != nil {
+					return nil, problem.From(err, "не удалось получить прикреплённые медиа",
+						problem.Detail{Err: storage.ErrNotFound, Msg: o.notFound})
+				}
+				return &attachedMediaOutput{Body: attached}, nil
+			},
+		)
+	}
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/internal/problem"
+	adminservice "github.com/WhiCu/school-museum/internal/web-admin/service"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// --- Link checks ---
+
+// ListBrokenLinks - битые ссылки на изображения.
+type listBrokenLinksInput struct {
+	Statuses []string `query:"status" enum:"ok,redirect,not_found,timeout,error" doc:"Результаты проверки (по умолчанию not_found, timeout и error)"`
+}
+
+type listBrokenLinksOutput struct {
+	Body []model.BrokenLinks
+}
+
+func (h *Handler) ListBrokenLinks(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "list-broken-links",
+			Method:      http.MethodGet,
+			Path:        "/links/broken",
+			Summary:     "Битые ссылки на изображения",
+			Description: "Возвращает результаты последней фоновой проверки внешних ссылок из image_urls, сгруппированные по новостям " +
+				"и экспонатам: not_found — файл удалён или закрыт, timeout — сервис не ответил вовремя, error — другая ошибка (код в error), " +
+				"redirect — ссылка ведёт на final_url. Ссылки, уже убранные из объекта, не показываются.",
+			Tags: []string{"Admin", "Media"},
+		},
+		func(ctx context.Context, req *listBrokenLinksInput) (*listBrokenLinksOutput, error) {
+			statuses := make([]model.LinkStatus, 0, len(req.Statuses))
+			for _, s := range req.Statuses {
+				statuses = append(statuses, model.LinkStatus(s))
+			}
+			groups, err := h.service.ListBrokenLinks(ctx, statuses)
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить битые ссылки",
+					problem.Detail{Err: adminservice.ErrLinkChecksDisabled, Msg: "проверка ссылок отключена"})
+			}
+			return &listBrokenLinksOutput{Body: groups}, nil
+		},
+	)
+}
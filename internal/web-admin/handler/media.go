@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/media"
+	"github.com/WhiCu/school-museum/internal/problem"
+	"github.com/WhiCu/school-museum/internal/resolver"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// --- Media ---
+
+// UploadMedia - загрузка файла в медиатеку.
+type uploadMediaForm struct {
+	File huma.FormFile `form:"file" required:"true" doc:"Изображение (JPEG, PNG, GIF, WebP) или видео (MP4, WebM)"`
+}
+
+type uploadMediaInput struct {
+	RawBody huma.MultipartFormFiles[uploadMediaForm]
+}
+
+type uploadMediaOutput struct {
+	Status int
+	Body   model.Asset
+}
+
+func (h *Handler) UploadMedia(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID:   "upload-media",
+			Method:        http.MethodPost,
+			Path:          "/media",
+			DefaultStatus: http.StatusCreated,
+			Summary:       "Загрузить файл",
+			Description: "Сохраняет изображение или видео на сервере и возвращает его постоянный URL под /media/, " +
+				"который можно указывать в image_urls новостей и экспонатов. Тип файла определяется по содержимому. " +
+				"Из изображений удаляются EXIF (в том числе координаты GPS и сведения об устройстве), XMP и IPTC; " +
+				"дата съёмки, размеры и ориентация сохраняются в поле metadata, а повёрнутые снимки JPEG и PNG поворачиваются. " +
+				"Файлы адресуются хешем содержимого: повторная загрузка того же файла возвращает уже сохранённый объект с кодом 200.",
+			Tags: []string{"Admin", "Media"},
+		},
+		func(ctx context.Context, req *uploadMediaInput) (*uploadMediaOutput, error) {
+			file := req.RawBody.Data().File
+			defer file.Close()
+
+			a, created, err := h.service.UploadMedia(ctx, file.Filename, file)
+			if err != nil {
+				return nil, problem.From(err, "не удалось загрузить файл",
+					problem.Detail{Err: media.ErrTooLarge, Msg: "файл слишком большой", Status: http.StatusRequestEntityTooLarge},
+					problem.Detail{Err: media.ErrUnsupported, Msg: "недопустимый тип файла: разрешены JPEG, PNG, GIF, WebP, MP4 и WebM", Status: http.StatusUnsupportedMediaType},
+					problem.Detail{Err: media.ErrUndecodable, Msg: "файл изображения повреждён"})
+			}
+			status := http.StatusOK
+			if created {
+				status = http.StatusCreated
+			}
+			return &uploadMediaOutput{Status: status, Body: a}, nil
+		},
+	)
+}
+
+// ListMedia - список загруженных файлов.
+type listMediaInput struct {
+	Limit  int `query:"limit" minimum:"1" maximum:"100" doc:"Размер страницы (без параметра — все файлы)"`
+	Offset int `query:"offset" minimum:"0" doc:"Смещение от начала списка"`
+}
+
+type listMediaOutput struct {
+	Total int `header:"X-Total-Count" doc:"Общее количество файлов"`
+	Body  []model.Asset
+}
+
+func (h *Handler) ListMedia(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "list-media",
+			Method:      http.MethodGet,
+			Path:        "/media",
+			Summary:     "Получить загруженные файлы",
+			Description: "Возвращает страницу загруженных файлов, новые первыми. Общее количество — в заголовке X-Total-Count.",
+			Tags:        []string{"Admin", "Media"},
+		},
+		func(ctx context.Context, req *listMediaInput) (*listMediaOutput, error) {
+			assets, total, err := h.service.ListAssets(ctx, req.Limit, req.Offset)
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить список файлов")
+			}
+			if assets == nil {
+				assets = []model.Asset{}
+			}
+			return &listMediaOutput{Total: total, Body: assets}, nil
+		},
+	)
+}
+
+// GetMedia - загруженный файл по хешу.
+type getMediaInput struct {
+	Hash string `path:"hash" pattern:"^[0-9a-f]{64}$" doc:"SHA-256 содержимого файла"`
+}
+
+type getMediaOutput struct {
+	Body model.Asset
+}
+
+func (h *Handler) GetMedia(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "get-media",
+			Method:      http.MethodGet,
+			Path:        "/media/{hash}",
+			Summary:     "Получить загруженный файл",
+			Description: "Возвращает сведения о загруженном файле по хешу его содержимого.",
+			Tags:        []string{"Admin", "Media"},
+		},
+		func(ctx context.Context, req *getMediaInput) (*getMediaOutput, error) {
+			a, err := h.service.GetAsset(ctx, req.Hash)
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить файл",
+					problem.Detail{Err: storage.ErrNotFound, Msg: "файл не найден"})
+			}
+			return &getMediaOutput{Body: a}, nil
+		},
+	)
+}
+
+// InvalidateResolvedMedia - сброс кэша внешних ссылок.
+type invalidateResolvedMediaInput struct {
+	URL string `query:"url" doc:"Ссылка на внешний сервис, которую нужно получить заново"`
+	All bool   `query:"all" doc:"Сбросить весь кэш"`
+}
+
+type invalidateResolvedMediaOutput struct {
+	Body resolver.Purged
+}
+
+func (h *Handler) InvalidateResolvedMedia(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "invalidate-resolved-media",
+			Method:      http.MethodDelete,
+			Path:        "/media/resolved",
+			Summary:     "Сбросить кэш внешних ссылок",
+			Description: "Удаляет из кэша результат разбора ссылки url (imgur, YouTube и т. п.), чтобы при следующем просмотре " +
+				"она была получена заново, или весь кэш при all=true. Возвращает количество удалённых записей.",
+			Tags: []string{"Admin", "Media"},
+		},
+		func(ctx context.Context, req *invalidateResolvedMediaInput) (*invalidateResolvedMediaOutput, error) {
+			if req.URL == "" && !req.All {
+				return nil, huma.Error422UnprocessableEntity("укажите url или all=true")
+			}
+			url := req.URL
+			if req.All {
+				url = ""
+			}
+			p, err := h.service.InvalidateResolvedMedia(ctx, url)
+			if err != nil {
+				return nil, problem.From(err, "не удалось сбросить кэш")
+			}
+			return &invalidateResolvedMediaOutput{Body: p}, nil
+		},
+	)
+}
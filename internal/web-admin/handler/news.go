@@ -3,15 +3,19 @@ package handler
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/problem"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/google/uuid"
 )
 
 // CreateNews - создание новой новости.
 type createNewsInput struct {
-	Body struct {
+	Import bool `query:"import" doc:"Скачать внешние изображения в медиатеку сервера в фоне, см. POST {id}/import"`
+	Body   struct {
 		Title     string   `json:"title" minLength:"1" doc:"Заголовок новости"`
 		Content   string   `json:"content" doc:"Содержание новости"`
 		ImageURLs []string `json:"image_urls" doc:"URLs изображений новости"`
@@ -19,7 +23,9 @@ type createNewsInput struct {
 }
 
 type createNewsOutput struct {
-	Body model.News
+	ETag      string `header:"ETag"`
+	ImportJob string `header:"X-Import-Job" doc:"ID задания импорта, если он запущен"`
+	Body      model.News
 }
 
 func (h *Handler) CreateNews(api huma.API) {
@@ -40,17 +46,25 @@ func (h *Handler) CreateNews(api huma.API) {
 				ImageURLs: req.Body.ImageURLs,
 			})
 			if err != nil {
-				return nil, huma.Error500InternalServerError("не удалось создать новость")
+				return nil, problem.From(err, "не удалось создать новость")
 			}
-			return &createNewsOutput{Body: n}, nil
+			out := &createNewsOutput{ETag: etag(n.UpdatedAt), Body: n}
+			if req.Import {
+				if out.ImportJob, err = h.importAfterSave(ctx, model.EntityNews, n.ID); err != nil {
+					return nil, err
+				}
+			}
+			return out, nil
 		},
 	)
 }
 
 // UpdateNews - обновление новости.
 type updateNewsInput struct {
-	ID   uuid.UUID `path:"id" format:"uuid" doc:"ID новости"`
-	Body struct {
+	ID      uuid.UUID `path:"id" format:"uuid" doc:"ID новости"`
+	IfMatch string    `header:"If-Match" doc:"ETag текущей версии (updated_at в кавычках), несколько ETag через запятую или *"`
+	Import  bool      `query:"import" doc:"Скачать внешние изображения в медиатеку сервера в фоне, см. POST {id}/import"`
+	Body    struct {
 		Title     string   `json:"title" doc:"Заголовок новости"`
 		Content   string   `json:"content" doc:"Содержание новости"`
 		ImageURLs []string `json:"image_urls" doc:"URLs изображений новости"`
@@ -58,7 +72,9 @@ type updateNewsInput struct {
 }
 
 type updateNewsOutput struct {
-	Body model.News
+	ETag      string `header:"ETag"`
+	ImportJob string `header:"X-Import-Job" doc:"ID задания импорта, если он запущен"`
+	Body      model.News
 }
 
 func (h *Handler) UpdateNews(api huma.API) {
@@ -69,20 +85,35 @@ func (h *Handler) UpdateNews(api huma.API) {
 			Method:      http.MethodPut,
 			Path:        "/news/{id}",
 			Summary:     "Обновить новость",
-			Description: "Обновляет данные существующей новости.",
+			Description: "Обновляет данные существующей новости. Требует If-Match с ETag версии, которую видел редактор; если объект с тех пор изменили, возвращает 412.",
 			Tags:        []string{"Admin", "News"},
 		},
 		func(ctx context.Context, req *updateNewsInput) (*updateNewsOutput, error) {
-			n, err := h.service.UpdateNews(ctx, model.News{
-				ID:        req.ID,
-				Title:     req.Body.Title,
-				Content:   req.Body.Content,
-				ImageURLs: req.Body.ImageURLs,
+			versions, err := ifMatch(req.IfMatch)
+			if err != nil {
+				return nil, err
+			}
+			n, err := matching(versions, func(expected time.Time) (model.News, error) {
+				return h.service.UpdateNews(ctx, model.News{
+					ID:        req.ID,
+					UpdatedAt: expected,
+					Title:     req.Body.Title,
+					Content:   req.Body.Content,
+					ImageURLs: req.Body.ImageURLs,
+				})
 			})
 			if err != nil {
-				return nil, huma.Error500InternalServerError("не удалось обновить новость")
+				return nil, problem.From(err, "не удалось обновить новость",
+					problem.Detail{Err: storage.ErrStale, Msg: "новость была изменена другим пользователем, обновите страницу"},
+					problem.Detail{Err: storage.ErrNotFound, Msg: "новость не найдена"})
+			}
+			out := &updateNewsOutput{ETag: etag(n.UpdatedAt), Body: n}
+			if req.Import {
+				if out.ImportJob, err = h.importAfterSave(ctx, model.EntityNews, n.ID); err != nil {
+					return nil, err
+				}
 			}
-			return &updateNewsOutput{Body: n}, nil
+			return out, nil
 		},
 	)
 }
@@ -107,7 +138,8 @@ func (h *Handler) DeleteNews(api huma.API) {
 		},
 		func(ctx context.Context, req *deleteNewsInput) (*struct{}, error) {
 			if err := h.service.DeleteNews(ctx, req.ID); err != nil {
-				return nil, huma.Error500InternalServerError("не удалось удалить новость")
+				return nil, problem.From(err, "не удалось удалить новость",
+					problem.Detail{Err: storage.ErrNotFound, Msg: "новость не найдена"})
 			}
 			return nil, nil
 		},
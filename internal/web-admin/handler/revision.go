@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/problem"
+	adminservice "github.com/WhiCu/school-museum/internal/web-admin/service"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+// --- Revisions ---
+
+// revisioned lists the admin collections that keep a revision history.
+var revisioned = []struct {
+	path string
+	typ  model.EntityType
+	tag  string
+}{
+	{"/news", model.EntityNews, "News"},
+	{"/exhibitions", model.EntityExhibition, "Exhibitions"},
+	{"/exhibits", model.EntityExhibit, "Exhibits"},
+}
+
+// ListRevisions - история изменений объекта.
+type listRevisionsInput struct {
+	ID uuid.UUID `path:"id" format:"uuid" doc:"ID объекта"`
+}
+
+type listRevisionsOutput struct {
+	Body []model.Revision `json:"revisions"`
+}
+
+func (h *Handler) ListRevisions(api huma.API) {
+	for _, r := range revisioned {
+		huma.Register(
+			api,
+			huma.Operation{
+				OperationID: "list-" + string(r.typ) + "-revisions",
+				Method:      http.MethodGet,
+				Path:        r.path + "/{id}/revisions",
+				Summary:     "История изменений",
+				Description: "Возвращает все ревизии объекта, от первой к последней: кто и когда изменил объект, полный снимок и список изменённых полей.",
+				Tags:        []string{"Admin", r.tag, "Revisions"},
+			},
+			func(ctx context.Context, req *listRevisionsInput) (*listRevisionsOutput, error) {
+				revs, err := h.service.ListRevisions(ctx, r.typ, req.ID)
+				if err != nil {
+					return nil, problem.From(err, "не удалось получить историю изменений")
+				}
+				return &listRevisionsOutput{Body: revs}, nil
+			},
+		)
+	}
+}
+
+// DiffRevisions - сравнение двух ревизий объекта.
+type diffRevisionsInput struct {
+	ID   uuid.UUID `path:"id" format:"uuid" doc:"ID объекта"`
+	From int       `query:"from" required:"true" minimum:"1" doc:"Номер исходной ревизии"`
+	To   int       `query:"to" required:"true" minimum:"1" doc:"Номер конечной ревизии"`
+}
+
+type diffRevisionsOutput struct {
+	Body []model.FieldChange `json:"changes"`
+}
+
+func (h *Handler) DiffRevisions(api huma.API) {
+	for _, r := range revisioned {
+		huma.Register(
+			api,
+			huma.Operation{
+				OperationID: "diff-" + string(r.typ) + "-revisions",
+				Method:      http.MethodGet,
+				Path:        r.path + "/{id}/revisions/diff",
+				Summary:     "Сравнить ревизии",
+				Description: "Возвращает поля, которые различаются между двумя ревизиями объекта.",
+				Tags:        []string{"Admin", r.tag, "Revisions"},
+			},
+			func(ctx context.Context, req *diffRevisionsInput) (*diffRevisionsOutput, error) {
+				changes, err := h.service.DiffRevisions(ctx, r.typ, req.ID, req.From, req.To)
+				if err != nil {
+					return nil, problem.From(err, "не удалось сравнить ревизии",
+						problem.Detail{Err: storage.ErrNotFound, Msg: "ревизия не найдена"})
+				}
+				return &diffRevisionsOutput{Body: changes}, nil
+			},
+		)
+	}
+}
+
+// RestoreRevision - откат объекта к ревизии.
+type restoreRevisionInput struct {
+	ID  uuid.UUID `path:"id" format:"uuid" doc:"ID объекта"`
+	Rev int       `path:"rev" minimum:"1" doc:"Номер ревизии"`
+}
+
+type restoreRevisionOutput struct {
+	Body model.Revision
+}
+
+func (h *Handler) RestoreRevision(api huma.API) {
+	for _, r := range revisioned {
+		huma.Register(
+			api,
+			huma.Operation{
+				OperationID: "restore-" + string(r.typ) + "-revision",
+				Method:      http.MethodPost,
+				Path:        r.path + "/{id}/revisions/{rev}/restore",
+				Summary:     "Откатить к ревизии",
+				Description: "Возвращает объект к состоянию из указанной ревизии (удалённый объект сначала восстанавливается из корзины). Откат сам записывается новой ревизией, которая и возвращается.",
+				Tags:        []string{"Admin", r.tag, "Revisions"},
+			},
+			func(ctx context.Context, req *restoreRevisionInput) (*restoreRevisionOutput, error) {
+				rev, err := h.service.RollbackRevision(ctx, r.typ, req.ID, req.Rev)
+				if err != nil {
+					return nil, problem.From(err, "не удалось откатить объект",
+						problem.Detail{Err: storage.ErrNotFound, Msg: "объект или ревизия не найдены"},
+						problem.Detail{Err: adminservice.ErrExhibitionDeleted, Msg: "экспозиция экспоната удалена, сначала восстановите её"})
+				}
+				return &restoreRevisionOutput{Body: rev}, nil
+			},
+		)
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/auth"
+	"github.com/WhiCu/school-museum/internal/problem"
+	adminservice "github.com/WhiCu/school-museum/internal/web-admin/service"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// --- Security log ---
+
+// lockedDetail answers the sign-ins refused after too many failures.
+var lockedDetail = problem.Detail{
+	Err: auth.ErrLocked,
+	Msg: "слишком много неудачных попыток входа, попробуйте позже",
+}
+
+// withRetryAfter adds to p, the problem of err, the Retry-After header if
+// err is a lockout.
+func withRetryAfter(err error, p huma.StatusError) error {
+	var locked *auth.LockedError
+	if !errors.As(err, &locked) {
+		return p
+	}
+	return huma.ErrorWithHeaders(p, http.Header{"Retry-After": {strconv.Itoa(locked.Seconds())}})
+}
+
+// ListLoginFailures - журнал неудачных входов.
+type listLoginFailuresInput struct {
+	Login     string `query:"login" maxLength:"100" doc:"Только попытки входа под этим логином"`
+	Limit     int    `query:"limit" minimum:"1" maximum:"100" default:"50" doc:"Размер страницы"`
+	Offset    int    `query:"offset" minimum:"0" doc:"Смещение от начала списка"`
+	Direction string `query:"direction" enum:"asc,desc" default:"desc" doc:"Направление сортировки по времени"`
+}
+
+type listLoginFailuresOutput struct {
+	Total int `header:"X-Total-Count" doc:"Общее количество попыток"`
+	Body  []model.LoginFailure
+}
+
+func (h *Handler) ListLoginFailures(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "list-login-failures",
+			Method:      http.MethodGet,
+			Path:        "/login-failures",
+			Summary:     "Неудачные входы",
+			Description: "Возвращает страницу журнала неудачных попыток входа — через форму и с Basic Auth — с логином, адресом и браузером, " +
+				"начиная с последних. Поле locked отмечает попытки, после которых логин или адрес были временно заблокированы. " +
+				"Общее количество — в заголовке X-Total-Count. Доступно владельцам.",
+			Tags:     []string{"Admin", "Security"},
+			Metadata: requires(model.RoleOwner),
+		},
+		func(ctx context.Context, req *listLoginFailuresInput) (*listLoginFailuresOutput, error) {
+			failures, total, err := h.service.ListLoginFailures(ctx, req.Login, storage.ListOptions{
+				Limit:  req.Limit,
+				Offset: req.Offset,
+				Desc:   req.Direction == "desc",
+			})
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить журнал входов",
+					problem.Detail{Err: adminservice.ErrLoginFailuresDisabled, Msg: "журнал входов отключён"})
+			}
+			return &listLoginFailuresOutput{Total: total, Body: failures}, nil
+		},
+	)
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/auth"
+	"github.com/WhiCu/school-museum/internal/problem"
+	adminservice "github.com/WhiCu/school-museum/internal/web-admin/service"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+// --- Sessions ---
+
+// sessionErrors are the messages of the errors of the session operations.
+var sessionErrors = []problem.Detail{
+	{Err: adminservice.ErrNoSession, Msg: "запрос сделан без сессии"},
+	{Err: storage.ErrNotFound, Msg: "сессия не найдена"},
+	{Err: adminservice.ErrSessionsDisabled, Msg: "вход через форму отключён"},
+}
+
+// sessionBody is a session with its user and CSRF token.
+type sessionBody struct {
+	User      model.AdminUser    `json:"user"`
+	Session   model.AdminSession `json:"session"`
+	CSRFToken string             `json:"csrf_token" doc:"Передаётся в заголовке X-CSRF-Token запросов, которые что-то меняют"`
+}
+
+// Login - вход через форму.
+type loginInput struct {
+	Body struct {
+		Login    string `json:"login" doc:"Логин"`
+		Password string `json:"password" doc:"Пароль"`
+		Code     string `json:"code,omitempty" required:"false" maxLength:"32" doc:"Код из приложения-аутентификатора или код восстановления, если включена двухфакторная аутентификация"`
+	}
+}
+
+type loginOutput struct {
+	SetCookie http.Cookie `header:"Set-Cookie"`
+	Body      sessionBody
+}
+
+func (h *Handler) Login(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "login",
+			Method:      http.MethodPost,
+			Path:        "/login",
+			Summary:     "Войти",
+			Description: "Проверяет логин и пароль и начинает сессию: её токен приходит в cookie museum_session (HttpOnly, SameSite=Strict), " +
+				"а в ответе — CSRF-токен для заголовка X-CSRF-Token. Сессия заканчивается после простоя или через срок от входа, " +
+				"что наступит раньше. После нескольких неудачных попыток логин и адрес блокируются на время, которое растёт с каждой ошибкой; " +
+				"тогда ответ — 429 с заголовком Retry-After. Пользователям с двухфакторной аутентификацией нужен ещё код: без него ответ — 401 с кодом totp_required. " +
+				"Скрипты могут по-прежнему обращаться к админке с Basic Auth, без сессии и CSRF-токена.",
+			Tags:     []string{"Admin", "Sessions"},
+			Metadata: public(),
+		},
+		func(ctx context.Context, req *loginInput) (*loginOutput, error) {
+			login, err := h.service.Login(ctx, req.Body.Login, req.Body.Password, req.Body.Code)
+			if err != nil {
+				return nil, withRetryAfter(err, problem.From(err, "не удалось войти",
+					problem.Detail{Err: auth.ErrInvalidCredentials, Msg: "неверный логин или пароль", Status: http.StatusUnauthorized},
+					problem.Detail{Err: auth.ErrTOTPRequired, Msg: "введите код из приложения-аутентификатора", Status: http.StatusUnauthorized},
+					problem.Detail{Err: auth.ErrInvalidTOTP, Msg: "неверный код", Status: http.StatusUnauthorized},
+					lockedDetail,
+					problem.Detail{Err: adminservice.ErrSessionsDisabled, Msg: "вход через форму отключён"}))
+			}
+			return &loginOutput{
+				SetCookie: *login.Cookie,
+				Body:      sessionBody{User: login.User, Session: login.Session, CSRFToken: login.Session.CSRFToken},
+			}, nil
+		},
+	)
+}
+
+// Logout - выход.
+type logoutOutput struct {
+	SetCookie http.Cookie `header:"Set-Cookie"`
+}
+
+func (h *Handler) Logout(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "logout",
+			Method:      http.MethodPost,
+			Path:        "/logout",
+			Summary:     "Выйти",
+			Description: "Заканчивает сессию запроса и удаляет её cookie. Для запросов с Basic Auth ничего не делает.",
+			Tags:        []string{"Admin", "Sessions"},
+			Metadata:    duringEnrollment(requires(model.RoleViewer)),
+		},
+		func(ctx context.Context, _ *struct{}) (*logoutOutput, error) {
+			cookie, err := h.service.Logout(ctx)
+			if err != nil {
+				return nil, problem.From(err, "не удалось выйти", sessionErrors...)
+			}
+			return &logoutOutput{SetCookie: *cookie}, nil
+		},
+	)
+}
+
+// GetCurrentSession - текущая сессия.
+type sessionOutput struct {
+	Body sessionBody
+}
+
+func (h *Handler) GetCurrentSession(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "get-current-session",
+			Method:      http.MethodGet,
+			Path:        "/session",
+			Summary:     "Текущая сессия",
+			Description: "Возвращает сессию запроса с пользователем и CSRF-токеном, например после перезагрузки страницы. " +
+				"Для запросов с Basic Auth возвращает 404.",
+			Tags:     []string{"Admin", "Sessions"},
+			Metadata: duringEnrollment(nil),
+		},
+		func(ctx context.Context, _ *struct{}) (*sessionOutput, error) {
+			user, as, err := h.service.CurrentSession(ctx)
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить сессию", sessionErrors...)
+			}
+			return &sessionOutput{Body: sessionBody{User: user, Session: as, CSRFToken: as.CSRFToken}}, nil
+		},
+	)
+}
+
+// ListSessions - свои активные сессии.
+type listSessionsOutput struct {
+	Body []model.AdminSession
+}
+
+func (h *Handler) ListSessions(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "list-sessions",
+			Method:      http.MethodGet,
+			Path:        "/sessions",
+			Summary:     "Мои сессии",
+			Description: "Возвращает активные сессии пользователя, от имени которого сделан запрос, начиная с последней использованной. " +
+				"Сессия запроса отмечена полем current.",
+			Tags: []string{"Admin", "Sessions"},
+		},
+		func(ctx context.Context, _ *struct{}) (*listSessionsOutput, error) {
+			sessions, err := h.service.ListSessions(ctx)
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить сессии", sessionErrors...)
+			}
+			return &listSessionsOutput{Body: sessions}, nil
+		},
+	)
+}
+
+// RevokeSession - завершение своей сессии.
+type revokeSessionInput struct {
+	ID uuid.UUID `path:"id" format:"uuid" doc:"ID сессии"`
+}
+
+func (h *Handler) RevokeSession(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "revoke-session",
+			Method:      http.MethodDelete,
+			Path:        "/sessions/{id}",
+			Summary:     "Завершить сессию",
+			Description: "Завершает одну из своих сессий, например на чужом компьютере. Доступно всем ролям.",
+			Tags:        []string{"Admin", "Sessions"},
+			Metadata:    requires(model.RoleViewer),
+		},
+		func(ctx context.Context, req *revokeSessionInput) (*struct{}, error) {
+			if err := h.service.RevokeSession(ctx, req.ID); err != nil {
+				return nil, problem.From(err, "не удалось завершить сессию", sessionErrors...)
+			}
+			return nil, nil
+		},
+	)
+}
+
+// ListUserSessions - активные сессии пользователя.
+type userSessionsInput struct {
+	ID uuid.UUID `path:"id" format:"uuid" doc:"ID пользователя"`
+}
+
+func (h *Handler) ListUserSessions(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "list-user-sessions",
+			Method:      http.MethodGet,
+			Path:        "/users/{id}/sessions",
+			Summary:     "Сессии пользователя",
+			Description: "Возвращает активные сессии пользователя, начиная с последней использованной. Доступно владельцам.",
+			Tags:        []string{"Admin", "Users", "Sessions"},
+			Metadata:    requires(model.RoleOwner),
+		},
+		func(ctx context.Context, req *userSessionsInput) (*listSessionsOutput, error) {
+			sessions, err := h.service.ListUserSessions(ctx, req.ID)
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить сессии", userErrors...)
+			}
+			return &listSessionsOutput{Body: sessions}, nil
+		},
+	)
+}
+
+// RevokeUserSessions - завершение всех сессий пользователя.
+type revokeUserSessionsOutput struct {
+	Body struct {
+		Revoked int `json:"revoked" doc:"Число завершённых сессий"`
+	}
+}
+
+func (h *Handler) RevokeUserSessions(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "revoke-user-sessions",
+			Method:      http.MethodDelete,
+			Path:        "/users/{id}/sessions",
+			Summary:     "Завершить сессии пользователя",
+			Description: "Завершает все сессии пользователя, кроме сессии запроса. Доступно владельцам.",
+			Tags:        []string{"Admin", "Users", "Sessions"},
+			Metadata:    requires(model.RoleOwner),
+		},
+		func(ctx context.Context, req *userSessionsInput) (*revokeUserSessionsOutput, error) {
+			n, err := h.service.RevokeUserSessions(ctx, req.ID)
+			if err != nil {
+				return nil, problem.From(err, "не удалось завершить сессии", userErrors...)
+			}
+			out := &revokeUserSessionsOutput{}
+			out.Body.Revoked = n
+			return out, nil
+		},
+	)
+}
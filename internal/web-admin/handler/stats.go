@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/internal/problem"
 	"github.com/danielgtaylor/huma/v2"
 )
 
@@ -27,7 +28,7 @@ func (h *Handler) GetStats(api huma.API) {
 		func(ctx context.Context, req *struct{}) (*getStatsOutput, error) {
 			stats, err := h.service.GetStats(ctx)
 			if err != nil {
-				return nil, huma.Error500InternalServerError("не удалось получить статистику")
+				return nil, problem.From(err, "не удалось получить статистику")
 			}
 			return &getStatsOutput{Body: stats}, nil
 		},
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/auth"
+	"github.com/WhiCu/school-museum/internal/problem"
+	adminservice "github.com/WhiCu/school-museum/internal/web-admin/service"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+// --- API tokens ---
+
+// tokenErrors are the messages of the errors of the token operations.
+var tokenErrors = []problem.Detail{
+	{Err: storage.ErrNotFound, Msg: "токен не найден"},
+	{Err: auth.ErrInvalidTokenName, Msg: "название токена должно быть от 1 до 100 символов"},
+	{Err: auth.ErrInvalidScopes, Msg: "нужна хотя бы одна известная область доступа"},
+	{Err: auth.ErrInvalidExpiry, Msg: "срок действия токена должен быть в будущем"},
+	{Err: auth.ErrScopeNotAllowed, Msg: "роль не позволяет выдать токену права на изменение"},
+	{Err: adminservice.ErrTokensDisabled, Msg: "API-токены отключены"},
+}
+
+// ListTokens - свои API-токены.
+type listTokensOutput struct {
+	Body []model.APIToken
+}
+
+func (h *Handler) ListTokens(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "list-tokens",
+			Method:      http.MethodGet,
+			Path:        "/tokens",
+			Summary:     "Мои API-токены",
+			Description: "Возвращает API-токены пользователя, от имени которого сделан запрос, начиная с новых. Сами токены не возвращаются, только их начало.",
+			Tags:        []string{"Admin", "Tokens"},
+		},
+		func(ctx context.Context, _ *struct{}) (*listTokensOutput, error) {
+			tokens, err := h.service.ListTokens(ctx)
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить токены", tokenErrors...)
+			}
+			return &listTokensOutput{Body: tokens}, nil
+		},
+	)
+}
+
+// CreateToken - выпуск API-токена.
+type createTokenInput struct {
+	Body struct {
+		Name   string        `json:"name" minLength:"1" maxLength:"100" doc:"Название, например имя скрипта"`
+		Scopes []model.Scope `json:"scopes" minItems:"1" enum:"news:read,news:write,exhibitions:read,exhibitions:write,exhibits:read,exhibits:write,media:read,media:write,trash:read,trash:write,stats:read" doc:"Области доступа; права на изменение включают чтение"`
+		// ExpiresAt is nil for tokens that never expire.
+		ExpiresAt *time.Time `json:"expires_at,omitempty" required:"false" doc:"Срок действия; без него токен бессрочный"`
+	}
+}
+
+type createTokenOutput struct {
+	Body struct {
+		Token  model.APIToken `json:"token"`
+		Secret string         `json:"secret" doc:"Токен для заголовка Authorization: Bearer. Показывается один раз"`
+	}
+}
+
+func (h *Handler) CreateToken(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "create-token",
+			Method:      http.MethodPost,
+			Path:        "/tokens",
+			Summary:     "Выпустить API-токен",
+			Description: "Выпускает API-токен для скриптов от имени пользователя, от имени которого сделан запрос. " +
+				"Токен действует не больше роли пользователя и только в своих областях доступа; " +
+				"пользователями, сессиями и токенами с ним управлять нельзя. Токен показывается один раз. Доступно всем ролям.",
+			Tags:          []string{"Admin", "Tokens"},
+			DefaultStatus: http.StatusCreated,
+			Metadata:      requires(model.RoleViewer),
+		},
+		func(ctx context.Context, req *createTokenInput) (*createTokenOutput, error) {
+			tok, secret, err := h.service.CreateToken(ctx, req.Body.Name, req.Body.Scopes, req.Body.ExpiresAt)
+			if err != nil {
+				return nil, problem.From(err, "не удалось выпустить токен", tokenErrors...)
+			}
+			out := &createTokenOutput{}
+			out.Body.Token, out.Body.Secret = tok, secret
+			return out, nil
+		},
+	)
+}
+
+// RevokeToken - отзыв своего API-токена.
+type revokeTokenInput struct {
+	ID uuid.UUID `path:"id" format:"uuid" doc:"ID токена"`
+}
+
+func (h *Handler) RevokeToken(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "revoke-token",
+			Method:      http.MethodDelete,
+			Path:        "/tokens/{id}",
+			Summary:     "Отозвать API-токен",
+			Description: "Удаляет один из своих API-токенов; запросы с ним больше не проходят. Доступно всем ролям.",
+			Tags:        []string{"Admin", "Tokens"},
+			Metadata:    requires(model.RoleViewer),
+		},
+		func(ctx context.Context, req *revokeTokenInput) (*struct{}, error) {
+			if err := h.service.RevokeToken(ctx, req.ID); err != nil {
+				return nil, problem.From(err, "не удалось отозвать токен", tokenErrors...)
+			}
+			return nil, nil
+		},
+	)
+}
+
+// ListUserTokens - API-токены пользователя.
+type userTokensInput struct {
+	ID uuid.UUID `path:"id" format:"uuid" doc:"ID пользователя"`
+}
+
+func (h *Handler) ListUserTokens(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "list-user-tokens",
+			Method:      http.MethodGet,
+			Path:        "/users/{id}/tokens",
+			Summary:     "API-токены пользователя",
+			Description: "Возвращает API-токены пользователя, начиная с новых, с временем и адресом последнего использования. Доступно владельцам.",
+			Tags:        []string{"Admin", "Users", "Tokens"},
+			Metadata:    requires(model.RoleOwner),
+		},
+		func(ctx context.Context, req *userTokensInput) (*listTokensOutput, error) {
+			tokens, err := h.service.ListUserTokens(ctx, req.ID)
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить токены", userErrors...)
+			}
+			return &listTokensOutput{Body: tokens}, nil
+		},
+	)
+}
+
+// RevokeUserToken - отзыв API-токена пользователя.
+type revokeUserTokenInput struct {
+	ID      uuid.UUID `path:"id" format:"uuid" doc:"ID пользователя"`
+	TokenID uuid.UUID `path:"token_id" format:"uuid" doc:"ID токена"`
+}
+
+func (h *Handler) RevokeUserToken(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "revoke-user-token",
+			Method:      http.MethodDelete,
+			Path:        "/users/{id}/tokens/{token_id}",
+			Summary:     "Отозвать API-токен пользователя",
+			Description: "Удаляет API-токен пользователя, например утёкший. Доступно владельцам.",
+			Tags:        []string{"Admin", "Users", "Tokens"},
+			Metadata:    requires(model.RoleOwner),
+		},
+		func(ctx context.Context, req *revokeUserTokenInput) (*struct{}, error) {
+			if err := h.service.RevokeUserToken(ctx, req.ID, req.TokenID); err != nil {
+				return nil, problem.From(err, "не удалось отозвать токен", tokenErrors...)
+			}
+			return nil, nil
+		},
+	)
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/problem"
+	adminservice "github.com/WhiCu/school-museum/internal/web-admin/service"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+// --- Trash ---
+
+// ListTrash - список удалённых объектов.
+type listTrashInput struct {
+	Types []string `query:"type" enum:"news,exhibition,exhibit" doc:"Типы объектов (по умолчанию все)"`
+}
+
+type listTrashOutput struct {
+	Body []model.TrashItem `json:"items"`
+}
+
+func (h *Handler) ListTrash(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "list-trash",
+			Method:      http.MethodGet,
+			Path:        "/trash",
+			Summary:     "Корзина",
+			Description: "Возвращает удалённые новости, экспозиции и экспонаты, сначала удалённые последними. Экспонаты, удалённые вместе с экспозицией, учитываются в её поле deleted_exhibits.",
+			Tags:        []string{"Admin", "Trash"},
+		},
+		func(ctx context.Context, req *listTrashInput) (*listTrashOutput, error) {
+			types := make([]model.EntityType, 0, len(req.Types))
+			for _, t := range req.Types {
+				types = append(types, model.EntityType(t))
+			}
+			items, err := h.service.ListTrash(ctx, types)
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить корзину")
+			}
+			return &listTrashOutput{Body: items}, nil
+		},
+	)
+}
+
+// RestoreTrash - восстановление объекта из корзины.
+type restoreTrashInput struct {
+	Type     string    `path:"type" enum:"news,exhibition,exhibit" doc:"Тип объекта"`
+	ID       uuid.UUID `path:"id" format:"uuid" doc:"ID объекта"`
+	Exhibits bool      `query:"exhibits" default:"true" doc:"Для экспозиции: восстановить и экспонаты, удалённые вместе с ней"`
+}
+
+type restoreTrashOutput struct {
+	Body struct {
+		RestoredExhibits int `json:"restored_exhibits" doc:"Число восстановленных экспонатов"`
+	}
+}
+
+func (h *Handler) RestoreTrash(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "restore-trash",
+			Method:      http.MethodPost,
+			Path:        "/trash/{type}/{id}/restore",
+			Summary:     "Восстановить из корзины",
+			Description: "Восстанавливает удалённый объект. Экспонат можно восстановить, только если его экспозиция не удалена.",
+			Tags:        []string{"Admin", "Trash"},
+		},
+		func(ctx context.Context, req *restoreTrashInput) (*restoreTrashOutput, error) {
+			n, err := h.service.Restore(ctx, model.EntityType(req.Type), req.ID, req.Exhibits)
+			if err != nil {
+				return nil, problem.From(err, "не удалось восстановить объект",
+					problem.Detail{Err: storage.ErrNotFound, Msg: "объект не найден в корзине"},
+					problem.Detail{Err: adminservice.ErrExhibitionDeleted, Msg: "экспозиция экспоната удалена, сначала восстановите её"})
+			}
+			out := &restoreTrashOutput{}
+			out.Body.RestoredExhibits = n
+			return out, nil
+		},
+	)
+}
+
+// PurgeTrash - окончательное удаление объекта из корзины.
+type purgeTrashInput struct {
+	Type string    `path:"type" enum:"news,exhibition,exhibit" doc:"Тип объекта"`
+	ID   uuid.UUID `path:"id" format:"uuid" doc:"ID объекта"`
+}
+
+func (h *Handler) PurgeTrash(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "purge-trash",
+			Method:      http.MethodDelete,
+			Path:        "/trash/{type}/{id}",
+			Summary:     "Удалить из корзины навсегда",
+			Description: "Окончательно удаляет объект из корзины. Вместе с экспозицией удаляются её экспонаты, находящиеся в корзине. Доступно владельцам.",
+			Tags:        []string{"Admin", "Trash"},
+			Metadata:    requires(model.RoleOwner),
+		},
+		func(ctx context.Context, req *purgeTrashInput) (*struct{}, error) {
+			err := h.service.Purge(ctx, model.EntityType(req.Type), req.ID)
+			if err != nil {
+				return nil, problem.From(err, "не удалось удалить объект",
+					problem.Detail{Err: storage.ErrNotFound, Msg: "объект не найден в корзине"})
+			}
+			return nil, nil
+		},
+	)
+}
+
+// PurgeExpiredTrash - очистка корзины от объектов старше срока хранения.
+type purgeExpiredTrashOutput struct {
+	Body model.PurgeResult
+}
+
+func (h *Handler) PurgeExpiredTrash(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "purge-expired-trash",
+			Method:      http.MethodPost,
+			Path:        "/trash/purge",
+			Summary:     "Очистить корзину",
+			Description: "Окончательно удаляет объекты, пролежавшие в корзине дольше срока хранения (trash.retention). То же самое периодически делает фоновая задача. Доступно владельцам.",
+			Tags:        []string{"Admin", "Trash"},
+			Metadata:    requires(model.RoleOwner),
+		},
+		func(ctx context.Context, req *struct{}) (*purgeExpiredTrashOutput, error) {
+			res, err := h.service.PurgeExpired(ctx)
+			if err != nil {
+				return nil, problem.From(err, "не удалось очистить корзину",
+					problem.Detail{Err: adminservice.ErrRetentionDisabled, Msg: "срок хранения корзины не задан"})
+			}
+			return &purgeExpiredTrashOutput{Body: res}, nil
+		},
+	)
+}
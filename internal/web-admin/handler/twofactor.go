@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/auth"
+	"github.com/WhiCu/school-museum/internal/problem"
+	adminservice "github.com/WhiCu/school-museum/internal/web-admin/service"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+// --- Two-factor authentication ---
+
+// twoFactorErrors are the messages of the errors of the two-factor
+// operations.
+var twoFactorErrors = []problem.Detail{
+	{Err: auth.ErrInvalidTOTP, Msg: "неверный код"},
+	{Err: auth.ErrTOTPEnabled, Msg: "двухфакторная аутентификация уже включена"},
+	{Err: auth.ErrTOTPDisabled, Msg: "двухфакторная аутентификация не включена"},
+	{Err: auth.ErrTOTPNotStarted, Msg: "сначала начните подключение приложения"},
+	{Err: auth.ErrTOTPEnforced, Msg: "роль пользователя требует двухфакторной аутентификации"},
+	lockedDetail,
+	{Err: storage.ErrNotFound, Msg: "пользователь не найден"},
+	{Err: adminservice.ErrTwoFactorDisabled, Msg: "двухфакторная аутентификация отключена"},
+}
+
+// codeInput is a request confirmed with a one-time or a recovery code.
+type codeInput struct {
+	Body struct {
+		Code string `json:"code" minLength:"1" maxLength:"32" doc:"Код из приложения-аутентификатора или код восстановления"`
+	}
+}
+
+// recoveryCodesOutput shows the recovery codes once.
+type recoveryCodesOutput struct {
+	Body struct {
+		RecoveryCodes []string `json:"recovery_codes" doc:"Одноразовые коды для входа без телефона. Показываются один раз"`
+	}
+}
+
+// GetTwoFactor - состояние своей двухфакторной аутентификации.
+type twoFactorOutput struct {
+	Body struct {
+		Enabled           bool `json:"enabled" doc:"Включена ли двухфакторная аутентификация"`
+		Required          bool `json:"required" doc:"Требует ли её роль пользователя"`
+		RecoveryCodesLeft int  `json:"recovery_codes_left" doc:"Сколько кодов восстановления осталось"`
+	}
+}
+
+func (h *Handler) GetTwoFactor(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "get-two-factor",
+			Method:      http.MethodGet,
+			Path:        "/me/totp",
+			Summary:     "Двухфакторная аутентификация",
+			Description: "Возвращает, включена ли двухфакторная аутентификация пользователя, от имени которого сделан запрос, " +
+				"требует ли её его роль и сколько осталось кодов восстановления.",
+			Tags:     []string{"Admin", "Two-Factor"},
+			Metadata: duringEnrollment(nil),
+		},
+		func(ctx context.Context, _ *struct{}) (*twoFactorOutput, error) {
+			st, err := h.service.TwoFactorStatus(ctx)
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить состояние двухфакторной аутентификации", twoFactorErrors...)
+			}
+			out := &twoFactorOutput{}
+			out.Body.Enabled, out.Body.Required, out.Body.RecoveryCodesLeft = st.Enabled, st.Required, st.RecoveryCodesLeft
+			return out, nil
+		},
+	)
+}
+
+// BeginTwoFactor - начало подключения приложения-аутентификатора.
+type beginTwoFactorOutput struct {
+	Body struct {
+		Secret string `json:"secret" doc:"Секрет в base32 для ручного ввода в приложение"`
+		URI    string `json:"uri" doc:"URI otpauth:// для QR-кода"`
+	}
+}
+
+func (h *Handler) BeginTwoFactor(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "begin-two-factor",
+			Method:      http.MethodPost,
+			Path:        "/me/totp",
+			Summary:     "Подключить приложение-аутентификатор",
+			Description: "Создаёт новый секрет TOTP и возвращает его с URI otpauth:// для QR-кода. " +
+				"Двухфакторная аутентификация включится после подтверждения кодом из приложения. Доступно всем ролям.",
+			Tags:     []string{"Admin", "Two-Factor"},
+			Metadata: duringEnrollment(requires(model.RoleViewer)),
+		},
+		func(ctx context.Context, _ *struct{}) (*beginTwoFactorOutput, error) {
+			secret, uri, err := h.service.BeginTwoFactor(ctx)
+			if err != nil {
+				return nil, problem.From(err, "не удалось начать подключение", twoFactorErrors...)
+			}
+			out := &beginTwoFactorOutput{}
+			out.Body.Secret, out.Body.URI = secret, uri
+			return out, nil
+		},
+	)
+}
+
+// ConfirmTwoFactor - включение двухфакторной аутентификации.
+func (h *Handler) ConfirmTwoFactor(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "confirm-two-factor",
+			Method:      http.MethodPost,
+			Path:        "/me/totp/confirm",
+			Summary:     "Подтвердить подключение",
+			Description: "Проверяет код из приложения и включает двухфакторную аутентификацию. " +
+				"Возвращает коды восстановления — они показываются один раз. Доступно всем ролям.",
+			Tags:     []string{"Admin", "Two-Factor"},
+			Metadata: duringEnrollment(requires(model.RoleViewer)),
+		},
+		func(ctx context.Context, req *codeInput) (*recoveryCodesOutput, error) {
+			codes, err := h.service.ConfirmTwoFactor(ctx, req.Body.Code)
+			if err != nil {
+				return nil, withRetryAfter(err, problem.From(err, "не удалось включить двухфакторную аутентификацию", twoFactorErrors...))
+			}
+			out := &recoveryCodesOutput{}
+			out.Body.RecoveryCodes = codes
+			return out, nil
+		},
+	)
+}
+
+// DisableTwoFactor - отключение своей двухфакторной аутентификации.
+func (h *Handler) DisableTwoFactor(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "disable-two-factor",
+			Method:      http.MethodPost,
+			Path:        "/me/totp/disable",
+			Summary:     "Отключить двухфакторную аутентификацию",
+			Description: "Отключает двухфакторную аутентификацию по коду из приложения или коду восстановления, " +
+				"если роль пользователя её не требует. Доступно всем ролям.",
+			Tags:     []string{"Admin", "Two-Factor"},
+			Metadata: requires(model.RoleViewer),
+		},
+		func(ctx context.Context, req *codeInput) (*struct{}, error) {
+			if err := h.service.DisableTwoFactor(ctx, req.Body.Code); err != nil {
+				return nil, withRetryAfter(err, problem.From(err, "не удалось отключить двухфакторную аутентификацию", twoFactorErrors...))
+			}
+			return nil, nil
+		},
+	)
+}
+
+// RegenerateRecoveryCodes - новые коды восстановления.
+func (h *Handler) RegenerateRecoveryCodes(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "regenerate-recovery-codes",
+			Method:      http.MethodPost,
+			Path:        "/me/totp/recovery-codes",
+			Summary:     "Новые коды восстановления",
+			Description: "Заменяет коды восстановления новыми по коду из приложения или коду восстановления. " +
+				"Старые коды перестают действовать. Доступно всем ролям.",
+			Tags:     []string{"Admin", "Two-Factor"},
+			Metadata: requires(model.RoleViewer),
+		},
+		func(ctx context.Context, req *codeInput) (*recoveryCodesOutput, error) {
+			codes, err := h.service.RegenerateRecoveryCodes(ctx, req.Body.Code)
+			if err != nil {
+				return nil, withRetryAfter(err, problem.From(err, "не удалось создать коды восстановления", twoFactorErrors...))
+			}
+			out := &recoveryCodesOutput{}
+			out.Body.RecoveryCodes = codes
+			return out, nil
+		},
+	)
+}
+
+// ResetUserTwoFactor - сброс двухфакторной аутентификации пользователя.
+type resetUserTwoFactorInput struct {
+	ID uuid.UUID `path:"id" format:"uuid" doc:"ID пользователя"`
+}
+
+func (h *Handler) ResetUserTwoFactor(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "reset-user-two-factor",
+			Method:      http.MethodDelete,
+			Path:        "/users/{id}/totp",
+			Summary:     "Сбросить двухфакторную аутентификацию",
+			Description: "Отключает двухфакторную аутентификацию пользователя, потерявшего телефон и коды восстановления. " +
+				"Если её требует роль, при следующем входе пользователь подключит приложение заново. Доступно владельцам.",
+			Tags:     []string{"Admin", "Users", "Two-Factor"},
+			Metadata: requires(model.RoleOwner),
+		},
+		func(ctx context.Context, req *resetUserTwoFactorInput) (*userOutput, error) {
+			u, err := h.service.ResetUserTwoFactor(ctx, req.ID)
+			if err != nil {
+				return nil, problem.From(err, "не удалось сбросить двухфакторную аутентификацию", twoFactorErrors...)
+			}
+			return &userOutput{Body: u}, nil
+		},
+	)
+}
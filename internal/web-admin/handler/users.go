@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/auth"
+	"github.com/WhiCu/school-museum/internal/problem"
+	adminservice "github.com/WhiCu/school-museum/internal/web-admin/service"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+// --- Users ---
+
+// userErrors are the messages of the errors of the user operations.
+var userErrors = []problem.Detail{
+	{Err: storage.ErrNotFound, Msg: "пользователь не найден"},
+	{Err: auth.ErrInvalidLogin, Msg: "логин должен быть от 1 до 64 символов, без пробелов и двоеточий"},
+	{Err: auth.ErrInvalidPassword, Msg: "пароль должен быть от 8 до 72 байт"},
+	{Err: auth.ErrLoginTaken, Msg: "логин уже занят"},
+	{Err: auth.ErrLastOwner, Msg: "нельзя понизить или отключить последнего владельца"},
+	{Err: auth.ErrInvalidCredentials, Msg: "неверный текущий пароль"},
+	lockedDetail,
+	{Err: adminservice.ErrUsersDisabled, Msg: "учётные записи отключены"},
+}
+
+// ListUsers - список пользователей админки.
+type listUsersOutput struct {
+	Body []model.AdminUser
+}
+
+func (h *Handler) ListUsers(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "list-users",
+			Method:      http.MethodGet,
+			Path:        "/users",
+			Summary:     "Пользователи",
+			Description: "Возвращает пользователей админки по логину. Доступно владельцам.",
+			Tags:        []string{"Admin", "Users"},
+			Metadata:    requires(model.RoleOwner),
+		},
+		func(ctx context.Context, _ *struct{}) (*listUsersOutput, error) {
+			users, err := h.service.ListUsers(ctx)
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить пользователей", userErrors...)
+			}
+			return &listUsersOutput{Body: users}, nil
+		},
+	)
+}
+
+// CreateUser - создание пользователя.
+type createUserInput struct {
+	Body struct {
+		Login    string     `json:"login" minLength:"1" maxLength:"64" doc:"Логин, без учёта регистра"`
+		Password string     `json:"password" minLength:"8" maxLength:"72" doc:"Пароль"`
+		Role     model.Role `json:"role" enum:"owner,editor,viewer" doc:"Роль: owner управляет пользователями, editor меняет контент, viewer только читает"`
+	}
+}
+
+type userOutput struct {
+	Body model.AdminUser
+}
+
+func (h *Handler) CreateUser(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID:   "create-user",
+			Method:        http.MethodPost,
+			Path:          "/users",
+			Summary:       "Создать пользователя",
+			Description:   "Создаёт пользователя админки с паролем и ролью. Доступно владельцам.",
+			Tags:          []string{"Admin", "Users"},
+			DefaultStatus: http.StatusCreated,
+			Metadata:      requires(model.RoleOwner),
+		},
+		func(ctx context.Context, req *createUserInput) (*userOutput, error) {
+			u, err := h.service.CreateUser(ctx, req.Body.Login, req.Body.Password, req.Body.Role)
+			if err != nil {
+				return nil, problem.From(err, "не удалось создать пользователя", userErrors...)
+			}
+			return &userOutput{Body: u}, nil
+		},
+	)
+}
+
+// UpdateUser - изменение роли пользователя или его отключение.
+type updateUserInput struct {
+	ID   uuid.UUID `path:"id" format:"uuid" doc:"ID пользователя"`
+	Body struct {
+		Role     model.Role `json:"role" enum:"owner,editor,viewer" doc:"Роль"`
+		Disabled bool       `json:"disabled" required:"false" doc:"Отключённый пользователь не может войти"`
+	}
+}
+
+func (h *Handler) UpdateUser(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "update-user",
+			Method:      http.MethodPut,
+			Path:        "/users/{id}",
+			Summary:     "Изменить пользователя",
+			Description: "Меняет роль пользователя, отключает или включает его. Последнего владельца нельзя понизить или отключить. Доступно владельцам.",
+			Tags:        []string{"Admin", "Users"},
+			Metadata:    requires(model.RoleOwner),
+		},
+		func(ctx context.Context, req *updateUserInput) (*userOutput, error) {
+			u, err := h.service.UpdateUser(ctx, req.ID, req.Body.Role, req.Body.Disabled)
+			if err != nil {
+				return nil, problem.From(err, "не удалось изменить пользователя", userErrors...)
+			}
+			return &userOutput{Body: u}, nil
+		},
+	)
+}
+
+// SetUserPassword - смена пароля пользователя владельцем.
+type setUserPasswordInput struct {
+	ID   uuid.UUID `path:"id" format:"uuid" doc:"ID пользователя"`
+	Body struct {
+		Password string `json:"password" minLength:"8" maxLength:"72" doc:"Новый пароль"`
+	}
+}
+
+func (h *Handler) SetUserPassword(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "set-user-password",
+			Method:      http.MethodPut,
+			Path:        "/users/{id}/password",
+			Summary:     "Задать пароль пользователя",
+			Description: "Задаёт пользователю новый пароль, например забытый. Доступно владельцам.",
+			Tags:        []string{"Admin", "Users"},
+			Metadata:    requires(model.RoleOwner),
+		},
+		func(ctx context.Context, req *setUserPasswordInput) (*struct{}, error) {
+			if err := h.service.SetUserPassword(ctx, req.ID, req.Body.Password); err != nil {
+				return nil, problem.From(err, "не удалось задать пароль", userErrors...)
+			}
+			return nil, nil
+		},
+	)
+}
+
+// GetCurrentUser - текущий пользователь.
+func (h *Handler) GetCurrentUser(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "get-current-user",
+			Method:      http.MethodGet,
+			Path:        "/me",
+			Summary:     "Текущий пользователь",
+			Description: "Возвращает пользователя, от имени которого сделан запрос, с его ролью.",
+			Tags:        []string{"Admin", "Users"},
+			Metadata:    duringEnrollment(nil),
+		},
+		func(ctx context.Context, _ *struct{}) (*userOutput, error) {
+			u, err := h.service.CurrentUser(ctx)
+			if err != nil {
+				return nil, problem.From(err, "не удалось получить пользователя", userErrors...)
+			}
+			return &userOutput{Body: u}, nil
+		},
+	)
+}
+
+// ChangeOwnPassword - смена своего пароля.
+type changeOwnPasswordInput struct {
+	Body struct {
+		CurrentPassword string `json:"current_password" doc:"Текущий пароль"`
+		Password        string `json:"password" minLength:"8" maxLength:"72" doc:"Новый пароль"`
+	}
+}
+
+func (h *Handler) ChangeOwnPassword(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "change-own-password",
+			Method:      http.MethodPut,
+			Path:        "/me/password",
+			Summary:     "Сменить свой пароль",
+			Description: "Меняет пароль пользователя, от имени которого сделан запрос. Доступно всем ролям.",
+			Tags:        []string{"Admin", "Users"},
+			Metadata:    requires(model.RoleViewer),
+		},
+		func(ctx context.Context, req *changeOwnPasswordInput) (*struct{}, error) {
+			if err := h.service.ChangeOwnPassword(ctx, req.Body.CurrentPassword, req.Body.Password); err != nil {
+				return nil, withRetryAfter(err, problem.From(err, "не удалось сменить пароль", userErrors...))
+			}
+			return nil, nil
+		},
+	)
+}
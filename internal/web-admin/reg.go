@@ -14,14 +14,24 @@ import (
 func RegisterHandlers(
 	api huma.API,
 	news storage.Storage[model.News],
-	exhibitions *storage.ExhibitionStorage,
+	exhibitions storage.Exhibitions,
 	exhibits storage.Storage[model.Exhibit],
-	visits *storage.VisitStorage,
-	log *slog.Logger) {
-	stg := client.NewStorage(news, exhibitions, exhibits, visits, log.WithGroup("storage"))
-	srv := service.NewService(stg, log.WithGroup("service"))
+	visits storage.Visits,
+	trash client.Trash,
+	tx storage.Transactor,
+	revisions storage.Revisions,
+	assets storage.Assets,
+	media storage.MediaLibrary,
+	imports storage.ImportJobs,
+	log *slog.Logger,
+	opts ...service.Option) *service.Service {
+	stg := client.NewStorage(news, exhibitions, exhibits, visits, trash, tx, revisions, assets, media, imports, log.WithGroup("storage"))
+	srv := service.NewService(stg, log.WithGroup("service"), opts...)
 	h := handler.NewHandler(srv, log.WithGroup("handler"))
 
+	// Roles, before any operation
+	h.Authorize(api)
+
 	h.Ping(api)
 
 	// News
@@ -39,7 +49,75 @@ func RegisterHandlers(
 	h.CreateExhibit(api)
 	h.UpdateExhibit(api)
 	h.DeleteExhibit(api)
+	h.MoveExhibits(api)
 
 	// Stats
 	h.GetStats(api)
+
+	// Revisions
+	h.ListRevisions(api)
+	h.DiffRevisions(api)
+	h.RestoreRevision(api)
+
+	// Trash
+	h.ListTrash(api)
+	h.RestoreTrash(api)
+	h.PurgeTrash(api)
+	h.PurgeExpiredTrash(api)
+
+	// Media
+	h.UploadMedia(api)
+	h.ListMedia(api)
+	h.GetMedia(api)
+	h.InvalidateResolvedMedia(api)
+	h.StartImport(api)
+	h.ListImportJobs(api)
+	h.GetImportJob(api)
+	h.ListBrokenLinks(api)
+
+	// Media library
+	h.CreateMediaItem(api)
+	h.ListMediaItems(api)
+	h.GetMediaItem(api)
+	h.UpdateMediaItem(api)
+	h.DeleteMediaItem(api)
+	h.SetAttachedMedia(api)
+	h.GetAttachedMedia(api)
+
+	// Users
+	h.ListUsers(api)
+	h.CreateUser(api)
+	h.UpdateUser(api)
+	h.SetUserPassword(api)
+	h.GetCurrentUser(api)
+	h.ChangeOwnPassword(api)
+
+	// Sessions
+	h.Login(api)
+	h.Logout(api)
+	h.GetCurrentSession(api)
+	h.ListSessions(api)
+	h.RevokeSession(api)
+	h.ListUserSessions(api)
+	h.RevokeUserSessions(api)
+
+	// API tokens
+	h.ListTokens(api)
+	h.CreateToken(api)
+	h.RevokeToken(api)
+	h.ListUserTokens(api)
+	h.RevokeUserToken(api)
+
+	// Security log
+	h.ListLoginFailures(api)
+
+	// Two-factor authentication
+	h.GetTwoFactor(api)
+	h.BeginTwoFactor(api)
+	h.ConfirmTwoFactor(api)
+	h.DisableTwoFactor(api)
+	h.RegenerateRecoveryCodes(api)
+	h.ResetUserTwoFactor(api)
+
+	return srv
 }
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/mediaproxy"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrImportsDisabled is returned by StartImport when the service has no
+	// media source or no file store.
+	ErrImportsDisabled = storage.NewError(storage.ErrUnavailable, "imports_disabled", "media imports are disabled")
+	ErrNothingToImport = storage.NewError(storage.ErrInvalid, "nothing_to_import", "no external image URLs to import")
+)
+
+// MediaSource opens the files behind external media links.
+type MediaSource interface {
+	Open(ctx context.Context, rawURL string) (*mediaproxy.File, error)
+}
+
+// WithImports enables importing external images from src. Imports also
+// need the file store, see WithFiles.
+func WithImports(src MediaSource) Option {
+	return func(s *Service) {
+		s.imports = src
+	}
+}
+
+// --- Imports ---
+
+// StartImport creates a job importing the external image URLs of a news
+// item or an exhibit into the local media storage. RunImports carries it
+// out in the background.
+func (s *Service) StartImport(ctx context.Context, owner model.EntityType, id uuid.UUID) (model.ImportJob, error) {
+	if s.imports == nil || s.files == nil {
+		return model.ImportJob{}, ErrImportsDisabled
+	}
+	urls, err := s.ownerImageURLs(ctx, owner, id)
+	if err != nil {
+		return model.ImportJob{}, err
+	}
+
+	var items []model.ImportItem
+	for _, u := range urls {
+		if !external(u) || slices.ContainsFunc(items, func(it model.ImportItem) bool { return it.SourceURL == u }) {
+			continue
+		}
+		items = append(items, model.ImportItem{SourceURL: u, Status: model.ImportPending})
+	}
+	if len(items) == 0 {
+		return model.ImportJob{}, ErrNothingToImport
+	}
+
+	j, err := s.storage.CreateImportJob(ctx, model.ImportJob{
+		OwnerType: owner,
+		OwnerID:   id,
+		Status:    model.ImportPending,
+		Items:     items,
+		Author:    author(ctx),
+	})
+	if err != nil {
+		return model.ImportJob{}, err
+	}
+	s.wakeImports()
+	return j, nil
+}
+
+func (s *Service) GetImportJob(ctx context.Context, id uuid.UUID) (model.ImportJob, error) {
+	return s.storage.GetImportJob(ctx, id)
+}
+
+// ListImportJobs returns the import jobs of a news item or an exhibit,
+// newest first.
+func (s *Service) ListImportJobs(ctx context.Context, owner model.EntityType, id uuid.UUID) ([]model.ImportJob, error) {
+	if err := s.mediaOwnerExists(ctx, owner, id); err != nil {
+		return nil, err
+	}
+	return s.storage.ImportJobsByOwner(ctx, owner, id)
+}
+
+// wakeImports tells RunImports to pick up newly pending jobs.
+func (s *Service) wakeImports() {
+	select {
+	case s.importsWake <- struct{}{}:
+	default:
+		// A wake-up is already pending.
+	}
+}
+
+// RunImports carries out pending import jobs one at a time until ctx is
+// done. It resumes the jobs interrupted by a previous run first, then the
+// ones started while it runs. If the jobs cannot be listed, it tries again
+// every interval, or with the next job if interval is zero.
+func (s *Service) RunImports(ctx context.Context, interval time.Duration) error {
+	if s.imports == nil || s.files == nil {
+		s.log.Info("media import job disabled")
+		return nil
+	}
+
+	var retry <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		retry = ticker.C
+	}
+
+	for {
+		jobs, err := s.storage.PendingImportJobs(ctx)
+		if err == nil {
+			for _, j := range jobs {
+				if ctx.Err() != nil {
+					return nil
+				}
+				s.runImport(ctx, j)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.importsWake:
+		case <-retry:
+		}
+	}
+}
+
+// runImport downloads the pending items of j, then points the image URLs
+// of the owner to the imported copies. Progress is saved after every item,
+// so an interrupted job resumes where it stopped.
+func (s *Service) runImport(ctx context.Context, j model.ImportJob) {
+	log := s.log.With(slog.String("job", j.ID.String()))
+	// Assets and revisions are recorded in the name of whoever started the job.
+	ctx = context.WithValue(ctx, model.CtxKeyAdmin, j.Author)
+
+	j.Status = model.ImportRunning
+	if err := s.storage.UpdateImportJob(ctx, j); err != nil {
+		return
+	}
+
+	for i := range j.Items {
+		it := &j.Items[i]
+		if it.Status != model.ImportPending {
+			continue
+		}
+		a, err := s.importFile(ctx, it.SourceURL)
+		switch {
+		case ctx.Err() != nil:
+			// Interrupted by shutdown: the item stays pending.
+			return
+		case errors.Is(err, mediaproxy.ErrNotProxyable):
+			it.Status, it.Error = model.ImportSkipped, errorCode(err)
+		case err != nil:
+			log.Warn("failed to import media", slog.String("url", it.SourceURL), slog.String("error", err.Error()))
+			it.Status, it.Error = model.ImportFailed, errorCode(err)
+		default:
+			it.Status, it.URL = model.ImportDone, a.URL
+		}
+		if err := s.storage.UpdateImportJob(ctx, j); err != nil {
+			return
+		}
+	}
+
+	imported := map[string]string{}
+	for _, it := range j.Items {
+		if it.Status == model.ImportDone {
+			imported[it.SourceURL] = it.URL
+		}
+	}
+	if err := s.replaceImageURLs(ctx, j.OwnerType, j.OwnerID, imported); err != nil {
+		log.Error("failed to replace imported image URLs", slog.String("error", err.Error()))
+		j.Status, j.Error = model.ImportFailed, errorCode(err)
+	} else {
+		j.Status = model.ImportDone
+	}
+	finished := time.Now()
+	j.FinishedAt = &finished
+	if err := s.storage.UpdateImportJob(ctx, j); err == nil {
+		log.Info("finished media import", slog.String("status", string(j.Status)), slog.Int("imported", len(imported)))
+	}
+}
+
+// importFile downloads the file behind rawURL into the media storage.
+func (s *Service) importFile(ctx context.Context, rawURL string) (model.Asset, error) {
+	f, err := s.imports.Open(ctx, rawURL)
+	if err != nil {
+		return model.Asset{}, err
+	}
+	defer f.Close()
+
+	a, _, err := s.UploadMedia(ctx, importName(rawURL), f)
+	return a, err
+}
+
+// ownerImageURLs returns the image URLs of a news item or an exhibit.
+func (s *Service) ownerImageURLs(ctx context.Context, owner model.EntityType, id uuid.UUID) ([]string, error) {
+	switch owner {
+	case model.EntityNews:
+		n, err := s.storage.GetNews(ctx, id)
+		return n.ImageURLs, err
+	case model.EntityExhibit:
+		e, err := s.storage.GetExhibit(ctx, id)
+		return e.ImageURLs, err
+	default:
+		return nil, fmt.Errorf("%s has no images: %w", owner, storage.ErrInvalid)
+	}
+}
+
+// replaceImageURLs replaces the image URLs of a news item or an exhibit
+// found in urls with their values, recording the change as a revision.
+// URLs removed from the owner since the import started stay removed.
+func (s *Service) replaceImageURLs(ctx context.Context, owner model.EntityType, id uuid.UUID, urls map[string]string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	current, err := s.ownerImageURLs(ctx, owner, id)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(current, func(u string) bool { _, ok := urls[u]; return ok }) {
+		return nil
+	}
+	replace := func(imageURLs []string) ([]string, bool) {
+		out := slices.Clone(imageURLs)
+		changed := false
+		for i, u := range out {
+			if local, ok := urls[u]; ok {
+				out[i], changed = local, true
+			}
+		}
+		return out, changed
+	}
+
+	switch owner {
+	case model.EntityNews:
+		_, err = updated(ctx, s, s.newsEntity(), id, func(ctx context.Context) (model.News, error) {
+			n, err := s.storage.GetNews(ctx, id)
+			if err != nil {
+				return model.News{}, err
+			}
+			var changed bool
+			if n.ImageURLs, changed = replace(n.ImageURLs); !changed {
+				return n, nil
+			}
+			return s.storage.ReplaceNews(ctx, n)
+		})
+	case model.EntityExhibit:
+		_, err = updated(ctx, s, s.exhibitEntity(), id, func(ctx context.Context) (model.Exhibit, error) {
+			e, err := s.storage.GetExhibit(ctx, id)
+			if err != nil {
+				return model.Exhibit{}, err
+			}
+			var changed bool
+			if e.ImageURLs, changed = replace(e.ImageURLs); !changed {
+				return e, nil
+			}
+			return s.storage.ReplaceExhibit(ctx, e)
+		})
+	default:
+		err = fmt.Errorf("%s has no images: %w", owner, storage.ErrInvalid)
+	}
+	return err
+}
+
+// external reports whether u is an image URL on another site.
+func external(u string) bool {
+	return strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://")
+}
+
+// importName returns the name an imported file is recorded under: the last
+// element of its URL path, or the host for URLs without a path.
+func importName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if name := path.Base(u.Path); name != "." && name != "/" {
+		return name
+	}
+	return u.Hostname()
+}
+
+// errorCode returns the machine-readable code of err, as in API errors.
+func errorCode(err error) string {
+	var se *storage.Error
+	if errors.As(err, &se) {
+		return se.Code
+	}
+	if kind := storage.Classify(err); kind != nil {
+		return strings.ToLower(strings.ReplaceAll(kind.Error(), " ", "_"))
+	}
+	return "internal"
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// Errors of SetAttachedMedia.
+var (
+	ErrDuplicateMedia = storage.NewError(storage.ErrInvalid, "duplicate_media", "media item is listed twice")
+	ErrUnknownMedia   = storage.NewError(storage.ErrInvalid, "media_not_found", "media item does not exist")
+)
+
+// --- Media library ---
+
+func (s *Service) CreateMediaItem(ctx context.Context, m model.Media) (model.Media, error) {
+	return s.storage.CreateMediaItem(ctx, m)
+}
+
+func (s *Service) GetMediaItem(ctx context.Context, id uuid.UUID) (model.Media, error) {
+	return s.storage.GetMediaItem(ctx, id)
+}
+
+func (s *Service) UpdateMediaItem(ctx context.Context, m model.Media) (model.Media, error) {
+	return s.storage.UpdateMediaItem(ctx, m)
+}
+
+func (s *Service) DeleteMediaItem(ctx context.Context, id uuid.UUID) error {
+	return s.storage.DeleteMediaItem(ctx, id)
+}
+
+func (s *Service) ListMediaItems(ctx context.Context, opts storage.ListOptions) ([]model.Media, int, error) {
+	return s.storage.ListMediaItems(ctx, opts)
+}
+
+// SetAttachedMedia replaces the media attached to a news item or an exhibit
+// with the items ids, in display order, and returns the new attachments.
+func (s *Service) SetAttachedMedia(ctx context.Context, owner model.EntityType, id uuid.UUID, ids []uuid.UUID) ([]model.AttachedMedia, error) {
+	for i, m := range ids {
+		if slices.Contains(ids[:i], m) {
+			return nil, fmt.Errorf("%s: %w", m, ErrDuplicateMedia)
+		}
+	}
+
+	var attached []model.AttachedMedia
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		if err := s.mediaOwnerExists(ctx, owner, id); err != nil {
+			return err
+		}
+		if err := s.storage.AttachMedia(ctx, owner, id, ids); err != nil {
+			// The owner exists, so the storage can only reject the items.
+			if storage.Classify(err) == storage.ErrInvalid {
+				return fmt.Errorf("%w: %w", ErrUnknownMedia, err)
+			}
+			return err
+		}
+		var err error
+		attached, err = s.storage.AttachedMedia(ctx, owner, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attached, nil
+}
+
+// GetAttachedMedia returns the media attached to a news item or an exhibit, in display order.
+func (s *Service) GetAttachedMedia(ctx context.Context, owner model.EntityType, id uuid.UUID) ([]model.AttachedMedia, error) {
+	if err := s.mediaOwnerExists(ctx, owner, id); err != nil {
+		return nil, err
+	}
+	return s.storage.AttachedMedia(ctx, owner, id)
+}
+
+// mediaOwnerExists returns storage.ErrNotFound unless the news item or
+// exhibit exists and is not in the trash.
+func (s *Service) mediaOwnerExists(ctx context.Context, owner model.EntityType, id uuid.UUID) error {
+	var err error
+	switch owner {
+	case model.EntityNews:
+		_, err = s.storage.GetNews(ctx, id)
+	case model.EntityExhibit:
+		_, err = s.storage.GetExhibit(ctx, id)
+	default:
+		err = fmt.Errorf("%s has no media: %w", owner, storage.ErrInvalid)
+	}
+	return err
+}
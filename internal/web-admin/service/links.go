@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+)
+
+// ErrLinkChecksDisabled is returned by ListBrokenLinks when the service has
+// no link checker.
+var ErrLinkChecksDisabled = storage.NewError(storage.ErrUnavailable, "link_checks_disabled", "link checks are disabled")
+
+// LinkChecker reports the broken image links found by the link check job.
+type LinkChecker interface {
+	Broken(ctx context.Context, statuses ...model.LinkStatus) ([]model.BrokenLinks, error)
+}
+
+// WithLinkChecks lets the admin list the broken image links.
+func WithLinkChecks(c LinkChecker) Option {
+	return func(s *Service) {
+		s.links = c
+	}
+}
+
+// --- Link checks ---
+
+// ListBrokenLinks returns the image links with one of statuses, the broken
+// ones by default, grouped by news item and exhibit.
+func (s *Service) ListBrokenLinks(ctx context.Context, statuses []model.LinkStatus) ([]model.BrokenLinks, error) {
+	if s.links == nil {
+		return nil, ErrLinkChecksDisabled
+	}
+	return s.links.Broken(ctx, statuses...)
+}
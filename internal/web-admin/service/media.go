@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/media"
+)
+
+// ErrUploadsDisabled is returned by UploadMedia when the service has no file store.
+var ErrUploadsDisabled = storage.NewError(storage.ErrUnavailable, "uploads_disabled", "media uploads are disabled")
+
+// Files keeps the content of uploaded media.
+type Files interface {
+	Save(r io.Reader) (media.File, error)
+	Variants(f media.File) (media.Resized, error)
+}
+
+// WithFiles enables media uploads into f.
+func WithFiles(f Files) Option {
+	return func(s *Service) {
+		s.files = f
+	}
+}
+
+// --- Media ---
+
+// UploadMedia stores an uploaded file and records it as an asset under the
+// given original file name. Uploading content that is already stored returns
+// the existing asset with created = false.
+func (s *Service) UploadMedia(ctx context.Context, name string, r io.Reader) (a model.Asset, created bool, err error) {
+	if s.files == nil {
+		return model.Asset{}, false, ErrUploadsDisabled
+	}
+	f, err := s.files.Save(r)
+	if err != nil {
+		if storage.Classify(err) == nil {
+			s.log.Error("failed to store uploaded file", slog.String("name", name), slog.String("error", err.Error()))
+		}
+		return model.Asset{}, false, err
+	}
+	a = model.Asset{
+		Hash:     f.Hash,
+		URL:      f.URL(),
+		MIMEType: f.MIMEType,
+		Size:     f.Size,
+		Name:     name,
+	}
+	if f.Meta != nil {
+		a.Width, a.Height = f.Meta.Width, f.Meta.Height
+		a.Metadata = &model.MediaMetadata{
+			Width:       f.Meta.Width,
+			Height:      f.Meta.Height,
+			Orientation: f.Meta.Orientation,
+			Rotated:     f.Meta.Rotated,
+			Removed:     append([]string{}, f.Meta.Removed...),
+		}
+		if !f.Meta.CapturedAt.IsZero() {
+			a.Metadata.CapturedAt = &f.Meta.CapturedAt
+		}
+	}
+	a, created, err = s.storage.SaveAsset(ctx, a)
+	if err == nil && created {
+		s.wakeVariants()
+	}
+	return a, created, err
+}
+
+func (s *Service) GetAsset(ctx context.Context, hash string) (model.Asset, error) {
+	return s.storage.GetAsset(ctx, hash)
+}
+
+func (s *Service) ListAssets(ctx context.Context, limit, offset int) ([]model.Asset, int, error) {
+	return s.storage.ListAssets(ctx, limit, offset)
+}
+
+// wakeVariants tells RunVariants to pick up newly pending assets.
+func (s *Service) wakeVariants() {
+	select {
+	case s.variantsWake <- struct{}{}:
+	default:
+		// A wake-up is already pending.
+	}
+}
+
+// RunVariants generates the image variants of pending assets with the given
+// number of workers until ctx is done. It processes the assets left pending
+// by a previous run first, then the ones uploaded while it runs.
+// Assets whose variants cannot be generated because of the file itself are
+// recorded without variants; the others stay pending and are retried every
+// interval, or with the next upload if interval is zero.
+func (s *Service) RunVariants(ctx context.Context, workers int, interval time.Duration) error {
+	if s.files == nil || workers <= 0 {
+		s.log.Info("image variants job disabled")
+		return nil
+	}
+
+	var retry <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		retry = ticker.C
+	}
+
+	for {
+		pending, err := s.storage.PendingAssets(ctx)
+		if err == nil {
+			s.generateVariants(ctx, pending, workers)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.variantsWake:
+		case <-retry:
+		}
+	}
+}
+
+// generateVariants processes assets with a pool of workers and waits for it.
+func (s *Service) generateVariants(ctx context.Context, assets []model.Asset, workers int) {
+	queue := make(chan model.Asset)
+	var wg sync.WaitGroup
+	for range min(workers, len(assets)) {
+		wg.Go(func() {
+			for a := range queue {
+				s.generateAssetVariants(ctx, a)
+			}
+		})
+	}
+	defer wg.Wait()
+	defer close(queue)
+
+	for _, a := range assets {
+		select {
+		case <-ctx.Done():
+			return
+		case queue <- a:
+		}
+	}
+}
+
+func (s *Service) generateAssetVariants(ctx context.Context, a model.Asset) {
+	f := media.File{
+		Hash:     a.Hash,
+		Path:     strings.TrimPrefix(a.URL, media.URLPrefix),
+		MIMEType: a.MIMEType,
+		Size:     a.Size,
+	}
+	if a.Metadata != nil {
+		f.Meta = &media.Metadata{Orientation: a.Metadata.Orientation, Rotated: a.Metadata.Rotated}
+	}
+	res, err := s.files.Variants(f)
+	if err != nil {
+		s.log.Error("failed to generate image variants", slog.String("hash", a.Hash), slog.String("error", err.Error()))
+		if storage.Classify(err) != storage.ErrInvalid {
+			return
+		}
+	}
+
+	variants := make([]model.ImageVariant, 0, len(res.Variants))
+	for _, v := range res.Variants {
+		variants = append(variants, model.ImageVariant{
+			Name:   v.Name,
+			URL:    v.URL(),
+			Width:  v.Width,
+			Height: v.Height,
+		})
+	}
+	if err := s.storage.SetAssetVariants(ctx, a.Hash, res.Width, res.Height, variants); err == nil && len(variants) > 0 {
+		s.log.Info("generated image variants", slog.String("hash", a.Hash), slog.Int("count", len(variants)))
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+
+	"github.com/WhiCu/school-museum/internal/resolver"
+)
+
+// ResolvedMedia is the cache of resolved external media links.
+type ResolvedMedia interface {
+	Invalidate(ctx context.Context, rawURL string) (resolver.Purged, error)
+}
+
+// WithResolvedMedia lets the admin invalidate the cache of resolved links.
+func WithResolvedMedia(c ResolvedMedia) Option {
+	return func(s *Service) {
+		s.resolved = c
+	}
+}
+
+// --- Resolved media ---
+
+// InvalidateResolvedMedia forgets what rawURL resolved to, or every link
+// if rawURL is empty, so that they are resolved again on the next view.
+func (s *Service) InvalidateResolvedMedia(ctx context.Context, rawURL string) (resolver.Purged, error) {
+	if s.resolved == nil {
+		return resolver.Purged{}, nil
+	}
+	return s.resolved.Invalidate(ctx, rawURL)
+}
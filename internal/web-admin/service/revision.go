@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// --- Revisions ---
+
+// Every change made through the service is recorded as a revision in the
+// same unit of work as the change itself, so history never diverges from
+// the content.
+
+// entity describes a content type to the generic revision helpers.
+type entity[T any] struct {
+	typ     model.EntityType
+	id      func(T) uuid.UUID
+	get     func(ctx context.Context, id uuid.UUID) (T, error)
+	replace func(ctx context.Context, v T) (T, error)
+	// prepare validates a snapshot before it is restored and fits it to the
+	// content around it as it is now.
+	prepare func(ctx context.Context, v T) (T, error)
+}
+
+func (s *Service) newsEntity() entity[model.News] {
+	return entity[model.News]{
+		typ:     model.EntityNews,
+		id:      func(n model.News) uuid.UUID { return n.ID },
+		get:     s.storage.GetNews,
+		replace: s.storage.ReplaceNews,
+	}
+}
+
+func (s *Service) exhibitionEntity() entity[model.Exhibition] {
+	return entity[model.Exhibition]{
+		typ:     model.EntityExhibition,
+		id:      func(ex model.Exhibition) uuid.UUID { return ex.ID },
+		get:     s.storage.GetExhibition,
+		replace: s.storage.ReplaceExhibition,
+		prepare: s.fitPreview,
+	}
+}
+
+// fitPreview clears the preview of an exhibition snapshot if the exhibit is
+// no longer a live exhibit of the exhibition.
+func (s *Service) fitPreview(ctx context.Context, ex model.Exhibition) (model.Exhibition, error) {
+	if ex.PreviewExhibitID == nil {
+		return ex, nil
+	}
+	e, err := s.storage.GetExhibit(ctx, *ex.PreviewExhibitID)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		ex.PreviewExhibitID = nil
+	case err != nil:
+		return model.Exhibition{}, err
+	case e.ExhibitionID != ex.ID:
+		ex.PreviewExhibitID = nil
+	}
+	return ex, nil
+}
+
+func (s *Service) exhibitEntity() entity[model.Exhibit] {
+	return entity[model.Exhibit]{
+		typ:     model.EntityExhibit,
+		id:      func(e model.Exhibit) uuid.UUID { return e.ID },
+		get:     s.storage.GetExhibit,
+		replace: s.storage.ReplaceExhibit,
+		prepare: func(ctx context.Context, e model.Exhibit) (model.Exhibit, error) {
+			return e, s.exhibitionExists(ctx, e.ExhibitionID, ErrExhibitionDeleted)
+		},
+	}
+}
+
+// created runs create and records the new entity as its first revision.
+func created[T any](ctx context.Context, s *Service, e entity[T], create func(ctx context.Context) (T, error)) (out T, err error) {
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		v, err := create(ctx)
+		if err != nil {
+			return err
+		}
+		if out, err = e.get(ctx, e.id(v)); err != nil {
+			return err
+		}
+		_, err = s.record(ctx, e.typ, e.id(out), model.RevisionCreate, nil, out)
+		return err
+	})
+	return out, err
+}
+
+// updated runs update and records the changes it made to entity id.
+func updated[T any](ctx context.Context, s *Service, e entity[T], id uuid.UUID, update func(ctx context.Context) (T, error)) (out T, err error) {
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		before, err := e.get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if out, err = update(ctx); err != nil {
+			return err
+		}
+		after, err := e.get(ctx, id)
+		if err != nil {
+			return err
+		}
+		_, err = s.record(ctx, e.typ, id, model.RevisionUpdate, before, after)
+		return err
+	})
+	return out, err
+}
+
+// deleted runs del and records the last state of entity id. A missing
+// entity is a storage.ErrNotFound.
+func deleted[T any](ctx context.Context, s *Service, e entity[T], id uuid.UUID, del func(ctx context.Context) error) error {
+	return s.storage.InTx(ctx, func(ctx context.Context) error {
+		before, err := e.get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := del(ctx); err != nil {
+			return err
+		}
+		_, err = s.record(ctx, e.typ, id, model.RevisionDelete, before, nil)
+		return err
+	})
+}
+
+// rollback overwrites entity id with the snapshot of revision rev, restoring
+// it from the trash first if needed, and records that as a new revision.
+func rollback[T any](ctx context.Context, s *Service, e entity[T], id uuid.UUID, rev int) (out model.Revision, err error) {
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		r, err := s.storage.GetRevision(ctx, e.typ, id, rev)
+		if err != nil {
+			return err
+		}
+		var target T
+		if err := json.Unmarshal(r.Snapshot, &target); err != nil {
+			return fmt.Errorf("revision %d of %s %s: %w", rev, e.typ, id, err)
+		}
+		if e.prepare != nil {
+			if target, err = e.prepare(ctx, target); err != nil {
+				return err
+			}
+		}
+
+		before, err := e.get(ctx, id)
+		if errors.Is(err, storage.ErrNotFound) {
+			if _, err := s.restore(ctx, e.typ, id, false); err != nil {
+				return err
+			}
+			before, err = e.get(ctx, id)
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := e.replace(ctx, target); err != nil {
+			return err
+		}
+		after, err := e.get(ctx, id)
+		if err != nil {
+			return err
+		}
+		out, err = s.record(ctx, e.typ, id, model.RevisionRollback, before, after)
+		return err
+	})
+	return out, err
+}
+
+// record appends a revision of entity id. after is the snapshot stored for
+// creates, updates, restores and rollbacks; deletes store before instead.
+func (s *Service) record(ctx context.Context, t model.EntityType, id uuid.UUID, action model.RevisionAction, before, after any) (model.Revision, error) {
+	prev, err := snapshot(before)
+	if err != nil {
+		return model.Revision{}, err
+	}
+	next, err := snapshot(after)
+	if err != nil {
+		return model.Revision{}, err
+	}
+
+	r := model.Revision{
+		EntityType: t,
+		EntityID:   id,
+		Action:     action,
+		Author:     author(ctx),
+		Snapshot:   next,
+		Changes:    []model.FieldChange{},
+	}
+	switch action {
+	case model.RevisionDelete:
+		r.Snapshot = prev
+	case model.RevisionCreate, model.RevisionUpdate, model.RevisionRollback:
+		if r.Changes, err = model.Diff(prev, next); err != nil {
+			return model.Revision{}, err
+		}
+	}
+	return s.storage.AppendRevision(ctx, r)
+}
+
+// snapshot encodes an entity for a revision. Exhibits of an exhibition have
+// their own history and are left out.
+func snapshot(v any) (json.RawMessage, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case model.Exhibition:
+		v.Exhibits = nil
+		return json.Marshal(v)
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// author returns the login of the admin making the request.
+func author(ctx context.Context) string {
+	login, _ := ctx.Value(model.CtxKeyAdmin).(string)
+	return login
+}
+
+func (s *Service) ListRevisions(ctx context.Context, t model.EntityType, id uuid.UUID) ([]model.Revision, error) {
+	return s.storage.ListRevisions(ctx, t, id)
+}
+
+// DiffRevisions returns the fields that differ between two revisions of an entity.
+func (s *Service) DiffRevisions(ctx context.Context, t model.EntityType, id uuid.UUID, from, to int) ([]model.FieldChange, error) {
+	a, err := s.storage.GetRevision(ctx, t, id, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.storage.GetRevision(ctx, t, id, to)
+	if err != nil {
+		return nil, err
+	}
+	return model.Diff(a.Snapshot, b.Snapshot)
+}
+
+// RollbackRevision restores entity id to the state saved in revision rev.
+// It returns the new revision recording the rollback.
+func (s *Service) RollbackRevision(ctx context.Context, t model.EntityType, id uuid.UUID, rev int) (model.Revision, error) {
+	switch t {
+	case model.EntityNews:
+		return rollback(ctx, s, s.newsEntity(), id, rev)
+	case model.EntityExhibition:
+		return rollback(ctx, s, s.exhibitionEntity(), id, rev)
+	case model.EntityExhibit:
+		return rollback(ctx, s, s.exhibitEntity(), id, rev)
+	default:
+		return model.Revision{}, fmt.Errorf("unknown entity type %q: %w", t, storage.ErrInvalid)
+	}
+}
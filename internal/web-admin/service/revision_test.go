@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/db/storage/memory"
+	"github.com/WhiCu/school-museum/internal/web-admin/client"
+	"github.com/google/uuid"
+)
+
+// newService returns a service over in-memory storages.
+func newService(t *testing.T) *Service {
+	t.Helper()
+	db := memory.NewDB()
+	news := memory.NewNewsStorage(db)
+	exhibitions := memory.NewExhibitionStorage(db)
+	exhibits := memory.NewExhibitStorage(db)
+	log := slog.New(slog.DiscardHandler)
+	stg := client.NewStorage(news, exhibitions, exhibits, memory.NewVisitStorage(db),
+		client.Trash{News: news, Exhibitions: exhibitions, Exhibits: exhibits}, db,
+		memory.NewRevisionStorage(db), memory.NewAssetStorage(db), memory.NewMediaStorage(db),
+		memory.NewImportJobStorage(db), log)
+	return NewService(stg, log)
+}
+
+// actions returns the actions of the revisions of entity id, oldest first.
+func actions(t *testing.T, s *Service, typ model.EntityType, id uuid.UUID) []model.RevisionAction {
+	t.Helper()
+	revs, err := s.ListRevisions(context.Background(), typ, id)
+	if err != nil {
+		t.Fatalf("ListRevisions: %v", err)
+	}
+	out := make([]model.RevisionAction, len(revs))
+	for i, r := range revs {
+		out[i] = r.Action
+	}
+	return out
+}
+
+func TestDeleteMissing(t *testing.T) {
+	s := newService(t)
+	ctx := context.Background()
+
+	n, err := s.CreateNews(ctx, model.News{Title: "Открытие"})
+	if err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	if err := s.DeleteNews(ctx, n.ID); err != nil {
+		t.Fatalf("DeleteNews: %v", err)
+	}
+	// A deleted entity is as missing as one that never was.
+	for _, id := range []uuid.UUID{n.ID, uuid.New()} {
+		if err := s.DeleteNews(ctx, id); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("DeleteNews(%s): %v, want ErrNotFound", id, err)
+		}
+		if err := s.DeleteExhibition(ctx, id); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("DeleteExhibition(%s): %v, want ErrNotFound", id, err)
+		}
+		if err := s.DeleteExhibit(ctx, id); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("DeleteExhibit(%s): %v, want ErrNotFound", id, err)
+		}
+	}
+	if got := actions(t, s, model.EntityNews, n.ID); len(got) != 2 || got[1] != model.RevisionDelete {
+		t.Errorf("revisions = %v, want create and one delete", got)
+	}
+}
+
+// lastRev returns the number of the last revision of entity id.
+func lastRev(t *testing.T, s *Service, typ model.EntityType, id uuid.UUID) int {
+	t.Helper()
+	revs, err := s.ListRevisions(context.Background(), typ, id)
+	if err != nil || len(revs) == 0 {
+		t.Fatalf("ListRevisions = %v, %v", revs, err)
+	}
+	return revs[len(revs)-1].Rev
+}
+
+func TestRollbackPreview(t *testing.T) {
+	s := newService(t)
+	ctx := context.Background()
+	hall, err := s.CreateExhibition(ctx, model.Exhibition{Title: "Зал славы"})
+	if err != nil {
+		t.Fatalf("CreateExhibition: %v", err)
+	}
+	other, err := s.CreateExhibition(ctx, model.Exhibition{Title: "Запасник"})
+	if err != nil {
+		t.Fatalf("CreateExhibition: %v", err)
+	}
+	exhibit := func(title string) model.Exhibit {
+		t.Helper()
+		e, err := s.CreateExhibit(ctx, model.Exhibit{ExhibitionID: hall.ID, Title: title})
+		if err != nil {
+			t.Fatalf("CreateExhibit: %v", err)
+		}
+		return e
+	}
+	// withPreview returns the revision of hall with e as its preview, and
+	// clears the preview after it.
+	withPreview := func(e model.Exhibit) int {
+		t.Helper()
+		if _, err := s.SetExhibitionPreview(ctx, hall.ID, &e.ID); err != nil {
+			t.Fatalf("SetExhibitionPreview: %v", err)
+		}
+		rev := lastRev(t, s, model.EntityExhibition, hall.ID)
+		if _, err := s.SetExhibitionPreview(ctx, hall.ID, nil); err != nil {
+			t.Fatalf("SetExhibitionPreview: %v", err)
+		}
+		return rev
+	}
+	rollback := func(rev int) *uuid.UUID {
+		t.Helper()
+		if _, err := s.RollbackRevision(ctx, model.EntityExhibition, hall.ID, rev); err != nil {
+			t.Fatalf("RollbackRevision(%d): %v", rev, err)
+		}
+		ex, err := s.storage.GetExhibition(ctx, hall.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ex.PreviewExhibitID
+	}
+
+	kept, deleted, moved := exhibit("Знамя"), exhibit("Каска"), exhibit("Письмо")
+	keptRev, deletedRev, movedRev := withPreview(kept), withPreview(deleted), withPreview(moved)
+	if err := s.DeleteExhibit(ctx, deleted.ID); err != nil {
+		t.Fatalf("DeleteExhibit: %v", err)
+	}
+	if _, err := s.MoveExhibits(ctx, []uuid.UUID{moved.ID}, other.ID); err != nil {
+		t.Fatalf("MoveExhibits: %v", err)
+	}
+
+	if got := rollback(keptRev); got == nil || *got != kept.ID {
+		t.Errorf("preview after a rollback = %v, want the exhibit %s", got, kept.ID)
+	}
+	if got := rollback(deletedRev); got != nil {
+		t.Errorf("preview of a deleted exhibit after a rollback = %v, want none", *got)
+	}
+	if got := rollback(movedRev); got != nil {
+		t.Errorf("preview of a moved exhibit after a rollback = %v, want none", *got)
+	}
+}
+
+func TestDeleteExhibitionRevisions(t *testing.T) {
+	s := newService(t)
+	ctx := context.Background()
+	hall, err := s.CreateExhibition(ctx, model.Exhibition{Title: "Зал славы"})
+	if err != nil {
+		t.Fatalf("CreateExhibition: %v", err)
+	}
+	var exhibits []model.Exhibit
+	for _, title := range []string{"Знамя", "Каска", "Письмо"} {
+		e, err := s.CreateExhibit(ctx, model.Exhibit{ExhibitionID: hall.ID, Title: title})
+		if err != nil {
+			t.Fatalf("CreateExhibit: %v", err)
+		}
+		exhibits = append(exhibits, e)
+	}
+	// Deleted before the exhibition, so neither deleted nor restored with it.
+	if err := s.DeleteExhibit(ctx, exhibits[2].ID); err != nil {
+		t.Fatalf("DeleteExhibit: %v", err)
+	}
+
+	if err := s.DeleteExhibition(ctx, hall.ID); err != nil {
+		t.Fatalf("DeleteExhibition: %v", err)
+	}
+	want := []model.RevisionAction{model.RevisionCreate, model.RevisionDelete}
+	for _, e := range exhibits {
+		if got := actions(t, s, model.EntityExhibit, e.ID); !slices.Equal(got, want) {
+			t.Errorf("revisions of %s after the exhibition was deleted = %v, want %v", e.Title, got, want)
+		}
+	}
+	revs, err := s.ListRevisions(ctx, model.EntityExhibit, exhibits[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last model.Exhibit
+	if err := json.Unmarshal(revs[len(revs)-1].Snapshot, &last); err != nil || last.Title != "Знамя" {
+		t.Errorf("snapshot of the delete = %s, %v, want the exhibit", revs[len(revs)-1].Snapshot, err)
+	}
+
+	if n, err := s.Restore(ctx, model.EntityExhibition, hall.ID, true); err != nil || n != 2 {
+		t.Fatalf("Restore = %d, %v, want 2 exhibits", n, err)
+	}
+	want = append(want, model.RevisionRestore)
+	for _, e := range exhibits[:2] {
+		if got := actions(t, s, model.EntityExhibit, e.ID); !slices.Equal(got, want) {
+			t.Errorf("revisions of %s after the exhibition was restored = %v, want %v", e.Title, got, want)
+		}
+	}
+	if got := actions(t, s, model.EntityExhibit, exhibits[2].ID); len(got) != 2 {
+		t.Errorf("revisions of the exhibit deleted alone = %v, want create and delete", got)
+	}
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+)
+
+// ErrLoginFailuresDisabled is returned by ListLoginFailures when the service
+// has no security log.
+var ErrLoginFailuresDisabled = storage.NewError(storage.ErrUnavailable, "login_failures_disabled", "the security log is disabled")
+
+// LoginFailures is the security log of the failed sign-ins, see
+// auth.Lockout.
+type LoginFailures interface {
+	List(ctx context.Context, login string, opts storage.ListOptions) ([]model.LoginFailure, int, error)
+}
+
+// WithLoginFailures lets the owners read the security log.
+func WithLoginFailures(l LoginFailures) Option {
+	return func(s *Service) {
+		s.loginFailures = l
+	}
+}
+
+// --- Security log ---
+
+// ListLoginFailures returns a page of the failed sign-ins to login, or to
+// any login if it is empty, the newest first by default.
+func (s *Service) ListLoginFailures(ctx context.Context, login string, opts storage.ListOptions) ([]model.LoginFailure, int, error) {
+	if s.loginFailures == nil {
+		return nil, 0, ErrLoginFailuresDisabled
+	}
+	return s.loginFailures.List(ctx, login, opts)
+}
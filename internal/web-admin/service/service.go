@@ -2,15 +2,20 @@ package service
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
 	"github.com/google/uuid"
 )
 
-var ErrExhibitionNotFound = errors.New("exhibition not found")
+var (
+	ErrExhibitionNotFound = storage.NewError(storage.ErrInvalid, "exhibition_not_found", "exhibition not found")
+	ErrExhibitionDeleted  = storage.NewError(storage.ErrConflict, "exhibition_deleted", "exhibition is in the trash")
+	ErrRetentionDisabled  = storage.NewError(storage.ErrConflict, "retention_disabled", "trash retention is disabled")
+)
 
 type Storage interface {
 	CreateNews(ctx context.Context, n model.News) (model.News, error)
@@ -20,75 +25,214 @@ type Storage interface {
 	CreateExhibition(ctx context.Context, ex model.Exhibition) (model.Exhibition, error)
 	UpdateExhibition(ctx context.Context, ex model.Exhibition) (model.Exhibition, error)
 	DeleteExhibition(ctx context.Context, id uuid.UUID) error
-	ExhibitionExists(ctx context.Context, id uuid.UUID) bool
+	ExhibitionExists(ctx context.Context, id uuid.UUID) (bool, error)
 	SetExhibitionPreview(ctx context.Context, exhibitionID uuid.UUID, exhibitID *uuid.UUID) (model.Exhibition, error)
 
 	CreateExhibit(ctx context.Context, e model.Exhibit) (model.Exhibit, error)
 	UpdateExhibit(ctx context.Context, e model.Exhibit) (model.Exhibit, error)
 	DeleteExhibit(ctx context.Context, id uuid.UUID) error
+	MoveExhibits(ctx context.Context, exhibitIDs []uuid.UUID, exhibitionID uuid.UUID) (model.Exhibition, error)
 
 	GetStats(ctx context.Context) (model.VisitStats, error)
+
+	ListTrash(ctx context.Context, types []model.EntityType) ([]model.TrashItem, error)
+	DeletedExhibit(ctx context.Context, id uuid.UUID) (model.Exhibit, error)
+	RestoreNews(ctx context.Context, id uuid.UUID) error
+	RestoreExhibition(ctx context.Context, id uuid.UUID, withExhibits bool) (int, error)
+	RestoreExhibit(ctx context.Context, id uuid.UUID) error
+	PurgeNews(ctx context.Context, id uuid.UUID) error
+	PurgeExhibition(ctx context.Context, id uuid.UUID) error
+	PurgeExhibit(ctx context.Context, id uuid.UUID) error
+	PurgeDeletedBefore(ctx context.Context, t time.Time) (model.PurgeResult, error)
+
+	InTx(ctx context.Context, f func(ctx context.Context) error) error
+	GetNews(ctx context.Context, id uuid.UUID) (model.News, error)
+	GetExhibition(ctx context.Context, id uuid.UUID) (model.Exhibition, error)
+	GetExhibit(ctx context.Context, id uuid.UUID) (model.Exhibit, error)
+	ReplaceNews(ctx context.Context, n model.News) (model.News, error)
+	ReplaceExhibition(ctx context.Context, ex model.Exhibition) (model.Exhibition, error)
+	ReplaceExhibit(ctx context.Context, e model.Exhibit) (model.Exhibit, error)
+	AppendRevision(ctx context.Context, r model.Revision) (model.Revision, error)
+	ListRevisions(ctx context.Context, t model.EntityType, id uuid.UUID) ([]model.Revision, error)
+	GetRevision(ctx context.Context, t model.EntityType, id uuid.UUID, rev int) (model.Revision, error)
+
+	SaveAsset(ctx context.Context, a model.Asset) (model.Asset, bool, error)
+	GetAsset(ctx context.Context, hash string) (model.Asset, error)
+	ListAssets(ctx context.Context, limit, offset int) ([]model.Asset, int, error)
+	PendingAssets(ctx context.Context) ([]model.Asset, error)
+	SetAssetVariants(ctx context.Context, hash string, width, height int, v []model.ImageVariant) error
+
+	CreateMediaItem(ctx context.Context, m model.Media) (model.Media, error)
+	GetMediaItem(ctx context.Context, id uuid.UUID) (model.Media, error)
+	UpdateMediaItem(ctx context.Context, m model.Media) (model.Media, error)
+	DeleteMediaItem(ctx context.Context, id uuid.UUID) error
+	ListMediaItems(ctx context.Context, opts storage.ListOptions) ([]model.Media, int, error)
+	AttachMedia(ctx context.Context, owner model.EntityType, ownerID uuid.UUID, ids []uuid.UUID) error
+	AttachedMedia(ctx context.Context, owner model.EntityType, ownerID uuid.UUID) ([]model.AttachedMedia, error)
+
+	CreateImportJob(ctx context.Context, j model.ImportJob) (model.ImportJob, error)
+	GetImportJob(ctx context.Context, id uuid.UUID) (model.ImportJob, error)
+	UpdateImportJob(ctx context.Context, j model.ImportJob) error
+	PendingImportJobs(ctx context.Context) ([]model.ImportJob, error)
+	ImportJobsByOwner(ctx context.Context, owner model.EntityType, ownerID uuid.UUID) ([]model.ImportJob, error)
 }
 
 type Service struct {
 	storage Storage
 	log     *slog.Logger
+
+	trashRetention time.Duration
+	files          Files
+	resolved       ResolvedMedia
+	imports        MediaSource
+	links          LinkChecker
+	users          Users
+	sessions       Sessions
+	tokens         Tokens
+	loginFailures  LoginFailures
+	twoFactor      TwoFactor
+	// variantsWake signals RunVariants that new assets are pending.
+	variantsWake chan struct{}
+	// importsWake signals RunImports that new jobs are pending.
+	importsWake chan struct{}
+}
+
+type Option func(*Service)
+
+// WithTrashRetention sets how long deleted content stays in the trash.
+// Zero keeps it forever.
+func WithTrashRetention(d time.Duration) Option {
+	return func(s *Service) {
+		s.trashRetention = d
+	}
 }
 
-func NewService(storage Storage, log *slog.Logger) *Service {
-	return &Service{
-		storage: storage,
-		log:     log,
+func NewService(storage Storage, log *slog.Logger, opts ...Option) *Service {
+	s := &Service{
+		storage:      storage,
+		log:          log,
+		variantsWake: make(chan struct{}, 1),
+		importsWake:  make(chan struct{}, 1),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // --- News ---
 
 func (s *Service) CreateNews(ctx context.Context, n model.News) (model.News, error) {
-	return s.storage.CreateNews(ctx, n)
+	return created(ctx, s, s.newsEntity(), func(ctx context.Context) (model.News, error) {
+		return s.storage.CreateNews(ctx, n)
+	})
 }
 
 func (s *Service) UpdateNews(ctx context.Context, n model.News) (model.News, error) {
-	return s.storage.UpdateNews(ctx, n)
+	return updated(ctx, s, s.newsEntity(), n.ID, func(ctx context.Context) (model.News, error) {
+		return s.storage.UpdateNews(ctx, n)
+	})
 }
 
 func (s *Service) DeleteNews(ctx context.Context, id uuid.UUID) error {
-	return s.storage.DeleteNews(ctx, id)
+	return deleted(ctx, s, s.newsEntity(), id, func(ctx context.Context) error {
+		return s.storage.DeleteNews(ctx, id)
+	})
 }
 
 // --- Exhibitions ---
 
 func (s *Service) CreateExhibition(ctx context.Context, ex model.Exhibition) (model.Exhibition, error) {
-	return s.storage.CreateExhibition(ctx, ex)
+	return created(ctx, s, s.exhibitionEntity(), func(ctx context.Context) (model.Exhibition, error) {
+		return s.storage.CreateExhibition(ctx, ex)
+	})
 }
 
 func (s *Service) UpdateExhibition(ctx context.Context, ex model.Exhibition) (model.Exhibition, error) {
-	return s.storage.UpdateExhibition(ctx, ex)
+	return updated(ctx, s, s.exhibitionEntity(), ex.ID, func(ctx context.Context) (model.Exhibition, error) {
+		return s.storage.UpdateExhibition(ctx, ex)
+	})
 }
 
+// DeleteExhibition moves an exhibition to the trash together with its
+// exhibits, recording a delete revision for each of them too.
 func (s *Service) DeleteExhibition(ctx context.Context, id uuid.UUID) error {
-	return s.storage.DeleteExhibition(ctx, id)
+	return deleted(ctx, s, s.exhibitionEntity(), id, func(ctx context.Context) error {
+		ex, err := s.storage.GetExhibition(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.storage.DeleteExhibition(ctx, id); err != nil {
+			return err
+		}
+		for _, e := range ex.Exhibits {
+			if _, err := s.record(ctx, model.EntityExhibit, e.ID, model.RevisionDelete, e, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (s *Service) SetExhibitionPreview(ctx context.Context, exhibitionID uuid.UUID, exhibitID *uuid.UUID) (model.Exhibition, error) {
-	return s.storage.SetExhibitionPreview(ctx, exhibitionID, exhibitID)
+	return updated(ctx, s, s.exhibitionEntity(), exhibitionID, func(ctx context.Context) (model.Exhibition, error) {
+		return s.storage.SetExhibitionPreview(ctx, exhibitionID, exhibitID)
+	})
 }
 
 // --- Exhibits ---
 
 func (s *Service) CreateExhibit(ctx context.Context, e model.Exhibit) (model.Exhibit, error) {
-	if !s.storage.ExhibitionExists(ctx, e.ExhibitionID) {
-		return model.Exhibit{}, fmt.Errorf("exhibition %s: %w", e.ExhibitionID, ErrExhibitionNotFound)
+	if err := s.exhibitionExists(ctx, e.ExhibitionID, ErrExhibitionNotFound); err != nil {
+		return model.Exhibit{}, err
 	}
-	return s.storage.CreateExhibit(ctx, e)
+	return created(ctx, s, s.exhibitEntity(), func(ctx context.Context) (model.Exhibit, error) {
+		return s.storage.CreateExhibit(ctx, e)
+	})
 }
 
 func (s *Service) UpdateExhibit(ctx context.Context, e model.Exhibit) (model.Exhibit, error) {
-	return s.storage.UpdateExhibit(ctx, e)
+	return updated(ctx, s, s.exhibitEntity(), e.ID, func(ctx context.Context) (model.Exhibit, error) {
+		return s.storage.UpdateExhibit(ctx, e)
+	})
 }
 
 func (s *Service) DeleteExhibit(ctx context.Context, id uuid.UUID) error {
-	return s.storage.DeleteExhibit(ctx, id)
+	return deleted(ctx, s, s.exhibitEntity(), id, func(ctx context.Context) error {
+		return s.storage.DeleteExhibit(ctx, id)
+	})
+}
+
+// MoveExhibits moves exhibits to another exhibition, recording an update
+// revision for every exhibit that actually changed exhibition.
+func (s *Service) MoveExhibits(ctx context.Context, exhibitIDs []uuid.UUID, exhibitionID uuid.UUID) (target model.Exhibition, err error) {
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		before := make([]model.Exhibit, 0, len(exhibitIDs))
+		for _, id := range exhibitIDs {
+			e, err := s.storage.GetExhibit(ctx, id)
+			if err != nil {
+				return err
+			}
+			before = append(before, e)
+		}
+		if target, err = s.storage.MoveExhibits(ctx, exhibitIDs, exhibitionID); err != nil {
+			return err
+		}
+		for _, b := range before {
+			if b.ExhibitionID == exhibitionID {
+				continue
+			}
+			after, err := s.storage.GetExhibit(ctx, b.ID)
+			if err != nil {
+				return err
+			}
+			if _, err := s.record(ctx, model.EntityExhibit, b.ID, model.RevisionUpdate, b, after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return target, err
 }
 
 // --- Stats ---
@@ -96,3 +240,16 @@ func (s *Service) DeleteExhibit(ctx context.Context, id uuid.UUID) error {
 func (s *Service) GetStats(ctx context.Context) (model.VisitStats, error) {
 	return s.storage.GetStats(ctx)
 }
+
+// exhibitionExists returns notFound, wrapped with the id, if there is no live
+// exhibition with the given id.
+func (s *Service) exhibitionExists(ctx context.Context, id uuid.UUID, notFound error) error {
+	ok, err := s.storage.ExhibitionExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("exhibition %s: %w", id, notFound)
+	}
+	return nil
+}
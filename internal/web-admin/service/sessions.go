@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrSessionsDisabled is returned by the session methods when the
+	// service has no sessions.
+	ErrSessionsDisabled = storage.NewError(storage.ErrUnavailable, "sessions_disabled", "sessions are disabled")
+	// ErrNoSession is returned by CurrentSession for the requests made with
+	// Basic Auth.
+	ErrNoSession = storage.NewError(storage.ErrNotFound, "no_session", "the request is not made with a session")
+)
+
+// Sessions manages the sessions of the admin login form, see auth.Sessions.
+type Sessions interface {
+	Start(ctx context.Context, user model.AdminUser, userAgent, ip string) (model.AdminSession, string, error)
+	List(ctx context.Context, userID uuid.UUID) ([]model.AdminSession, error)
+	Revoke(ctx context.Context, userID, id uuid.UUID) error
+	RevokeAll(ctx context.Context, userID, except uuid.UUID) (int, error)
+	Cookie(token string, expires time.Time) *http.Cookie
+	ClearCookie() *http.Cookie
+}
+
+// WithSessions lets the admin sign users in with the login form.
+func WithSessions(s Sessions) Option {
+	return func(srv *Service) {
+		srv.sessions = s
+	}
+}
+
+// Login is a successful sign-in.
+type Login struct {
+	User    model.AdminUser
+	Session model.AdminSession
+	// Cookie carries the token of the session.
+	Cookie *http.Cookie
+}
+
+// --- Sessions ---
+
+// Login starts a session for the user with login and password, and code
+// if the user has two-factor authentication: a one-time or a recovery
+// code.
+func (s *Service) Login(ctx context.Context, login, password, code string) (Login, error) {
+	if s.users == nil || s.sessions == nil {
+		return Login{}, ErrSessionsDisabled
+	}
+	user, err := s.users.Authenticate(ctx, login, password)
+	if err != nil {
+		return Login{}, err
+	}
+	if s.twoFactor != nil {
+		if err := s.twoFactor.Verify(ctx, user, code); err != nil {
+			return Login{}, err
+		}
+	}
+	ip, _ := ctx.Value(model.CtxKeyVisitorIP).(string)
+	ua, _ := ctx.Value(model.CtxKeyVisitorUA).(string)
+	as, token, err := s.sessions.Start(ctx, user, ua, ip)
+	if err != nil {
+		return Login{}, err
+	}
+	s.log.Info("admin user signed in", slog.String("login", user.Login), slog.String("ip", ip))
+	return Login{User: user, Session: as, Cookie: s.sessions.Cookie(token, as.ExpiresAt)}, nil
+}
+
+// Logout ends the session of the request, if any, and returns the cookie
+// removing it from the browser.
+func (s *Service) Logout(ctx context.Context) (*http.Cookie, error) {
+	if s.sessions == nil {
+		return nil, ErrSessionsDisabled
+	}
+	id, ok := ctx.Value(model.CtxKeyAdminSession).(uuid.UUID)
+	if !ok {
+		return s.sessions.ClearCookie(), nil
+	}
+	user, err := s.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessions.Revoke(ctx, user.ID, id); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	return s.sessions.ClearCookie(), nil
+}
+
+// CurrentSession returns the session of the request with its user.
+func (s *Service) CurrentSession(ctx context.Context) (model.AdminUser, model.AdminSession, error) {
+	id, ok := ctx.Value(model.CtxKeyAdminSession).(uuid.UUID)
+	if !ok {
+		return model.AdminUser{}, model.AdminSession{}, ErrNoSession
+	}
+	user, sessions, err := s.currentSessions(ctx)
+	if err != nil {
+		return model.AdminUser{}, model.AdminSession{}, err
+	}
+	for _, as := range sessions {
+		if as.ID == id {
+			return user, as, nil
+		}
+	}
+	return model.AdminUser{}, model.AdminSession{}, ErrNoSession
+}
+
+// ListSessions returns the active sessions of the user making the request.
+func (s *Service) ListSessions(ctx context.Context) ([]model.AdminSession, error) {
+	_, sessions, err := s.currentSessions(ctx)
+	return sessions, err
+}
+
+// RevokeSession ends a session of the user making the request.
+func (s *Service) RevokeSession(ctx context.Context, id uuid.UUID) error {
+	if s.sessions == nil {
+		return ErrSessionsDisabled
+	}
+	user, err := s.CurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+	return s.sessions.Revoke(ctx, user.ID, id)
+}
+
+// ListUserSessions returns the active sessions of a user.
+func (s *Service) ListUserSessions(ctx context.Context, userID uuid.UUID) ([]model.AdminSession, error) {
+	if s.users == nil || s.sessions == nil {
+		return nil, ErrSessionsDisabled
+	}
+	if _, err := s.users.Get(ctx, userID); err != nil {
+		return nil, err
+	}
+	sessions, err := s.sessions.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	markCurrent(ctx, sessions)
+	return sessions, nil
+}
+
+// RevokeUserSessions ends the sessions of a user but the one of the
+// request and returns their number.
+func (s *Service) RevokeUserSessions(ctx context.Context, userID uuid.UUID) (int, error) {
+	if s.users == nil || s.sessions == nil {
+		return 0, ErrSessionsDisabled
+	}
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	current, _ := ctx.Value(model.CtxKeyAdminSession).(uuid.UUID)
+	n, err := s.sessions.RevokeAll(ctx, userID, current)
+	if err == nil {
+		s.log.Info("admin sessions revoked",
+			slog.String("login", user.Login),
+			slog.Int("sessions", n),
+			slog.String("by", author(ctx)))
+	}
+	return n, err
+}
+
+func (s *Service) currentSessions(ctx context.Context) (model.AdminUser, []model.AdminSession, error) {
+	if s.sessions == nil {
+		return model.AdminUser{}, nil, ErrSessionsDisabled
+	}
+	user, err := s.CurrentUser(ctx)
+	if err != nil {
+		return model.AdminUser{}, nil, err
+	}
+	sessions, err := s.sessions.List(ctx, user.ID)
+	if err != nil {
+		return model.AdminUser{}, nil, err
+	}
+	markCurrent(ctx, sessions)
+	return user, sessions, nil
+}
+
+// markCurrent sets Current on the session of the request among sessions.
+func markCurrent(ctx context.Context, sessions []model.AdminSession) {
+	current, _ := ctx.Value(model.CtxKeyAdminSession).(uuid.UUID)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+}
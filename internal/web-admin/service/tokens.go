@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// ErrTokensDisabled is returned by the token methods when the service has
+// no API tokens.
+var ErrTokensDisabled = storage.NewError(storage.ErrUnavailable, "tokens_disabled", "API tokens are disabled")
+
+// Tokens manages the personal API tokens of the admin users, see
+// auth.Tokens.
+type Tokens interface {
+	Create(ctx context.Context, user model.AdminUser, name string, scopes []model.Scope, expiresAt *time.Time) (model.APIToken, string, error)
+	List(ctx context.Context, userID uuid.UUID) ([]model.APIToken, error)
+	Revoke(ctx context.Context, userID, id uuid.UUID) error
+}
+
+// WithTokens lets the admin users issue API tokens for scripts.
+func WithTokens(t Tokens) Option {
+	return func(s *Service) {
+		s.tokens = t
+	}
+}
+
+// --- Tokens ---
+
+// CreateToken issues a token of the user making the request and returns it
+// with its secret, which cannot be shown again.
+func (s *Service) CreateToken(ctx context.Context, name string, scopes []model.Scope, expiresAt *time.Time) (model.APIToken, string, error) {
+	if s.tokens == nil {
+		return model.APIToken{}, "", ErrTokensDisabled
+	}
+	user, err := s.CurrentUser(ctx)
+	if err != nil {
+		return model.APIToken{}, "", err
+	}
+	tok, secret, err := s.tokens.Create(ctx, user, name, scopes, expiresAt)
+	if err == nil {
+		s.log.Info("API token created",
+			slog.String("login", user.Login),
+			slog.String("name", tok.Name),
+			slog.String("prefix", tok.Prefix))
+	}
+	return tok, secret, err
+}
+
+// ListTokens returns the tokens of the user making the request.
+func (s *Service) ListTokens(ctx context.Context) ([]model.APIToken, error) {
+	if s.tokens == nil {
+		return nil, ErrTokensDisabled
+	}
+	user, err := s.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.tokens.List(ctx, user.ID)
+}
+
+// RevokeToken deletes a token of the user making the request.
+func (s *Service) RevokeToken(ctx context.Context, id uuid.UUID) error {
+	if s.tokens == nil {
+		return ErrTokensDisabled
+	}
+	user, err := s.CurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+	return s.tokens.Revoke(ctx, user.ID, id)
+}
+
+// ListUserTokens returns the tokens of a user.
+func (s *Service) ListUserTokens(ctx context.Context, userID uuid.UUID) ([]model.APIToken, error) {
+	if s.users == nil || s.tokens == nil {
+		return nil, ErrTokensDisabled
+	}
+	if _, err := s.users.Get(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.tokens.List(ctx, userID)
+}
+
+// RevokeUserToken deletes a token of a user.
+func (s *Service) RevokeUserToken(ctx context.Context, userID, id uuid.UUID) error {
+	if s.users == nil || s.tokens == nil {
+		return ErrTokensDisabled
+	}
+	user, err := s.users.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = s.tokens.Revoke(ctx, userID, id)
+	if err == nil {
+		s.log.Info("API token revoked",
+			slog.String("login", user.Login),
+			slog.String("id", id.String()),
+			slog.String("by", author(ctx)))
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// --- Trash ---
+
+func (s *Service) ListTrash(ctx context.Context, types []model.EntityType) ([]model.TrashItem, error) {
+	return s.storage.ListTrash(ctx, types)
+}
+
+// Restore moves an item out of the trash and records that as a revision.
+// For exhibitions withExhibits also restores the exhibits deleted together
+// with it, with a revision each; the number of restored exhibits is returned.
+// An exhibit can only be restored while its exhibition is alive.
+func (s *Service) Restore(ctx context.Context, t model.EntityType, id uuid.UUID, withExhibits bool) (n int, err error) {
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		if n, err = s.restore(ctx, t, id, withExhibits); err != nil {
+			return err
+		}
+		var after any
+		switch t {
+		case model.EntityNews:
+			after, err = s.storage.GetNews(ctx, id)
+		case model.EntityExhibition:
+			after, err = s.storage.GetExhibition(ctx, id)
+		case model.EntityExhibit:
+			after, err = s.storage.GetExhibit(ctx, id)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := s.record(ctx, t, id, model.RevisionRestore, nil, after); err != nil {
+			return err
+		}
+		// Exhibits deleted together with the exhibition are the only ones it
+		// has once restored with them.
+		if ex, ok := after.(model.Exhibition); ok && withExhibits {
+			for _, e := range ex.Exhibits {
+				if _, err := s.record(ctx, model.EntityExhibit, e.ID, model.RevisionRestore, nil, e); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+func (s *Service) restore(ctx context.Context, t model.EntityType, id uuid.UUID, withExhibits bool) (int, error) {
+	switch t {
+	case model.EntityNews:
+		return 0, s.storage.RestoreNews(ctx, id)
+	case model.EntityExhibition:
+		return s.storage.RestoreExhibition(ctx, id, withExhibits)
+	case model.EntityExhibit:
+		e, err := s.storage.DeletedExhibit(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		if err := s.exhibitionExists(ctx, e.ExhibitionID, ErrExhibitionDeleted); err != nil {
+			return 0, err
+		}
+		return 0, s.storage.RestoreExhibit(ctx, id)
+	default:
+		return 0, fmt.Errorf("unknown entity type %q: %w", t, storage.ErrInvalid)
+	}
+}
+
+// Purge permanently removes an item from the trash.
+func (s *Service) Purge(ctx context.Context, t model.EntityType, id uuid.UUID) error {
+	switch t {
+	case model.EntityNews:
+		return s.storage.PurgeNews(ctx, id)
+	case model.EntityExhibition:
+		return s.storage.PurgeExhibition(ctx, id)
+	case model.EntityExhibit:
+		return s.storage.PurgeExhibit(ctx, id)
+	default:
+		return fmt.Errorf("unknown entity type %q: %w", t, storage.ErrInvalid)
+	}
+}
+
+// PurgeExpired permanently removes content that has been in the trash
+// longer than the configured retention.
+func (s *Service) PurgeExpired(ctx context.Context) (model.PurgeResult, error) {
+	if s.trashRetention <= 0 {
+		return model.PurgeResult{}, ErrRetentionDisabled
+	}
+	return s.storage.PurgeDeletedBefore(ctx, time.Now().Add(-s.trashRetention))
+}
+
+// RunTrashPurge calls PurgeExpired every interval until ctx is done.
+// It returns immediately if retention is disabled.
+func (s *Service) RunTrashPurge(ctx context.Context, interval time.Duration) error {
+	if s.trashRetention <= 0 || interval <= 0 {
+		s.log.Info("trash purge job disabled")
+		return nil
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		res, err := s.PurgeExpired(ctx)
+		if err != nil && ctx.Err() == nil {
+			s.log.Error("failed to purge trash", slog.String("error", err.Error()))
+		} else if err == nil && res != (model.PurgeResult{}) {
+			s.log.Info("purged trash",
+				slog.Int("news", res.News),
+				slog.Int("exhibitions", res.Exhibitions),
+				slog.Int("exhibits", res.Exhibits))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
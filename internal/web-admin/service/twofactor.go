@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// ErrTwoFactorDisabled is returned by the two-factor methods when the
+// service has no two-factor authentication.
+var ErrTwoFactorDisabled = storage.NewError(storage.ErrUnavailable, "two_factor_disabled", "two-factor authentication is disabled")
+
+// TwoFactor manages the two-factor authentication of the admin users, see
+// auth.TwoFactor.
+type TwoFactor interface {
+	Required(role model.Role) bool
+	Begin(ctx context.Context, user model.AdminUser) (string, string, error)
+	Confirm(ctx context.Context, user model.AdminUser, code string) ([]string, error)
+	Verify(ctx context.Context, user model.AdminUser, code string) error
+	Disable(ctx context.Context, user model.AdminUser, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, user model.AdminUser, code string) ([]string, error)
+	Reset(ctx context.Context, id uuid.UUID) (model.AdminUser, error)
+}
+
+// WithTwoFactor lets the admin users protect their accounts with one-time
+// codes.
+func WithTwoFactor(t TwoFactor) Option {
+	return func(s *Service) {
+		s.twoFactor = t
+	}
+}
+
+// TwoFactorStatus is the two-factor authentication of a user.
+type TwoFactorStatus struct {
+	Enabled bool
+	// Required is set when the role of the user requires it.
+	Required          bool
+	RecoveryCodesLeft int
+}
+
+// --- Two-factor authentication ---
+
+// TwoFactorStatus returns the two-factor authentication of the user making
+// the request.
+func (s *Service) TwoFactorStatus(ctx context.Context) (TwoFactorStatus, error) {
+	user, err := s.twoFactorUser(ctx)
+	if err != nil {
+		return TwoFactorStatus{}, err
+	}
+	return TwoFactorStatus{
+		Enabled:           user.TOTPEnabled,
+		Required:          s.twoFactor.Required(user.Role),
+		RecoveryCodesLeft: len(user.RecoveryCodes),
+	}, nil
+}
+
+// BeginTwoFactor starts the enrollment of the user making the request and
+// returns the secret with its otpauth URI.
+func (s *Service) BeginTwoFactor(ctx context.Context) (string, string, error) {
+	user, err := s.twoFactorUser(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return s.twoFactor.Begin(ctx, user)
+}
+
+// ConfirmTwoFactor enables two-factor authentication for the user making
+// the request and returns their recovery codes.
+func (s *Service) ConfirmTwoFactor(ctx context.Context, code string) ([]string, error) {
+	user, err := s.twoFactorUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.twoFactor.Confirm(ctx, user, code)
+}
+
+// DisableTwoFactor turns two-factor authentication of the user making the
+// request off.
+func (s *Service) DisableTwoFactor(ctx context.Context, code string) error {
+	user, err := s.twoFactorUser(ctx)
+	if err != nil {
+		return err
+	}
+	return s.twoFactor.Disable(ctx, user, code)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user making
+// the request.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error) {
+	user, err := s.twoFactorUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.twoFactor.RegenerateRecoveryCodes(ctx, user, code)
+}
+
+// ResetUserTwoFactor turns two-factor authentication of a user off.
+func (s *Service) ResetUserTwoFactor(ctx context.Context, id uuid.UUID) (model.AdminUser, error) {
+	if s.twoFactor == nil {
+		return model.AdminUser{}, ErrTwoFactorDisabled
+	}
+	u, err := s.twoFactor.Reset(ctx, id)
+	if err == nil {
+		s.log.Info("two-factor authentication reset",
+			slog.String("login", u.Login),
+			slog.String("by", author(ctx)))
+	}
+	return u, err
+}
+
+func (s *Service) twoFactorUser(ctx context.Context) (model.AdminUser, error) {
+	if s.twoFactor == nil {
+		return model.AdminUser{}, ErrTwoFactorDisabled
+	}
+	return s.CurrentUser(ctx)
+}
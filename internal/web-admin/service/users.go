@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/google/uuid"
+)
+
+// ErrUsersDisabled is returned by the user methods when the service has no
+// user accounts.
+var ErrUsersDisabled = storage.NewError(storage.ErrUnavailable, "users_disabled", "user accounts are disabled")
+
+// Users manages the accounts of the admin, see auth.Users.
+type Users interface {
+	Authenticate(ctx context.Context, login, password string) (model.AdminUser, error)
+	List(ctx context.Context) ([]model.AdminUser, error)
+	Get(ctx context.Context, id uuid.UUID) (model.AdminUser, error)
+	GetByLogin(ctx context.Context, login string) (model.AdminUser, error)
+	Add(ctx context.Context, login, password string, role model.Role) (model.AdminUser, error)
+	Update(ctx context.Context, id uuid.UUID, role model.Role, disabled bool) (model.AdminUser, error)
+	SetPassword(ctx context.Context, id uuid.UUID, password string) error
+}
+
+// WithUsers lets the admin manage its user accounts.
+func WithUsers(u Users) Option {
+	return func(s *Service) {
+		s.users = u
+	}
+}
+
+// --- Users ---
+
+func (s *Service) ListUsers(ctx context.Context) ([]model.AdminUser, error) {
+	if s.users == nil {
+		return nil, ErrUsersDisabled
+	}
+	return s.users.List(ctx)
+}
+
+func (s *Service) CreateUser(ctx context.Context, login, password string, role model.Role) (model.AdminUser, error) {
+	if s.users == nil {
+		return model.AdminUser{}, ErrUsersDisabled
+	}
+	u, err := s.users.Add(ctx, login, password, role)
+	if err == nil {
+		s.log.Info("admin user created",
+			slog.String("login", u.Login),
+			slog.String("role", string(u.Role)),
+			slog.String("by", author(ctx)))
+	}
+	return u, err
+}
+
+// UpdateUser sets the role of a user and disables or enables them.
+func (s *Service) UpdateUser(ctx context.Context, id uuid.UUID, role model.Role, disabled bool) (model.AdminUser, error) {
+	if s.users == nil {
+		return model.AdminUser{}, ErrUsersDisabled
+	}
+	u, err := s.users.Update(ctx, id, role, disabled)
+	if err == nil {
+		s.log.Info("admin user updated",
+			slog.String("login", u.Login),
+			slog.String("role", string(u.Role)),
+			slog.Bool("disabled", u.Disabled),
+			slog.String("by", author(ctx)))
+	}
+	return u, err
+}
+
+// SetUserPassword replaces the password of a user without asking for the
+// current one, for owners resetting forgotten passwords.
+func (s *Service) SetUserPassword(ctx context.Context, id uuid.UUID, password string) error {
+	if s.users == nil {
+		return ErrUsersDisabled
+	}
+	return s.users.SetPassword(ctx, id, password)
+}
+
+// CurrentUser returns the user making the request.
+func (s *Service) CurrentUser(ctx context.Context) (model.AdminUser, error) {
+	if s.users == nil {
+		return model.AdminUser{}, ErrUsersDisabled
+	}
+	return s.users.GetByLogin(ctx, author(ctx))
+}
+
+// ChangeOwnPassword replaces the password of the user making the request
+// if current is their password.
+func (s *Service) ChangeOwnPassword(ctx context.Context, current, password string) error {
+	if s.users == nil {
+		return ErrUsersDisabled
+	}
+	u, err := s.users.Authenticate(ctx, author(ctx), current)
+	if err != nil {
+		return err
+	}
+	return s.users.SetPassword(ctx, u.ID, password)
+}
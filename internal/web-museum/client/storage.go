@@ -13,29 +13,35 @@ type Storage struct {
 	News        storage.Storage[model.News]
 	Exhibitions storage.Storage[model.Exhibition]
 	Exhibits    storage.Storage[model.Exhibit]
-	Visits      *storage.VisitStorage
+	Visits      storage.Visits
+	Search      storage.Searcher
+	Assets      storage.Assets
+	Media       storage.MediaLibrary
 	log         *slog.Logger
 }
 
-func NewStorage(news storage.Storage[model.News], exhibitions storage.Storage[model.Exhibition], exhibits storage.Storage[model.Exhibit], visits *storage.VisitStorage, log *slog.Logger) *Storage {
+func NewStorage(news storage.Storage[model.News], exhibitions storage.Storage[model.Exhibition], exhibits storage.Storage[model.Exhibit], visits storage.Visits, search storage.Searcher, assets storage.Assets, media storage.MediaLibrary, log *slog.Logger) *Storage {
 	return &Storage{
 		News:        news,
 		Exhibitions: exhibitions,
 		Exhibits:    exhibits,
 		Visits:      visits,
+		Search:      search,
+		Assets:      assets,
+		Media:       media,
 		log:         log,
 	}
 }
 
 // --- News ---
 
-func (s *Storage) GetAllNews(ctx context.Context) ([]model.News, error) {
-	news, err := s.News.List(ctx)
+func (s *Storage) GetAllNews(ctx context.Context, opts storage.ListOptions) ([]model.News, int, error) {
+	news, total, err := s.News.Query(ctx, opts)
 	if err != nil {
 		s.log.Error("failed to get all news", slog.String("error", err.Error()))
-		return nil, err
+		return nil, 0, err
 	}
-	return news, nil
+	return news, total, nil
 }
 
 func (s *Storage) GetNewsByID(ctx context.Context, id uuid.UUID) (model.News, error) {
@@ -49,13 +55,13 @@ func (s *Storage) GetNewsByID(ctx context.Context, id uuid.UUID) (model.News, er
 
 // --- Exhibitions ---
 
-func (s *Storage) GetAllExhibitions(ctx context.Context) ([]model.Exhibition, error) {
-	exhibitions, err := s.Exhibitions.List(ctx)
+func (s *Storage) GetAllExhibitions(ctx context.Context, opts storage.ListOptions) ([]model.Exhibition, int, error) {
+	exhibitions, total, err := s.Exhibitions.Query(ctx, opts)
 	if err != nil {
 		s.log.Error("failed to get all exhibitions", slog.String("error", err.Error()))
-		return nil, err
+		return nil, 0, err
 	}
-	return exhibitions, nil
+	return exhibitions, total, nil
 }
 
 func (s *Storage) GetExhibitionByID(ctx context.Context, id uuid.UUID) (model.Exhibition, error) {
@@ -76,3 +82,34 @@ func (s *Storage) RecordVisit(ctx context.Context, v model.Visitor) error {
 	}
 	return nil
 }
+
+// --- Search ---
+
+func (s *Storage) SearchContent(ctx context.Context, q storage.SearchQuery) ([]model.SearchHit, error) {
+	hits, err := s.Search.Search(ctx, q)
+	if err != nil {
+		s.log.Error("failed to search", slog.String("query", q.Text), slog.String("error", err.Error()))
+		return nil, err
+	}
+	return hits, nil
+}
+
+// --- Media ---
+
+func (s *Storage) AssetsByURL(ctx context.Context, urls []string) ([]model.Asset, error) {
+	assets, err := s.Assets.ByURLs(ctx, urls)
+	if err != nil {
+		s.log.Error("failed to get assets by url", slog.Int("count", len(urls)), slog.String("error", err.Error()))
+		return nil, err
+	}
+	return assets, nil
+}
+
+func (s *Storage) AttachedMedia(ctx context.Context, owner model.EntityType, ownerIDs []uuid.UUID) (map[uuid.UUID][]model.AttachedMedia, error) {
+	attached, err := s.Media.Attached(ctx, owner, ownerIDs)
+	if err != nil {
+		s.log.Error("failed to get attached media", slog.String("owner", string(owner)), slog.Int("count", len(ownerIDs)), slog.String("error", err.Error()))
+		return nil, err
+	}
+	return attached, nil
+}
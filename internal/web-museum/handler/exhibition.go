@@ -5,13 +5,21 @@ import (
 	"net/http"
 
 	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/problem"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/google/uuid"
 )
 
-// GetAllExhibitions - получение списка всех экспозиций.
+// GetAllExhibitions - получение списка экспозиций с пагинацией.
+type getAllExhibitionsInput struct {
+	ListParams
+	Exhibits bool `query:"exhibits" default:"false" doc:"Загружать экспонаты каждой экспозиции"`
+}
+
 type getAllExhibitionsOutput struct {
-	Body []model.Exhibition `json:"exhibitions"`
+	Total int                `header:"X-Total-Count" doc:"Общее количество экспозиций"`
+	Body  []model.Exhibition `json:"exhibitions"`
 }
 
 func (h *Handler) GetAllExhibitions(api huma.API) {
@@ -21,19 +29,21 @@ func (h *Handler) GetAllExhibitions(api huma.API) {
 			OperationID: "get-all-exhibitions",
 			Method:      http.MethodGet,
 			Path:        "/exhibitions",
-			Summary:     "Получить все экспозиции",
-			Description: "Возвращает список всех экспозиций музея.",
+			Summary:     "Получить экспозиции",
+			Description: "Возвращает страницу экспозиций музея. Общее количество — в заголовке X-Total-Count.",
 			Tags:        []string{"Exhibitions"},
 		},
-		func(ctx context.Context, req *struct{}) (*getAllExhibitionsOutput, error) {
-			exhibitions, err := h.service.GetAllExhibitions(ctx)
+		func(ctx context.Context, req *getAllExhibitionsInput) (*getAllExhibitionsOutput, error) {
+			opts := req.options()
+			opts.WithRelations = req.Exhibits
+			exhibitions, total, err := h.service.GetAllExhibitions(ctx, opts)
 			if err != nil {
-				return nil, huma.Error500InternalServerError("не удалось получить экспозиции")
+				return nil, problem.From(err, "не удалось получить экспозиции")
 			}
 			if exhibitions == nil {
 				exhibitions = []model.Exhibition{}
 			}
-			return &getAllExhibitionsOutput{Body: exhibitions}, nil
+			return &getAllExhibitionsOutput{Total: total, Body: exhibitions}, nil
 		},
 	)
 }
@@ -61,7 +71,8 @@ func (h *Handler) GetExhibitionByID(api huma.API) {
 		func(ctx context.Context, req *getExhibitionByIDInput) (*getExhibitionByIDOutput, error) {
 			detail, err := h.service.GetExhibitionByID(ctx, req.ID)
 			if err != nil {
-				return nil, huma.Error404NotFound("экспозиция не найдена")
+				return nil, problem.From(err, "не удалось получить экспозицию",
+					problem.Detail{Err: storage.ErrNotFound, Msg: "экспозиция не найдена"})
 			}
 			return &getExhibitionByIDOutput{Body: detail}, nil
 		},
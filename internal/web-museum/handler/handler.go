@@ -5,16 +5,21 @@ import (
 	"log/slog"
 
 	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/mediaproxy"
+	"github.com/WhiCu/school-museum/internal/resolver"
 	"github.com/google/uuid"
 )
 
 type service interface {
-	GetAllNews(ctx context.Context) ([]model.News, error)
+	GetAllNews(ctx context.Context, opts storage.ListOptions) ([]model.News, int, error)
 	GetNewsByID(ctx context.Context, id uuid.UUID) (model.News, error)
-	GetAllExhibitions(ctx context.Context) ([]model.Exhibition, error)
+	GetAllExhibitions(ctx context.Context, opts storage.ListOptions) ([]model.Exhibition, int, error)
 	GetExhibitionByID(ctx context.Context, id uuid.UUID) (model.Exhibition, error)
 	RecordVisit(ctx context.Context, v model.Visitor) error
-	ResolveExternalMedia(ctx context.Context, rawURL string) (string, string, error)
+	Search(ctx context.Context, q storage.SearchQuery) ([]model.SearchHit, error)
+	ResolveExternalMedia(ctx context.Context, rawURL string) (resolver.Resolved, error)
+	OpenProxiedMedia(ctx context.Context, rawURL string) (*mediaproxy.File, error)
 }
 
 type Handler struct {
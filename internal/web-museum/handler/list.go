@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/WhiCu/school-museum/db/storage"
+)
+
+// ListParams - общие параметры пагинации, сортировки и фильтрации списков.
+type ListParams struct {
+	Limit         int       `query:"limit" minimum:"1" maximum:"100" default:"20" doc:"Размер страницы"`
+	Offset        int       `query:"offset" minimum:"0" doc:"Смещение от начала списка"`
+	Sort          string    `query:"sort" enum:"created_at,title" default:"created_at" doc:"Поле сортировки"`
+	Direction     string    `query:"direction" enum:"asc,desc" default:"desc" doc:"Направление сортировки"`
+	CreatedAfter  time.Time `query:"created_after" doc:"Только записи, созданные не раньше (RFC 3339)"`
+	CreatedBefore time.Time `query:"created_before" doc:"Только записи, созданные раньше (RFC 3339)"`
+}
+
+func (in ListParams) options() storage.ListOptions {
+	return storage.ListOptions{
+		Limit:         in.Limit,
+		Offset:        in.Offset,
+		Sort:          storage.SortField(in.Sort),
+		Desc:          in.Direction == "desc",
+		CreatedAfter:  in.CreatedAfter,
+		CreatedBefore: in.CreatedBefore,
+	}
+}
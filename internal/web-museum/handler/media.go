@@ -4,7 +4,13 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/WhiCu/school-museum/internal/egress"
+	"github.com/WhiCu/school-museum/internal/media"
+	"github.com/WhiCu/school-museum/internal/mediaproxy"
+	"github.com/WhiCu/school-museum/internal/problem"
+	"github.com/WhiCu/school-museum/internal/resolver"
 	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humabunrouter"
 )
 
 type resolveMediaInput struct {
@@ -12,10 +18,7 @@ type resolveMediaInput struct {
 }
 
 type resolveMediaOutput struct {
-	Body struct {
-		URL  string `json:"url"`
-		Type string `json:"type"`
-	}
+	Body resolver.Resolved
 }
 
 func (h *Handler) ResolveMedia(api huma.API) {
@@ -26,23 +29,83 @@ func (h *Handler) ResolveMedia(api huma.API) {
 			Method:      http.MethodGet,
 			Path:        "/media/resolve",
 			Summary:     "Resolve external media",
-			Description: "Resolves external media page URL to a direct media file URL.",
-			Tags:        []string{"Media"},
+			Description: "Resolves external media page URL to a direct media file URL (type image or video) " +
+				"or to an embeddable player URL (type embed). Supported: imgur, YouTube, Rutube, VK video, " +
+				"public Google Drive and Yandex Disk files, and direct links to images and videos.",
+			Tags: []string{"Media"},
 		},
 		func(ctx context.Context, req *resolveMediaInput) (*resolveMediaOutput, error) {
 			if req.URL == "" {
 				return nil, huma.Error400BadRequest("url is required")
 			}
 
-			resolvedURL, mediaType, err := h.service.ResolveExternalMedia(ctx, req.URL)
+			res, err := h.service.ResolveExternalMedia(ctx, req.URL)
 			if err != nil {
-				return nil, huma.Error422UnprocessableEntity(err.Error())
+				return nil, externalMediaError(err)
 			}
+			return &resolveMediaOutput{Body: res}, nil
+		},
+	)
+}
+
+type proxyMediaInput struct {
+	URL string `query:"url" required:"true" doc:"External media page URL"`
+}
 
-			out := &resolveMediaOutput{}
-			out.Body.URL = resolvedURL
-			out.Body.Type = mediaType
-			return out, nil
+func (h *Handler) ProxyMedia(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "proxy-media",
+			Method:      http.MethodGet,
+			Path:        "/media/proxy",
+			Summary:     "Proxy external media",
+			Description: "Serves the image or video behind an external media URL from the site's own origin, " +
+				"for networks that block the media services. Accepts the same links as /media/resolve, " +
+				"except players; direct links only to the hosts allowed in the configuration. " +
+				"Files are cached on disk; Range and conditional requests are supported. " +
+				"Requests from one address are rate limited.",
+			Tags: []string{"Media"},
+			Responses: map[string]*huma.Response{
+				"200": {Description: "Media file"},
+				"206": {Description: "Part of the media file"},
+				"304": {Description: "Not modified"},
+			},
+		},
+		func(ctx context.Context, req *proxyMediaInput) (*huma.StreamResponse, error) {
+			if req.URL == "" {
+				return nil, huma.Error400BadRequest("url is required")
+			}
+
+			f, err := h.service.OpenProxiedMedia(ctx, req.URL)
+			if err != nil {
+				return nil, externalMediaError(err,
+					problem.Detail{Err: mediaproxy.ErrNotProxyable, Msg: "плееры нельзя загрузить через сервер"},
+					problem.Detail{Err: mediaproxy.ErrHostNotAllowed, Msg: "прямые ссылки на этот сайт нельзя загрузить через сервер"},
+					problem.Detail{Err: media.ErrUnsupported, Msg: "ссылка ведёт не на изображение или видео"})
+			}
+			return &huma.StreamResponse{
+				Body: func(hctx huma.Context) {
+					defer f.Close()
+					r, w := humabunrouter.Unwrap(hctx)
+					f.ServeHTTP(w, r.Request)
+				},
+			}, nil
 		},
 	)
 }
+
+// externalMediaError describes the failures of resolving and fetching
+// external media, and then those of extra.
+func externalMediaError(err error, extra ...problem.Detail) error {
+	details := append(extra,
+		problem.Detail{Err: egress.ErrBlocked, Msg: "ссылка ведёт на недопустимый адрес"},
+		problem.Detail{Err: egress.ErrTooManyRedirects, Msg: "слишком много перенаправлений"},
+		problem.Detail{Err: egress.ErrTooLarge, Msg: "ответ внешнего сервиса слишком большой"},
+		problem.Detail{Err: egress.ErrContentType, Msg: "ссылка ведёт на файл недопустимого типа"},
+		problem.Detail{Err: resolver.ErrInvalidURL, Msg: "некорректная ссылка"},
+		problem.Detail{Err: resolver.ErrUnsupportedURL, Msg: "ссылки этого сервиса не поддерживаются"},
+		problem.Detail{Err: resolver.ErrNotFound, Msg: "на странице не найдено медиа"},
+		problem.Detail{Err: resolver.ErrSource, Msg: "внешний сервис недоступен"})
+	return problem.From(err, "не удалось получить медиафайл", details...)
+}
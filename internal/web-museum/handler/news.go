@@ -5,13 +5,20 @@ import (
 	"net/http"
 
 	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/problem"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/google/uuid"
 )
 
-// GetAllNews - получение списка всех новостей.
+// GetAllNews - получение списка новостей с пагинацией.
+type getAllNewsInput struct {
+	ListParams
+}
+
 type getAllNewsOutput struct {
-	Body []model.News `json:"news"`
+	Total int          `header:"X-Total-Count" doc:"Общее количество новостей"`
+	Body  []model.News `json:"news"`
 }
 
 func (h *Handler) GetAllNews(api huma.API) {
@@ -21,19 +28,19 @@ func (h *Handler) GetAllNews(api huma.API) {
 			OperationID: "get-all-news",
 			Method:      http.MethodGet,
 			Path:        "/news",
-			Summary:     "Получить все новости",
-			Description: "Возвращает список всех новостей музея.",
+			Summary:     "Получить новости",
+			Description: "Возвращает страницу новостей музея. Общее количество — в заголовке X-Total-Count.",
 			Tags:        []string{"News"},
 		},
-		func(ctx context.Context, req *struct{}) (*getAllNewsOutput, error) {
-			news, err := h.service.GetAllNews(ctx)
+		func(ctx context.Context, req *getAllNewsInput) (*getAllNewsOutput, error) {
+			news, total, err := h.service.GetAllNews(ctx, req.options())
 			if err != nil {
-				return nil, huma.Error500InternalServerError("не удалось получить новости")
+				return nil, problem.From(err, "не удалось получить новости")
 			}
 			if news == nil {
 				news = []model.News{}
 			}
-			return &getAllNewsOutput{Body: news}, nil
+			return &getAllNewsOutput{Total: total, Body: news}, nil
 		},
 	)
 }
@@ -61,7 +68,8 @@ func (h *Handler) GetNewsByID(api huma.API) {
 		func(ctx context.Context, req *getNewsByIDInput) (*getNewsByIDOutput, error) {
 			n, err := h.service.GetNewsByID(ctx, req.ID)
 			if err != nil {
-				return nil, huma.Error404NotFound("новость не найдена")
+				return nil, problem.From(err, "не удалось получить новость",
+					problem.Detail{Err: storage.ErrNotFound, Msg: "новость не найдена"})
 			}
 			return &getNewsByIDOutput{Body: n}, nil
 		},
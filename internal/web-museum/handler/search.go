@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/problem"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Search - полнотекстовый поиск по новостям, экспозициям и экспонатам.
+type searchInput struct {
+	Query string   `query:"q" required:"true" minLength:"1" maxLength:"200" doc:"Поисковый запрос"`
+	Types []string `query:"type" enum:"news,exhibition,exhibit" doc:"Типы объектов для поиска (по умолчанию все)"`
+	Limit int      `query:"limit" minimum:"1" maximum:"50" default:"20" doc:"Максимальное число результатов"`
+}
+
+type searchOutput struct {
+	Body []model.SearchHit `json:"hits"`
+}
+
+func (h *Handler) Search(api huma.API) {
+	huma.Register(
+		api,
+		huma.Operation{
+			OperationID: "search",
+			Method:      http.MethodGet,
+			Path:        "/search",
+			Summary:     "Поиск",
+			Description: "Ищет по заголовкам и текстам новостей, экспозиций и экспонатов. Результаты упорядочены по релевантности, совпадения в snippet выделены тегом <mark>.",
+			Tags:        []string{"Search"},
+		},
+		func(ctx context.Context, req *searchInput) (*searchOutput, error) {
+			q := storage.SearchQuery{
+				Text:  req.Query,
+				Limit: req.Limit,
+			}
+			for _, t := range req.Types {
+				q.Types = append(q.Types, model.EntityType(t))
+			}
+
+			hits, err := h.service.Search(ctx, q)
+			if err != nil {
+				return nil, problem.From(err, "не удалось выполнить поиск")
+			}
+			return &searchOutput{Body: hits}, nil
+		},
+	)
+}
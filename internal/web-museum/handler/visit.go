@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/internal/problem"
 	"github.com/danielgtaylor/huma/v2"
 )
 
@@ -54,7 +55,7 @@ func (h *Handler) RecordVisit(api huma.API) {
 
 			if err := h.service.RecordVisit(ctx, v); err != nil {
 				h.log.Error("failed to record visit")
-				return nil, huma.Error500InternalServerError("failed to record visit")
+				return nil, problem.From(err, "failed to record visit")
 			}
 			out := &recordVisitOutput{}
 			out.Body.OK = true
@@ -16,11 +16,16 @@ func RegisterHandlers(
 	news storage.Storage[model.News],
 	exhibitions storage.Storage[model.Exhibition],
 	exhibits storage.Storage[model.Exhibit],
-	visits *storage.VisitStorage,
+	visits storage.Visits,
+	search storage.Searcher,
+	assets storage.Assets,
+	media storage.MediaLibrary,
+	resolvers service.MediaResolver,
+	proxy service.MediaProxy,
 	log *slog.Logger) {
 
-	stg := client.NewStorage(news, exhibitions, exhibits, visits, log.WithGroup("storage"))
-	srv := service.NewService(stg, log.WithGroup("service"))
+	stg := client.NewStorage(news, exhibitions, exhibits, visits, search, assets, media, log.WithGroup("storage"))
+	srv := service.NewService(stg, resolvers, proxy, log.WithGroup("service"))
 	h := handler.NewHandler(srv, log.WithGroup("handler"))
 
 	h.Ping(api)
@@ -29,5 +34,7 @@ func RegisterHandlers(
 	h.GetAllExhibitions(api)
 	h.GetExhibitionByID(api)
 	h.RecordVisit(api)
+	h.Search(api)
 	h.ResolveMedia(api)
+	h.ProxyMedia(api)
 }
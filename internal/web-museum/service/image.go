@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/internal/media"
+	"github.com/google/uuid"
+)
+
+// imageSet looks up the locally stored images among urls and describes them
+// with their variants, keyed by URL. Images that are not found have no entry.
+// A failed lookup is not an error: the content is still served, just
+// without variants.
+func (s *Service) imageSet(ctx context.Context, urls []string) map[string]model.Image {
+	local := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if strings.HasPrefix(u, media.URLPrefix) {
+			local = append(local, u)
+		}
+	}
+	if len(local) == 0 {
+		return nil
+	}
+
+	assets, err := s.storage.AssetsByURL(ctx, local)
+	if err != nil {
+		return nil
+	}
+	set := make(map[string]model.Image, len(assets))
+	for _, a := range assets {
+		if len(a.Variants) == 0 {
+			continue
+		}
+		srcset := make([]string, 0, len(a.Variants)+1)
+		for _, v := range a.Variants {
+			srcset = append(srcset, fmt.Sprintf("%s %dw", v.URL, v.Width))
+		}
+		srcset = append(srcset, fmt.Sprintf("%s %dw", a.URL, a.Width))
+		set[a.URL] = model.Image{
+			URL:      a.URL,
+			Srcset:   strings.Join(srcset, ", "),
+			Variants: a.Variants,
+		}
+	}
+	return set
+}
+
+// images describes urls in order, using the images found in set.
+func images(urls []string, set map[string]model.Image) []model.Image {
+	imgs := make([]model.Image, 0, len(urls))
+	for _, u := range urls {
+		img, ok := set[u]
+		if !ok {
+			img = model.Image{URL: u}
+		}
+		imgs = append(imgs, img)
+	}
+	return imgs
+}
+
+// mediaList returns the attached media followed by the urls that are not
+// among them, as bare items, so content edited before the media library
+// keeps its images.
+func mediaList(attached []model.AttachedMedia, urls []string) []model.AttachedMedia {
+	list := make([]model.AttachedMedia, 0, len(attached)+len(urls))
+	list = append(list, attached...)
+	for _, u := range urls {
+		if slices.ContainsFunc(attached, func(a model.AttachedMedia) bool { return a.URL == u }) {
+			continue
+		}
+		list = append(list, model.AttachedMedia{Media: model.Media{URL: u}})
+	}
+	for i := range list {
+		list[i].Position = i
+	}
+	return list
+}
+
+// mediaURLs returns the URLs of list, in order.
+func mediaURLs(list []model.AttachedMedia) []string {
+	urls := make([]string, len(list))
+	for i, m := range list {
+		urls[i] = m.URL
+	}
+	return urls
+}
+
+// withSrcset sets the srcset of the images of list found in set.
+func withSrcset(list []model.AttachedMedia, set map[string]model.Image) {
+	for i := range list {
+		list[i].Srcset = set[list[i].URL].Srcset
+	}
+}
+
+// withNewsMedia fills the media and images of news. ImageURLs is replaced by
+// the URLs of the media, so clients that only know image_urls see attached
+// media too.
+func (s *Service) withNewsMedia(ctx context.Context, news []model.News) error {
+	ids := make([]uuid.UUID, len(news))
+	for i, n := range news {
+		ids[i] = n.ID
+	}
+	attached, err := s.storage.AttachedMedia(ctx, model.EntityNews, ids)
+	if err != nil {
+		return err
+	}
+
+	var urls []string
+	for i := range news {
+		n := &news[i]
+		n.Media = mediaList(attached[n.ID], n.ImageURLs)
+		n.ImageURLs = mediaURLs(n.Media)
+		urls = append(urls, n.ImageURLs...)
+	}
+	set := s.imageSet(ctx, urls)
+	for i := range news {
+		withSrcset(news[i].Media, set)
+		news[i].Images = images(news[i].ImageURLs, set)
+	}
+	return nil
+}
+
+// withExhibitionMedia fills the media and images of the exhibits of
+// exhibitions, as withNewsMedia does for news.
+func (s *Service) withExhibitionMedia(ctx context.Context, exhibitions []model.Exhibition) error {
+	var ids []uuid.UUID
+	for _, ex := range exhibitions {
+		for _, e := range ex.Exhibits {
+			ids = append(ids, e.ID)
+		}
+	}
+	attached, err := s.storage.AttachedMedia(ctx, model.EntityExhibit, ids)
+	if err != nil {
+		return err
+	}
+
+	var urls []string
+	for i := range exhibitions {
+		for j := range exhibitions[i].Exhibits {
+			e := &exhibitions[i].Exhibits[j]
+			e.Media = mediaList(attached[e.ID], e.ImageURLs)
+			e.ImageURLs = mediaURLs(e.Media)
+			urls = append(urls, e.ImageURLs...)
+		}
+	}
+	set := s.imageSet(ctx, urls)
+	for i := range exhibitions {
+		for j := range exhibitions[i].Exhibits {
+			e := &exhibitions[i].Exhibits[j]
+			withSrcset(e.Media, set)
+			e.Images = images(e.ImageURLs, set)
+		}
+	}
+	return nil
+}
@@ -3,47 +3,106 @@ package service
 import (
 	"context"
 	"log/slog"
+	"strings"
 
 	"github.com/WhiCu/school-museum/db/model"
+	"github.com/WhiCu/school-museum/db/storage"
+	"github.com/WhiCu/school-museum/internal/mediaproxy"
+	"github.com/WhiCu/school-museum/internal/resolver"
 	"github.com/google/uuid"
 )
 
 type Storage interface {
-	GetAllNews(ctx context.Context) ([]model.News, error)
+	GetAllNews(ctx context.Context, opts storage.ListOptions) ([]model.News, int, error)
 	GetNewsByID(ctx context.Context, id uuid.UUID) (model.News, error)
-	GetAllExhibitions(ctx context.Context) ([]model.Exhibition, error)
+	GetAllExhibitions(ctx context.Context, opts storage.ListOptions) ([]model.Exhibition, int, error)
 	GetExhibitionByID(ctx context.Context, id uuid.UUID) (model.Exhibition, error)
 	RecordVisit(ctx context.Context, v model.Visitor) error
+	SearchContent(ctx context.Context, q storage.SearchQuery) ([]model.SearchHit, error)
+	AssetsByURL(ctx context.Context, urls []string) ([]model.Asset, error)
+	AttachedMedia(ctx context.Context, owner model.EntityType, ownerIDs []uuid.UUID) (map[uuid.UUID][]model.AttachedMedia, error)
+}
+
+// MediaResolver resolves links to external media services.
+type MediaResolver interface {
+	Resolve(ctx context.Context, rawURL string) (resolver.Resolved, error)
+}
+
+// MediaProxy serves external media files from the site's origin.
+type MediaProxy interface {
+	OpenPublic(ctx context.Context, rawURL string) (*mediaproxy.File, error)
 }
 
 type Service struct {
-	storage Storage
-	log     *slog.Logger
+	storage   Storage
+	resolvers MediaResolver
+	proxy     MediaProxy
+	log       *slog.Logger
 }
 
-func NewService(storage Storage, log *slog.Logger) *Service {
+func NewService(storage Storage, resolvers MediaResolver, proxy MediaProxy, log *slog.Logger) *Service {
 	return &Service{
-		storage: storage,
-		log:     log,
+		storage:   storage,
+		resolvers: resolvers,
+		proxy:     proxy,
+		log:       log,
 	}
 }
 
-func (s *Service) GetAllNews(ctx context.Context) ([]model.News, error) {
-	return s.storage.GetAllNews(ctx)
+func (s *Service) GetAllNews(ctx context.Context, opts storage.ListOptions) ([]model.News, int, error) {
+	news, total, err := s.storage.GetAllNews(ctx, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.withNewsMedia(ctx, news); err != nil {
+		return nil, 0, err
+	}
+	return news, total, nil
 }
 
 func (s *Service) GetNewsByID(ctx context.Context, id uuid.UUID) (model.News, error) {
-	return s.storage.GetNewsByID(ctx, id)
+	n, err := s.storage.GetNewsByID(ctx, id)
+	if err != nil {
+		return model.News{}, err
+	}
+	news := []model.News{n}
+	if err := s.withNewsMedia(ctx, news); err != nil {
+		return model.News{}, err
+	}
+	return news[0], nil
 }
 
-func (s *Service) GetAllExhibitions(ctx context.Context) ([]model.Exhibition, error) {
-	return s.storage.GetAllExhibitions(ctx)
+func (s *Service) GetAllExhibitions(ctx context.Context, opts storage.ListOptions) ([]model.Exhibition, int, error) {
+	exhibitions, total, err := s.storage.GetAllExhibitions(ctx, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.withExhibitionMedia(ctx, exhibitions); err != nil {
+		return nil, 0, err
+	}
+	return exhibitions, total, nil
 }
 
 func (s *Service) GetExhibitionByID(ctx context.Context, id uuid.UUID) (model.Exhibition, error) {
-	return s.storage.GetExhibitionByID(ctx, id)
+	ex, err := s.storage.GetExhibitionByID(ctx, id)
+	if err != nil {
+		return model.Exhibition{}, err
+	}
+	exhibitions := []model.Exhibition{ex}
+	if err := s.withExhibitionMedia(ctx, exhibitions); err != nil {
+		return model.Exhibition{}, err
+	}
+	return exhibitions[0], nil
 }
 
 func (s *Service) RecordVisit(ctx context.Context, v model.Visitor) error {
 	return s.storage.RecordVisit(ctx, v)
 }
+
+func (s *Service) Search(ctx context.Context, q storage.SearchQuery) ([]model.SearchHit, error) {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Text == "" {
+		return []model.SearchHit{}, nil
+	}
+	return s.storage.SearchContent(ctx, q)
+}
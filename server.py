@@ -19,7 +19,7 @@ BACKEND_URL = 'http://localhost:8080'
 PORT = 5500
 
 # Пути, которые проксируются на бэкенд
-PROXY_PREFIXES = ('/museum/', '/admin/', '/ping')
+PROXY_PREFIXES = ('/museum/', '/admin/', '/media/', '/ping')
 
 
 class ProxyHandler(http.server.SimpleHTTPRequestHandler):
@@ -72,8 +72,10 @@ class ProxyHandler(http.server.SimpleHTTPRequestHandler):
 
         req = urllib.request.Request(url, data=body, method=method)
 
-        # Копируем заголовки
-        for key in ('Content-Type', 'Accept', 'Authorization'):
+        # Копируем заголовки, включая условные и Range: без них бэкенд
+        # не ответит 304 и 206 на запросы к файлам
+        for key in ('Content-Type', 'Accept', 'Authorization', 'If-Match', 'Cookie', 'X-CSRF-Token', 'User-Agent',
+                    'Range', 'If-Range', 'If-None-Match', 'If-Modified-Since'):
             val = self.headers.get(key)
             if val:
                 req.add_header(key, val)